@@ -0,0 +1,283 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package changeset
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// DiffType is the type of a difference between two fields
+type DiffType int
+
+const (
+	// FieldChanged indicates the field has a different value
+	FieldChanged DiffType = iota
+
+	// FieldAdded indicates the field, element or entry is only present in the
+	// new message
+	FieldAdded
+
+	// FieldRemoved indicates the field, element or entry is only present in the
+	// old message
+	FieldRemoved
+)
+
+func (t DiffType) String() string {
+	switch t {
+	case FieldChanged:
+		return "changed"
+	case FieldAdded:
+		return "added"
+	case FieldRemoved:
+		return "removed"
+	default:
+		return "unknown"
+	}
+}
+
+// A FieldDiff describes a single difference between two proto messages
+type FieldDiff struct {
+	// Path is the location of the field, using proto field names.  Repeated
+	// elements are addressed by index and map entries by key, for example
+	// instances[i1].shards[0].state
+	Path string
+
+	// Type is the type of the difference
+	Type DiffType
+
+	// Old is the value in the old message, nil if the field was added
+	Old interface{}
+
+	// New is the value in the new message, nil if the field was removed
+	New interface{}
+}
+
+func (d FieldDiff) String() string {
+	switch d.Type {
+	case FieldAdded:
+		return fmt.Sprintf("+ %s: %s", d.Path, fmtDiffValue(d.New))
+	case FieldRemoved:
+		return fmt.Sprintf("- %s: %s", d.Path, fmtDiffValue(d.Old))
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", d.Path, fmtDiffValue(d.Old), fmtDiffValue(d.New))
+	}
+}
+
+// A ConfigDiff is the set of field level differences between two versions of
+// a configuration, in the order the fields are declared
+type ConfigDiff []FieldDiff
+
+func (d ConfigDiff) String() string {
+	var buf bytes.Buffer
+	for _, fd := range d {
+		buf.WriteString(fd.String())
+		buf.WriteString("\n")
+	}
+
+	return buf.String()
+}
+
+// Diff computes the field level differences between two proto messages of
+// the same type
+func Diff(from, to proto.Message) ConfigDiff {
+	var diffs ConfigDiff
+	diffValues("", reflect.ValueOf(from), reflect.ValueOf(to), &diffs)
+	return diffs
+}
+
+func diffValues(path string, from, to reflect.Value, diffs *ConfigDiff) {
+	switch from.Kind() {
+	case reflect.Ptr:
+		if from.IsNil() && to.IsNil() {
+			return
+		}
+
+		if from.IsNil() {
+			*diffs = append(*diffs, FieldDiff{Path: path, Type: FieldAdded, New: to.Interface()})
+			return
+		}
+
+		if to.IsNil() {
+			*diffs = append(*diffs, FieldDiff{Path: path, Type: FieldRemoved, Old: from.Interface()})
+			return
+		}
+
+		diffValues(path, from.Elem(), to.Elem(), diffs)
+	case reflect.Struct:
+		diffStructs(path, from, to, diffs)
+	case reflect.Slice:
+		if from.Type().Elem().Kind() == reflect.Uint8 {
+			if !bytes.Equal(from.Bytes(), to.Bytes()) {
+				*diffs = append(*diffs, FieldDiff{Path: path, Type: FieldChanged, Old: from.Interface(), New: to.Interface()})
+			}
+			return
+		}
+
+		diffSlices(path, from, to, diffs)
+	case reflect.Map:
+		diffMaps(path, from, to, diffs)
+	default:
+		if from.Interface() != to.Interface() {
+			*diffs = append(*diffs, FieldDiff{Path: path, Type: FieldChanged, Old: from.Interface(), New: to.Interface()})
+		}
+	}
+}
+
+func diffStructs(path string, from, to reflect.Value, diffs *ConfigDiff) {
+	t := from.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if strings.HasPrefix(f.Name, "XXX_") {
+			continue
+		}
+
+		if f.Tag.Get("protobuf_oneof") != "" {
+			diffOneofs(path, from.Field(i), to.Field(i), diffs)
+			continue
+		}
+
+		name := protoFieldName(f)
+		if name == "" {
+			continue
+		}
+
+		diffValues(joinPath(path, name), from.Field(i), to.Field(i), diffs)
+	}
+}
+
+func diffOneofs(path string, from, to reflect.Value, diffs *ConfigDiff) {
+	if from.IsNil() && to.IsNil() {
+		return
+	}
+
+	// Oneof values are wrapped in a single field struct naming the chosen field
+	if !from.IsNil() && !to.IsNil() && from.Elem().Type() == to.Elem().Type() {
+		diffStructs(path, from.Elem().Elem(), to.Elem().Elem(), diffs)
+		return
+	}
+
+	if !from.IsNil() {
+		fromField := from.Elem().Elem()
+		name := protoFieldName(fromField.Type().Field(0))
+		*diffs = append(*diffs, FieldDiff{
+			Path: joinPath(path, name),
+			Type: FieldRemoved,
+			Old:  fromField.Field(0).Interface(),
+		})
+	}
+
+	if !to.IsNil() {
+		toField := to.Elem().Elem()
+		name := protoFieldName(toField.Type().Field(0))
+		*diffs = append(*diffs, FieldDiff{
+			Path: joinPath(path, name),
+			Type: FieldAdded,
+			New:  toField.Field(0).Interface(),
+		})
+	}
+}
+
+func diffSlices(path string, from, to reflect.Value, diffs *ConfigDiff) {
+	for i := 0; i < from.Len() || i < to.Len(); i++ {
+		elemPath := fmt.Sprintf("%s[%d]", path, i)
+		switch {
+		case i >= to.Len():
+			*diffs = append(*diffs, FieldDiff{Path: elemPath, Type: FieldRemoved, Old: from.Index(i).Interface()})
+		case i >= from.Len():
+			*diffs = append(*diffs, FieldDiff{Path: elemPath, Type: FieldAdded, New: to.Index(i).Interface()})
+		default:
+			diffValues(elemPath, from.Index(i), to.Index(i), diffs)
+		}
+	}
+}
+
+func diffMaps(path string, from, to reflect.Value, diffs *ConfigDiff) {
+	keys := make(map[string]reflect.Value, from.Len()+to.Len())
+	for _, k := range from.MapKeys() {
+		keys[fmt.Sprint(k.Interface())] = k
+	}
+	for _, k := range to.MapKeys() {
+		keys[fmt.Sprint(k.Interface())] = k
+	}
+
+	sortedKeys := make([]string, 0, len(keys))
+	for k := range keys {
+		sortedKeys = append(sortedKeys, k)
+	}
+	sort.Strings(sortedKeys)
+
+	for _, k := range sortedKeys {
+		var (
+			key       = keys[k]
+			entryPath = fmt.Sprintf("%s[%s]", path, k)
+			fromEntry = from.MapIndex(key)
+			toEntry   = to.MapIndex(key)
+		)
+
+		switch {
+		case !toEntry.IsValid():
+			*diffs = append(*diffs, FieldDiff{Path: entryPath, Type: FieldRemoved, Old: fromEntry.Interface()})
+		case !fromEntry.IsValid():
+			*diffs = append(*diffs, FieldDiff{Path: entryPath, Type: FieldAdded, New: toEntry.Interface()})
+		default:
+			diffValues(entryPath, fromEntry, toEntry, diffs)
+		}
+	}
+}
+
+// protoFieldName returns the proto name of a generated struct field, or an
+// empty string if the field is not part of the message
+func protoFieldName(f reflect.StructField) string {
+	for _, part := range strings.Split(f.Tag.Get("protobuf"), ",") {
+		if strings.HasPrefix(part, "name=") {
+			return strings.TrimPrefix(part, "name=")
+		}
+	}
+
+	return ""
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
+
+func fmtDiffValue(v interface{}) string {
+	switch v := v.(type) {
+	case proto.Message:
+		return "{" + proto.CompactTextString(v) + "}"
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []byte:
+		return fmt.Sprintf("%q", v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
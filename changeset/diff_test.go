@@ -0,0 +1,103 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package changeset
+
+import (
+	"testing"
+
+	"github.com/m3db/m3cluster/generated/proto/changesettest"
+	"github.com/m3db/m3cluster/generated/proto/placementpb"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffScalars(t *testing.T) {
+	diff := Diff(&changesettest.Config{Text: "foo"}, &changesettest.Config{Text: "bar"})
+	require.Equal(t, ConfigDiff{
+		{Path: "text", Type: FieldChanged, Old: "foo", New: "bar"},
+	}, diff)
+	require.Equal(t, "~ text: \"foo\" -> \"bar\"\n", diff.String())
+
+	require.Empty(t, Diff(&changesettest.Config{Text: "foo"}, &changesettest.Config{Text: "foo"}))
+}
+
+func TestDiffRepeated(t *testing.T) {
+	diff := Diff(
+		&changesettest.Changes{Lines: []string{"a", "b", "c"}},
+		&changesettest.Changes{Lines: []string{"a", "x"}},
+	)
+	require.Equal(t, ConfigDiff{
+		{Path: "lines[1]", Type: FieldChanged, Old: "b", New: "x"},
+		{Path: "lines[2]", Type: FieldRemoved, Old: "c"},
+	}, diff)
+
+	diff = Diff(
+		&changesettest.Changes{Lines: []string{"a"}},
+		&changesettest.Changes{Lines: []string{"a", "b"}},
+	)
+	require.Equal(t, "+ lines[1]: \"b\"\n", diff.String())
+}
+
+func TestDiffNestedMessages(t *testing.T) {
+	from := &placementpb.Placement{
+		Instances: map[string]*placementpb.Instance{
+			"i1": {
+				Id:     "i1",
+				Weight: 1,
+				Shards: []*placementpb.Shard{
+					{Id: 0, State: placementpb.ShardState_INITIALIZING},
+				},
+			},
+			"i2": {Id: "i2"},
+		},
+		ReplicaFactor: 1,
+	}
+	to := &placementpb.Placement{
+		Instances: map[string]*placementpb.Instance{
+			"i1": {
+				Id:     "i1",
+				Weight: 2,
+				Shards: []*placementpb.Shard{
+					{Id: 0, State: placementpb.ShardState_AVAILABLE},
+				},
+			},
+			"i3": {Id: "i3"},
+		},
+		ReplicaFactor: 1,
+	}
+
+	diff := Diff(from, to)
+	require.Equal(t, 4, len(diff))
+	require.Equal(t, FieldDiff{
+		Path: "instances[i1].weight", Type: FieldChanged, Old: uint32(1), New: uint32(2),
+	}, diff[0])
+	require.Equal(t, FieldDiff{
+		Path: "instances[i1].shards[0].state", Type: FieldChanged,
+		Old: placementpb.ShardState_INITIALIZING, New: placementpb.ShardState_AVAILABLE,
+	}, diff[1])
+	require.Equal(t, "instances[i2]", diff[2].Path)
+	require.Equal(t, FieldRemoved, diff[2].Type)
+	require.Equal(t, "instances[i3]", diff[3].Path)
+	require.Equal(t, FieldAdded, diff[3].Type)
+
+	require.Equal(t, "~ instances[i1].weight: 1 -> 2", diff[0].String())
+	require.Equal(t, "~ instances[i1].shards[0].state: INITIALIZING -> AVAILABLE", diff[1].String())
+}
@@ -23,6 +23,7 @@ package changeset
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/m3db/m3cluster/generated/proto/changesetpb"
@@ -47,11 +48,62 @@ var (
 	// a version that doesn't exist
 	ErrUnknownVersion = errors.New("unknown version")
 
+	// ErrNotCommitted is returned when attempting to retrieve the committed
+	// changes for a version that was not produced by a committed ChangeSet
+	ErrNotCommitted = errors.New("change set not committed")
+
+	// ErrApprovalRequired is returned when attempting to commit a ChangeSet
+	// that has not yet reached its approval quorum
+	ErrApprovalRequired = errors.New("change set approval quorum not reached")
+
+	// ErrChangeSetRejected is returned when attempting to commit a ChangeSet
+	// that has been rejected by one of its approvers
+	ErrChangeSetRejected = errors.New("change set rejected")
+
+	// ErrNotApprover is returned when an identity that is not one of the
+	// required approvers attempts to approve or reject a ChangeSet
+	ErrNotApprover = errors.New("not an approver for change set")
+
+	// ErrSelfApproval is returned when one of the authors of a ChangeSet
+	// attempts to approve it
+	ErrSelfApproval = errors.New("change set authors may not approve their own changes")
+
+	// ErrAuthorRequired is returned when attempting to make a change without an
+	// author to a ChangeSet that requires approval
+	ErrAuthorRequired = errors.New("changes requiring approval must have an author")
+
+	// ErrApproverRequired is returned when attempting to approve or reject a
+	// ChangeSet without identifying the approver
+	ErrApproverRequired = errors.New("votes must have an approver")
+
+	// ErrCommitWindowPassed is returned when a scheduled ChangeSet was not
+	// committed before the end of its commit window.  It is returned once, by
+	// the call marking the schedule as expired
+	ErrCommitWindowPassed = errors.New("change set commit window has passed")
+
+	// ErrInvalidCommitWindow is returned when attempting to schedule a commit
+	// with a window that ends before it starts
+	ErrInvalidCommitWindow = errors.New("commit window must end after it starts")
+
+	// ErrPendingChanges is returned when attempting to roll back the
+	// configuration while changes are pending against the current version
+	ErrPendingChanges = errors.New("changes are pending against the current version")
+
+	// ErrRollbackPending is returned when attempting to make changes while a
+	// rollback is pending against the current version
+	ErrRollbackPending = errors.New("a rollback is pending against the current version")
+
+	// ErrVersionNotInHistory is returned when attempting to roll back to a
+	// version that is no longer retained by the store
+	ErrVersionNotInHistory = errors.New("version not found in configuration history")
+
 	errOptsNotSet       = errors.New("opts must not be nil")
 	errKVNotSet         = errors.New("KV must be specified")
 	errConfigKeyNotSet  = errors.New("configKey must be specified")
 	errConfigTypeNotSet = errors.New("configType must be specified")
 	errChangeTypeNotSet = errors.New("changesType must be specified")
+	errMergeFnNotSet    = errors.New("merge function must be specified")
+	errInvalidQuorum    = errors.New("approval quorum must be between 0 and the number of approvers")
 )
 
 // ManagerOptions are options used in creating a new ChangeSet Manager
@@ -80,6 +132,16 @@ type ManagerOptions interface {
 	SetChangesType(changes proto.Message) ManagerOptions
 	ChangesType() proto.Message
 
+	// Approvers are the identities allowed to approve or reject new ChangeSets.
+	// If empty, any identity may approve or reject
+	SetApprovers(approvers []string) ManagerOptions
+	Approvers() []string
+
+	// ApprovalQuorum is the number of approvals new ChangeSets need before they
+	// can be committed.  A quorum of zero disables the approval workflow
+	SetApprovalQuorum(quorum int) ManagerOptions
+	ApprovalQuorum() int
+
 	// Validate validates the options
 	Validate() error
 }
@@ -94,6 +156,139 @@ type ChangeFn func(config, changes proto.Message) error
 // configuration
 type ApplyFn func(config, changes proto.Message) error
 
+// ChangeSetStatus is the status of a ChangeSet
+type ChangeSetStatus int
+
+const (
+	// ChangeSetOpen indicates the ChangeSet is accepting new changes
+	ChangeSetOpen ChangeSetStatus = iota
+
+	// ChangeSetClosed indicates the ChangeSet is being committed, or that a
+	// commit was attempted but did not produce a new configuration version
+	ChangeSetClosed
+
+	// ChangeSetCommitted indicates the ChangeSet has been applied to produce
+	// a new configuration version
+	ChangeSetCommitted
+
+	// ChangeSetStranded indicates the ChangeSet is still open but the
+	// configuration has moved on to a later version without it, typically
+	// because the configuration was modified outside of the Manager
+	ChangeSetStranded
+
+	// ChangeSetRebased indicates the changes in the ChangeSet have been moved
+	// onto a later version of the configuration
+	ChangeSetRebased
+)
+
+func (s ChangeSetStatus) String() string {
+	switch s {
+	case ChangeSetOpen:
+		return "open"
+	case ChangeSetClosed:
+		return "closed"
+	case ChangeSetCommitted:
+		return "committed"
+	case ChangeSetStranded:
+		return "stranded"
+	case ChangeSetRebased:
+		return "rebased"
+	default:
+		return "unknown"
+	}
+}
+
+// ChangeSetInfo describes a ChangeSet stored for a version of the configuration
+type ChangeSetInfo struct {
+	// Key is the key under which the ChangeSet is stored
+	Key string
+
+	// ForVersion is the version of configuration on which the ChangeSet is built
+	ForVersion int
+
+	// Status is the status of the ChangeSet
+	Status ChangeSetStatus
+
+	// Quorum is the number of approvals needed to commit the ChangeSet
+	Quorum int
+
+	// Approvals are the identities which have approved the ChangeSet
+	Approvals []string
+
+	// Rejections are the identities which have rejected the ChangeSet
+	Rejections []string
+
+	// Authors are the identities which made the changes in the ChangeSet
+	Authors []string
+
+	// RebasedTo is the version the changes were moved onto if the ChangeSet
+	// was rebased
+	RebasedTo int
+
+	// CommitNotBefore is the start of the scheduled commit window, zero if the
+	// commit is not scheduled
+	CommitNotBefore time.Time
+
+	// CommitNotAfter is the end of the scheduled commit window, zero if the
+	// window is open ended
+	CommitNotAfter time.Time
+
+	// CommitWindowExpired is true if the scheduled commit window passed without
+	// the ChangeSet being committed
+	CommitWindowExpired bool
+
+	// RollbackTo is the earlier version of the configuration restored by the
+	// ChangeSet, zero if the ChangeSet is not a rollback
+	RollbackTo int
+
+	// CommittedVersion is the version of the configuration produced by
+	// committing the ChangeSet, zero if the ChangeSet has not been committed
+	CommittedVersion int
+}
+
+// A MergeFn merges changes that were built against an earlier version of the
+// configuration (stranded) into the changes pending against the latest
+// version (pending).  It should return an error if the stranded changes
+// conflict with the latest configuration or with the pending changes
+type MergeFn func(config, pending, stranded proto.Message) error
+
+// RebaseConflictError is returned when changes cannot be rebased because the
+// MergeFn reported a conflict
+type RebaseConflictError struct {
+	// FromVersion is the version the stranded changes were built against
+	FromVersion int
+
+	// ToVersion is the version the changes were being rebased onto
+	ToVersion int
+
+	// Err is the conflict reported by the MergeFn
+	Err error
+}
+
+func (e RebaseConflictError) Error() string {
+	return fmt.Sprintf("could not rebase changes from version %d onto version %d: %v",
+		e.FromVersion, e.ToVersion, e.Err)
+}
+
+// A CommitPreview describes the result of committing a ChangeSet, without the
+// result having been stored
+type CommitPreview struct {
+	// Version is the version of the configuration the changes apply to
+	Version int
+
+	// Config is the current configuration
+	Config proto.Message
+
+	// NewConfig is the configuration the changes would produce
+	NewConfig proto.Message
+
+	// Changes are the changes that would be applied
+	Changes proto.Message
+
+	// Diff is the difference between the current and new configurations
+	Diff ConfigDiff
+}
+
 // A Manager manages sets of changes in a version friendly manager.  Changes to
 // a given version of a configuration object are stored under
 // <key>/_changes/<version>.  Multiple changes can be added, then committed all
@@ -103,9 +298,14 @@ type ApplyFn func(config, changes proto.Message) error
 // object itself.
 type Manager interface {
 	// Change creates a new change against the latest configuration, adding it
-	// to the set of pending changes for that configuration
+	// to the set of pending changes for that configuration.  The change has no
+	// author, so it is rejected if the ChangeSet requires approval
 	Change(change ChangeFn) error
 
+	// ChangeAs creates a new change on behalf of the author, who is then not
+	// allowed to approve the pending changes
+	ChangeAs(author string, change ChangeFn) error
+
 	// GetPendingChanges gets the latest uncommitted changes
 	GetPendingChanges() (int, proto.Message, proto.Message, error)
 
@@ -113,8 +313,66 @@ type Manager interface {
 	// which they are based into a new configuration, and storing that new
 	// configuration as a next versions. Ensures that changes are applied as a
 	// batch, are not applied more than once, and that new changes are not
-	// started while a commit is underway
+	// started while a commit is underway.  If the ChangeSet requires approval,
+	// the commit is rejected until the approval quorum has been reached
 	Commit(version int, apply ApplyFn) error
+
+	// Approve records an approval of the pending changes for the specified
+	// version.  Approvals are cleared whenever new changes are added, and the
+	// authors of the changes cannot approve them
+	Approve(version int, approver string) error
+
+	// Reject records a rejection of the pending changes for the specified
+	// version, blocking the commit until new changes are added
+	Reject(version int, approver string) error
+
+	// Abort discards the pending changes or rollback for the specified version,
+	// leaving an empty open ChangeSet in their place.  Changes that are already
+	// being committed cannot be aborted
+	Abort(version int) error
+
+	// ChangeSets lists the ChangeSets stored against each version of the
+	// configuration, in version order
+	ChangeSets() ([]ChangeSetInfo, error)
+
+	// GetCommittedChanges returns the changes that were committed to produce the
+	// specified version of the configuration
+	GetCommittedChanges(version int) (proto.Message, error)
+
+	// Rebase moves the changes pending against the specified version onto the
+	// latest version of the configuration, using the MergeFn to combine them
+	// with any changes already pending there.  A stranded rollback is carried
+	// over as is, provided nothing is pending against the latest version.
+	// Returns the version the changes now apply to, or a RebaseConflictError
+	// if they could not be merged
+	Rebase(version int, merge MergeFn) (int, error)
+
+	// Preview applies the changes pending against the specified version to a
+	// copy of the configuration without storing anything, returning the
+	// configuration a Commit would produce and how it differs from the current
+	// configuration
+	Preview(version int, apply ApplyFn) (CommitPreview, error)
+
+	// Schedule arranges for the changes pending against the specified version
+	// to be committed by a Scheduler once the time is within the window
+	// [notBefore, notAfter).  A zero notAfter leaves the window open ended, and
+	// zero notBefore and notAfter remove the schedule
+	Schedule(version int, notBefore, notAfter time.Time) error
+
+	// CommitIfDue commits the changes pending against the latest version of the
+	// configuration if they are scheduled and now is within their commit
+	// window.  Changes that have not yet reached their approval quorum are not
+	// due.  Returns true if this call committed the changes
+	CommitIfDue(now time.Time, apply ApplyFn) (bool, error)
+
+	// Rollback records a rollback of the configuration to an earlier version,
+	// on behalf of the author, as the ChangeSet pending against the current
+	// version.  Like any other ChangeSet it is applied by Commit once it has
+	// reached its approval quorum, which stores the earlier configuration as a
+	// new version so the history of the configuration stays linear.  Rollbacks
+	// are rejected while changes are pending against the current version.
+	// Returns the version the rollback is pending against
+	Rollback(toVersion int, author string) (int, error)
 }
 
 // NewManager creates a new change list Manager
@@ -137,6 +395,8 @@ func NewManager(opts ManagerOptions) (Manager, error) {
 		kv:          opts.KV(),
 		configType:  proto.Clone(opts.ConfigType()),
 		changesType: proto.Clone(opts.ChangesType()),
+		approvers:   opts.Approvers(),
+		quorum:      opts.ApprovalQuorum(),
 		log:         logger,
 	}, nil
 }
@@ -146,10 +406,16 @@ type manager struct {
 	kv          kv.Store
 	configType  proto.Message
 	changesType proto.Message
+	approvers   []string
+	quorum      int
 	log         log.Logger
 }
 
 func (m manager) Change(change ChangeFn) error {
+	return m.ChangeAs("", change)
+}
+
+func (m manager) ChangeAs(author string, change ChangeFn) error {
 	for {
 		// Retrieve the current configuration, creating an empty config if one does
 		// not exist
@@ -161,10 +427,7 @@ func (m manager) Change(change ChangeFn) error {
 
 		// Retrieve the changes for the current configuration, creating an empty
 		// change set if one does not exist
-		changeset := &changesetpb.ChangeSet{
-			ForVersion: int32(configVersion),
-			State:      changesetpb.ChangeSetState_OPEN,
-		}
+		changeset := m.newChangeSet(configVersion)
 
 		changeSetKey := fmtChangeSetKey(m.key, configVersion)
 		csVersion, err := m.getOrCreate(changeSetKey, changeset)
@@ -177,6 +440,18 @@ func (m manager) Change(change ChangeFn) error {
 			return ErrChangeSetClosed
 		}
 
+		// Approval is only meaningful if the authors are known, so they can't
+		// approve their own changes
+		if author == "" && changeset.Quorum > 0 {
+			return ErrAuthorRequired
+		}
+
+		// A pending rollback replaces the configuration, so changes recorded
+		// alongside it would be lost
+		if changeset.RollbackTo != 0 {
+			return ErrRollbackPending
+		}
+
 		// Apply the new changes...
 		changes := proto.Clone(m.changesType)
 		if err := proto.Unmarshal(changeset.Changes, changes); err != nil {
@@ -192,8 +467,12 @@ func (m manager) Change(change ChangeFn) error {
 			return err
 		}
 
-		// ...and update the stored changes
+		// ...and update the stored changes. Any prior votes were cast on the old
+		// changes, so they no longer apply
 		changeset.Changes = changeBytes
+		changeset.Approvals = nil
+		changeset.Rejections = nil
+		changeset.Authors = addAuthor(changeset.Authors, author)
 		if _, err := m.kv.CheckAndSet(changeSetKey, csVersion, changeset); err != nil {
 			if err == kv.ErrVersionMismatch {
 				// Someone else updated the changes first - try again
@@ -283,11 +562,18 @@ func (m manager) Commit(version int, apply ApplyFn) error {
 		return err
 	}
 
-	// If the change set is not already CLOSED, mark it as such to prevent new
-	// changes from being recorded while the commit is underway
+	// If the change set is not already CLOSED, make sure it has been approved
+	// and mark it as such to prevent new changes from being recorded while the
+	// commit is underway.  The CAS guarantees no votes or changes were recorded
+	// since the approvals were checked
+	changeSetVersion := changeSetVal.Version()
 	if changeset.State != changesetpb.ChangeSetState_CLOSED {
+		if err := checkApprovals(&changeset); err != nil {
+			return err
+		}
+
 		changeset.State = changesetpb.ChangeSetState_CLOSED
-		if _, err := m.kv.CheckAndSet(changeSetKey, changeSetVal.Version(), &changeset); err != nil {
+		if changeSetVersion, err = m.kv.CheckAndSet(changeSetKey, changeSetVersion, &changeset); err != nil {
 			if err == kv.ErrVersionMismatch {
 				return ErrCommitInProgress
 			}
@@ -296,27 +582,738 @@ func (m manager) Commit(version int, apply ApplyFn) error {
 		}
 	}
 
-	// Transform the current configuration according to the change list
-	changes := proto.Clone(m.changesType)
-	if err := proto.Unmarshal(changeset.Changes, changes); err != nil {
-		return err
-	}
+	// Transform the current configuration according to the change list, or
+	// restore the earlier configuration if this is a rollback
+	if changeset.RollbackTo != 0 {
+		if config, err = m.historicalConfig(int(changeset.RollbackTo)); err != nil {
+			return err
+		}
+	} else {
+		changes := proto.Clone(m.changesType)
+		if err := proto.Unmarshal(changeset.Changes, changes); err != nil {
+			return err
+		}
 
-	if err := apply(config, changes); err != nil {
-		return err
+		if err := apply(config, changes); err != nil {
+			return err
+		}
 	}
 
 	// Save the updated config.  This updates the version number for the config, so
 	// attempting to commit the current version again will fail
-	if _, err := m.kv.CheckAndSet(m.key, configVal.Version(), config); err != nil {
+	_, err = m.storeCommit(changeSetKey, changeSetVersion, &changeset, configVal.Version(), config)
+	return err
+}
+
+// storeCommit stores the configuration produced by a closed ChangeSet as the
+// next version, and records that version in the ChangeSet.  Stores supporting
+// transactions write both together; otherwise the ChangeSet is updated once the
+// configuration has been stored.  Returns the new configuration version
+func (m manager) storeCommit(
+	changeSetKey string,
+	changeSetVersion int,
+	changeset *changesetpb.ChangeSet,
+	configVersion int,
+	config proto.Message,
+) (int, error) {
+	changeset.CommittedVersion = int32(configVersion + 1)
+
+	if txn, ok := m.kv.(kv.TxnStore); ok {
+		_, err := txn.Commit(
+			[]kv.Condition{
+				newVersionCondition(m.key, configVersion),
+				newVersionCondition(changeSetKey, changeSetVersion),
+			},
+			[]kv.Op{
+				kv.NewSetOp(m.key, config),
+				kv.NewSetOp(changeSetKey, changeset),
+			},
+		)
+		if err != nil {
+			if err == kv.ErrConditionCheckFailed {
+				return 0, ErrAlreadyCommitted
+			}
+
+			return 0, err
+		}
+
+		return configVersion + 1, nil
+	}
+
+	newVersion, err := m.kv.CheckAndSet(m.key, configVersion, config)
+	if err != nil {
 		if err == kv.ErrVersionMismatch {
+			return 0, ErrAlreadyCommitted
+		}
+
+		return 0, err
+	}
+
+	// The configuration is committed at this point, so failing to record the
+	// version only leaves the ChangeSet reporting as closed
+	changeset.CommittedVersion = int32(newVersion)
+	if _, err := m.kv.CheckAndSet(changeSetKey, changeSetVersion, changeset); err != nil {
+		m.log.Errorf("could not record commit of change set %s as version %d: %v",
+			changeSetKey, newVersion, err)
+	}
+
+	return newVersion, nil
+}
+
+func (m manager) Rollback(toVersion int, author string) (int, error) {
+	for {
+		configVal, err := m.kv.Get(m.key)
+		if err != nil {
+			return 0, err
+		}
+
+		configVersion := configVal.Version()
+		if toVersion < 1 || toVersion >= configVersion {
+			return 0, ErrUnknownVersion
+		}
+
+		// Make sure the old configuration is still available before recording
+		// the rollback, otherwise it could never be committed
+		if _, err := m.historicalConfig(toVersion); err != nil {
+			return 0, err
+		}
+
+		changeSetKey := fmtChangeSetKey(m.key, configVersion)
+		changeSetVal, err := m.kv.Get(changeSetKey)
+		if err != nil && err != kv.ErrNotFound {
+			return 0, err
+		}
+
+		exists := err == nil
+		if exists {
+			var existing changesetpb.ChangeSet
+			if err := changeSetVal.Unmarshal(&existing); err != nil {
+				return 0, err
+			}
+
+			if existing.State != changesetpb.ChangeSetState_OPEN {
+				return 0, ErrChangeSetClosed
+			}
+
+			if len(existing.Changes) != 0 {
+				return 0, ErrPendingChanges
+			}
+		}
+
+		// The rollback replaces any earlier votes and is subject to the
+		// current approval policy, the same as any other change
+		changeset := m.newChangeSet(configVersion)
+		if author == "" && changeset.Quorum > 0 {
+			return 0, ErrAuthorRequired
+		}
+
+		changeset.RollbackTo = int32(toVersion)
+		changeset.Authors = addAuthor(nil, author)
+
+		if exists {
+			_, err = m.kv.CheckAndSet(changeSetKey, changeSetVal.Version(), changeset)
+		} else {
+			_, err = m.kv.SetIfNotExists(changeSetKey, changeset)
+		}
+
+		if err != nil {
+			if err == kv.ErrAlreadyExists || err == kv.ErrVersionMismatch {
+				// Someone else updated the change set first - try again
+				continue
+			}
+
+			return 0, err
+		}
+
+		return configVersion, nil
+	}
+}
+
+// historicalConfig returns the configuration as of the specified version
+func (m manager) historicalConfig(version int) (proto.Message, error) {
+	vals, err := m.kv.History(m.key, version, version+1)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(vals) == 0 || vals[0].Version() != version {
+		return nil, ErrVersionNotInHistory
+	}
+
+	config := proto.Clone(m.configType)
+	if err := vals[0].Unmarshal(config); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+func (m manager) Preview(version int, apply ApplyFn) (CommitPreview, error) {
+	configVal, err := m.kv.Get(m.key)
+	if err != nil {
+		return CommitPreview{}, err
+	}
+
+	if configVal.Version() < version {
+		return CommitPreview{}, ErrUnknownVersion
+	}
+
+	if configVal.Version() > version {
+		return CommitPreview{}, ErrAlreadyCommitted
+	}
+
+	config := proto.Clone(m.configType)
+	if err := configVal.Unmarshal(config); err != nil {
+		return CommitPreview{}, err
+	}
+
+	// No change set just means there is nothing pending, so preview an empty
+	// set of changes
+	changes := proto.Clone(m.changesType)
+	changeSetVal, err := m.kv.Get(fmtChangeSetKey(m.key, version))
+	if err != nil && err != kv.ErrNotFound {
+		return CommitPreview{}, err
+	}
+
+	var rollbackTo int
+	if err == nil {
+		var changeset changesetpb.ChangeSet
+		if err := changeSetVal.Unmarshal(&changeset); err != nil {
+			return CommitPreview{}, err
+		}
+
+		if err := proto.Unmarshal(changeset.Changes, changes); err != nil {
+			return CommitPreview{}, err
+		}
+
+		rollbackTo = int(changeset.RollbackTo)
+	}
+
+	// A rollback restores the earlier configuration rather than applying changes
+	var newConfig proto.Message
+	if rollbackTo != 0 {
+		if newConfig, err = m.historicalConfig(rollbackTo); err != nil {
+			return CommitPreview{}, err
+		}
+	} else {
+		newConfig = proto.Clone(config)
+		if err := apply(newConfig, changes); err != nil {
+			return CommitPreview{}, err
+		}
+	}
+
+	return CommitPreview{
+		Version:   version,
+		Config:    config,
+		NewConfig: newConfig,
+		Changes:   changes,
+		Diff:      Diff(config, newConfig),
+	}, nil
+}
+
+func (m manager) Schedule(version int, notBefore, notAfter time.Time) error {
+	if !notAfter.IsZero() && !notAfter.After(notBefore) {
+		return ErrInvalidCommitWindow
+	}
+
+	var schedule *changesetpb.CommitSchedule
+	if !notBefore.IsZero() || !notAfter.IsZero() {
+		schedule = &changesetpb.CommitSchedule{
+			NotBeforeNanos: toNanos(notBefore),
+			NotAfterNanos:  toNanos(notAfter),
+		}
+	}
+
+	for {
+		configVal, err := m.kv.Get(m.key)
+		if err != nil {
+			return err
+		}
+
+		if configVal.Version() < version {
+			return ErrUnknownVersion
+		}
+
+		if configVal.Version() > version {
 			return ErrAlreadyCommitted
 		}
 
-		return err
+		changeSetKey := fmtChangeSetKey(m.key, version)
+		changeSetVal, err := m.kv.Get(changeSetKey)
+		if err != nil {
+			return err
+		}
+
+		var changeset changesetpb.ChangeSet
+		if err := changeSetVal.Unmarshal(&changeset); err != nil {
+			return err
+		}
+
+		if changeset.State != changesetpb.ChangeSetState_OPEN {
+			return ErrChangeSetClosed
+		}
+
+		changeset.Schedule = schedule
+		if _, err := m.kv.CheckAndSet(changeSetKey, changeSetVal.Version(), &changeset); err != nil {
+			if err == kv.ErrVersionMismatch {
+				// Someone else updated the change set first - try again
+				continue
+			}
+
+			return err
+		}
+
+		return nil
 	}
+}
 
-	return nil
+func (m manager) CommitIfDue(now time.Time, apply ApplyFn) (bool, error) {
+	configVal, err := m.kv.Get(m.key)
+	if err != nil {
+		if err == kv.ErrNotFound {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	changeSetKey := fmtChangeSetKey(m.key, configVal.Version())
+	changeSetVal, err := m.kv.Get(changeSetKey)
+	if err != nil {
+		if err == kv.ErrNotFound {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	var changeset changesetpb.ChangeSet
+	if err := changeSetVal.Unmarshal(&changeset); err != nil {
+		return false, err
+	}
+
+	schedule := changeset.Schedule
+	if schedule == nil || changeset.State == changesetpb.ChangeSetState_REBASED {
+		return false, nil
+	}
+
+	// A CLOSED change set is a commit interrupted part way through, which is
+	// completed whether or not the window has since passed
+	if changeset.State != changesetpb.ChangeSetState_CLOSED {
+		if schedule.Expired || now.Before(fromNanos(schedule.NotBeforeNanos)) {
+			return false, nil
+		}
+
+		if schedule.NotAfterNanos != 0 && !now.Before(fromNanos(schedule.NotAfterNanos)) {
+			// Only report the passed window once, the CAS ensures a single
+			// caller marks the schedule as expired
+			schedule.Expired = true
+			if _, err := m.kv.CheckAndSet(changeSetKey, changeSetVal.Version(), &changeset); err != nil {
+				if err == kv.ErrVersionMismatch {
+					return false, nil
+				}
+
+				return false, err
+			}
+
+			return false, ErrCommitWindowPassed
+		}
+
+		// The changes become due once they are approved, which may be well
+		// after the window opens
+		if err := checkApprovals(&changeset); err == ErrApprovalRequired {
+			return false, nil
+		}
+	}
+
+	// The commit is guarded by CAS on the config version, so if someone else
+	// committed the changes first this is a no-op
+	if err := m.Commit(configVal.Version(), apply); err != nil {
+		if err == ErrAlreadyCommitted || err == ErrCommitInProgress {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (m manager) Abort(version int) error {
+	for {
+		configVal, err := m.kv.Get(m.key)
+		if err != nil {
+			return err
+		}
+
+		if configVal.Version() < version {
+			return ErrUnknownVersion
+		}
+
+		if configVal.Version() > version {
+			return ErrAlreadyCommitted
+		}
+
+		changeSetKey := fmtChangeSetKey(m.key, version)
+		changeSetVal, err := m.kv.Get(changeSetKey)
+		if err != nil {
+			return err
+		}
+
+		var changeset changesetpb.ChangeSet
+		if err := changeSetVal.Unmarshal(&changeset); err != nil {
+			return err
+		}
+
+		// Once a commit has started the changes belong to it
+		if changeset.State != changesetpb.ChangeSetState_OPEN {
+			return ErrChangeSetClosed
+		}
+
+		changeset.Changes = nil
+		changeset.Approvals = nil
+		changeset.Rejections = nil
+		changeset.Authors = nil
+		changeset.RollbackTo = 0
+		if _, err := m.kv.CheckAndSet(changeSetKey, changeSetVal.Version(), &changeset); err != nil {
+			if err == kv.ErrVersionMismatch {
+				// The change set was updated underneath us - try again
+				continue
+			}
+
+			return err
+		}
+
+		return nil
+	}
+}
+
+func (m manager) ChangeSets() ([]ChangeSetInfo, error) {
+	configVal, err := m.kv.Get(m.key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Change sets are keyed by config version, so walk each version looking for
+	// the ones that had changes recorded against them
+	var infos []ChangeSetInfo
+	for version := 1; version <= configVal.Version(); version++ {
+		changeSetKey := fmtChangeSetKey(m.key, version)
+		changeSetVal, err := m.kv.Get(changeSetKey)
+		if err != nil {
+			if err == kv.ErrNotFound {
+				continue
+			}
+
+			return nil, err
+		}
+
+		var changeset changesetpb.ChangeSet
+		if err := changeSetVal.Unmarshal(&changeset); err != nil {
+			return nil, err
+		}
+
+		info := ChangeSetInfo{
+			Key:              changeSetKey,
+			ForVersion:       version,
+			Status:           changeSetStatus(&changeset, configVal.Version()),
+			Quorum:           int(changeset.Quorum),
+			Approvals:        changeset.Approvals,
+			Rejections:       changeset.Rejections,
+			Authors:          changeset.Authors,
+			RebasedTo:        int(changeset.RebasedTo),
+			RollbackTo:       int(changeset.RollbackTo),
+			CommittedVersion: int(changeset.CommittedVersion),
+		}
+		if schedule := changeset.Schedule; schedule != nil {
+			info.CommitNotBefore = fromNanos(schedule.NotBeforeNanos)
+			info.CommitNotAfter = fromNanos(schedule.NotAfterNanos)
+			info.CommitWindowExpired = schedule.Expired
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+func (m manager) GetCommittedChanges(version int) (proto.Message, error) {
+	configVal, err := m.kv.Get(m.key)
+	if err != nil {
+		return nil, err
+	}
+
+	if version <= 1 || configVal.Version() < version {
+		return nil, ErrUnknownVersion
+	}
+
+	// The changes producing a version are stored against the version before it
+	changeSetVal, err := m.kv.Get(fmtChangeSetKey(m.key, version-1))
+	if err != nil {
+		if err == kv.ErrNotFound {
+			return nil, ErrNotCommitted
+		}
+
+		return nil, err
+	}
+
+	var changeset changesetpb.ChangeSet
+	if err := changeSetVal.Unmarshal(&changeset); err != nil {
+		return nil, err
+	}
+
+	// The version may have been written outside of the Manager, in which case
+	// the change set never produced it
+	if int(changeset.CommittedVersion) != version {
+		return nil, ErrNotCommitted
+	}
+
+	changes := proto.Clone(m.changesType)
+	if err := proto.Unmarshal(changeset.Changes, changes); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+func (m manager) Approve(version int, approver string) error {
+	return m.vote(version, approver, true)
+}
+
+func (m manager) Reject(version int, approver string) error {
+	return m.vote(version, approver, false)
+}
+
+func (m manager) vote(version int, approver string, approve bool) error {
+	// Anonymous votes would count towards the quorum without any way of
+	// telling who cast them
+	if approver == "" {
+		return ErrApproverRequired
+	}
+
+	for {
+		configVal, err := m.kv.Get(m.key)
+		if err != nil {
+			return err
+		}
+
+		if configVal.Version() < version {
+			return ErrUnknownVersion
+		}
+
+		if configVal.Version() > version {
+			return ErrAlreadyCommitted
+		}
+
+		changeSetKey := fmtChangeSetKey(m.key, version)
+		changeSetVal, err := m.kv.Get(changeSetKey)
+		if err != nil {
+			return err
+		}
+
+		var changeset changesetpb.ChangeSet
+		if err := changeSetVal.Unmarshal(&changeset); err != nil {
+			return err
+		}
+
+		// Votes can only be cast while the changes can still be committed
+		if changeset.State != changesetpb.ChangeSetState_OPEN {
+			return ErrChangeSetClosed
+		}
+
+		if len(changeset.RequiredApprovers) != 0 && !contains(changeset.RequiredApprovers, approver) {
+			return ErrNotApprover
+		}
+
+		// Authors may still reject their own changes, blocking the commit
+		if approve && contains(changeset.Authors, approver) {
+			return ErrSelfApproval
+		}
+
+		// Only the most recent vote from an approver counts
+		changeset.Approvals = remove(changeset.Approvals, approver)
+		changeset.Rejections = remove(changeset.Rejections, approver)
+		if approve {
+			changeset.Approvals = append(changeset.Approvals, approver)
+		} else {
+			changeset.Rejections = append(changeset.Rejections, approver)
+		}
+
+		if _, err := m.kv.CheckAndSet(changeSetKey, changeSetVal.Version(), &changeset); err != nil {
+			if err == kv.ErrVersionMismatch {
+				// Someone else updated the change set first - try again
+				continue
+			}
+
+			return err
+		}
+
+		return nil
+	}
+}
+
+func (m manager) Rebase(version int, merge MergeFn) (int, error) {
+	if merge == nil {
+		return 0, errMergeFnNotSet
+	}
+
+	configVal, err := m.kv.Get(m.key)
+	if err != nil {
+		return 0, err
+	}
+
+	if configVal.Version() < version {
+		return 0, ErrUnknownVersion
+	}
+
+	if configVal.Version() == version {
+		// Already based on the latest config, nothing to do
+		return version, nil
+	}
+
+	fromKey := fmtChangeSetKey(m.key, version)
+	fromVal, err := m.kv.Get(fromKey)
+	if err != nil {
+		return 0, err
+	}
+
+	var from changesetpb.ChangeSet
+	if err := fromVal.Unmarshal(&from); err != nil {
+		return 0, err
+	}
+
+	switch changeSetStatus(&from, configVal.Version()) {
+	case ChangeSetStranded:
+	case ChangeSetRebased:
+		return 0, ErrChangeSetClosed
+	default:
+		return 0, ErrAlreadyCommitted
+	}
+
+	stranded := proto.Clone(m.changesType)
+	if err := proto.Unmarshal(from.Changes, stranded); err != nil {
+		return 0, err
+	}
+
+	// Claim the stranded change set so it can't be rebased twice
+	from.State = changesetpb.ChangeSetState_REBASED
+	fromVersion, err := m.kv.CheckAndSet(fromKey, fromVal.Version(), &from)
+	if err != nil {
+		if err == kv.ErrVersionMismatch {
+			return 0, ErrChangeSetClosed
+		}
+
+		return 0, err
+	}
+
+	toVersion, err := m.mergeInto(version, stranded, int(from.RollbackTo), from.Authors, merge)
+	if err != nil {
+		// Release the stranded change set so the rebase can be retried
+		from.State = changesetpb.ChangeSetState_OPEN
+		if _, rerr := m.kv.CheckAndSet(fromKey, fromVersion, &from); rerr != nil {
+			m.log.Errorf("could not reopen change set %s after failed rebase: %v", fromKey, rerr)
+		}
+
+		return 0, err
+	}
+
+	// Record where the changes went
+	from.RebasedTo = int32(toVersion)
+	if _, err := m.kv.CheckAndSet(fromKey, fromVersion, &from); err != nil {
+		return 0, err
+	}
+
+	return toVersion, nil
+}
+
+// mergeInto merges stranded changes, or a stranded rollback, into the change
+// set for the latest version of the configuration, returning that version
+func (m manager) mergeInto(
+	fromVersion int,
+	stranded proto.Message,
+	rollbackTo int,
+	authors []string,
+	merge MergeFn,
+) (int, error) {
+	for {
+		config := proto.Clone(m.configType)
+		configVersion, err := m.getOrCreate(m.key, config)
+		if err != nil {
+			return 0, err
+		}
+
+		changeset := m.newChangeSet(configVersion)
+
+		changeSetKey := fmtChangeSetKey(m.key, configVersion)
+		csVersion, err := m.getOrCreate(changeSetKey, changeset)
+		if err != nil {
+			return 0, err
+		}
+
+		if changeset.State != changesetpb.ChangeSetState_OPEN {
+			return 0, ErrChangeSetClosed
+		}
+
+		// The same rules apply as to ChangeAs and Rollback, changes cannot be
+		// recorded alongside a rollback
+		if changeset.RollbackTo != 0 {
+			return 0, ErrRollbackPending
+		}
+
+		if rollbackTo != 0 {
+			if len(changeset.Changes) != 0 {
+				return 0, ErrPendingChanges
+			}
+
+			changeset.RollbackTo = int32(rollbackTo)
+		} else {
+			pending := proto.Clone(m.changesType)
+			if err := proto.Unmarshal(changeset.Changes, pending); err != nil {
+				return 0, err
+			}
+
+			if err := merge(config, pending, stranded); err != nil {
+				return 0, RebaseConflictError{
+					FromVersion: fromVersion,
+					ToVersion:   configVersion,
+					Err:         err,
+				}
+			}
+
+			changeBytes, err := proto.Marshal(pending)
+			if err != nil {
+				return 0, err
+			}
+
+			changeset.Changes = changeBytes
+		}
+
+		changeset.Approvals = nil
+		changeset.Rejections = nil
+		for _, author := range authors {
+			changeset.Authors = addAuthor(changeset.Authors, author)
+		}
+		if _, err := m.kv.CheckAndSet(changeSetKey, csVersion, changeset); err != nil {
+			if err == kv.ErrVersionMismatch {
+				// Someone else updated the changes first - try again
+				continue
+			}
+
+			return 0, err
+		}
+
+		return configVersion, nil
+	}
+}
+
+// newChangeSet creates an empty open ChangeSet for the given config version
+func (m manager) newChangeSet(configVersion int) *changesetpb.ChangeSet {
+	return &changesetpb.ChangeSet{
+		ForVersion:        int32(configVersion),
+		State:             changesetpb.ChangeSetState_OPEN,
+		RequiredApprovers: m.approvers,
+		Quorum:            int32(m.quorum),
+	}
 }
 
 func (m manager) getOrCreate(k string, v proto.Message) (int, error) {
@@ -351,6 +1348,96 @@ func (m manager) getOrCreate(k string, v proto.Message) (int, error) {
 	}
 }
 
+// changeSetStatus determines the status of a ChangeSet given the latest
+// version of the configuration it applies to
+func changeSetStatus(changeset *changesetpb.ChangeSet, configVersion int) ChangeSetStatus {
+	switch changeset.State {
+	case changesetpb.ChangeSetState_OPEN:
+		if int(changeset.ForVersion) < configVersion {
+			return ChangeSetStranded
+		}
+
+		return ChangeSetOpen
+	case changesetpb.ChangeSetState_REBASED:
+		return ChangeSetRebased
+	}
+
+	if changeset.CommittedVersion != 0 {
+		return ChangeSetCommitted
+	}
+
+	return ChangeSetClosed
+}
+
+// checkApprovals confirms a ChangeSet has been approved by enough approvers
+// and has not been rejected by any
+func checkApprovals(changeset *changesetpb.ChangeSet) error {
+	if len(changeset.Rejections) != 0 {
+		return ErrChangeSetRejected
+	}
+
+	if len(changeset.Approvals) < int(changeset.Quorum) {
+		return ErrApprovalRequired
+	}
+
+	return nil
+}
+
+func newVersionCondition(key string, version int) kv.Condition {
+	return kv.NewCondition().
+		SetKey(key).
+		SetTargetType(kv.TargetVersion).
+		SetCompareType(kv.CompareEqual).
+		SetValue(version)
+}
+
+func contains(ids []string, id string) bool {
+	for _, existing := range ids {
+		if existing == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// addAuthor adds an author to the authors of a ChangeSet, ignoring changes
+// without an author
+func addAuthor(authors []string, author string) []string {
+	if author == "" || contains(authors, author) {
+		return authors
+	}
+
+	return append(authors, author)
+}
+
+func remove(ids []string, id string) []string {
+	var res []string
+	for _, existing := range ids {
+		if existing != id {
+			res = append(res, existing)
+		}
+	}
+
+	return res
+}
+
+func toNanos(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.UnixNano()
+}
+
+func fromNanos(nanos int64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, nanos)
+}
+
 func fmtChangeSetKey(configKey string, configVers int) string {
 	return fmt.Sprintf("%s/_changes/%d", configKey, configVers)
 }
@@ -361,6 +1448,8 @@ type managerOptions struct {
 	configKey   string
 	configType  proto.Message
 	changesType proto.Message
+	approvers   []string
+	quorum      int
 }
 
 func (opts managerOptions) KV() kv.Store               { return opts.kv }
@@ -368,6 +1457,8 @@ func (opts managerOptions) Logger() log.Logger         { return opts.logger }
 func (opts managerOptions) ConfigKey() string          { return opts.configKey }
 func (opts managerOptions) ConfigType() proto.Message  { return opts.configType }
 func (opts managerOptions) ChangesType() proto.Message { return opts.changesType }
+func (opts managerOptions) Approvers() []string        { return opts.approvers }
+func (opts managerOptions) ApprovalQuorum() int        { return opts.quorum }
 
 func (opts managerOptions) SetKV(kv kv.Store) ManagerOptions {
 	opts.kv = kv
@@ -389,6 +1480,14 @@ func (opts managerOptions) SetChangesType(ct proto.Message) ManagerOptions {
 	opts.changesType = ct
 	return opts
 }
+func (opts managerOptions) SetApprovers(approvers []string) ManagerOptions {
+	opts.approvers = approvers
+	return opts
+}
+func (opts managerOptions) SetApprovalQuorum(quorum int) ManagerOptions {
+	opts.quorum = quorum
+	return opts
+}
 
 func (opts managerOptions) Validate() error {
 	if opts.ConfigKey() == "" {
@@ -407,5 +1506,13 @@ func (opts managerOptions) Validate() error {
 		return errChangeTypeNotSet
 	}
 
+	if opts.ApprovalQuorum() < 0 {
+		return errInvalidQuorum
+	}
+
+	if len(opts.Approvers()) != 0 && opts.ApprovalQuorum() > len(opts.Approvers()) {
+		return errInvalidQuorum
+	}
+
 	return nil
 }
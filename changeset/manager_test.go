@@ -24,6 +24,7 @@ import (
 	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/golang/protobuf/proto"
@@ -282,6 +283,7 @@ func TestManagerCommit_Success(t *testing.T) {
 
 	var (
 		changeSet1 = new(changeSetMatcher)
+		changeSet2 = new(changeSetMatcher)
 		config1    = new(configMatcher)
 
 		committedVersion = 22
@@ -309,6 +311,10 @@ func TestManagerCommit_Success(t *testing.T) {
 		// Update the transformed confi
 		s.kv.EXPECT().CheckAndSet(s.configKey, committedVersion, config1).
 			Return(committedVersion+1, nil),
+
+		// Record the version produced by the commit
+		s.kv.EXPECT().CheckAndSet(changeSetKey, changeSetVersion+1, changeSet2).
+			Return(changeSetVersion+2, nil),
 	)
 
 	err := s.mgr.Commit(committedVersion, commit)
@@ -316,6 +322,7 @@ func TestManagerCommit_Success(t *testing.T) {
 
 	require.Equal(t, changesetpb.ChangeSetState_CLOSED, changeSet1.changeset().State)
 	require.Equal(t, "shoop\nwoop\nhoop\nfoo\nbar", config1.config().Text)
+	require.Equal(t, int32(committedVersion+1), changeSet2.changeset().CommittedVersion)
 }
 
 func TestManagerCommit_ConfigNotFound(t *testing.T) {
@@ -434,6 +441,10 @@ func TestManagerCommit_ChangeSetClosing(t *testing.T) {
 		// Update the transformed config
 		s.kv.EXPECT().CheckAndSet(s.configKey, committedVersion, config1).
 			Return(committedVersion+1, nil),
+
+		// The config is committed even if the version can't be recorded
+		s.kv.EXPECT().CheckAndSet(changeSetKey, changeSetVersion, gomock.Any()).
+			Return(0, errBadThingsHappened),
 	)
 
 	err := s.mgr.Commit(committedVersion, commit)
@@ -741,6 +752,687 @@ func TestManager_GetPendingChangesChangeUnmarshalError(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestManager_AbortSuccess(t *testing.T) {
+	s := newTestSuite(t)
+	defer s.finish()
+
+	var (
+		changeSet1 = new(changeSetMatcher)
+
+		configVersion    = 13
+		changeSetVersion = 24
+		changeSetKey     = fmtChangeSetKey(s.configKey, configVersion)
+	)
+
+	gomock.InOrder(
+		s.kv.EXPECT().Get(s.configKey).Return(mem.NewValue(configVersion, &changesettest.Config{}), nil),
+		s.kv.EXPECT().Get(changeSetKey).Return(mem.NewValue(changeSetVersion,
+			s.newOpenChangeSet(configVersion, &changesettest.Changes{
+				Lines: []string{"foo", "bar"},
+			})), nil),
+		s.kv.EXPECT().CheckAndSet(changeSetKey, changeSetVersion, changeSet1).
+			Return(changeSetVersion+1, nil),
+	)
+
+	require.NoError(t, s.mgr.Abort(configVersion))
+	require.Equal(t, int32(configVersion), changeSet1.changeset().ForVersion)
+	require.Equal(t, changesetpb.ChangeSetState_OPEN, changeSet1.changeset().State)
+	require.Nil(t, changeSet1.changeset().Changes)
+}
+
+func TestManager_AbortVersionMismatchUpdatingChangeSet(t *testing.T) {
+	s := newTestSuite(t)
+	defer s.finish()
+
+	var (
+		configVersion = 13
+		changeSetKey  = fmtChangeSetKey(s.configKey, configVersion)
+	)
+
+	gomock.InOrder(
+		s.kv.EXPECT().Get(s.configKey).Return(mem.NewValue(configVersion, &changesettest.Config{}), nil),
+		s.kv.EXPECT().Get(changeSetKey).Return(mem.NewValue(24,
+			s.newOpenChangeSet(configVersion, &changesettest.Changes{})), nil),
+		s.kv.EXPECT().CheckAndSet(changeSetKey, 24, gomock.Any()).Return(0, kv.ErrVersionMismatch),
+
+		// Will refetch and try again
+		s.kv.EXPECT().Get(s.configKey).Return(mem.NewValue(configVersion, &changesettest.Config{}), nil),
+		s.kv.EXPECT().Get(changeSetKey).Return(mem.NewValue(25,
+			s.newOpenChangeSet(configVersion, &changesettest.Changes{})), nil),
+		s.kv.EXPECT().CheckAndSet(changeSetKey, 25, gomock.Any()).Return(26, nil),
+	)
+
+	require.NoError(t, s.mgr.Abort(configVersion))
+}
+
+func TestManager_AbortClosedChangeSet(t *testing.T) {
+	s := newTestSuite(t)
+	defer s.finish()
+
+	gomock.InOrder(
+		s.kv.EXPECT().Get(s.configKey).Return(mem.NewValue(13, &changesettest.Config{}), nil),
+		s.kv.EXPECT().Get(fmtChangeSetKey(s.configKey, 13)).Return(mem.NewValue(24,
+			s.newChangeSet(13, changesetpb.ChangeSetState_CLOSED, &changesettest.Changes{})), nil),
+	)
+
+	require.Equal(t, ErrChangeSetClosed, s.mgr.Abort(13))
+}
+
+func TestManager_AbortWrongVersion(t *testing.T) {
+	s := newTestSuite(t)
+	defer s.finish()
+
+	s.kv.EXPECT().Get(s.configKey).Return(mem.NewValue(13, &changesettest.Config{}), nil).Times(2)
+
+	require.Equal(t, ErrUnknownVersion, s.mgr.Abort(14))
+	require.Equal(t, ErrAlreadyCommitted, s.mgr.Abort(12))
+}
+
+func TestManager_AbortGetChangeSetError(t *testing.T) {
+	s := newTestSuite(t)
+	defer s.finish()
+
+	gomock.InOrder(
+		s.kv.EXPECT().Get(s.configKey).Return(mem.NewValue(13, &changesettest.Config{}), nil),
+		s.kv.EXPECT().Get(fmtChangeSetKey(s.configKey, 13)).Return(nil, errBadThingsHappened),
+	)
+
+	require.Equal(t, errBadThingsHappened, s.mgr.Abort(13))
+}
+
+func TestManager_ChangeSets(t *testing.T) {
+	s := newTestSuite(t)
+	defer s.finish()
+
+	gomock.InOrder(
+		s.kv.EXPECT().Get(s.configKey).Return(mem.NewValue(5, &changesettest.Config{}), nil),
+		s.kv.EXPECT().Get(fmtChangeSetKey(s.configKey, 1)).Return(mem.NewValue(3,
+			s.newCommittedChangeSet(1, &changesettest.Changes{})), nil),
+		s.kv.EXPECT().Get(fmtChangeSetKey(s.configKey, 2)).Return(nil, kv.ErrNotFound),
+		s.kv.EXPECT().Get(fmtChangeSetKey(s.configKey, 3)).Return(mem.NewValue(2,
+			s.newOpenChangeSet(3, &changesettest.Changes{})), nil),
+
+		// Closed, but the config was then written outside of the Manager
+		s.kv.EXPECT().Get(fmtChangeSetKey(s.configKey, 4)).Return(mem.NewValue(5,
+			s.newChangeSet(4, changesetpb.ChangeSetState_CLOSED, &changesettest.Changes{})), nil),
+		s.kv.EXPECT().Get(fmtChangeSetKey(s.configKey, 5)).Return(mem.NewValue(5,
+			s.newChangeSet(5, changesetpb.ChangeSetState_CLOSED, &changesettest.Changes{})), nil),
+	)
+
+	infos, err := s.mgr.ChangeSets()
+	require.NoError(t, err)
+	require.Equal(t, []ChangeSetInfo{
+		{Key: "config/_changes/1", ForVersion: 1, Status: ChangeSetCommitted, CommittedVersion: 2},
+		{Key: "config/_changes/3", ForVersion: 3, Status: ChangeSetStranded},
+		{Key: "config/_changes/4", ForVersion: 4, Status: ChangeSetClosed},
+		{Key: "config/_changes/5", ForVersion: 5, Status: ChangeSetClosed},
+	}, infos)
+}
+
+func TestManager_ChangeSetsGetChangeSetError(t *testing.T) {
+	s := newTestSuite(t)
+	defer s.finish()
+
+	gomock.InOrder(
+		s.kv.EXPECT().Get(s.configKey).Return(mem.NewValue(2, &changesettest.Config{}), nil),
+		s.kv.EXPECT().Get(fmtChangeSetKey(s.configKey, 1)).Return(nil, errBadThingsHappened),
+	)
+
+	_, err := s.mgr.ChangeSets()
+	require.Equal(t, errBadThingsHappened, err)
+}
+
+func TestManager_GetCommittedChangesSuccess(t *testing.T) {
+	s := newTestSuite(t)
+	defer s.finish()
+
+	changes := &changesettest.Changes{
+		Lines: []string{"zed", "brack"},
+	}
+
+	gomock.InOrder(
+		s.kv.EXPECT().Get(s.configKey).Return(mem.NewValue(14, &changesettest.Config{}), nil),
+		s.kv.EXPECT().Get(fmtChangeSetKey(s.configKey, 12)).Return(mem.NewValue(7,
+			s.newCommittedChangeSet(12, changes)), nil),
+	)
+
+	returnedChanges, err := s.mgr.GetCommittedChanges(13)
+	require.NoError(t, err)
+	require.Equal(t, *changes, *(returnedChanges.(*changesettest.Changes)))
+}
+
+func TestManager_GetCommittedChangesNotCommitted(t *testing.T) {
+	s := newTestSuite(t)
+	defer s.finish()
+
+	gomock.InOrder(
+		// Change set still open
+		s.kv.EXPECT().Get(s.configKey).Return(mem.NewValue(14, &changesettest.Config{}), nil),
+		s.kv.EXPECT().Get(fmtChangeSetKey(s.configKey, 12)).Return(mem.NewValue(7,
+			s.newOpenChangeSet(12, &changesettest.Changes{})), nil),
+
+		// Config updated outside of a change set
+		s.kv.EXPECT().Get(s.configKey).Return(mem.NewValue(14, &changesettest.Config{}), nil),
+		s.kv.EXPECT().Get(fmtChangeSetKey(s.configKey, 12)).Return(nil, kv.ErrNotFound),
+
+		// Change set closed, but its commit failed and the config was updated
+		// outside of the change set
+		s.kv.EXPECT().Get(s.configKey).Return(mem.NewValue(14, &changesettest.Config{}), nil),
+		s.kv.EXPECT().Get(fmtChangeSetKey(s.configKey, 12)).Return(mem.NewValue(7,
+			s.newChangeSet(12, changesetpb.ChangeSetState_CLOSED, &changesettest.Changes{})), nil),
+	)
+
+	_, err := s.mgr.GetCommittedChanges(13)
+	require.Equal(t, ErrNotCommitted, err)
+
+	_, err = s.mgr.GetCommittedChanges(13)
+	require.Equal(t, ErrNotCommitted, err)
+
+	_, err = s.mgr.GetCommittedChanges(13)
+	require.Equal(t, ErrNotCommitted, err)
+}
+
+func TestManager_GetCommittedChangesUnknownVersion(t *testing.T) {
+	s := newTestSuite(t)
+	defer s.finish()
+
+	s.kv.EXPECT().Get(s.configKey).Return(mem.NewValue(14, &changesettest.Config{}), nil).Times(2)
+
+	_, err := s.mgr.GetCommittedChanges(15)
+	require.Equal(t, ErrUnknownVersion, err)
+
+	_, err = s.mgr.GetCommittedChanges(1)
+	require.Equal(t, ErrUnknownVersion, err)
+}
+
+func TestManager_CommittedVersionRecorded(t *testing.T) {
+	store := mem.NewStore()
+	mgr := newMemManager(t, store)
+
+	require.NoError(t, mgr.Change(addLines("foo")))
+	require.NoError(t, mgr.Commit(1, commit))
+
+	// A commit whose apply fails leaves the change set closed, even once the
+	// config is written outside of the Manager
+	require.NoError(t, mgr.Change(addLines("bar")))
+	require.Equal(t, errBadThingsHappened, mgr.Commit(2, func(proto.Message, proto.Message) error {
+		return errBadThingsHappened
+	}))
+	_, err := store.Set("config", &changesettest.Config{Text: "external"})
+	require.NoError(t, err)
+
+	infos, err := mgr.ChangeSets()
+	require.NoError(t, err)
+	require.Equal(t, []ChangeSetInfo{
+		{Key: "config/_changes/1", ForVersion: 1, Status: ChangeSetCommitted, CommittedVersion: 2},
+		{Key: "config/_changes/2", ForVersion: 2, Status: ChangeSetClosed},
+	}, infos)
+
+	changes, err := mgr.GetCommittedChanges(2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"foo"}, changes.(*changesettest.Changes).Lines)
+
+	_, err = mgr.GetCommittedChanges(3)
+	require.Equal(t, ErrNotCommitted, err)
+}
+
+func TestManager_ChangeRecordsApprovalPolicy(t *testing.T) {
+	s := newTestSuiteWithOptions(t, NewManagerOptions().
+		SetApprovers([]string{"alice", "bob"}).
+		SetApprovalQuorum(2))
+	defer s.finish()
+
+	var (
+		changes1 = new(changeSetMatcher)
+		changes2 = new(changeSetMatcher)
+	)
+
+	gomock.InOrder(
+		s.kv.EXPECT().Get("config").Return(mem.NewValue(3, &changesettest.Config{}), nil),
+		s.kv.EXPECT().Get("config/_changes/3").Return(nil, kv.ErrNotFound),
+		s.kv.EXPECT().SetIfNotExists("config/_changes/3", changes1).Return(1, nil),
+		s.kv.EXPECT().CheckAndSet("config/_changes/3", 1, gomock.Any()).Return(2, nil),
+
+		// Existing votes are discarded when the changes are updated
+		s.kv.EXPECT().Get("config").Return(mem.NewValue(3, &changesettest.Config{}), nil),
+		s.kv.EXPECT().Get("config/_changes/3").Return(mem.NewValue(2, &changesetpb.ChangeSet{
+			ForVersion:        3,
+			State:             changesetpb.ChangeSetState_OPEN,
+			RequiredApprovers: []string{"alice", "bob"},
+			Quorum:            2,
+			Approvals:         []string{"alice"},
+			Rejections:        []string{"bob"},
+		}), nil),
+		s.kv.EXPECT().CheckAndSet("config/_changes/3", 2, changes2).Return(3, nil),
+	)
+
+	require.NoError(t, s.mgr.ChangeAs("carol", addLines("foo")))
+	require.Equal(t, []string{"alice", "bob"}, changes1.changeset().RequiredApprovers)
+	require.Equal(t, int32(2), changes1.changeset().Quorum)
+
+	require.NoError(t, s.mgr.ChangeAs("carol", addLines("bar")))
+	require.Equal(t, []string{"alice", "bob"}, changes2.changeset().RequiredApprovers)
+	require.Equal(t, int32(2), changes2.changeset().Quorum)
+	require.Nil(t, changes2.changeset().Approvals)
+	require.Nil(t, changes2.changeset().Rejections)
+	require.Equal(t, []string{"carol"}, changes2.changeset().Authors)
+}
+
+func TestManager_AuthorCannotApprove(t *testing.T) {
+	store := mem.NewStore()
+	mgr, err := NewManager(NewManagerOptions().
+		SetKV(store).
+		SetConfigType(&changesettest.Config{}).
+		SetChangesType(&changesettest.Changes{}).
+		SetConfigKey("config").
+		SetApprovers([]string{"alice", "bob"}).
+		SetApprovalQuorum(1))
+	require.NoError(t, err)
+
+	// Changes requiring approval must be attributed
+	require.Equal(t, ErrAuthorRequired, mgr.Change(addLines("foo")))
+
+	require.NoError(t, mgr.ChangeAs("alice", addLines("foo")))
+	require.Equal(t, ErrSelfApproval, mgr.Approve(1, "alice"))
+	require.Equal(t, ErrApprovalRequired, mgr.Commit(1, commit))
+
+	// Authors can still block their own changes
+	require.NoError(t, mgr.Reject(1, "alice"))
+	require.NoError(t, mgr.ChangeAs("bob", addLines("bar")))
+	require.Equal(t, ErrSelfApproval, mgr.Approve(1, "bob"))
+
+	infos, err := mgr.ChangeSets()
+	require.NoError(t, err)
+	require.Equal(t, []string{"alice", "bob"}, infos[0].Authors)
+
+	// Aborting discards the authors along with the changes
+	require.NoError(t, mgr.Abort(1))
+	require.NoError(t, mgr.ChangeAs("bob", addLines("baz")))
+	require.NoError(t, mgr.Approve(1, "alice"))
+	require.NoError(t, mgr.Commit(1, commit))
+	requireConfigText(t, store, 2, "baz")
+}
+
+func TestManager_ApproveSuccess(t *testing.T) {
+	s := newTestSuite(t)
+	defer s.finish()
+
+	var (
+		changeSet1   = new(changeSetMatcher)
+		changeSetKey = fmtChangeSetKey(s.configKey, 13)
+	)
+
+	gomock.InOrder(
+		s.kv.EXPECT().Get(s.configKey).Return(mem.NewValue(13, &changesettest.Config{}), nil),
+		s.kv.EXPECT().Get(changeSetKey).Return(mem.NewValue(24, &changesetpb.ChangeSet{
+			ForVersion:        13,
+			State:             changesetpb.ChangeSetState_OPEN,
+			RequiredApprovers: []string{"alice", "bob"},
+			Quorum:            1,
+			Rejections:        []string{"alice"},
+		}), nil),
+		s.kv.EXPECT().CheckAndSet(changeSetKey, 24, changeSet1).Return(25, nil),
+	)
+
+	require.NoError(t, s.mgr.Approve(13, "alice"))
+	require.Equal(t, []string{"alice"}, changeSet1.changeset().Approvals)
+	require.Nil(t, changeSet1.changeset().Rejections)
+}
+
+func TestManager_RejectVersionMismatchUpdatingChangeSet(t *testing.T) {
+	s := newTestSuite(t)
+	defer s.finish()
+
+	var (
+		changeSet1   = new(changeSetMatcher)
+		changeSetKey = fmtChangeSetKey(s.configKey, 13)
+	)
+
+	gomock.InOrder(
+		s.kv.EXPECT().Get(s.configKey).Return(mem.NewValue(13, &changesettest.Config{}), nil),
+		s.kv.EXPECT().Get(changeSetKey).Return(mem.NewValue(24, &changesetpb.ChangeSet{
+			ForVersion: 13,
+			State:      changesetpb.ChangeSetState_OPEN,
+			Quorum:     1,
+		}), nil),
+		s.kv.EXPECT().CheckAndSet(changeSetKey, 24, gomock.Any()).Return(0, kv.ErrVersionMismatch),
+
+		// Will refetch and vote again
+		s.kv.EXPECT().Get(s.configKey).Return(mem.NewValue(13, &changesettest.Config{}), nil),
+		s.kv.EXPECT().Get(changeSetKey).Return(mem.NewValue(25, &changesetpb.ChangeSet{
+			ForVersion: 13,
+			State:      changesetpb.ChangeSetState_OPEN,
+			Quorum:     1,
+			Approvals:  []string{"alice", "bob"},
+		}), nil),
+		s.kv.EXPECT().CheckAndSet(changeSetKey, 25, changeSet1).Return(26, nil),
+	)
+
+	require.NoError(t, s.mgr.Reject(13, "bob"))
+	require.Equal(t, []string{"alice"}, changeSet1.changeset().Approvals)
+	require.Equal(t, []string{"bob"}, changeSet1.changeset().Rejections)
+}
+
+func TestManager_ApproveNotApprover(t *testing.T) {
+	s := newTestSuite(t)
+	defer s.finish()
+
+	gomock.InOrder(
+		s.kv.EXPECT().Get(s.configKey).Return(mem.NewValue(13, &changesettest.Config{}), nil),
+		s.kv.EXPECT().Get(fmtChangeSetKey(s.configKey, 13)).Return(mem.NewValue(24, &changesetpb.ChangeSet{
+			ForVersion:        13,
+			State:             changesetpb.ChangeSetState_OPEN,
+			RequiredApprovers: []string{"alice", "bob"},
+			Quorum:            1,
+		}), nil),
+	)
+
+	require.Equal(t, ErrNotApprover, s.mgr.Approve(13, "mallory"))
+}
+
+func TestManager_ApproveAnonymous(t *testing.T) {
+	s := newTestSuite(t)
+	defer s.finish()
+
+	require.Equal(t, ErrApproverRequired, s.mgr.Approve(13, ""))
+	require.Equal(t, ErrApproverRequired, s.mgr.Reject(13, ""))
+}
+
+func TestManager_ApproveClosedChangeSet(t *testing.T) {
+	s := newTestSuite(t)
+	defer s.finish()
+
+	gomock.InOrder(
+		s.kv.EXPECT().Get(s.configKey).Return(mem.NewValue(13, &changesettest.Config{}), nil),
+		s.kv.EXPECT().Get(fmtChangeSetKey(s.configKey, 13)).Return(mem.NewValue(24,
+			s.newChangeSet(13, changesetpb.ChangeSetState_CLOSED, &changesettest.Changes{})), nil),
+	)
+
+	require.Equal(t, ErrChangeSetClosed, s.mgr.Approve(13, "alice"))
+}
+
+func TestManager_ApproveWrongVersion(t *testing.T) {
+	s := newTestSuite(t)
+	defer s.finish()
+
+	s.kv.EXPECT().Get(s.configKey).Return(mem.NewValue(13, &changesettest.Config{}), nil).Times(2)
+
+	require.Equal(t, ErrUnknownVersion, s.mgr.Approve(14, "alice"))
+	require.Equal(t, ErrAlreadyCommitted, s.mgr.Reject(12, "alice"))
+}
+
+func TestManagerCommit_ApprovalRequired(t *testing.T) {
+	s := newTestSuite(t)
+	defer s.finish()
+
+	changeSet := s.newOpenChangeSet(13, &changesettest.Changes{Lines: []string{"foo"}})
+	changeSet.Quorum = 2
+	changeSet.Approvals = []string{"alice"}
+
+	gomock.InOrder(
+		s.kv.EXPECT().Get(s.configKey).Return(mem.NewValue(13, &changesettest.Config{}), nil),
+		s.kv.EXPECT().Get(fmtChangeSetKey(s.configKey, 13)).Return(mem.NewValue(24, changeSet), nil),
+	)
+
+	require.Equal(t, ErrApprovalRequired, s.mgr.Commit(13, commit))
+}
+
+func TestManagerCommit_Rejected(t *testing.T) {
+	s := newTestSuite(t)
+	defer s.finish()
+
+	changeSet := s.newOpenChangeSet(13, &changesettest.Changes{Lines: []string{"foo"}})
+	changeSet.Quorum = 1
+	changeSet.Approvals = []string{"alice"}
+	changeSet.Rejections = []string{"bob"}
+
+	gomock.InOrder(
+		s.kv.EXPECT().Get(s.configKey).Return(mem.NewValue(13, &changesettest.Config{}), nil),
+		s.kv.EXPECT().Get(fmtChangeSetKey(s.configKey, 13)).Return(mem.NewValue(24, changeSet), nil),
+	)
+
+	require.Equal(t, ErrChangeSetRejected, s.mgr.Commit(13, commit))
+}
+
+func TestManagerCommit_Approved(t *testing.T) {
+	s := newTestSuite(t)
+	defer s.finish()
+
+	var (
+		changeSet1   = new(changeSetMatcher)
+		config1      = new(configMatcher)
+		changeSetKey = fmtChangeSetKey(s.configKey, 13)
+	)
+
+	changeSet := s.newOpenChangeSet(13, &changesettest.Changes{Lines: []string{"foo"}})
+	changeSet.Quorum = 2
+	changeSet.Approvals = []string{"alice", "bob"}
+
+	gomock.InOrder(
+		s.kv.EXPECT().Get(s.configKey).Return(mem.NewValue(13, &changesettest.Config{}), nil),
+		s.kv.EXPECT().Get(changeSetKey).Return(mem.NewValue(24, changeSet), nil),
+		s.kv.EXPECT().CheckAndSet(changeSetKey, 24, changeSet1).Return(25, nil),
+		s.kv.EXPECT().CheckAndSet(s.configKey, 13, config1).Return(14, nil),
+		s.kv.EXPECT().CheckAndSet(changeSetKey, 25, gomock.Any()).Return(26, nil),
+	)
+
+	require.NoError(t, s.mgr.Commit(13, commit))
+	require.Equal(t, changesetpb.ChangeSetState_CLOSED, changeSet1.changeset().State)
+	require.Equal(t, []string{"alice", "bob"}, changeSet1.changeset().Approvals)
+	require.Equal(t, "foo", config1.config().Text)
+}
+
+func TestManager_RebaseStrandedChanges(t *testing.T) {
+	store := mem.NewStore()
+	mgr := newMemManager(t, store)
+
+	// Record some changes, then update the config behind the manager's back
+	require.NoError(t, mgr.ChangeAs("alice", addLines("foo")))
+	_, err := store.Set("config", &changesettest.Config{Text: "external"})
+	require.NoError(t, err)
+	require.NoError(t, mgr.ChangeAs("bob", addLines("bar")))
+
+	infos, err := mgr.ChangeSets()
+	require.NoError(t, err)
+	require.Equal(t, []ChangeSetInfo{
+		{Key: "config/_changes/1", ForVersion: 1, Status: ChangeSetStranded, Authors: []string{"alice"}},
+		{Key: "config/_changes/2", ForVersion: 2, Status: ChangeSetOpen, Authors: []string{"bob"}},
+	}, infos)
+
+	version, err := mgr.Rebase(1, mergeLines)
+	require.NoError(t, err)
+	require.Equal(t, 2, version)
+
+	vers, _, changes, err := mgr.GetPendingChanges()
+	require.NoError(t, err)
+	require.Equal(t, 2, vers)
+	require.Equal(t, []string{"bar", "foo"}, changes.(*changesettest.Changes).Lines)
+
+	infos, err = mgr.ChangeSets()
+	require.NoError(t, err)
+	require.Equal(t, []ChangeSetInfo{
+		{Key: "config/_changes/1", ForVersion: 1, Status: ChangeSetRebased, RebasedTo: 2, Authors: []string{"alice"}},
+		{Key: "config/_changes/2", ForVersion: 2, Status: ChangeSetOpen, Authors: []string{"bob", "alice"}},
+	}, infos)
+
+	// Can't rebase the same changes twice
+	_, err = mgr.Rebase(1, mergeLines)
+	require.Equal(t, ErrChangeSetClosed, err)
+
+	require.NoError(t, mgr.Commit(2, commit))
+	config, err := store.Get("config")
+	require.NoError(t, err)
+
+	var cfg changesettest.Config
+	require.NoError(t, config.Unmarshal(&cfg))
+	require.Equal(t, "external\nbar\nfoo", cfg.Text)
+}
+
+func TestManager_RebaseConflict(t *testing.T) {
+	store := mem.NewStore()
+	mgr := newMemManager(t, store)
+
+	require.NoError(t, mgr.Change(addLines("foo")))
+	_, err := store.Set("config", &changesettest.Config{Text: "foo"})
+	require.NoError(t, err)
+
+	_, err = mgr.Rebase(1, func(cfgProto, pendingProto, strandedProto proto.Message) error {
+		return errBadThingsHappened
+	})
+	require.Equal(t, RebaseConflictError{
+		FromVersion: 1,
+		ToVersion:   2,
+		Err:         errBadThingsHappened,
+	}, err)
+
+	// The stranded changes are left in place so the rebase can be retried
+	infos, err := mgr.ChangeSets()
+	require.NoError(t, err)
+	require.Equal(t, ChangeSetStranded, infos[0].Status)
+
+	version, err := mgr.Rebase(1, mergeLines)
+	require.NoError(t, err)
+	require.Equal(t, 2, version)
+}
+
+func TestManager_RebaseStrandedRollback(t *testing.T) {
+	store := mem.NewStore()
+	mgr := newMemManager(t, store)
+
+	require.NoError(t, mgr.Change(addLines("foo")))
+	require.NoError(t, mgr.Commit(1, commit))
+	require.NoError(t, mgr.Change(addLines("bar")))
+	require.NoError(t, mgr.Commit(2, commit))
+
+	// Strand a rollback, then add changes against the latest version
+	_, err := mgr.Rollback(2, "alice")
+	require.NoError(t, err)
+	_, err = store.Set("config", &changesettest.Config{Text: "foo\nbar\nexternal"})
+	require.NoError(t, err)
+	require.NoError(t, mgr.ChangeAs("bob", addLines("baz")))
+
+	// The rollback can't be recorded alongside the pending changes
+	_, err = mgr.Rebase(3, mergeLines)
+	require.Equal(t, ErrPendingChanges, err)
+
+	infos, err := mgr.ChangeSets()
+	require.NoError(t, err)
+	require.Equal(t, ChangeSetStranded, infos[2].Status)
+
+	require.NoError(t, mgr.Abort(4))
+	version, err := mgr.Rebase(3, mergeLines)
+	require.NoError(t, err)
+	require.Equal(t, 4, version)
+
+	infos, err = mgr.ChangeSets()
+	require.NoError(t, err)
+	require.Equal(t, ChangeSetInfo{
+		Key:        "config/_changes/4",
+		ForVersion: 4,
+		Status:     ChangeSetOpen,
+		RollbackTo: 2,
+		Authors:    []string{"alice"},
+	}, infos[3])
+
+	require.NoError(t, mgr.Commit(4, commit))
+	requireConfigText(t, store, 5, "foo")
+}
+
+func TestManager_RebaseOntoPendingRollback(t *testing.T) {
+	store := mem.NewStore()
+	mgr := newMemManager(t, store)
+
+	require.NoError(t, mgr.Change(addLines("foo")))
+	require.NoError(t, mgr.Commit(1, commit))
+	require.NoError(t, mgr.Change(addLines("bar")))
+	_, err := store.Set("config", &changesettest.Config{Text: "external"})
+	require.NoError(t, err)
+	_, err = mgr.Rollback(2, "alice")
+	require.NoError(t, err)
+
+	_, err = mgr.Rebase(2, mergeLines)
+	require.Equal(t, ErrRollbackPending, err)
+
+	// The stranded changes are left in place and the rollback is untouched
+	infos, err := mgr.ChangeSets()
+	require.NoError(t, err)
+	require.Equal(t, ChangeSetStranded, infos[1].Status)
+	require.Equal(t, ChangeSetInfo{
+		Key:        "config/_changes/3",
+		ForVersion: 3,
+		Status:     ChangeSetOpen,
+		RollbackTo: 2,
+		Authors:    []string{"alice"},
+	}, infos[2])
+}
+
+func TestManager_RebaseNotStranded(t *testing.T) {
+	store := mem.NewStore()
+	mgr := newMemManager(t, store)
+
+	require.NoError(t, mgr.Change(addLines("foo")))
+
+	// Already on the latest version
+	version, err := mgr.Rebase(1, mergeLines)
+	require.NoError(t, err)
+	require.Equal(t, 1, version)
+
+	_, err = mgr.Rebase(2, mergeLines)
+	require.Equal(t, ErrUnknownVersion, err)
+
+	// Committed changes can't be rebased
+	require.NoError(t, mgr.Commit(1, commit))
+	_, err = mgr.Rebase(1, mergeLines)
+	require.Equal(t, ErrAlreadyCommitted, err)
+}
+
+func TestManager_Preview(t *testing.T) {
+	store := mem.NewStore()
+	mgr := newMemManager(t, store)
+
+	require.NoError(t, mgr.Change(addLines("foo", "bar")))
+	require.NoError(t, mgr.Commit(1, commit))
+	require.NoError(t, mgr.Change(addLines("baz")))
+
+	preview, err := mgr.Preview(2, commit)
+	require.NoError(t, err)
+	require.Equal(t, 2, preview.Version)
+	require.Equal(t, "foo\nbar", preview.Config.(*changesettest.Config).Text)
+	require.Equal(t, "foo\nbar\nbaz", preview.NewConfig.(*changesettest.Config).Text)
+	require.Equal(t, []string{"baz"}, preview.Changes.(*changesettest.Changes).Lines)
+	require.Equal(t, ConfigDiff{
+		{Path: "text", Type: FieldChanged, Old: "foo\nbar", New: "foo\nbar\nbaz"},
+	}, preview.Diff)
+
+	// Nothing is stored by the preview
+	vers, config, changes, err := mgr.GetPendingChanges()
+	require.NoError(t, err)
+	require.Equal(t, 2, vers)
+	require.Equal(t, "foo\nbar", config.(*changesettest.Config).Text)
+	require.Equal(t, []string{"baz"}, changes.(*changesettest.Changes).Lines)
+
+	_, err = mgr.Preview(1, commit)
+	require.Equal(t, ErrAlreadyCommitted, err)
+
+	_, err = mgr.Preview(3, commit)
+	require.Equal(t, ErrUnknownVersion, err)
+}
+
+func TestManager_PreviewApplyFunctionFails(t *testing.T) {
+	s := newTestSuite(t)
+	defer s.finish()
+
+	gomock.InOrder(
+		s.kv.EXPECT().Get(s.configKey).Return(mem.NewValue(13, &changesettest.Config{}), nil),
+		s.kv.EXPECT().Get(fmtChangeSetKey(s.configKey, 13)).Return(nil, kv.ErrNotFound),
+	)
+
+	_, err := s.mgr.Preview(13, func(cfg, changes proto.Message) error {
+		return errBadThingsHappened
+	})
+	require.Equal(t, errBadThingsHappened, err)
+}
+
 func TestManagerOptions_Validate(t *testing.T) {
 	tests := []struct {
 		err  error
@@ -765,6 +1457,28 @@ func TestManagerOptions_Validate(t *testing.T) {
 			SetConfigKey("muzzle").
 			SetConfigType(&changesettest.Config{}).
 			SetChangesType(&changesettest.Changes{})},
+
+		{errInvalidQuorum, NewManagerOptions().
+			SetConfigKey("puzzle").
+			SetConfigType(&changesettest.Config{}).
+			SetChangesType(&changesettest.Changes{}).
+			SetKV(mem.NewStore()).
+			SetApprovalQuorum(-1)},
+
+		{errInvalidQuorum, NewManagerOptions().
+			SetConfigKey("guzzle").
+			SetConfigType(&changesettest.Config{}).
+			SetChangesType(&changesettest.Changes{}).
+			SetKV(mem.NewStore()).
+			SetApprovers([]string{"alice"}).
+			SetApprovalQuorum(2)},
+
+		{nil, NewManagerOptions().
+			SetConfigKey("nozzle").
+			SetConfigType(&changesettest.Config{}).
+			SetChangesType(&changesettest.Changes{}).
+			SetKV(mem.NewStore()).
+			SetApprovalQuorum(2)},
 	}
 
 	for _, test := range tests {
@@ -794,6 +1508,288 @@ func (m *changeSetMatcher) changes(t *testing.T) *changesettest.Changes {
 	return changes
 }
 
+func TestManager_ScheduledCommit(t *testing.T) {
+	store := mem.NewStore()
+	mgr := newMemManager(t, store)
+
+	require.NoError(t, mgr.Change(addLines("foo")))
+
+	// Nothing is due until a schedule is set
+	now := time.Unix(1000, 0)
+	committed, err := mgr.CommitIfDue(now, commit)
+	require.NoError(t, err)
+	require.False(t, committed)
+
+	notBefore, notAfter := now.Add(time.Minute), now.Add(time.Hour)
+	require.NoError(t, mgr.Schedule(1, notBefore, notAfter))
+
+	infos, err := mgr.ChangeSets()
+	require.NoError(t, err)
+	require.Equal(t, notBefore, infos[0].CommitNotBefore)
+	require.Equal(t, notAfter, infos[0].CommitNotAfter)
+
+	// Not yet in the window
+	committed, err = mgr.CommitIfDue(now, commit)
+	require.NoError(t, err)
+	require.False(t, committed)
+
+	committed, err = mgr.CommitIfDue(notBefore, commit)
+	require.NoError(t, err)
+	require.True(t, committed)
+
+	var cfg changesettest.Config
+	cfgVal, err := store.Get("config")
+	require.NoError(t, err)
+	require.NoError(t, cfgVal.Unmarshal(&cfg))
+	require.Equal(t, "foo", cfg.Text)
+
+	// Already committed, so a second check is a no-op
+	committed, err = mgr.CommitIfDue(notBefore, commit)
+	require.NoError(t, err)
+	require.False(t, committed)
+}
+
+func TestManager_ScheduledCommitAwaitsApproval(t *testing.T) {
+	store := mem.NewStore()
+	mgr, err := NewManager(NewManagerOptions().
+		SetKV(store).
+		SetConfigType(&changesettest.Config{}).
+		SetChangesType(&changesettest.Changes{}).
+		SetConfigKey("config").
+		SetApprovers([]string{"alice", "bob"}).
+		SetApprovalQuorum(1))
+	require.NoError(t, err)
+
+	now := time.Unix(1000, 0)
+	require.NoError(t, mgr.ChangeAs("alice", addLines("foo")))
+	require.NoError(t, mgr.Schedule(1, now, time.Time{}))
+
+	// Changes are not due until they have been approved
+	committed, err := mgr.CommitIfDue(now, commit)
+	require.NoError(t, err)
+	require.False(t, committed)
+
+	require.NoError(t, mgr.Approve(1, "bob"))
+	committed, err = mgr.CommitIfDue(now, commit)
+	require.NoError(t, err)
+	require.True(t, committed)
+	requireConfigText(t, store, 2, "foo")
+}
+
+func TestManager_ScheduledCommitWindowPassed(t *testing.T) {
+	store := mem.NewStore()
+	mgr := newMemManager(t, store)
+
+	now := time.Unix(1000, 0)
+	require.NoError(t, mgr.Change(addLines("foo")))
+	require.NoError(t, mgr.Schedule(1, now, now.Add(time.Minute)))
+
+	committed, err := mgr.CommitIfDue(now.Add(time.Minute), commit)
+	require.Equal(t, ErrCommitWindowPassed, err)
+	require.False(t, committed)
+
+	// The passed window is only reported once
+	committed, err = mgr.CommitIfDue(now.Add(time.Hour), commit)
+	require.NoError(t, err)
+	require.False(t, committed)
+
+	infos, err := mgr.ChangeSets()
+	require.NoError(t, err)
+	require.True(t, infos[0].CommitWindowExpired)
+
+	// Clearing the schedule means the changes are no longer due
+	require.NoError(t, mgr.Schedule(1, time.Time{}, time.Time{}))
+	infos, err = mgr.ChangeSets()
+	require.NoError(t, err)
+	require.True(t, infos[0].CommitNotBefore.IsZero())
+	require.False(t, infos[0].CommitWindowExpired)
+
+	committed, err = mgr.CommitIfDue(now.Add(time.Minute), commit)
+	require.NoError(t, err)
+	require.False(t, committed)
+}
+
+func TestManager_ScheduledCommitCompletesClosedChangeSet(t *testing.T) {
+	store := mem.NewStore()
+	mgr := newMemManager(t, store)
+
+	now := time.Unix(1000, 0)
+	require.NoError(t, mgr.Change(addLines("foo")))
+	require.NoError(t, mgr.Schedule(1, now, now.Add(time.Minute)))
+
+	// The commit is interrupted after closing the change set
+	require.Equal(t, errBadThingsHappened, mgr.Commit(1, func(proto.Message, proto.Message) error {
+		return errBadThingsHappened
+	}))
+
+	// It is completed even though the window has since passed
+	committed, err := mgr.CommitIfDue(now.Add(time.Hour), commit)
+	require.NoError(t, err)
+	require.True(t, committed)
+	requireConfigText(t, store, 2, "foo")
+}
+
+func TestManager_ScheduleErrors(t *testing.T) {
+	store := mem.NewStore()
+	mgr := newMemManager(t, store)
+
+	now := time.Unix(1000, 0)
+	require.Equal(t, ErrInvalidCommitWindow, mgr.Schedule(1, now, now))
+	require.Equal(t, kv.ErrNotFound, mgr.Schedule(1, now, time.Time{}))
+
+	require.NoError(t, mgr.Change(addLines("foo")))
+	require.Equal(t, ErrUnknownVersion, mgr.Schedule(2, now, time.Time{}))
+
+	require.NoError(t, mgr.Commit(1, commit))
+	require.Equal(t, ErrAlreadyCommitted, mgr.Schedule(1, now, time.Time{}))
+}
+
+func TestManager_Rollback(t *testing.T) {
+	store := mem.NewStore()
+	mgr := newMemManager(t, store)
+
+	require.NoError(t, mgr.Change(addLines("foo")))
+	require.NoError(t, mgr.Commit(1, commit))
+	require.NoError(t, mgr.Change(addLines("bar")))
+	require.NoError(t, mgr.Commit(2, commit))
+	requireConfigText(t, store, 3, "foo\nbar")
+
+	// The rollback is pending until committed
+	version, err := mgr.Rollback(2, "alice")
+	require.NoError(t, err)
+	require.Equal(t, 3, version)
+	requireConfigText(t, store, 3, "foo\nbar")
+	require.Equal(t, ErrRollbackPending, mgr.Change(addLines("baz")))
+
+	infos, err := mgr.ChangeSets()
+	require.NoError(t, err)
+	require.Equal(t, ChangeSetInfo{
+		Key:        "config/_changes/3",
+		ForVersion: 3,
+		Status:     ChangeSetOpen,
+		RollbackTo: 2,
+		Authors:    []string{"alice"},
+	}, infos[2])
+
+	preview, err := mgr.Preview(3, commit)
+	require.NoError(t, err)
+	require.Equal(t, "foo", preview.NewConfig.(*changesettest.Config).Text)
+
+	require.NoError(t, mgr.Commit(3, commit))
+	requireConfigText(t, store, 4, "foo")
+
+	infos, err = mgr.ChangeSets()
+	require.NoError(t, err)
+	require.Equal(t, ChangeSetInfo{
+		Key:              "config/_changes/3",
+		ForVersion:       3,
+		Status:           ChangeSetCommitted,
+		RollbackTo:       2,
+		Authors:          []string{"alice"},
+		CommittedVersion: 4,
+	}, infos[2])
+
+	// New changes build on the restored configuration
+	require.NoError(t, mgr.Change(addLines("baz")))
+	require.NoError(t, mgr.Commit(4, commit))
+	requireConfigText(t, store, 5, "foo\nbaz")
+}
+
+func TestManager_RollbackCompletedByCommit(t *testing.T) {
+	store := mem.NewStore()
+	mgr := newMemManager(t, store)
+
+	require.NoError(t, mgr.Change(addLines("foo")))
+	require.NoError(t, mgr.Commit(1, commit))
+
+	// Simulate a rollback that closed the change set but did not get as far as
+	// restoring the configuration
+	_, err := store.Set("config/_changes/2", &changesetpb.ChangeSet{
+		ForVersion: 2,
+		State:      changesetpb.ChangeSetState_CLOSED,
+		RollbackTo: 1,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, mgr.Commit(2, func(proto.Message, proto.Message) error {
+		return errBadThingsHappened
+	}))
+	requireConfigText(t, store, 3, "")
+}
+
+func TestManager_RollbackErrors(t *testing.T) {
+	store := mem.NewStore()
+	mgr := newMemManager(t, store)
+
+	_, err := mgr.Rollback(1, "")
+	require.Equal(t, kv.ErrNotFound, err)
+
+	require.NoError(t, mgr.Change(addLines("foo")))
+	require.NoError(t, mgr.Commit(1, commit))
+
+	// Can only roll back to earlier versions
+	_, err = mgr.Rollback(0, "")
+	require.Equal(t, ErrUnknownVersion, err)
+	_, err = mgr.Rollback(2, "")
+	require.Equal(t, ErrUnknownVersion, err)
+
+	require.NoError(t, mgr.Change(addLines("bar")))
+	_, err = mgr.Rollback(1, "")
+	require.Equal(t, ErrPendingChanges, err)
+
+	// Aborting the pending changes allows the rollback to proceed
+	require.NoError(t, mgr.Abort(2))
+	version, err := mgr.Rollback(1, "")
+	require.NoError(t, err)
+	require.Equal(t, 2, version)
+
+	// Aborting the rollback allows changes again
+	require.NoError(t, mgr.Abort(2))
+	require.NoError(t, mgr.Change(addLines("bar")))
+	require.NoError(t, mgr.Commit(2, commit))
+	requireConfigText(t, store, 3, "foo\nbar")
+}
+
+func TestManager_RollbackRequiresApproval(t *testing.T) {
+	store := mem.NewStore()
+	mgr, err := NewManager(NewManagerOptions().
+		SetKV(store).
+		SetConfigType(&changesettest.Config{}).
+		SetChangesType(&changesettest.Changes{}).
+		SetConfigKey("config").
+		SetApprovers([]string{"alice", "bob"}).
+		SetApprovalQuorum(1))
+	require.NoError(t, err)
+
+	require.NoError(t, mgr.ChangeAs("alice", addLines("foo")))
+	require.NoError(t, mgr.Approve(1, "bob"))
+	require.NoError(t, mgr.Commit(1, commit))
+
+	_, err = mgr.Rollback(1, "")
+	require.Equal(t, ErrAuthorRequired, err)
+
+	version, err := mgr.Rollback(1, "alice")
+	require.NoError(t, err)
+	require.Equal(t, ErrApprovalRequired, mgr.Commit(version, commit))
+	require.Equal(t, ErrSelfApproval, mgr.Approve(version, "alice"))
+	requireConfigText(t, store, 2, "foo")
+
+	require.NoError(t, mgr.Approve(version, "bob"))
+	require.NoError(t, mgr.Commit(version, commit))
+	requireConfigText(t, store, 3, "")
+}
+
+func TestManager_RollbackVersionNotInHistory(t *testing.T) {
+	s := newTestSuite(t)
+	defer s.finish()
+
+	s.kv.EXPECT().Get("config").Return(mem.NewValue(3, &changesettest.Config{}), nil)
+	s.kv.EXPECT().History("config", 1, 2).Return(nil, nil)
+
+	_, err := s.mgr.Rollback(1, "alice")
+	require.Equal(t, ErrVersionNotInHistory, err)
+}
+
 func addLines(lines ...string) ChangeFn {
 	return func(cfgProto, changesProto proto.Message) error {
 		changes := changesProto.(*changesettest.Changes)
@@ -812,6 +1808,33 @@ func commit(cfgProto, changesProto proto.Message) error {
 	return nil
 }
 
+func requireConfigText(t *testing.T, store kv.Store, version int, text string) {
+	cfgVal, err := store.Get("config")
+	require.NoError(t, err)
+	require.Equal(t, version, cfgVal.Version())
+
+	var cfg changesettest.Config
+	require.NoError(t, cfgVal.Unmarshal(&cfg))
+	require.Equal(t, text, cfg.Text)
+}
+
+func mergeLines(cfgProto, pendingProto, strandedProto proto.Message) error {
+	pending := pendingProto.(*changesettest.Changes)
+	stranded := strandedProto.(*changesettest.Changes)
+	pending.Lines = append(pending.Lines, stranded.Lines...)
+	return nil
+}
+
+func newMemManager(t *testing.T, store kv.Store) Manager {
+	mgr, err := NewManager(NewManagerOptions().
+		SetKV(store).
+		SetConfigType(&changesettest.Config{}).
+		SetChangesType(&changesettest.Changes{}).
+		SetConfigKey("config"))
+	require.NoError(t, err)
+	return mgr
+}
+
 type testSuite struct {
 	t         *testing.T
 	kv        *kv.MockStore
@@ -821,10 +1844,14 @@ type testSuite struct {
 }
 
 func newTestSuite(t *testing.T) *testSuite {
+	return newTestSuiteWithOptions(t, NewManagerOptions())
+}
+
+func newTestSuiteWithOptions(t *testing.T, opts ManagerOptions) *testSuite {
 	mc := gomock.NewController(t)
 	kvStore := kv.NewMockStore(mc)
 	configKey := "config"
-	mgr, err := NewManager(NewManagerOptions().
+	mgr, err := NewManager(opts.
 		SetKV(kvStore).
 		SetConfigType(&changesettest.Config{}).
 		SetChangesType(&changesettest.Changes{}).
@@ -851,6 +1878,12 @@ func (t *testSuite) marshal(msg proto.Message) []byte {
 	return b
 }
 
+func (t *testSuite) newCommittedChangeSet(forVersion int, changes proto.Message) *changesetpb.ChangeSet {
+	changeSet := t.newChangeSet(forVersion, changesetpb.ChangeSetState_CLOSED, changes)
+	changeSet.CommittedVersion = int32(forVersion + 1)
+	return changeSet
+}
+
 func (t *testSuite) newOpenChangeSet(forVersion int, changes proto.Message) *changesetpb.ChangeSet {
 	return t.newChangeSet(forVersion, changesetpb.ChangeSetState_OPEN, changes)
 }
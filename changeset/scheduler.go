@@ -0,0 +1,282 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package changeset
+
+import (
+	"errors"
+	"time"
+
+	"github.com/m3db/m3cluster/services"
+	"github.com/m3db/m3cluster/services/leader/elected"
+	"github.com/m3db/m3x/clock"
+	"github.com/m3db/m3x/instrument"
+
+	"github.com/uber-go/tally"
+)
+
+const (
+	defaultCheckInterval = 10 * time.Second
+)
+
+var (
+	errLeaderServiceNotSet   = errors.New("leader service must be specified")
+	errElectionIDNotSet      = errors.New("election id must be specified")
+	errCampaignOptionsNotSet = errors.New("campaign options must be specified")
+	errApplyFnNotSet         = errors.New("apply function must be specified")
+	errInvalidCheckInterval  = errors.New("check interval must be positive")
+)
+
+// SchedulerOptions are options used in creating a new Scheduler
+type SchedulerOptions interface {
+	// LeaderService is the service used to elect the scheduler that performs
+	// the commits
+	LeaderService() services.LeaderService
+	SetLeaderService(leaderService services.LeaderService) SchedulerOptions
+
+	// ElectionID is the election the schedulers for a configuration campaign in
+	ElectionID() string
+	SetElectionID(electionID string) SchedulerOptions
+
+	// CampaignOptions are the options used when campaigning for leadership
+	CampaignOptions() services.CampaignOptions
+	SetCampaignOptions(opts services.CampaignOptions) SchedulerOptions
+
+	// CheckInterval is how often the leader checks for changes that are due
+	CheckInterval() time.Duration
+	SetCheckInterval(interval time.Duration) SchedulerOptions
+
+	// ApplyFn is used to apply scheduled changes to the configuration
+	ApplyFn() ApplyFn
+	SetApplyFn(apply ApplyFn) SchedulerOptions
+
+	// ClockOptions are the clock options
+	ClockOptions() clock.Options
+	SetClockOptions(opts clock.Options) SchedulerOptions
+
+	// InstrumentOptions are the instrument options
+	InstrumentOptions() instrument.Options
+	SetInstrumentOptions(opts instrument.Options) SchedulerOptions
+
+	// Validate validates the options
+	Validate() error
+}
+
+// NewSchedulerOptions creates a new set of SchedulerOptions
+func NewSchedulerOptions() SchedulerOptions {
+	return &schedulerOptions{
+		checkInterval:  defaultCheckInterval,
+		clockOpts:      clock.NewOptions(),
+		instrumentOpts: instrument.NewOptions(),
+	}
+}
+
+// A Scheduler commits scheduled ChangeSets once their commit window opens.
+// Any number of schedulers may run against the same configuration; only the
+// elected leader performs commits, and each commit is guarded by a version
+// check so a ChangeSet is committed exactly once
+type Scheduler interface {
+	// Start campaigns for leadership and begins checking for due changes
+	Start() error
+
+	// Stop resigns leadership and stops checking for due changes
+	Stop() error
+}
+
+type schedulerMetrics struct {
+	commits       tally.Counter
+	commitErrors  tally.Counter
+	windowsPassed tally.Counter
+}
+
+func newSchedulerMetrics(scope tally.Scope) schedulerMetrics {
+	return schedulerMetrics{
+		commits:       scope.Counter("commits"),
+		commitErrors:  scope.Counter("commit-errors"),
+		windowsPassed: scope.Counter("commit-windows-passed"),
+	}
+}
+
+type scheduler struct {
+	elected.Loop
+
+	mgr     Manager
+	opts    SchedulerOptions
+	nowFn   clock.NowFn
+	metrics schedulerMetrics
+}
+
+// NewScheduler creates a new Scheduler for the configuration managed by the
+// given Manager
+func NewScheduler(mgr Manager, opts SchedulerOptions) (Scheduler, error) {
+	if opts == nil {
+		return nil, errOptsNotSet
+	}
+
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	instrumentOpts := opts.InstrumentOptions()
+	scope := instrumentOpts.MetricsScope().SubScope("changeset-scheduler")
+	s := &scheduler{
+		mgr:     mgr,
+		opts:    opts,
+		nowFn:   opts.ClockOptions().NowFn(),
+		metrics: newSchedulerMetrics(scope),
+	}
+
+	loop, err := elected.NewLoop(elected.NewOptions().
+		SetLeaderService(opts.LeaderService()).
+		SetElectionID(opts.ElectionID()).
+		SetCampaignOptions(opts.CampaignOptions()).
+		SetInterval(opts.CheckInterval()).
+		SetLeaderFn(s.commitIfDue).
+		SetInstrumentOptions(instrumentOpts.SetMetricsScope(scope)))
+	if err != nil {
+		return nil, err
+	}
+
+	s.Loop = loop
+	return s, nil
+}
+
+func (s *scheduler) commitIfDue() {
+	committed, err := s.mgr.CommitIfDue(s.nowFn(), s.opts.ApplyFn())
+	if err == ErrCommitWindowPassed {
+		s.metrics.windowsPassed.Inc(1)
+		s.opts.InstrumentOptions().Logger().Warnf("scheduled change set was not committed before its window passed")
+		return
+	}
+
+	if err != nil {
+		s.metrics.commitErrors.Inc(1)
+		s.opts.InstrumentOptions().Logger().Errorf("could not commit scheduled change set: %v", err)
+		return
+	}
+
+	if committed {
+		s.metrics.commits.Inc(1)
+	}
+}
+
+type schedulerOptions struct {
+	leaderService  services.LeaderService
+	electionID     string
+	campaignOpts   services.CampaignOptions
+	checkInterval  time.Duration
+	apply          ApplyFn
+	clockOpts      clock.Options
+	instrumentOpts instrument.Options
+}
+
+func (o *schedulerOptions) LeaderService() services.LeaderService {
+	return o.leaderService
+}
+
+func (o *schedulerOptions) SetLeaderService(leaderService services.LeaderService) SchedulerOptions {
+	opts := *o
+	opts.leaderService = leaderService
+	return &opts
+}
+
+func (o *schedulerOptions) ElectionID() string {
+	return o.electionID
+}
+
+func (o *schedulerOptions) SetElectionID(electionID string) SchedulerOptions {
+	opts := *o
+	opts.electionID = electionID
+	return &opts
+}
+
+func (o *schedulerOptions) CampaignOptions() services.CampaignOptions {
+	return o.campaignOpts
+}
+
+func (o *schedulerOptions) SetCampaignOptions(campaignOpts services.CampaignOptions) SchedulerOptions {
+	opts := *o
+	opts.campaignOpts = campaignOpts
+	return &opts
+}
+
+func (o *schedulerOptions) CheckInterval() time.Duration {
+	return o.checkInterval
+}
+
+func (o *schedulerOptions) SetCheckInterval(interval time.Duration) SchedulerOptions {
+	opts := *o
+	opts.checkInterval = interval
+	return &opts
+}
+
+func (o *schedulerOptions) ApplyFn() ApplyFn {
+	return o.apply
+}
+
+func (o *schedulerOptions) SetApplyFn(apply ApplyFn) SchedulerOptions {
+	opts := *o
+	opts.apply = apply
+	return &opts
+}
+
+func (o *schedulerOptions) ClockOptions() clock.Options {
+	return o.clockOpts
+}
+
+func (o *schedulerOptions) SetClockOptions(clockOpts clock.Options) SchedulerOptions {
+	opts := *o
+	opts.clockOpts = clockOpts
+	return &opts
+}
+
+func (o *schedulerOptions) InstrumentOptions() instrument.Options {
+	return o.instrumentOpts
+}
+
+func (o *schedulerOptions) SetInstrumentOptions(instrumentOpts instrument.Options) SchedulerOptions {
+	opts := *o
+	opts.instrumentOpts = instrumentOpts
+	return &opts
+}
+
+func (o *schedulerOptions) Validate() error {
+	if o.leaderService == nil {
+		return errLeaderServiceNotSet
+	}
+
+	if o.electionID == "" {
+		return errElectionIDNotSet
+	}
+
+	if o.campaignOpts == nil {
+		return errCampaignOptionsNotSet
+	}
+
+	if o.apply == nil {
+		return errApplyFnNotSet
+	}
+
+	if o.checkInterval <= 0 {
+		return errInvalidCheckInterval
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package changeset
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/m3db/m3cluster/generated/proto/changesettest"
+	"github.com/m3db/m3cluster/kv/mem"
+	"github.com/m3db/m3cluster/services"
+	"github.com/m3db/m3cluster/services/leader/campaign"
+	"github.com/m3db/m3cluster/services/leader/elected"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedulerOptionsValidate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	opts := NewSchedulerOptions()
+	require.Equal(t, errLeaderServiceNotSet, opts.Validate())
+
+	opts = opts.SetLeaderService(services.NewMockLeaderService(ctrl))
+	require.Equal(t, errElectionIDNotSet, opts.Validate())
+
+	opts = opts.SetElectionID("e1")
+	require.Equal(t, errCampaignOptionsNotSet, opts.Validate())
+
+	opts = opts.SetCampaignOptions(testCampaignOptions(t))
+	require.Equal(t, errApplyFnNotSet, opts.Validate())
+
+	opts = opts.SetApplyFn(commit)
+	require.NoError(t, opts.Validate())
+
+	require.Equal(t, errInvalidCheckInterval, opts.SetCheckInterval(0).Validate())
+}
+
+func TestSchedulerCommitsWhenLeader(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mem.NewStore()
+	mgr := newMemManager(t, store)
+	require.NoError(t, mgr.Change(addLines("foo")))
+	require.NoError(t, mgr.Schedule(1, time.Unix(1, 0), time.Time{}))
+
+	campaignOpts := testCampaignOptions(t)
+	statusCh := make(chan campaign.Status, 2)
+	leaderSvc := services.NewMockLeaderService(ctrl)
+	leaderSvc.EXPECT().Campaign("e1", campaignOpts).Return((<-chan campaign.Status)(statusCh), nil)
+	leaderSvc.EXPECT().Resign("e1").DoAndReturn(func(string) error {
+		close(statusCh)
+		return nil
+	})
+
+	s, err := NewScheduler(mgr, NewSchedulerOptions().
+		SetLeaderService(leaderSvc).
+		SetElectionID("e1").
+		SetCampaignOptions(campaignOpts).
+		SetCheckInterval(time.Millisecond).
+		SetApplyFn(commit))
+	require.NoError(t, err)
+	require.NoError(t, s.Start())
+	require.Equal(t, elected.ErrAlreadyStarted, s.Start())
+
+	// Followers never commit
+	statusCh <- campaign.NewStatus(campaign.Follower)
+	time.Sleep(20 * time.Millisecond)
+	cfgVal, err := store.Get("config")
+	require.NoError(t, err)
+	require.Equal(t, 1, cfgVal.Version())
+
+	statusCh <- campaign.NewStatus(campaign.Leader)
+	for {
+		cfgVal, err = store.Get("config")
+		require.NoError(t, err)
+		if cfgVal.Version() == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	var cfg changesettest.Config
+	require.NoError(t, cfgVal.Unmarshal(&cfg))
+	require.Equal(t, "foo", cfg.Text)
+
+	require.NoError(t, s.Stop())
+	require.Equal(t, elected.ErrNotStarted, s.Stop())
+}
+
+func testCampaignOptions(t *testing.T) services.CampaignOptions {
+	opts, err := services.NewCampaignOptions()
+	require.NoError(t, err)
+	return opts
+}
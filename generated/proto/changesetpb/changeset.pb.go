@@ -30,6 +30,7 @@ It is generated from these files:
 
 It has these top-level messages:
 	ChangeSet
+	CommitSchedule
 */
 package changesetpb
 
@@ -55,17 +56,20 @@ const (
 	ChangeSetState_UNKNOWN ChangeSetState = 0
 	ChangeSetState_OPEN    ChangeSetState = 1
 	ChangeSetState_CLOSED  ChangeSetState = 2
+	ChangeSetState_REBASED ChangeSetState = 3
 )
 
 var ChangeSetState_name = map[int32]string{
 	0: "UNKNOWN",
 	1: "OPEN",
 	2: "CLOSED",
+	3: "REBASED",
 }
 var ChangeSetState_value = map[string]int32{
 	"UNKNOWN": 0,
 	"OPEN":    1,
 	"CLOSED":  2,
+	"REBASED": 3,
 }
 
 func (x ChangeSetState) String() string {
@@ -83,6 +87,30 @@ type ChangeSet struct {
 	State ChangeSetState `protobuf:"varint,2,opt,name=state,enum=changesetpb.ChangeSetState" json:"state,omitempty"`
 	// changes are the marshalled form of the changes
 	Changes []byte `protobuf:"bytes,3,opt,name=changes,proto3" json:"changes,omitempty"`
+	// required_approvers are the identities allowed to approve or reject the
+	// ChangeSet. If empty, any identity may approve or reject
+	RequiredApprovers []string `protobuf:"bytes,4,rep,name=required_approvers,json=requiredApprovers" json:"required_approvers,omitempty"`
+	// quorum is the number of approvals needed before the ChangeSet can be
+	// committed. A quorum of zero means no approval is needed
+	Quorum int32 `protobuf:"varint,5,opt,name=quorum" json:"quorum,omitempty"`
+	// approvals are the identities which have approved the current changes
+	Approvals []string `protobuf:"bytes,6,rep,name=approvals" json:"approvals,omitempty"`
+	// rejections are the identities which have rejected the current changes
+	Rejections []string `protobuf:"bytes,7,rep,name=rejections" json:"rejections,omitempty"`
+	// rebased_to is the version of configuration the changes were moved onto
+	// when the ChangeSet was rebased
+	RebasedTo int32 `protobuf:"varint,8,opt,name=rebased_to,json=rebasedTo" json:"rebased_to,omitempty"`
+	// schedule restricts when the ChangeSet is committed by a scheduler
+	Schedule *CommitSchedule `protobuf:"bytes,9,opt,name=schedule" json:"schedule,omitempty"`
+	// rollback_to is the earlier version of configuration restored when the
+	// ChangeSet is committed, in place of applying changes
+	RollbackTo int32 `protobuf:"varint,10,opt,name=rollback_to,json=rollbackTo" json:"rollback_to,omitempty"`
+	// committed_version is the version of configuration produced by committing
+	// the ChangeSet. Zero until the commit has completed
+	CommittedVersion int32 `protobuf:"varint,11,opt,name=committed_version,json=committedVersion" json:"committed_version,omitempty"`
+	// authors are the identities which made the current changes. Authors may
+	// not approve their own changes
+	Authors []string `protobuf:"bytes,12,rep,name=authors" json:"authors,omitempty"`
 }
 
 func (m *ChangeSet) Reset()                    { *m = ChangeSet{} }
@@ -90,24 +118,65 @@ func (m *ChangeSet) String() string            { return proto.CompactTextString(
 func (*ChangeSet) ProtoMessage()               {}
 func (*ChangeSet) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
 
+func (m *ChangeSet) GetSchedule() *CommitSchedule {
+	if m != nil {
+		return m.Schedule
+	}
+	return nil
+}
+
+// A CommitSchedule describes the window in which a ChangeSet should be
+// committed
+type CommitSchedule struct {
+	// not_before_nanos is the earliest time the ChangeSet may be committed
+	NotBeforeNanos int64 `protobuf:"varint,1,opt,name=not_before_nanos,json=notBeforeNanos" json:"not_before_nanos,omitempty"`
+	// not_after_nanos is the time after which the ChangeSet may no longer be
+	// committed. Zero means there is no deadline
+	NotAfterNanos int64 `protobuf:"varint,2,opt,name=not_after_nanos,json=notAfterNanos" json:"not_after_nanos,omitempty"`
+	// expired is set once the window has passed without the ChangeSet being
+	// committed
+	Expired bool `protobuf:"varint,3,opt,name=expired" json:"expired,omitempty"`
+}
+
+func (m *CommitSchedule) Reset()                    { *m = CommitSchedule{} }
+func (m *CommitSchedule) String() string            { return proto.CompactTextString(m) }
+func (*CommitSchedule) ProtoMessage()               {}
+func (*CommitSchedule) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
 func init() {
 	proto.RegisterType((*ChangeSet)(nil), "changesetpb.ChangeSet")
+	proto.RegisterType((*CommitSchedule)(nil), "changesetpb.CommitSchedule")
 	proto.RegisterEnum("changesetpb.ChangeSetState", ChangeSetState_name, ChangeSetState_value)
 }
 
 func init() { proto.RegisterFile("changeset.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 176 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0xe2, 0x4f, 0xce, 0x48, 0xcc,
-	0x4b, 0x4f, 0x2d, 0x4e, 0x2d, 0xd1, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0xe2, 0x86, 0x0b, 0x14,
-	0x24, 0x29, 0x55, 0x72, 0x71, 0x3a, 0x83, 0xb9, 0xc1, 0xa9, 0x25, 0x42, 0xf2, 0x5c, 0xdc, 0x69,
-	0xf9, 0x45, 0xf1, 0x65, 0xa9, 0x45, 0xc5, 0x99, 0xf9, 0x79, 0x12, 0x8c, 0x0a, 0x8c, 0x1a, 0xac,
-	0x41, 0x5c, 0x69, 0xf9, 0x45, 0x61, 0x10, 0x11, 0x21, 0x43, 0x2e, 0xd6, 0xe2, 0x92, 0xc4, 0x92,
-	0x54, 0x09, 0x26, 0x05, 0x46, 0x0d, 0x3e, 0x23, 0x69, 0x3d, 0x24, 0xa3, 0xf4, 0xe0, 0xe6, 0x04,
-	0x83, 0x94, 0x04, 0x41, 0x54, 0x0a, 0x49, 0x70, 0xb1, 0x43, 0x15, 0x49, 0x30, 0x2b, 0x30, 0x6a,
-	0xf0, 0x04, 0xc1, 0xb8, 0x5a, 0xc6, 0x5c, 0x7c, 0xa8, 0x5a, 0x84, 0xb8, 0xb9, 0xd8, 0x43, 0xfd,
-	0xbc, 0xfd, 0xfc, 0xc3, 0xfd, 0x04, 0x18, 0x84, 0x38, 0xb8, 0x58, 0xfc, 0x03, 0x5c, 0xfd, 0x04,
-	0x18, 0x85, 0xb8, 0xb8, 0xd8, 0x9c, 0x7d, 0xfc, 0x83, 0x5d, 0x5d, 0x04, 0x98, 0x92, 0xd8, 0xc0,
-	0x7e, 0x30, 0x06, 0x04, 0x00, 0x00, 0xff, 0xff, 0x89, 0xba, 0xc3, 0x6a, 0xd6, 0x00, 0x00, 0x00,
+	// 414 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x5c, 0x92, 0x4f, 0x6f, 0xd3, 0x40,
+	0x10, 0xc5, 0x71, 0xdc, 0xc4, 0xf1, 0xb8, 0xa4, 0xee, 0x1c, 0xd0, 0x4a, 0xfc, 0xb3, 0x7a, 0x40,
+	0x16, 0x88, 0x48, 0xc0, 0x81, 0x2b, 0x69, 0xc9, 0x09, 0xe4, 0x20, 0xa7, 0xc0, 0xd1, 0xb2, 0x9d,
+	0x09, 0x09, 0x24, 0x1e, 0x77, 0x77, 0x8d, 0x38, 0xf0, 0xd9, 0xf8, 0x6c, 0x68, 0xd7, 0x7f, 0x68,
+	0x39, 0xbe, 0xf7, 0x7e, 0x33, 0xde, 0x99, 0x31, 0x9c, 0x95, 0xbb, 0xbc, 0xfa, 0x46, 0x8a, 0xf4,
+	0xbc, 0x96, 0xac, 0x19, 0x83, 0xc1, 0xa8, 0x8b, 0x8b, 0x3f, 0x2e, 0xf8, 0x57, 0x56, 0xaf, 0x49,
+	0xe3, 0x53, 0x08, 0xb6, 0x2c, 0xb3, 0x9f, 0x24, 0xd5, 0x9e, 0x2b, 0xe1, 0x44, 0x4e, 0x3c, 0x4e,
+	0x61, 0xcb, 0xf2, 0x4b, 0xeb, 0xe0, 0x2b, 0x18, 0x2b, 0x9d, 0x6b, 0x12, 0xa3, 0xc8, 0x89, 0x67,
+	0xaf, 0x1f, 0xce, 0x6f, 0xf5, 0x9a, 0x0f, 0x7d, 0xd6, 0x06, 0x49, 0x5b, 0x12, 0x05, 0x78, 0x1d,
+	0x24, 0xdc, 0xc8, 0x89, 0x4f, 0xd3, 0x5e, 0xe2, 0x4b, 0x40, 0x49, 0x37, 0xcd, 0x5e, 0xd2, 0x26,
+	0xcb, 0xeb, 0x5a, 0xb2, 0xf9, 0xae, 0x38, 0x89, 0xdc, 0xd8, 0x4f, 0xcf, 0xfb, 0x64, 0xd1, 0x07,
+	0xf8, 0x00, 0x26, 0x37, 0x0d, 0xcb, 0xe6, 0x28, 0xc6, 0xf6, 0x5d, 0x9d, 0xc2, 0x47, 0xe0, 0xb7,
+	0xd5, 0xf9, 0x41, 0x89, 0x89, 0xad, 0xfe, 0x67, 0xe0, 0x13, 0x00, 0x49, 0xdf, 0xa9, 0xd4, 0x7b,
+	0xae, 0x94, 0xf0, 0x6c, 0x7c, 0xcb, 0xc1, 0xc7, 0x26, 0x2f, 0x72, 0x45, 0x9b, 0x4c, 0xb3, 0x98,
+	0xda, 0xce, 0x7e, 0xe7, 0x5c, 0x33, 0xbe, 0x85, 0xa9, 0x2a, 0x77, 0xb4, 0x69, 0x0e, 0x24, 0xfc,
+	0xc8, 0x89, 0x83, 0xff, 0x67, 0xe6, 0xe3, 0x71, 0xaf, 0xd7, 0x1d, 0x92, 0x0e, 0xb0, 0x59, 0xa5,
+	0xe4, 0xc3, 0xa1, 0xc8, 0xcb, 0x1f, 0xa6, 0x31, 0xb4, 0xab, 0xec, 0xad, 0x6b, 0xc6, 0x17, 0x70,
+	0x5e, 0xda, 0x62, 0x4d, 0x9b, 0x61, 0xe3, 0x81, 0xc5, 0xc2, 0x21, 0xe8, 0xf7, 0x2e, 0xc0, 0xcb,
+	0x1b, 0xbd, 0x63, 0xa9, 0xc4, 0xa9, 0x1d, 0xa1, 0x97, 0x17, 0xbf, 0x61, 0x76, 0xf7, 0x0d, 0x18,
+	0x43, 0x58, 0xb1, 0xce, 0x0a, 0xda, 0xb2, 0xa4, 0xac, 0xca, 0x2b, 0x56, 0xf6, 0x92, 0x6e, 0x3a,
+	0xab, 0x58, 0x5f, 0x5a, 0x3b, 0x31, 0x2e, 0x3e, 0x83, 0x33, 0x43, 0xe6, 0x5b, 0x4d, 0xb2, 0x03,
+	0x47, 0x16, 0xbc, 0x5f, 0xb1, 0x5e, 0x18, 0xb7, 0xe5, 0x04, 0x78, 0xf4, 0xab, 0x36, 0xd7, 0xb0,
+	0x27, 0x9c, 0xa6, 0xbd, 0x7c, 0xfe, 0x0e, 0x66, 0x77, 0xaf, 0x8e, 0x01, 0x78, 0x9f, 0x93, 0x0f,
+	0xc9, 0xea, 0x6b, 0x12, 0xde, 0xc3, 0x29, 0x9c, 0xac, 0x3e, 0x2d, 0x93, 0xd0, 0x41, 0x80, 0xc9,
+	0xd5, 0xc7, 0xd5, 0x7a, 0xf9, 0x3e, 0x1c, 0x19, 0x24, 0x5d, 0x5e, 0x2e, 0x8c, 0x70, 0x8b, 0x89,
+	0xfd, 0x29, 0xdf, 0xfc, 0x1d, 0x00, 0xde, 0xb8, 0x6b, 0x49, 0xa7, 0x02, 0x00, 0x00,
 }
@@ -0,0 +1,79 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+
+// Code generated by protoc-gen-go.
+// source: controller.proto
+// DO NOT EDIT!
+
+/*
+Package controllerpb is a generated protocol buffer package.
+
+It is generated from these files:
+	controller.proto
+
+It has these top-level messages:
+	Replacements
+*/
+package controllerpb
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+// Replacements records the instance replacements made by the placement
+// controllers, so the rate limit holds across leadership changes
+type Replacements struct {
+	// replaced_at_nanos are the times of the replacements in the last hour
+	ReplacedAtNanos []int64 `protobuf:"varint,1,rep,packed,name=replaced_at_nanos,json=replacedAtNanos" json:"replaced_at_nanos,omitempty"`
+}
+
+func (m *Replacements) Reset()                    { *m = Replacements{} }
+func (m *Replacements) String() string            { return proto.CompactTextString(m) }
+func (*Replacements) ProtoMessage()               {}
+func (*Replacements) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+func init() {
+	proto.RegisterType((*Replacements)(nil), "controllerpb.Replacements")
+}
+
+func init() { proto.RegisterFile("controller.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 97 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0x12, 0x48, 0xce, 0xcf, 0x2b,
+	0x29, 0xca, 0xcf, 0xc9, 0x49, 0x2d, 0xd2, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0xe2, 0x41, 0x88,
+	0x14, 0x24, 0x29, 0x59, 0x71, 0xf1, 0x04, 0xa5, 0x16, 0xe4, 0x24, 0x26, 0xa7, 0xe6, 0xa6, 0xe6,
+	0x95, 0x14, 0x0b, 0x69, 0x71, 0x09, 0x16, 0x41, 0xf8, 0x29, 0xf1, 0x89, 0x25, 0xf1, 0x79, 0x89,
+	0x79, 0xf9, 0xc5, 0x12, 0x8c, 0x0a, 0xcc, 0x1a, 0xcc, 0x41, 0xfc, 0x30, 0x09, 0xc7, 0x12, 0x3f,
+	0x90, 0x70, 0x12, 0x1b, 0xd8, 0x40, 0x63, 0xc0, 0x00, 0xcc, 0xe7, 0x87, 0xb2, 0x64, 0x00, 0x00,
+	0x00,
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+
+// Code generated by protoc-gen-go.
+// source: migrationtest.proto
+// DO NOT EDIT!
+
+/*
+Package migrationtest is a generated protocol buffer package.
+
+It is generated from these files:
+	migrationtest.proto
+
+It has these top-level messages:
+	ConfigV1
+	ConfigV2
+*/
+package migrationtest
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+type ConfigV1 struct {
+	SchemaVersion int32  `protobuf:"varint,1,opt,name=schema_version,json=schemaVersion" json:"schema_version,omitempty"`
+	Hosts         string `protobuf:"bytes,2,opt,name=hosts" json:"hosts,omitempty"`
+}
+
+func (m *ConfigV1) Reset()                    { *m = ConfigV1{} }
+func (m *ConfigV1) String() string            { return proto.CompactTextString(m) }
+func (*ConfigV1) ProtoMessage()               {}
+func (*ConfigV1) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+type ConfigV2 struct {
+	SchemaVersion int32    `protobuf:"varint,1,opt,name=schema_version,json=schemaVersion" json:"schema_version,omitempty"`
+	Hosts         []string `protobuf:"bytes,2,rep,name=hosts" json:"hosts,omitempty"`
+}
+
+func (m *ConfigV2) Reset()                    { *m = ConfigV2{} }
+func (m *ConfigV2) String() string            { return proto.CompactTextString(m) }
+func (*ConfigV2) ProtoMessage()               {}
+func (*ConfigV2) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func init() {
+	proto.RegisterType((*ConfigV1)(nil), "migrationtest.ConfigV1")
+	proto.RegisterType((*ConfigV2)(nil), "migrationtest.ConfigV2")
+}
+
+func init() { proto.RegisterFile("migrationtest.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 121 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0x12, 0xce, 0xcd, 0x4c, 0x2f,
+	0x4a, 0x2c, 0xc9, 0xcc, 0xcf, 0x2b, 0x49, 0x2d, 0x2e, 0xd1, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17,
+	0xe2, 0x45, 0x11, 0x54, 0x72, 0xe7, 0xe2, 0x70, 0xce, 0xcf, 0x4b, 0xcb, 0x4c, 0x0f, 0x33, 0x14,
+	0x52, 0xe5, 0xe2, 0x2b, 0x4e, 0xce, 0x48, 0xcd, 0x4d, 0x8c, 0x2f, 0x4b, 0x2d, 0x2a, 0xce, 0xcc,
+	0xcf, 0x93, 0x60, 0x54, 0x60, 0xd4, 0x60, 0x0d, 0xe2, 0x85, 0x88, 0x86, 0x41, 0x04, 0x85, 0x44,
+	0xb8, 0x58, 0x33, 0xf2, 0x8b, 0x4b, 0x8a, 0x25, 0x98, 0x14, 0x18, 0x35, 0x38, 0x83, 0x20, 0x1c,
+	0x24, 0x83, 0x8c, 0xc8, 0x30, 0x88, 0x19, 0x6e, 0x50, 0x12, 0x1b, 0xd8, 0x9d, 0xc6, 0x80, 0x01,
+	0x00, 0x92, 0x87, 0xb4, 0xf2, 0xbe, 0x00, 0x00, 0x00,
+}
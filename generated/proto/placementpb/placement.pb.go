@@ -33,6 +33,7 @@ It has these top-level messages:
 	Instance
 	Shard
 	PlacementSnapshots
+	PendingShardMove
 */
 package placementpb
 
@@ -85,6 +86,12 @@ type Placement struct {
 	// shard placement.
 	CutoverTime int64 `protobuf:"varint,5,opt,name=cutover_time,json=cutoverTime" json:"cutover_time,omitempty"`
 	IsMirrored  bool  `protobuf:"varint,6,opt,name=is_mirrored,json=isMirrored" json:"is_mirrored,omitempty"`
+	// zone_replicas is the number of replicas of each shard placed in each zone
+	// for placements spanning multiple zones.
+	ZoneReplicas map[string]uint32 `protobuf:"bytes,7,rep,name=zone_replicas,json=zoneReplicas" json:"zone_replicas,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	// pending_shard_moves are the shard moves staged until the number of initializing
+	// shards drops below the configured limits.
+	PendingShardMoves []*PendingShardMove `protobuf:"bytes,8,rep,name=pending_shard_moves,json=pendingShardMoves" json:"pending_shard_moves,omitempty"`
 }
 
 func (m *Placement) Reset()                    { *m = Placement{} }
@@ -99,6 +106,20 @@ func (m *Placement) GetInstances() map[string]*Instance {
 	return nil
 }
 
+func (m *Placement) GetZoneReplicas() map[string]uint32 {
+	if m != nil {
+		return m.ZoneReplicas
+	}
+	return nil
+}
+
+func (m *Placement) GetPendingShardMoves() []*PendingShardMove {
+	if m != nil {
+		return m.PendingShardMoves
+	}
+	return nil
+}
+
 type Instance struct {
 	Id         string   `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
 	Rack       string   `protobuf:"bytes,2,opt,name=rack" json:"rack,omitempty"`
@@ -109,6 +130,7 @@ type Instance struct {
 	ShardSetId uint32   `protobuf:"varint,7,opt,name=shard_set_id,json=shardSetId" json:"shard_set_id,omitempty"`
 	Hostname   string   `protobuf:"bytes,8,opt,name=hostname" json:"hostname,omitempty"`
 	Port       uint32   `protobuf:"varint,9,opt,name=port" json:"port,omitempty"`
+	Region     string   `protobuf:"bytes,10,opt,name=region" json:"region,omitempty"`
 }
 
 func (m *Instance) Reset()                    { *m = Instance{} }
@@ -157,50 +179,69 @@ func (m *PlacementSnapshots) GetSnapshots() []*Placement {
 	return nil
 }
 
+type PendingShardMove struct {
+	ShardId  uint32 `protobuf:"varint,1,opt,name=shard_id,json=shardId" json:"shard_id,omitempty"`
+	SourceId string `protobuf:"bytes,2,opt,name=source_id,json=sourceId" json:"source_id,omitempty"`
+	TargetId string `protobuf:"bytes,3,opt,name=target_id,json=targetId" json:"target_id,omitempty"`
+}
+
+func (m *PendingShardMove) Reset()                    { *m = PendingShardMove{} }
+func (m *PendingShardMove) String() string            { return proto.CompactTextString(m) }
+func (*PendingShardMove) ProtoMessage()               {}
+func (*PendingShardMove) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
 func init() {
 	proto.RegisterType((*Placement)(nil), "placementpb.Placement")
 	proto.RegisterType((*Instance)(nil), "placementpb.Instance")
 	proto.RegisterType((*Shard)(nil), "placementpb.Shard")
 	proto.RegisterType((*PlacementSnapshots)(nil), "placementpb.PlacementSnapshots")
+	proto.RegisterType((*PendingShardMove)(nil), "placementpb.PendingShardMove")
 	proto.RegisterEnum("placementpb.ShardState", ShardState_name, ShardState_value)
 }
 
 func init() { proto.RegisterFile("placement.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 538 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x6c, 0x53, 0xdd, 0x6e, 0xd3, 0x4c,
-	0x10, 0xfd, 0xec, 0x34, 0x69, 0x76, 0x5c, 0xe7, 0x8b, 0x56, 0xa2, 0x58, 0x45, 0x08, 0x13, 0x54,
-	0x29, 0x2a, 0x22, 0x17, 0x85, 0x0b, 0xd4, 0xbb, 0x80, 0x02, 0x32, 0x0a, 0x11, 0x5a, 0x57, 0xbd,
-	0xe0, 0xc6, 0x72, 0xed, 0x0d, 0x59, 0x35, 0xde, 0xb5, 0x76, 0x37, 0x45, 0xe5, 0x95, 0x78, 0x17,
-	0x9e, 0x86, 0x07, 0x40, 0x1e, 0xdb, 0xf9, 0x11, 0xdc, 0xcd, 0x9c, 0x39, 0x3b, 0x33, 0xe7, 0x78,
-	0x0c, 0xff, 0x97, 0xeb, 0x34, 0xe3, 0x05, 0x97, 0x76, 0x52, 0x6a, 0x65, 0x15, 0xf5, 0xb6, 0x40,
-	0x79, 0x3b, 0xfa, 0xe5, 0x02, 0xf9, 0xd2, 0xe6, 0xf4, 0x3d, 0x10, 0x21, 0x8d, 0x4d, 0x65, 0xc6,
-	0x4d, 0xe0, 0x84, 0x9d, 0xb1, 0x77, 0x79, 0x3e, 0xd9, 0xa3, 0x4f, 0xb6, 0xd4, 0x49, 0xd4, 0xf2,
-	0x66, 0xd2, 0xea, 0x07, 0xb6, 0x7b, 0x47, 0xcf, 0x61, 0xa0, 0x79, 0xb9, 0x16, 0x59, 0x9a, 0x2c,
-	0xd3, 0xcc, 0x2a, 0x1d, 0xb8, 0xa1, 0x33, 0xf6, 0x99, 0xdf, 0xa0, 0x1f, 0x10, 0xa4, 0x4f, 0x01,
-	0xe4, 0xa6, 0x48, 0xcc, 0x2a, 0xd5, 0xb9, 0x09, 0x3a, 0x48, 0x21, 0x72, 0x53, 0xc4, 0x08, 0x54,
-	0x65, 0x61, 0xea, 0x2a, 0xcf, 0x83, 0xa3, 0xd0, 0x19, 0xf7, 0x19, 0x11, 0x26, 0xae, 0x01, 0xfa,
-	0x1c, 0x4e, 0xb2, 0x8d, 0x55, 0xf7, 0x5c, 0x27, 0x56, 0x14, 0x3c, 0xe8, 0x86, 0xce, 0xb8, 0xc3,
-	0xbc, 0x06, 0xbb, 0x16, 0x05, 0xa7, 0xcf, 0xc0, 0x13, 0x26, 0x29, 0x84, 0xd6, 0x4a, 0xf3, 0x3c,
-	0xe8, 0x61, 0x0b, 0x10, 0xe6, 0x73, 0x83, 0x9c, 0xc5, 0x30, 0x38, 0x54, 0x41, 0x87, 0xd0, 0xb9,
-	0xe3, 0x0f, 0x81, 0x13, 0x3a, 0x63, 0xc2, 0xaa, 0x90, 0xbe, 0x84, 0xee, 0x7d, 0xba, 0xde, 0x70,
-	0xd4, 0xe0, 0x5d, 0x3e, 0x3a, 0x70, 0xa3, 0x7d, 0xcd, 0x6a, 0xce, 0x95, 0xfb, 0xd6, 0x19, 0xfd,
-	0x76, 0xa0, 0xdf, 0xe2, 0x74, 0x00, 0xae, 0xc8, 0x9b, 0x76, 0xae, 0xc8, 0x29, 0x85, 0x23, 0x9d,
-	0x66, 0x77, 0xd8, 0x8c, 0x30, 0x8c, 0x2b, 0xec, 0x87, 0x92, 0x1c, 0x1d, 0x20, 0x0c, 0x63, 0x7a,
-	0x0a, 0xbd, 0xef, 0x5c, 0x7c, 0x5b, 0x59, 0x14, 0xee, 0xb3, 0x26, 0xa3, 0x67, 0xd0, 0xe7, 0x32,
-	0x2f, 0x95, 0x90, 0x16, 0x15, 0x13, 0xb6, 0xcd, 0xe9, 0x05, 0xf4, 0x1a, 0x2f, 0x7b, 0xf8, 0xe1,
-	0xe8, 0xc1, 0xaa, 0xe8, 0x1b, 0x6b, 0x18, 0x34, 0x84, 0x13, 0x8c, 0x12, 0xc3, 0x6d, 0x22, 0xf2,
-	0xe0, 0x18, 0xa7, 0x00, 0x62, 0x31, 0xb7, 0x51, 0x5e, 0x4d, 0x5a, 0x29, 0x63, 0x65, 0x5a, 0xf0,
-	0xa0, 0x5f, 0x4f, 0x6a, 0xf3, 0x6a, 0xe3, 0x52, 0x69, 0x1b, 0x10, 0x7c, 0x85, 0xf1, 0xe8, 0xa7,
-	0x03, 0x5d, 0x9c, 0xb1, 0xa7, 0xd9, 0x47, 0xcd, 0xaf, 0xa0, 0x6b, 0x6c, 0x6a, 0x6b, 0x07, 0x07,
-	0x97, 0x8f, 0xff, 0x5e, 0x2b, 0xae, 0xca, 0xac, 0x66, 0xd1, 0x27, 0x40, 0x8c, 0xda, 0xe8, 0x8c,
-	0x57, 0x7b, 0xd5, 0x9e, 0xf4, 0x6b, 0x20, 0xca, 0xe9, 0x0b, 0xf0, 0xdb, 0xaf, 0x2e, 0x53, 0xa9,
-	0x0c, 0xda, 0xd3, 0x61, 0xed, 0x29, 0x2c, 0x2a, 0xac, 0x3d, 0x8d, 0xe5, 0xb2, 0xe1, 0xec, 0x9d,
-	0xc6, 0x72, 0x89, 0x94, 0xd1, 0x27, 0xa0, 0xdb, 0x4b, 0x8e, 0x65, 0x5a, 0x9a, 0x95, 0xb2, 0x86,
-	0xbe, 0x01, 0x62, 0xda, 0xa4, 0xb9, 0xfe, 0xd3, 0x7f, 0x5f, 0x3f, 0xdb, 0x11, 0x2f, 0xae, 0x00,
-	0x76, 0x2a, 0xe8, 0x10, 0x4e, 0xa2, 0x45, 0x74, 0x1d, 0x4d, 0xe7, 0xd1, 0xd7, 0x68, 0xf1, 0x71,
-	0xf8, 0x1f, 0xf5, 0x81, 0x4c, 0x6f, 0xa6, 0xd1, 0x7c, 0xfa, 0x6e, 0x3e, 0x1b, 0x3a, 0xd4, 0x83,
-	0xe3, 0xf9, 0x6c, 0x7a, 0x53, 0xd5, 0xdc, 0xdb, 0x1e, 0xfe, 0x91, 0xaf, 0xff, 0x04, 0x00, 0x00,
-	0xff, 0xff, 0x20, 0x36, 0x61, 0x31, 0xa4, 0x03, 0x00, 0x00,
+	// 655 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x74, 0x54, 0x5d, 0x6f, 0xd3, 0x30,
+	0x14, 0x25, 0xe9, 0xda, 0x26, 0xb7, 0x4d, 0xe9, 0x0c, 0x8c, 0xb0, 0x69, 0xa2, 0x14, 0x4d, 0xaa,
+	0x86, 0xe8, 0xc3, 0xe0, 0x01, 0xed, 0x05, 0x15, 0x34, 0x50, 0x50, 0x3b, 0x21, 0x77, 0xda, 0xc3,
+	0x5e, 0xa2, 0x2c, 0x71, 0x5b, 0x6b, 0x8b, 0x1d, 0xd9, 0x6e, 0xd1, 0xf6, 0x1b, 0xf8, 0x27, 0x88,
+	0xff, 0x88, 0x62, 0x27, 0x5d, 0xba, 0x8d, 0xb7, 0xdc, 0xe3, 0x73, 0x3f, 0xce, 0xb1, 0x6f, 0xe0,
+	0x69, 0x76, 0x1d, 0xc5, 0x24, 0x25, 0x4c, 0x0d, 0x33, 0xc1, 0x15, 0x47, 0xad, 0x35, 0x90, 0x5d,
+	0xf6, 0xff, 0x6e, 0x81, 0xfb, 0xb3, 0x8c, 0xd1, 0x57, 0x70, 0x29, 0x93, 0x2a, 0x62, 0x31, 0x91,
+	0xbe, 0xd5, 0xab, 0x0d, 0x5a, 0x47, 0x07, 0xc3, 0x0a, 0x7d, 0xb8, 0xa6, 0x0e, 0x83, 0x92, 0x77,
+	0xc2, 0x94, 0xb8, 0xc1, 0x77, 0x79, 0xe8, 0x00, 0x3a, 0x82, 0x64, 0xd7, 0x34, 0x8e, 0xc2, 0x59,
+	0x14, 0x2b, 0x2e, 0x7c, 0xbb, 0x67, 0x0d, 0x3c, 0xec, 0x15, 0xe8, 0x37, 0x0d, 0xa2, 0x7d, 0x00,
+	0xb6, 0x4c, 0x43, 0xb9, 0x88, 0x44, 0x22, 0xfd, 0x9a, 0xa6, 0xb8, 0x6c, 0x99, 0x4e, 0x35, 0x90,
+	0x1f, 0x53, 0x69, 0x4e, 0x49, 0xe2, 0x6f, 0xf5, 0xac, 0x81, 0x83, 0x5d, 0x2a, 0xa7, 0x06, 0x40,
+	0x6f, 0xa0, 0x1d, 0x2f, 0x15, 0x5f, 0x11, 0x11, 0x2a, 0x9a, 0x12, 0xbf, 0xde, 0xb3, 0x06, 0x35,
+	0xdc, 0x2a, 0xb0, 0x33, 0x9a, 0x12, 0xf4, 0x1a, 0x5a, 0x54, 0x86, 0x29, 0x15, 0x82, 0x0b, 0x92,
+	0xf8, 0x0d, 0x5d, 0x02, 0xa8, 0x9c, 0x14, 0x08, 0x9a, 0x80, 0x77, 0xcb, 0x19, 0x09, 0x8b, 0xb9,
+	0xa4, 0xdf, 0xd4, 0x8a, 0x07, 0xff, 0x51, 0x7c, 0xc1, 0x19, 0xc1, 0x05, 0xd5, 0x88, 0x6e, 0xdf,
+	0x56, 0x20, 0x34, 0x81, 0x67, 0x19, 0x61, 0x09, 0x65, 0x73, 0x33, 0x76, 0x98, 0xf2, 0x15, 0x91,
+	0xbe, 0xa3, 0x8b, 0xee, 0x6f, 0x16, 0x35, 0x3c, 0x2d, 0x66, 0xc2, 0x57, 0x04, 0x6f, 0x67, 0xf7,
+	0x10, 0xb9, 0x3b, 0x85, 0xce, 0xa6, 0xc7, 0xa8, 0x0b, 0xb5, 0x2b, 0x72, 0xe3, 0x5b, 0x3d, 0x6b,
+	0xe0, 0xe2, 0xfc, 0x13, 0xbd, 0x83, 0xfa, 0x2a, 0xba, 0x5e, 0x12, 0xed, 0x70, 0xeb, 0xe8, 0xc5,
+	0x46, 0x93, 0x32, 0x1b, 0x1b, 0xce, 0xb1, 0xfd, 0xc9, 0xda, 0xfd, 0x0c, 0xdb, 0x0f, 0x64, 0x3c,
+	0x52, 0xf7, 0x79, 0xb5, 0xae, 0x57, 0x29, 0xd0, 0xff, 0x6d, 0x83, 0x53, 0x16, 0x46, 0x1d, 0xb0,
+	0x69, 0x52, 0xe4, 0xd9, 0x34, 0x41, 0x08, 0xb6, 0x44, 0x14, 0x5f, 0xe9, 0x2c, 0x17, 0xeb, 0xef,
+	0x1c, 0xcb, 0x5d, 0xd2, 0x17, 0xec, 0x62, 0xfd, 0x8d, 0x76, 0xa0, 0xf1, 0x8b, 0xd0, 0xf9, 0x42,
+	0xe9, 0x7b, 0xf5, 0x70, 0x11, 0xa1, 0x5d, 0x70, 0x08, 0x4b, 0x32, 0x4e, 0x99, 0xd2, 0x17, 0xea,
+	0xe2, 0x75, 0x8c, 0x0e, 0xa1, 0x51, 0x3c, 0x95, 0x86, 0x36, 0x14, 0x6d, 0x68, 0xd5, 0xbe, 0xe1,
+	0x82, 0x81, 0x7a, 0xd0, 0x36, 0x37, 0x20, 0x89, 0x0a, 0x69, 0xe2, 0x37, 0x75, 0x17, 0xd0, 0xd8,
+	0x94, 0xa8, 0x20, 0xc9, 0x3b, 0x2d, 0xb8, 0x54, 0x2c, 0x4a, 0x89, 0xef, 0x98, 0x4e, 0x65, 0x9c,
+	0x4f, 0x9c, 0x71, 0xa1, 0x7c, 0x57, 0x67, 0xe9, 0xef, 0x7c, 0x62, 0x41, 0xe6, 0x94, 0x33, 0x1f,
+	0x34, 0xbb, 0x88, 0xfa, 0x7f, 0x2c, 0xa8, 0xeb, 0xde, 0x15, 0x2f, 0x3c, 0xed, 0xc5, 0x7b, 0xa8,
+	0x4b, 0x15, 0x29, 0x63, 0x61, 0xe7, 0xe8, 0xe5, 0xc3, 0x71, 0xa7, 0xf9, 0x31, 0x36, 0x2c, 0xb4,
+	0x07, 0xae, 0xe4, 0x4b, 0x11, 0x93, 0x7c, 0x5e, 0xe3, 0x95, 0x63, 0x80, 0x20, 0x41, 0x6f, 0xc1,
+	0x2b, 0x1f, 0x3b, 0x8b, 0x18, 0x97, 0xda, 0xb6, 0x1a, 0x2e, 0x37, 0xe0, 0x34, 0xc7, 0xca, 0x8d,
+	0x98, 0xcd, 0x0a, 0x4e, 0x65, 0x23, 0x66, 0x33, 0x4d, 0xe9, 0xff, 0x00, 0xb4, 0x7e, 0xce, 0x53,
+	0x16, 0x65, 0x72, 0xc1, 0x95, 0x44, 0x1f, 0xc1, 0x95, 0x65, 0x50, 0x2c, 0xfd, 0xce, 0xe3, 0x2b,
+	0x80, 0xef, 0x88, 0xfd, 0x39, 0x74, 0xef, 0xbf, 0x62, 0xf4, 0x0a, 0x1c, 0xe3, 0xfb, 0xda, 0x89,
+	0xa6, 0x8e, 0x83, 0x64, 0x53, 0x9f, 0x7d, 0x4f, 0xdf, 0x1e, 0xb8, 0x2a, 0x12, 0x73, 0xa2, 0x2a,
+	0xe2, 0x0d, 0x10, 0x24, 0x87, 0xc7, 0x00, 0x77, 0x76, 0xa1, 0x2e, 0xb4, 0x83, 0xd3, 0xe0, 0x2c,
+	0x18, 0x8d, 0x83, 0x8b, 0xe0, 0xf4, 0x7b, 0xf7, 0x09, 0xf2, 0xc0, 0x1d, 0x9d, 0x8f, 0x82, 0xf1,
+	0xe8, 0xcb, 0xf8, 0xa4, 0x6b, 0xa1, 0x16, 0x34, 0xc7, 0x27, 0xa3, 0xf3, 0xfc, 0xcc, 0xbe, 0x6c,
+	0xe8, 0x3f, 0xde, 0x87, 0x7f, 0x03, 0x00, 0x19, 0xb9, 0x35, 0xe2, 0x04, 0x05, 0x00, 0x00,
 }
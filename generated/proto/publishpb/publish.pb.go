@@ -0,0 +1,91 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+
+// Code generated by protoc-gen-go.
+// source: publish.proto
+// DO NOT EDIT!
+
+/*
+Package publishpb is a generated protocol buffer package.
+
+It is generated from these files:
+	publish.proto
+
+It has these top-level messages:
+	Generation
+*/
+package publishpb
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+// A Generation records the versions of a group of keys that were published
+// together, so that watchers can observe a consistent set of values
+type Generation struct {
+	// generation is incremented on every publish
+	Generation int64 `protobuf:"varint,1,opt,name=generation" json:"generation,omitempty"`
+	// versions maps each key in the group to its version as of this generation
+	Versions map[string]int32 `protobuf:"bytes,2,rep,name=versions" json:"versions,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+}
+
+func (m *Generation) Reset()                    { *m = Generation{} }
+func (m *Generation) String() string            { return proto.CompactTextString(m) }
+func (*Generation) ProtoMessage()               {}
+func (*Generation) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+func (m *Generation) GetVersions() map[string]int32 {
+	if m != nil {
+		return m.Versions
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*Generation)(nil), "publishpb.Generation")
+}
+
+func init() { proto.RegisterFile("publish.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 157 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0xe2, 0x2d, 0x28, 0x4d, 0xca,
+	0xc9, 0x2c, 0xce, 0xd0, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0xe2, 0x84, 0x72, 0x0b, 0x92, 0x94,
+	0x56, 0x31, 0x72, 0x71, 0xb9, 0xa7, 0xe6, 0xa5, 0x16, 0x25, 0x96, 0x64, 0xe6, 0xe7, 0x09, 0xc9,
+	0x71, 0x71, 0xa5, 0xc3, 0x79, 0x12, 0x8c, 0x0a, 0x8c, 0x1a, 0xcc, 0x41, 0x48, 0x22, 0x42, 0xf6,
+	0x5c, 0x1c, 0x65, 0xa9, 0x45, 0xc5, 0x99, 0xf9, 0x79, 0xc5, 0x12, 0x4c, 0x0a, 0xcc, 0x1a, 0xdc,
+	0x46, 0xca, 0x7a, 0x70, 0xc3, 0xf4, 0x10, 0x06, 0xe9, 0x85, 0x41, 0x55, 0xb9, 0xe6, 0x95, 0x14,
+	0x55, 0x06, 0xc1, 0x35, 0x49, 0x59, 0x73, 0xf1, 0xa2, 0x48, 0x09, 0x09, 0x70, 0x31, 0x67, 0xa7,
+	0x56, 0x82, 0xad, 0xe2, 0x0c, 0x02, 0x31, 0x85, 0x44, 0xb8, 0x58, 0xcb, 0x12, 0x73, 0x4a, 0x53,
+	0x25, 0x98, 0x14, 0x18, 0x35, 0x58, 0x83, 0x20, 0x1c, 0x2b, 0x26, 0x0b, 0xc6, 0x24, 0x36, 0xb0,
+	0xf3, 0x8d, 0x01, 0x03, 0x00, 0x9d, 0x95, 0x57, 0xe4, 0xcf, 0x00, 0x00, 0x00,
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+
+// Code generated by protoc-gen-go.
+// source: replication.proto
+// DO NOT EDIT!
+
+/*
+Package replicationpb is a generated protocol buffer package.
+
+It is generated from these files:
+	replication.proto
+
+It has these top-level messages:
+	Origin
+*/
+package replicationpb
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+// An Origin tags a value written by replication with where it came from
+type Origin struct {
+	// zone is the zone the value was originally written in
+	Zone string `protobuf:"bytes,1,opt,name=zone" json:"zone,omitempty"`
+	// source_version is the version of the value in the zone it was
+	// replicated from
+	SourceVersion int32 `protobuf:"varint,2,opt,name=source_version,json=sourceVersion" json:"source_version,omitempty"`
+	// version is the version of the replicated value
+	Version int32 `protobuf:"varint,3,opt,name=version" json:"version,omitempty"`
+	// written_at_nanos is when the value was first seen in the zone it was
+	// originally written in
+	WrittenAtNanos int64 `protobuf:"varint,4,opt,name=written_at_nanos,json=writtenAtNanos" json:"written_at_nanos,omitempty"`
+}
+
+func (m *Origin) Reset()                    { *m = Origin{} }
+func (m *Origin) String() string            { return proto.CompactTextString(m) }
+func (*Origin) ProtoMessage()               {}
+func (*Origin) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+func init() {
+	proto.RegisterType((*Origin)(nil), "replicationpb.Origin")
+}
+
+func init() { proto.RegisterFile("replication.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 155 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0x12, 0x2c, 0x4a, 0x2d, 0xc8,
+	0xc9, 0x4c, 0x4e, 0x2c, 0xc9, 0xcc, 0xcf, 0xd3, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0xe2, 0x45,
+	0x12, 0x2a, 0x48, 0x52, 0x6a, 0x67, 0xe4, 0x62, 0xf3, 0x2f, 0xca, 0x4c, 0xcf, 0xcc, 0x13, 0x12,
+	0xe2, 0x62, 0xa9, 0xca, 0xcf, 0x4b, 0x95, 0x60, 0x54, 0x60, 0xd4, 0xe0, 0x0c, 0x02, 0xb3, 0x85,
+	0x54, 0xb9, 0xf8, 0x8a, 0xf3, 0x4b, 0x8b, 0x92, 0x53, 0xe3, 0xcb, 0x52, 0x8b, 0x8a, 0x33, 0xf3,
+	0xf3, 0x24, 0x98, 0x14, 0x18, 0x35, 0x58, 0x83, 0x78, 0x21, 0xa2, 0x61, 0x10, 0x41, 0x21, 0x09,
+	0x2e, 0x76, 0x98, 0x3c, 0x33, 0x58, 0x1e, 0xc6, 0x15, 0xd2, 0xe0, 0x12, 0x28, 0x2f, 0xca, 0x2c,
+	0x29, 0x49, 0xcd, 0x8b, 0x4f, 0x2c, 0x89, 0xcf, 0x4b, 0xcc, 0xcb, 0x2f, 0x96, 0x60, 0x51, 0x60,
+	0xd4, 0x60, 0x0e, 0xe2, 0x83, 0x8a, 0x3b, 0x96, 0xf8, 0x81, 0x44, 0x93, 0xd8, 0xc0, 0xee, 0x33,
+	0x06, 0x0c, 0x00, 0xbd, 0x8e, 0x90, 0xbe, 0xb4, 0x00, 0x00, 0x00,
+}
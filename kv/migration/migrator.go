@@ -0,0 +1,270 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package migration upgrades configuration values stored in kv to the latest
+// schema version as they are read, so that evolving a config proto does not
+// require rewriting existing values by hand.
+package migration
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/m3db/m3cluster/kv"
+	"github.com/m3db/m3x/log"
+
+	"github.com/uber-go/tally"
+)
+
+var (
+	// ErrUnknownSchemaVersion is returned when migrating a value whose schema
+	// version has not been registered
+	ErrUnknownSchemaVersion = errors.New("unknown schema version")
+
+	errVersionOutOfOrder = errors.New("schema versions must be registered in order")
+	errNilMessage        = errors.New("schema message must not be nil")
+	errNilUpgradeFn      = errors.New("upgrade function must be specified")
+)
+
+// UpgradeFn upgrades a value from the previous schema version, returning a
+// value of the next schema version.  For schemas that keep the version in a
+// field of the value, the upgrade function is responsible for setting it
+type UpgradeFn func(prev proto.Message) (proto.Message, error)
+
+// A Migrator upgrades values read from kv to the latest registered schema
+// version
+type Migrator interface {
+	// Register registers the message type of a schema version, along with the
+	// function that upgrades values from the previous schema version.  Schema
+	// versions must be registered in increasing order without gaps, and the
+	// upgrade function of the first registered version is ignored
+	Register(version int, msg proto.Message, upgrade UpgradeFn) error
+
+	// LatestVersion returns the latest registered schema version, or zero if
+	// no schema versions have been registered
+	LatestVersion() int
+
+	// Migrate returns the value read from key upgraded to the latest schema
+	// version.  The upgraded value keeps the kv version of the stored value.
+	// Values already at the latest schema version are returned as is
+	Migrate(key string, v kv.Value) (kv.Value, error)
+}
+
+type schemaVersion struct {
+	msg     proto.Message
+	upgrade UpgradeFn
+}
+
+type migratorMetrics struct {
+	upgraded        tally.Counter
+	upgradeErrors   tally.Counter
+	writeBacks      tally.Counter
+	writeBackErrors tally.Counter
+}
+
+func newMigratorMetrics(scope tally.Scope) migratorMetrics {
+	return migratorMetrics{
+		upgraded:        scope.Counter("upgraded"),
+		upgradeErrors:   scope.Counter("upgrade-errors"),
+		writeBacks:      scope.Counter("write-backs"),
+		writeBackErrors: scope.Counter("write-back-errors"),
+	}
+}
+
+type migrator struct {
+	sync.RWMutex
+
+	schema    Schema
+	store     kv.Store
+	writeBack bool
+	log       log.Logger
+	metrics   migratorMetrics
+
+	first    int
+	versions []schemaVersion
+}
+
+// NewMigrator creates a new Migrator
+func NewMigrator(opts Options) (Migrator, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	iopts := opts.InstrumentOptions()
+	return &migrator{
+		schema:    opts.Schema(),
+		store:     opts.KVStore(),
+		writeBack: opts.WriteBack(),
+		log:       iopts.Logger(),
+		metrics:   newMigratorMetrics(iopts.MetricsScope().SubScope("migration")),
+	}, nil
+}
+
+func (m *migrator) Register(version int, msg proto.Message, upgrade UpgradeFn) error {
+	if msg == nil {
+		return errNilMessage
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	if len(m.versions) == 0 {
+		m.first = version
+		m.versions = append(m.versions, schemaVersion{msg: msg})
+		return nil
+	}
+
+	if version != m.first+len(m.versions) {
+		return errVersionOutOfOrder
+	}
+
+	if upgrade == nil {
+		return errNilUpgradeFn
+	}
+
+	m.versions = append(m.versions, schemaVersion{msg: msg, upgrade: upgrade})
+	return nil
+}
+
+func (m *migrator) LatestVersion() int {
+	m.RLock()
+	defer m.RUnlock()
+	return m.latestWithLock()
+}
+
+func (m *migrator) latestWithLock() int {
+	if len(m.versions) == 0 {
+		return 0
+	}
+
+	return m.first + len(m.versions) - 1
+}
+
+func (m *migrator) Migrate(key string, v kv.Value) (kv.Value, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	from, err := m.schema.Version(key, v)
+	if err != nil {
+		return nil, err
+	}
+
+	m.RLock()
+	latest := m.latestWithLock()
+	if from == latest {
+		m.RUnlock()
+		return v, nil
+	}
+
+	if from < m.first || from > latest {
+		m.RUnlock()
+		return nil, ErrUnknownSchemaVersion
+	}
+
+	versions := m.versions[from-m.first:]
+	m.RUnlock()
+
+	msg, err := upgrade(v, versions)
+	if err != nil {
+		m.metrics.upgradeErrors.Inc(1)
+		return nil, fmt.Errorf("could not upgrade %s from schema version %d to %d: %v", key, from, latest, err)
+	}
+
+	m.metrics.upgraded.Inc(1)
+	if m.writeBack {
+		m.writeBackUpgraded(key, latest, v, msg)
+	}
+
+	return newUpgradedValue(v.Version(), msg), nil
+}
+
+// upgrade unmarshals a value as the first of the given schema versions and
+// applies the upgrade functions of the remaining versions in turn
+func upgrade(v kv.Value, versions []schemaVersion) (proto.Message, error) {
+	msg := proto.Clone(versions[0].msg)
+	if err := v.Unmarshal(msg); err != nil {
+		return nil, err
+	}
+
+	for _, next := range versions[1:] {
+		upgraded, err := next.upgrade(msg)
+		if err != nil {
+			return nil, err
+		}
+
+		msg = upgraded
+	}
+
+	return msg, nil
+}
+
+// writeBackUpgraded stores an upgraded value so later reads don't need to
+// upgrade it again.  Failing to write back does not fail the read, and losing
+// the race to another writer is expected when there are many readers
+func (m *migrator) writeBackUpgraded(key string, latest int, v kv.Value, msg proto.Message) {
+	var (
+		target = m.schema.Key(key, latest)
+		err    error
+	)
+
+	if target == key {
+		_, err = m.store.CheckAndSet(key, v.Version(), msg)
+	} else {
+		_, err = m.store.SetIfNotExists(target, msg)
+	}
+
+	switch err {
+	case nil:
+		m.metrics.writeBacks.Inc(1)
+	case kv.ErrVersionMismatch, kv.ErrAlreadyExists:
+	default:
+		m.metrics.writeBackErrors.Inc(1)
+		m.log.Errorf("could not write back upgraded value for %s: %v", target, err)
+	}
+}
+
+type upgradedValue struct {
+	version int
+	msg     proto.Message
+}
+
+func newUpgradedValue(version int, msg proto.Message) kv.Value {
+	return &upgradedValue{version: version, msg: msg}
+}
+
+func (v *upgradedValue) Unmarshal(msg proto.Message) error {
+	data, err := proto.Marshal(v.msg)
+	if err != nil {
+		return err
+	}
+
+	return proto.Unmarshal(data, msg)
+}
+
+func (v *upgradedValue) Version() int {
+	return v.version
+}
+
+func (v *upgradedValue) IsNewer(other kv.Value) bool {
+	return v.version > other.Version()
+}
@@ -0,0 +1,182 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package migration
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/m3db/m3cluster/generated/proto/migrationtest"
+	"github.com/m3db/m3cluster/kv/mem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigratorOptions(t *testing.T) {
+	_, err := NewMigrator(NewOptions())
+	require.Equal(t, errSchemaNotSet, err)
+
+	_, err = NewMigrator(NewOptions().SetSchema(testFieldSchema()).SetWriteBack(true))
+	require.Equal(t, errStoreNotSet, err)
+}
+
+func TestMigratorRegister(t *testing.T) {
+	m, err := NewMigrator(NewOptions().SetSchema(testFieldSchema()))
+	require.NoError(t, err)
+	require.Equal(t, 0, m.LatestVersion())
+
+	require.Equal(t, errNilMessage, m.Register(1, nil, nil))
+	require.NoError(t, m.Register(1, &migrationtest.ConfigV1{}, nil))
+	require.Equal(t, errVersionOutOfOrder, m.Register(3, &migrationtest.ConfigV2{}, upgradeV1))
+	require.Equal(t, errNilUpgradeFn, m.Register(2, &migrationtest.ConfigV2{}, nil))
+	require.NoError(t, m.Register(2, &migrationtest.ConfigV2{}, upgradeV1))
+	require.Equal(t, 2, m.LatestVersion())
+}
+
+func TestMigrateFieldSchema(t *testing.T) {
+	m := newTestMigrator(t, NewOptions().SetSchema(testFieldSchema()))
+
+	v1 := mem.NewValue(3, &migrationtest.ConfigV1{SchemaVersion: 1, Hosts: "a,b"})
+	migrated, err := m.Migrate("config", v1)
+	require.NoError(t, err)
+	require.Equal(t, 3, migrated.Version())
+	require.True(t, migrated.IsNewer(mem.NewValue(2, nil)))
+
+	var cfg migrationtest.ConfigV2
+	require.NoError(t, migrated.Unmarshal(&cfg))
+	require.Equal(t, int32(2), cfg.SchemaVersion)
+	require.Equal(t, []string{"a", "b"}, cfg.Hosts)
+
+	// Values already at the latest version are returned as is
+	v2 := mem.NewValue(4, &migrationtest.ConfigV2{SchemaVersion: 2, Hosts: []string{"c"}})
+	migrated, err = m.Migrate("config", v2)
+	require.NoError(t, err)
+	require.Equal(t, v2, migrated)
+
+	migrated, err = m.Migrate("config", nil)
+	require.NoError(t, err)
+	require.Nil(t, migrated)
+}
+
+func TestMigrateUnknownSchemaVersion(t *testing.T) {
+	m := newTestMigrator(t, NewOptions().SetSchema(testFieldSchema()))
+
+	for _, version := range []int32{0, 3} {
+		_, err := m.Migrate("config", mem.NewValue(1, &migrationtest.ConfigV2{SchemaVersion: version}))
+		require.Equal(t, ErrUnknownSchemaVersion, err)
+	}
+}
+
+func TestMigrateUpgradeError(t *testing.T) {
+	m, err := NewMigrator(NewOptions().SetSchema(testFieldSchema()))
+	require.NoError(t, err)
+	require.NoError(t, m.Register(1, &migrationtest.ConfigV1{}, nil))
+	require.NoError(t, m.Register(2, &migrationtest.ConfigV2{}, func(proto.Message) (proto.Message, error) {
+		return nil, errors.New("bad upgrade")
+	}))
+
+	_, err = m.Migrate("config", mem.NewValue(1, &migrationtest.ConfigV1{SchemaVersion: 1}))
+	require.Error(t, err)
+}
+
+func TestMigrateWriteBack(t *testing.T) {
+	store := mem.NewStore()
+	m := newTestMigrator(t, NewOptions().
+		SetSchema(testFieldSchema()).
+		SetKVStore(store).
+		SetWriteBack(true))
+
+	_, err := store.Set("config", &migrationtest.ConfigV1{SchemaVersion: 1, Hosts: "a"})
+	require.NoError(t, err)
+
+	v, err := store.Get("config")
+	require.NoError(t, err)
+	_, err = m.Migrate("config", v)
+	require.NoError(t, err)
+
+	// The upgraded value replaced the old one
+	v, err = store.Get("config")
+	require.NoError(t, err)
+	require.Equal(t, 2, v.Version())
+
+	var cfg migrationtest.ConfigV2
+	require.NoError(t, v.Unmarshal(&cfg))
+	require.Equal(t, int32(2), cfg.SchemaVersion)
+	require.Equal(t, []string{"a"}, cfg.Hosts)
+
+	// Losing the race to write back doesn't fail the read
+	stale := mem.NewValue(1, &migrationtest.ConfigV1{SchemaVersion: 1, Hosts: "a"})
+	_, err = m.Migrate("config", stale)
+	require.NoError(t, err)
+
+	v, err = store.Get("config")
+	require.NoError(t, err)
+	require.Equal(t, 2, v.Version())
+}
+
+func TestMigrateKeySuffixWriteBack(t *testing.T) {
+	store := mem.NewStore()
+	m := newTestMigrator(t, NewOptions().
+		SetSchema(NewKeySuffixSchema("/v")).
+		SetKVStore(store).
+		SetWriteBack(true))
+
+	_, err := store.Set("config/v1", &migrationtest.ConfigV1{Hosts: "a,b"})
+	require.NoError(t, err)
+
+	v, err := store.Get("config/v1")
+	require.NoError(t, err)
+	migrated, err := m.Migrate("config/v1", v)
+	require.NoError(t, err)
+
+	var cfg migrationtest.ConfigV2
+	require.NoError(t, migrated.Unmarshal(&cfg))
+	require.Equal(t, []string{"a", "b"}, cfg.Hosts)
+
+	// The upgraded value is stored under the key for the latest version
+	v, err = store.Get("config/v2")
+	require.NoError(t, err)
+	require.Equal(t, 1, v.Version())
+
+	_, err = store.Get("config/v1")
+	require.NoError(t, err)
+}
+
+func newTestMigrator(t *testing.T, opts Options) Migrator {
+	m, err := NewMigrator(opts)
+	require.NoError(t, err)
+	require.NoError(t, m.Register(1, &migrationtest.ConfigV1{}, nil))
+	require.NoError(t, m.Register(2, &migrationtest.ConfigV2{}, upgradeV1))
+	return m
+}
+
+func testFieldSchema() Schema {
+	return NewFieldSchema(&migrationtest.ConfigV2{}, "SchemaVersion")
+}
+
+func upgradeV1(prev proto.Message) (proto.Message, error) {
+	v1 := prev.(*migrationtest.ConfigV1)
+	return &migrationtest.ConfigV2{
+		SchemaVersion: 2,
+		Hosts:         strings.Split(v1.Hosts, ","),
+	}, nil
+}
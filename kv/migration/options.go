@@ -0,0 +1,131 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package migration
+
+import (
+	"errors"
+
+	"github.com/m3db/m3cluster/kv"
+	"github.com/m3db/m3x/instrument"
+)
+
+var (
+	errSchemaNotSet = errors.New("schema must be specified")
+	errStoreNotSet  = errors.New("kv store must be specified to write back upgraded values")
+)
+
+// Options provide a set of migration options.
+type Options interface {
+	// SetSchema sets the schema used to determine the schema version of values.
+	SetSchema(value Schema) Options
+
+	// Schema returns the schema used to determine the schema version of values.
+	Schema() Schema
+
+	// SetWriteBack sets whether upgraded values are written back to kv. With a
+	// key suffix schema the value is written under a new key, which watches of
+	// the original key do not see.
+	SetWriteBack(value bool) Options
+
+	// WriteBack returns whether upgraded values are written back to kv.
+	WriteBack() bool
+
+	// SetKVStore sets the kv store upgraded values are written back to.
+	SetKVStore(value kv.Store) Options
+
+	// KVStore returns the kv store upgraded values are written back to.
+	KVStore() kv.Store
+
+	// SetInstrumentOptions sets the instrument options.
+	SetInstrumentOptions(value instrument.Options) Options
+
+	// InstrumentOptions returns the instrument options.
+	InstrumentOptions() instrument.Options
+
+	// Validate validates the options.
+	Validate() error
+}
+
+type options struct {
+	schema         Schema
+	writeBack      bool
+	kvStore        kv.Store
+	instrumentOpts instrument.Options
+}
+
+// NewOptions creates a new set of options.
+func NewOptions() Options {
+	return &options{
+		instrumentOpts: instrument.NewOptions(),
+	}
+}
+
+func (o *options) SetSchema(value Schema) Options {
+	opts := *o
+	opts.schema = value
+	return &opts
+}
+
+func (o *options) Schema() Schema {
+	return o.schema
+}
+
+func (o *options) SetWriteBack(value bool) Options {
+	opts := *o
+	opts.writeBack = value
+	return &opts
+}
+
+func (o *options) WriteBack() bool {
+	return o.writeBack
+}
+
+func (o *options) SetKVStore(value kv.Store) Options {
+	opts := *o
+	opts.kvStore = value
+	return &opts
+}
+
+func (o *options) KVStore() kv.Store {
+	return o.kvStore
+}
+
+func (o *options) SetInstrumentOptions(value instrument.Options) Options {
+	opts := *o
+	opts.instrumentOpts = value
+	return &opts
+}
+
+func (o *options) InstrumentOptions() instrument.Options {
+	return o.instrumentOpts
+}
+
+func (o *options) Validate() error {
+	if o.schema == nil {
+		return errSchemaNotSet
+	}
+
+	if o.writeBack && o.kvStore == nil {
+		return errStoreNotSet
+	}
+
+	return nil
+}
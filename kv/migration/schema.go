@@ -0,0 +1,117 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package migration
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/m3db/m3cluster/kv"
+)
+
+var (
+	errNoSchemaVersion = errors.New("key has no schema version suffix")
+)
+
+// A Schema determines the schema version of values stored in kv, and the key
+// values of a given schema version are stored under
+type Schema interface {
+	// Version returns the schema version of a value stored under a key
+	Version(key string, v kv.Value) (int, error)
+
+	// Key returns the key a value of the given schema version is stored under,
+	// given the key of a value of any schema version
+	Key(key string, version int) string
+}
+
+type fieldSchema struct {
+	header    proto.Message
+	fieldName string
+}
+
+// NewFieldSchema returns a Schema that reads the schema version from an
+// integer field of the stored value.  The header is any message declaring
+// the version field under the same field number as every schema version,
+// typically the message for the latest schema, and fieldName is the Go name
+// of the version field.  All schema versions are stored under the same key
+func NewFieldSchema(header proto.Message, fieldName string) Schema {
+	return fieldSchema{header: header, fieldName: fieldName}
+}
+
+func (s fieldSchema) Version(key string, v kv.Value) (int, error) {
+	header := proto.Clone(s.header)
+	if err := v.Unmarshal(header); err != nil {
+		return 0, err
+	}
+
+	field := reflect.ValueOf(header).Elem().FieldByName(s.fieldName)
+	switch field.Kind() {
+	case reflect.Int32, reflect.Int64:
+		return int(field.Int()), nil
+	case reflect.Uint32, reflect.Uint64:
+		return int(field.Uint()), nil
+	default:
+		return 0, fmt.Errorf("schema version field %s is not an integer", s.fieldName)
+	}
+}
+
+func (s fieldSchema) Key(key string, version int) string {
+	return key
+}
+
+type keySuffixSchema struct {
+	separator string
+}
+
+// NewKeySuffixSchema returns a Schema that stores each schema version under
+// its own key, suffixed with the separator and the schema version, for
+// example config/v2 for a separator of "/v".
+//
+// Values under a key suffix schema are only upgraded on read.  Writing back
+// stores the upgraded value under the key of the latest schema version, but
+// watches, including those of kv/util and runtime.Value, stay on the key they
+// were created for and never see values written under the new key.  Watchers
+// that need to follow later writes should watch the key of the latest schema
+// version once the values have been migrated
+func NewKeySuffixSchema(separator string) Schema {
+	return keySuffixSchema{separator: separator}
+}
+
+func (s keySuffixSchema) Version(key string, v kv.Value) (int, error) {
+	idx := strings.LastIndex(key, s.separator)
+	if idx < 0 {
+		return 0, errNoSchemaVersion
+	}
+
+	return strconv.Atoi(key[idx+len(s.separator):])
+}
+
+func (s keySuffixSchema) Key(key string, version int) string {
+	if idx := strings.LastIndex(key, s.separator); idx >= 0 {
+		key = key[:idx]
+	}
+
+	return key + s.separator + strconv.Itoa(version)
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package migration
+
+import (
+	"testing"
+
+	"github.com/m3db/m3cluster/generated/proto/migrationtest"
+	"github.com/m3db/m3cluster/kv/mem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldSchema(t *testing.T) {
+	s := NewFieldSchema(&migrationtest.ConfigV2{}, "SchemaVersion")
+
+	// Older schema versions are read through the latest message
+	version, err := s.Version("config", mem.NewValue(1, &migrationtest.ConfigV1{SchemaVersion: 1, Hosts: "a"}))
+	require.NoError(t, err)
+	require.Equal(t, 1, version)
+	require.Equal(t, "config", s.Key("config", 2))
+
+	s = NewFieldSchema(&migrationtest.ConfigV2{}, "Hosts")
+	_, err = s.Version("config", mem.NewValue(1, &migrationtest.ConfigV2{}))
+	require.Error(t, err)
+}
+
+func TestKeySuffixSchema(t *testing.T) {
+	s := NewKeySuffixSchema("/v")
+
+	version, err := s.Version("config/v3", nil)
+	require.NoError(t, err)
+	require.Equal(t, 3, version)
+
+	_, err = s.Version("config", nil)
+	require.Equal(t, errNoSchemaVersion, err)
+
+	_, err = s.Version("config/vx", nil)
+	require.Error(t, err)
+
+	require.Equal(t, "config/v4", s.Key("config/v3", 4))
+	require.Equal(t, "config/v1", s.Key("config", 1))
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package publish
+
+import (
+	"errors"
+
+	"github.com/m3db/m3x/instrument"
+)
+
+var (
+	errGenerationKeyNotSet = errors.New("generation key must be specified")
+)
+
+// Options provide a set of publish options.
+type Options interface {
+	// SetGenerationKey sets the key the publish generation is stored under.
+	SetGenerationKey(value string) Options
+
+	// GenerationKey returns the key the publish generation is stored under.
+	GenerationKey() string
+
+	// SetInstrumentOptions sets the instrument options.
+	SetInstrumentOptions(value instrument.Options) Options
+
+	// InstrumentOptions returns the instrument options.
+	InstrumentOptions() instrument.Options
+
+	// Validate validates the options.
+	Validate() error
+}
+
+type options struct {
+	generationKey  string
+	instrumentOpts instrument.Options
+}
+
+// NewOptions creates a new set of options.
+func NewOptions() Options {
+	return &options{
+		instrumentOpts: instrument.NewOptions(),
+	}
+}
+
+func (o *options) SetGenerationKey(value string) Options {
+	opts := *o
+	opts.generationKey = value
+	return &opts
+}
+
+func (o *options) GenerationKey() string {
+	return o.generationKey
+}
+
+func (o *options) SetInstrumentOptions(value instrument.Options) Options {
+	opts := *o
+	opts.instrumentOpts = value
+	return &opts
+}
+
+func (o *options) InstrumentOptions() instrument.Options {
+	return o.instrumentOpts
+}
+
+func (o *options) Validate() error {
+	if o.generationKey == "" {
+		return errGenerationKeyNotSet
+	}
+
+	return nil
+}
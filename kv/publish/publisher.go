@@ -0,0 +1,263 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package publish provides atomic publishing of configuration that spans
+// several keys.  Every publish writes the staged keys together with a
+// generation record through a single kv.TxnStore transaction, guarded on the
+// version of every key involved, so either all of the keys change or none of
+// them do.  Watchers follow the generation record to observe the keys as a
+// consistent set.
+package publish
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/m3db/m3cluster/generated/proto/publishpb"
+	"github.com/m3db/m3cluster/kv"
+
+	"github.com/uber-go/tally"
+)
+
+var (
+	// ErrConflict is returned when one of the keys in a publish, or the
+	// generation itself, changed after its write was staged
+	ErrConflict = errors.New("publish conflicts with a concurrent update")
+
+	// ErrNothingStaged is returned when publishing without any staged writes
+	ErrNothingStaged = errors.New("no writes staged")
+
+	errGenerationKeyStaged = errors.New("cannot stage a write to the generation key")
+)
+
+// A Generation is a set of key versions published together
+type Generation struct {
+	// Number is the generation number, incremented on every publish
+	Number int64
+
+	// Versions is the version of each key in the group as of this generation
+	Versions map[string]int
+
+	// Values are the values of each key in the group as of this generation.
+	// Only populated for generations returned by a GenerationWatch
+	Values map[string]kv.Value
+}
+
+// A Publisher stages writes to several keys and publishes them atomically
+type Publisher interface {
+	// Stage stages a write of value to key, guarded on the version of the key
+	// at the time it is staged
+	Stage(key string, value proto.Message) error
+
+	// StageIfVersion stages a write of value to key, guarded on the key being
+	// at the given version.  A version of 0 requires that the key not exist
+	StageIfVersion(key string, version int, value proto.Message) error
+
+	// Publish atomically writes all staged values along with the next
+	// generation, returning ErrConflict if any of the guarded keys changed.
+	// Staged writes are discarded once Publish returns, whether or not it
+	// succeeded
+	Publish() (Generation, error)
+
+	// Reset discards all staged writes
+	Reset()
+}
+
+type stagedWrite struct {
+	key     string
+	version int
+	value   proto.Message
+}
+
+type publisherMetrics struct {
+	publishes tally.Counter
+	conflicts tally.Counter
+	errors    tally.Counter
+}
+
+func newPublisherMetrics(scope tally.Scope) publisherMetrics {
+	return publisherMetrics{
+		publishes: scope.Counter("publishes"),
+		conflicts: scope.Counter("conflicts"),
+		errors:    scope.Counter("errors"),
+	}
+}
+
+type publisher struct {
+	sync.Mutex
+
+	store         kv.TxnStore
+	generationKey string
+	metrics       publisherMetrics
+	staged        []stagedWrite
+}
+
+// NewPublisher creates a new Publisher
+func NewPublisher(store kv.TxnStore, opts Options) (Publisher, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	scope := opts.InstrumentOptions().MetricsScope().SubScope("publish")
+	return &publisher{
+		store:         store,
+		generationKey: opts.GenerationKey(),
+		metrics:       newPublisherMetrics(scope),
+	}, nil
+}
+
+func (p *publisher) Stage(key string, value proto.Message) error {
+	version := 0
+	v, err := p.store.Get(key)
+	if err == nil {
+		version = v.Version()
+	} else if err != kv.ErrNotFound {
+		return err
+	}
+
+	return p.StageIfVersion(key, version, value)
+}
+
+func (p *publisher) StageIfVersion(key string, version int, value proto.Message) error {
+	if key == p.generationKey {
+		return errGenerationKeyStaged
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	// Restaging a key replaces the earlier write
+	for i, w := range p.staged {
+		if w.key == key {
+			p.staged[i] = stagedWrite{key: key, version: version, value: value}
+			return nil
+		}
+	}
+
+	p.staged = append(p.staged, stagedWrite{key: key, version: version, value: value})
+	return nil
+}
+
+func (p *publisher) Publish() (Generation, error) {
+	p.Lock()
+	staged := p.staged
+	p.staged = nil
+	p.Unlock()
+
+	if len(staged) == 0 {
+		return Generation{}, ErrNothingStaged
+	}
+
+	gen, err := p.publish(staged)
+	if err != nil {
+		if err == ErrConflict {
+			p.metrics.conflicts.Inc(1)
+		} else {
+			p.metrics.errors.Inc(1)
+		}
+
+		return Generation{}, err
+	}
+
+	p.metrics.publishes.Inc(1)
+	return gen, nil
+}
+
+func (p *publisher) Reset() {
+	p.Lock()
+	p.staged = nil
+	p.Unlock()
+}
+
+func (p *publisher) publish(staged []stagedWrite) (Generation, error) {
+	var (
+		current    publishpb.Generation
+		genVersion int
+	)
+
+	genVal, err := p.store.Get(p.generationKey)
+	if err == nil {
+		if err := genVal.Unmarshal(&current); err != nil {
+			return Generation{}, err
+		}
+
+		genVersion = genVal.Version()
+	} else if err != kv.ErrNotFound {
+		return Generation{}, err
+	}
+
+	// The next generation carries forward the versions of keys in the group
+	// that are not part of this publish
+	next := &publishpb.Generation{
+		Generation: current.Generation + 1,
+		Versions:   make(map[string]int32, len(current.Versions)+len(staged)),
+	}
+	for k, v := range current.Versions {
+		next.Versions[k] = v
+	}
+
+	var (
+		conditions = make([]kv.Condition, 0, len(staged)+1)
+		ops        = make([]kv.Op, 0, len(staged)+1)
+	)
+
+	for _, w := range staged {
+		conditions = append(conditions, versionCondition(w.key, w.version))
+		ops = append(ops, kv.NewSetOp(w.key, w.value))
+
+		// Every successful write increments the version of the key by one, so
+		// the versions produced by the transaction are known up front
+		next.Versions[w.key] = int32(w.version + 1)
+	}
+
+	conditions = append(conditions, versionCondition(p.generationKey, genVersion))
+	ops = append(ops, kv.NewSetOp(p.generationKey, next))
+
+	if _, err := p.store.Commit(conditions, ops); err != nil {
+		if err == kv.ErrConditionCheckFailed {
+			return Generation{}, ErrConflict
+		}
+
+		return Generation{}, err
+	}
+
+	return generationFromProto(next), nil
+}
+
+func versionCondition(key string, version int) kv.Condition {
+	return kv.NewCondition().
+		SetKey(key).
+		SetTargetType(kv.TargetVersion).
+		SetCompareType(kv.CompareEqual).
+		SetValue(version)
+}
+
+func generationFromProto(pb *publishpb.Generation) Generation {
+	versions := make(map[string]int, len(pb.Versions))
+	for k, v := range pb.Versions {
+		versions[k] = int(v)
+	}
+
+	return Generation{
+		Number:   pb.Generation,
+		Versions: versions,
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package publish
+
+import (
+	"testing"
+
+	"github.com/m3db/m3cluster/generated/proto/commonpb"
+	"github.com/m3db/m3cluster/generated/proto/publishpb"
+	"github.com/m3db/m3cluster/kv"
+	"github.com/m3db/m3cluster/kv/mem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublisherOptions(t *testing.T) {
+	_, err := NewPublisher(mem.NewStore(), NewOptions())
+	require.Equal(t, errGenerationKeyNotSet, err)
+}
+
+func TestPublish(t *testing.T) {
+	store := mem.NewStore()
+	p := newTestPublisher(t, store)
+
+	_, err := p.Publish()
+	require.Equal(t, ErrNothingStaged, err)
+
+	require.NoError(t, p.Stage("routes", &commonpb.StringProto{Value: "r1"}))
+	require.NoError(t, p.Stage("schema", &commonpb.StringProto{Value: "s1"}))
+	gen, err := p.Publish()
+	require.NoError(t, err)
+	require.Equal(t, Generation{
+		Number:   1,
+		Versions: map[string]int{"routes": 1, "schema": 1},
+	}, gen)
+
+	// Keys not in a publish keep their version in the generation
+	require.NoError(t, p.Stage("routes", &commonpb.StringProto{Value: "r2"}))
+	gen, err = p.Publish()
+	require.NoError(t, err)
+	require.Equal(t, Generation{
+		Number:   2,
+		Versions: map[string]int{"routes": 2, "schema": 1},
+	}, gen)
+
+	requireString(t, store, "routes", 2, "r2")
+	requireString(t, store, "schema", 1, "s1")
+
+	var pb publishpb.Generation
+	genVal, err := store.Get("generation")
+	require.NoError(t, err)
+	require.NoError(t, genVal.Unmarshal(&pb))
+	require.Equal(t, int64(2), pb.Generation)
+	require.Equal(t, map[string]int32{"routes": 2, "schema": 1}, pb.Versions)
+}
+
+func TestPublishConflict(t *testing.T) {
+	store := mem.NewStore()
+	p := newTestPublisher(t, store)
+
+	require.NoError(t, p.Stage("routes", &commonpb.StringProto{Value: "r1"}))
+	require.NoError(t, p.Stage("schema", &commonpb.StringProto{Value: "s1"}))
+
+	// Someone else writes one of the keys after it was staged
+	_, err := store.Set("schema", &commonpb.StringProto{Value: "other"})
+	require.NoError(t, err)
+
+	_, err = p.Publish()
+	require.Equal(t, ErrConflict, err)
+
+	// Neither key was written by the failed publish
+	_, err = store.Get("routes")
+	require.Equal(t, kv.ErrNotFound, err)
+	requireString(t, store, "schema", 1, "other")
+
+	// Staged writes are discarded after a publish attempt
+	_, err = p.Publish()
+	require.Equal(t, ErrNothingStaged, err)
+}
+
+func TestPublishConcurrentGeneration(t *testing.T) {
+	store := mem.NewStore()
+	p1 := newTestPublisher(t, store)
+	p2 := newTestPublisher(t, store)
+
+	require.NoError(t, p1.StageIfVersion("routes", 0, &commonpb.StringProto{Value: "r1"}))
+	require.NoError(t, p2.StageIfVersion("schema", 0, &commonpb.StringProto{Value: "s1"}))
+
+	_, err := p1.Publish()
+	require.NoError(t, err)
+
+	// The second generation includes the keys from both publishes
+	gen, err := p2.Publish()
+	require.NoError(t, err)
+	require.Equal(t, int64(2), gen.Number)
+	require.Equal(t, map[string]int{"routes": 1, "schema": 1}, gen.Versions)
+}
+
+func TestReset(t *testing.T) {
+	p := newTestPublisher(t, mem.NewStore())
+
+	require.NoError(t, p.Stage("routes", &commonpb.StringProto{Value: "r1"}))
+	p.Reset()
+	_, err := p.Publish()
+	require.Equal(t, ErrNothingStaged, err)
+}
+
+func TestStageGenerationKey(t *testing.T) {
+	p := newTestPublisher(t, mem.NewStore())
+	require.Equal(t, errGenerationKeyStaged, p.Stage("generation", &commonpb.StringProto{}))
+}
+
+func TestStageReplacesEarlierWrite(t *testing.T) {
+	store := mem.NewStore()
+	p := newTestPublisher(t, store)
+
+	require.NoError(t, p.Stage("routes", &commonpb.StringProto{Value: "r1"}))
+	require.NoError(t, p.Stage("routes", &commonpb.StringProto{Value: "r2"}))
+	_, err := p.Publish()
+	require.NoError(t, err)
+
+	requireString(t, store, "routes", 1, "r2")
+}
+
+func newTestPublisher(t *testing.T, store kv.TxnStore) Publisher {
+	p, err := NewPublisher(store, NewOptions().SetGenerationKey("generation"))
+	require.NoError(t, err)
+	return p
+}
+
+func requireString(t *testing.T, store kv.Store, key string, version int, expected string) {
+	v, err := store.Get(key)
+	require.NoError(t, err)
+	require.Equal(t, version, v.Version())
+
+	var s commonpb.StringProto
+	require.NoError(t, v.Unmarshal(&s))
+	require.Equal(t, expected, s.Value)
+}
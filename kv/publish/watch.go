@@ -0,0 +1,194 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package publish
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/m3db/m3cluster/generated/proto/publishpb"
+	"github.com/m3db/m3cluster/kv"
+	"github.com/m3db/m3x/log"
+	xwatch "github.com/m3db/m3x/watch"
+)
+
+var (
+	errVersionUnavailable = errors.New("published version no longer available")
+)
+
+// A GenerationWatch observes the published generations of a group of keys.
+// Generations are only delivered once the value of every key in the group
+// has been read at the version recorded by the generation, so the values
+// seen by a watcher always belong to a single publish
+type GenerationWatch interface {
+	// C returns the notification channel
+	C() <-chan struct{}
+
+	// Get returns the latest generation, or a zero Generation if nothing
+	// has been published yet
+	Get() Generation
+
+	// Close stops watching for new generations
+	Close()
+}
+
+type generationWatch struct {
+	store     kv.Store
+	log       log.Logger
+	kvWatch   kv.ValueWatch
+	watchable xwatch.Watchable
+	watch     xwatch.Watch
+	doneCh    chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// Watch watches the generations published under the generation key
+func Watch(store kv.Store, opts Options) (GenerationWatch, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	kvWatch, err := store.Watch(opts.GenerationKey())
+	if err != nil {
+		return nil, err
+	}
+
+	watchable := xwatch.NewWatchable()
+	_, watch, err := watchable.Watch()
+	if err != nil {
+		kvWatch.Close()
+		return nil, err
+	}
+
+	w := &generationWatch{
+		store:     store,
+		log:       opts.InstrumentOptions().Logger(),
+		kvWatch:   kvWatch,
+		watchable: watchable,
+		watch:     watch,
+		doneCh:    make(chan struct{}),
+	}
+
+	w.wg.Add(1)
+	go w.run()
+	return w, nil
+}
+
+func (w *generationWatch) C() <-chan struct{} {
+	return w.watch.C()
+}
+
+func (w *generationWatch) Get() Generation {
+	if gen, ok := w.watch.Get().(Generation); ok {
+		return gen
+	}
+
+	return Generation{}
+}
+
+func (w *generationWatch) Close() {
+	w.closeOnce.Do(func() {
+		close(w.doneCh)
+		w.kvWatch.Close()
+		w.wg.Wait()
+		w.watch.Close()
+		w.watchable.Close()
+	})
+}
+
+func (w *generationWatch) run() {
+	defer w.wg.Done()
+
+	for {
+		select {
+		case <-w.doneCh:
+			return
+		case _, ok := <-w.kvWatch.C():
+			if !ok {
+				return
+			}
+
+			if err := w.update(w.kvWatch.Get()); err != nil {
+				w.log.Errorf("could not resolve published generation: %v", err)
+			}
+		}
+	}
+}
+
+func (w *generationWatch) update(v kv.Value) error {
+	if v == nil {
+		return nil
+	}
+
+	var pb publishpb.Generation
+	if err := v.Unmarshal(&pb); err != nil {
+		return err
+	}
+
+	// Generations can be skipped but never go backwards
+	if pb.Generation <= w.Get().Number {
+		return nil
+	}
+
+	gen := generationFromProto(&pb)
+	gen.Values = make(map[string]kv.Value, len(gen.Versions))
+	for key, version := range gen.Versions {
+		value, err := w.valueAt(key, version)
+		if err != nil {
+			// A later generation will replace this one, so wait for it rather
+			// than deliver an inconsistent set of values
+			return err
+		}
+
+		gen.Values[key] = value
+	}
+
+	return w.watchable.Update(gen)
+}
+
+// valueAt returns the value of a key at a specific version, falling back to
+// the history of the key if it has since been updated
+func (w *generationWatch) valueAt(key string, version int) (kv.Value, error) {
+	value, err := w.store.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if value.Version() == version {
+		return value, nil
+	}
+
+	if value.Version() < version {
+		return nil, errVersionUnavailable
+	}
+
+	history, err := w.store.History(key, version, version+1)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(history) != 1 || history[0].Version() != version {
+		return nil, errVersionUnavailable
+	}
+
+	return history[0], nil
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package publish
+
+import (
+	"testing"
+	"time"
+
+	"github.com/m3db/m3cluster/generated/proto/commonpb"
+	"github.com/m3db/m3cluster/kv/mem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchGenerations(t *testing.T) {
+	store := mem.NewStore()
+	p := newTestPublisher(t, store)
+
+	w, err := Watch(store, NewOptions().SetGenerationKey("generation"))
+	require.NoError(t, err)
+	defer w.Close()
+
+	require.Equal(t, Generation{}, w.Get())
+
+	require.NoError(t, p.Stage("routes", &commonpb.StringProto{Value: "r1"}))
+	require.NoError(t, p.Stage("schema", &commonpb.StringProto{Value: "s1"}))
+	_, err = p.Publish()
+	require.NoError(t, err)
+
+	gen := waitForGeneration(t, w, 1)
+	require.Equal(t, map[string]int{"routes": 1, "schema": 1}, gen.Versions)
+	requireValue(t, gen, "routes", "r1")
+	requireValue(t, gen, "schema", "s1")
+}
+
+func TestWatchReadsPublishedVersions(t *testing.T) {
+	store := mem.NewStore()
+	p := newTestPublisher(t, store)
+
+	require.NoError(t, p.Stage("routes", &commonpb.StringProto{Value: "r1"}))
+	require.NoError(t, p.Stage("schema", &commonpb.StringProto{Value: "s1"}))
+	_, err := p.Publish()
+	require.NoError(t, err)
+
+	// A key updated outside of a publish is read as of the generation
+	_, err = store.Set("schema", &commonpb.StringProto{Value: "unpublished"})
+	require.NoError(t, err)
+
+	w, err := Watch(store, NewOptions().SetGenerationKey("generation"))
+	require.NoError(t, err)
+	defer w.Close()
+
+	gen := waitForGeneration(t, w, 1)
+	requireValue(t, gen, "routes", "r1")
+	requireValue(t, gen, "schema", "s1")
+}
+
+func TestWatchOptions(t *testing.T) {
+	_, err := Watch(mem.NewStore(), NewOptions())
+	require.Equal(t, errGenerationKeyNotSet, err)
+}
+
+func waitForGeneration(t *testing.T, w GenerationWatch, number int64) Generation {
+	deadline := time.After(5 * time.Second)
+	for {
+		if gen := w.Get(); gen.Number >= number {
+			return gen
+		}
+
+		select {
+		case <-w.C():
+		case <-deadline:
+			require.FailNow(t, "timed out waiting for generation")
+		}
+	}
+}
+
+func requireValue(t *testing.T, gen Generation, key, expected string) {
+	v, ok := gen.Values[key]
+	require.True(t, ok)
+
+	var s commonpb.StringProto
+	require.NoError(t, v.Unmarshal(&s))
+	require.Equal(t, expected, s.Value)
+}
@@ -0,0 +1,203 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package queued
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/m3db/m3x/instrument"
+)
+
+const (
+	defaultReplayInterval = 10 * time.Second
+)
+
+// ConflictPolicy determines what happens when replaying a queued write to a
+// key that changed after the write was queued
+type ConflictPolicy int
+
+const (
+	// DropOnConflict discards the queued write, leaving the newer value in place
+	DropOnConflict ConflictPolicy = iota
+
+	// OverwriteOnConflict writes the queued value over the newer value
+	OverwriteOnConflict
+)
+
+func (p ConflictPolicy) String() string {
+	switch p {
+	case DropOnConflict:
+		return "drop"
+	case OverwriteOnConflict:
+		return "overwrite"
+	default:
+		return "unknown"
+	}
+}
+
+// UnavailableFn returns true if an error returned by the underlying store
+// means the store is unavailable and the write should be queued
+type UnavailableFn func(err error) bool
+
+var (
+	errQueueDirNotSet        = errors.New("queue directory must be specified")
+	errInvalidReplayInterval = errors.New("replay interval must be positive")
+)
+
+// Options provide a set of queued store options.
+type Options interface {
+	// SetQueueDir sets the directory queued writes are persisted to.
+	SetQueueDir(value string) Options
+
+	// QueueDir returns the directory queued writes are persisted to.
+	QueueDir() string
+
+	// SetConflictPolicy sets the conflict policy applied when replaying.
+	SetConflictPolicy(value ConflictPolicy) Options
+
+	// ConflictPolicy returns the conflict policy applied when replaying.
+	ConflictPolicy() ConflictPolicy
+
+	// SetReplayInterval sets how often queued writes are replayed.
+	SetReplayInterval(value time.Duration) Options
+
+	// ReplayInterval returns how often queued writes are replayed.
+	ReplayInterval() time.Duration
+
+	// SetUnavailableFn sets the function deciding which errors are queued.
+	SetUnavailableFn(value UnavailableFn) Options
+
+	// UnavailableFn returns the function deciding which errors are queued.
+	UnavailableFn() UnavailableFn
+
+	// SetInstrumentOptions sets the instrument options.
+	SetInstrumentOptions(value instrument.Options) Options
+
+	// InstrumentOptions returns the instrument options.
+	InstrumentOptions() instrument.Options
+
+	// Validate validates the options.
+	Validate() error
+}
+
+type options struct {
+	queueDir       string
+	conflictPolicy ConflictPolicy
+	replayInterval time.Duration
+	unavailableFn  UnavailableFn
+	instrumentOpts instrument.Options
+}
+
+// NewOptions creates a new set of options.
+func NewOptions() Options {
+	return &options{
+		conflictPolicy: DropOnConflict,
+		replayInterval: defaultReplayInterval,
+		unavailableFn:  isUnavailable,
+		instrumentOpts: instrument.NewOptions(),
+	}
+}
+
+func (o *options) SetQueueDir(value string) Options {
+	opts := *o
+	opts.queueDir = value
+	return &opts
+}
+
+func (o *options) QueueDir() string {
+	return o.queueDir
+}
+
+func (o *options) SetConflictPolicy(value ConflictPolicy) Options {
+	opts := *o
+	opts.conflictPolicy = value
+	return &opts
+}
+
+func (o *options) ConflictPolicy() ConflictPolicy {
+	return o.conflictPolicy
+}
+
+func (o *options) SetReplayInterval(value time.Duration) Options {
+	opts := *o
+	opts.replayInterval = value
+	return &opts
+}
+
+func (o *options) ReplayInterval() time.Duration {
+	return o.replayInterval
+}
+
+func (o *options) SetUnavailableFn(value UnavailableFn) Options {
+	opts := *o
+	opts.unavailableFn = value
+	return &opts
+}
+
+func (o *options) UnavailableFn() UnavailableFn {
+	return o.unavailableFn
+}
+
+func (o *options) SetInstrumentOptions(value instrument.Options) Options {
+	opts := *o
+	opts.instrumentOpts = value
+	return &opts
+}
+
+func (o *options) InstrumentOptions() instrument.Options {
+	return o.instrumentOpts
+}
+
+func (o *options) Validate() error {
+	if o.queueDir == "" {
+		return errQueueDirNotSet
+	}
+
+	if o.replayInterval <= 0 {
+		return errInvalidReplayInterval
+	}
+
+	return nil
+}
+
+// temporary is implemented by transport errors that may go away on retry
+type temporary interface {
+	Temporary() bool
+}
+
+// isUnavailable treats timeouts, network errors and temporary transport
+// errors as the store being unavailable.  Any other error is returned to the
+// writer, and a queued write failing with one is dropped on replay
+func isUnavailable(err error) bool {
+	if err == context.DeadlineExceeded {
+		return true
+	}
+
+	if _, ok := err.(net.Error); ok {
+		return true
+	}
+
+	t, ok := err.(temporary)
+	return ok && t.Temporary()
+}
@@ -0,0 +1,208 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package queued
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	queuedWriteExt = ".json"
+	tempFileExt    = ".tmp"
+
+	// unknownVersion is the expected version of a queued Set when the version
+	// of the key was not known at the time it was queued
+	unknownVersion = -1
+)
+
+type writeOp string
+
+const (
+	opSet            writeOp = "set"
+	opSetIfNotExists writeOp = "set-if-not-exists"
+	opCheckAndSet    writeOp = "check-and-set"
+)
+
+// A queuedWrite is a write waiting to be replayed against the store
+type queuedWrite struct {
+	Seq             uint64    `json:"seq"`
+	Key             string    `json:"key"`
+	Op              writeOp   `json:"op"`
+	ExpectedVersion int       `json:"expectedVersion"`
+	Value           []byte    `json:"value"`
+	QueuedAt        time.Time `json:"queuedAt"`
+}
+
+// diskQueue is an ordered queue of writes persisted as one file per write,
+// holding at most one write per key.  It is not safe for concurrent use
+type diskQueue struct {
+	dir     string
+	nextSeq uint64
+	writes  []*queuedWrite
+	byKey   map[string]*queuedWrite
+}
+
+// newDiskQueue opens the queue in dir, loading any writes persisted by a
+// previous process
+func newDiskQueue(dir string) (*diskQueue, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &diskQueue{
+		dir:     dir,
+		nextSeq: 1,
+		byKey:   make(map[string]*queuedWrite),
+	}
+
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), queuedWriteExt) {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		var w queuedWrite
+		if err := json.Unmarshal(data, &w); err != nil {
+			return nil, fmt.Errorf("could not read queued write %s: %v", f.Name(), err)
+		}
+
+		q.writes = append(q.writes, &w)
+		q.byKey[w.Key] = &w
+		if w.Seq >= q.nextSeq {
+			q.nextSeq = w.Seq + 1
+		}
+	}
+
+	sort.Sort(bySeqAscending(q.writes))
+	return q, nil
+}
+
+func (q *diskQueue) len() int {
+	return len(q.writes)
+}
+
+func (q *diskQueue) get(key string) (*queuedWrite, bool) {
+	w, ok := q.byKey[key]
+	return w, ok
+}
+
+// pending returns the queued writes in the order they were first queued
+func (q *diskQueue) pending() []*queuedWrite {
+	return append([]*queuedWrite(nil), q.writes...)
+}
+
+// enqueue adds a write to the queue.  A write to a key that already has a
+// queued write replaces its value, keeping its place in the queue and the
+// version it was guarded on
+func (q *diskQueue) enqueue(w queuedWrite) error {
+	if existing, ok := q.byKey[w.Key]; ok {
+		updated := *existing
+		updated.Value = w.Value
+		updated.QueuedAt = w.QueuedAt
+		if err := q.persist(&updated); err != nil {
+			return err
+		}
+
+		*existing = updated
+		return nil
+	}
+
+	w.Seq = q.nextSeq
+	if err := q.persist(&w); err != nil {
+		return err
+	}
+
+	q.nextSeq++
+	q.writes = append(q.writes, &w)
+	q.byKey[w.Key] = &w
+	return nil
+}
+
+// guard updates the version a queued write is guarded on
+func (q *diskQueue) guard(w *queuedWrite, version int) error {
+	updated := *w
+	updated.ExpectedVersion = version
+	if err := q.persist(&updated); err != nil {
+		return err
+	}
+
+	*w = updated
+	return nil
+}
+
+func (q *diskQueue) remove(w *queuedWrite) error {
+	if err := os.Remove(q.path(w)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	delete(q.byKey, w.Key)
+	for i, qw := range q.writes {
+		if qw == w {
+			q.writes = append(q.writes[:i], q.writes[i+1:]...)
+			break
+		}
+	}
+
+	return nil
+}
+
+// persist writes a queued write to disk, replacing any earlier version of it
+// atomically
+func (q *diskQueue) persist(w *queuedWrite) error {
+	data, err := json.Marshal(w)
+	if err != nil {
+		return err
+	}
+
+	path := q.path(w)
+	tmp := path + tempFileExt
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+func (q *diskQueue) path(w *queuedWrite) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%020d%s", w.Seq, queuedWriteExt))
+}
+
+type bySeqAscending []*queuedWrite
+
+func (s bySeqAscending) Len() int           { return len(s) }
+func (s bySeqAscending) Less(i, j int) bool { return s[i].Seq < s[j].Seq }
+func (s bySeqAscending) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
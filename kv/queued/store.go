@@ -0,0 +1,419 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package queued provides an opt-in kv.Store wrapper for non-critical writers
+// that would rather have a write persisted locally and replayed later than
+// fail while the underlying store is unavailable.
+package queued
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/m3db/m3cluster/kv"
+	"github.com/m3db/m3x/log"
+
+	"github.com/uber-go/tally"
+)
+
+var (
+	// ErrWriteQueued is returned when a write could not be applied because the
+	// underlying store is unavailable, and has been queued for replay instead
+	ErrWriteQueued = errors.New("store unavailable, write queued for replay")
+
+	errStoreClosed = errors.New("store is closed")
+)
+
+// Store is a kv.Store that queues writes which fail because the underlying
+// store is unavailable, persisting them locally and replaying them once the
+// store is available again.  Replays use CheckAndSet against the version the
+// key had when the write was queued.  If the key changed in the meantime, the
+// ConflictPolicy decides what happens to a queued Set, while a queued
+// SetIfNotExists or CheckAndSet is always dropped.  Reads are not affected by
+// queued writes
+type Store interface {
+	kv.Store
+
+	// Pending returns the number of writes waiting to be replayed
+	Pending() int
+
+	// Replay replays queued writes now, stopping at the first write that fails
+	// because the underlying store is unavailable
+	Replay() error
+
+	// Close stops replaying queued writes.  Writes that have not been replayed
+	// stay on disk and are replayed by the next Store opened on the directory
+	Close() error
+}
+
+type storeMetrics struct {
+	queued       tally.Counter
+	replayed     tally.Counter
+	conflicts    tally.Counter
+	dropped      tally.Counter
+	replayErrors tally.Counter
+	pending      tally.Gauge
+}
+
+func newStoreMetrics(scope tally.Scope) storeMetrics {
+	return storeMetrics{
+		queued:       scope.Counter("queued"),
+		replayed:     scope.Counter("replayed"),
+		conflicts:    scope.Counter("conflicts"),
+		dropped:      scope.Counter("dropped"),
+		replayErrors: scope.Counter("replay-errors"),
+		pending:      scope.Gauge("pending"),
+	}
+}
+
+type store struct {
+	sync.Mutex
+
+	// replayLock serializes replays, and deletes with replays, without
+	// holding the store lock across calls to the underlying store
+	replayLock sync.Mutex
+
+	kv            kv.Store
+	opts          Options
+	unavailableFn UnavailableFn
+	log           log.Logger
+	metrics       storeMetrics
+
+	queue    *diskQueue
+	versions map[string]int
+	closed   bool
+	doneCh   chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewStore wraps a kv.Store so that writes failing because the store is
+// unavailable are queued in the configured directory and replayed later
+func NewStore(kvStore kv.Store, opts Options) (Store, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	queue, err := newDiskQueue(opts.QueueDir())
+	if err != nil {
+		return nil, err
+	}
+
+	s := &store{
+		kv:            kvStore,
+		opts:          opts,
+		unavailableFn: opts.UnavailableFn(),
+		log:           opts.InstrumentOptions().Logger(),
+		metrics:       newStoreMetrics(opts.InstrumentOptions().MetricsScope().SubScope("queued-store")),
+		queue:         queue,
+		versions:      make(map[string]int),
+		doneCh:        make(chan struct{}),
+	}
+	s.metrics.pending.Update(float64(queue.len()))
+
+	s.wg.Add(1)
+	go s.replayLoop()
+	return s, nil
+}
+
+func (s *store) Get(key string) (kv.Value, error) {
+	v, err := s.kv.Get(key)
+	if err == nil {
+		s.Lock()
+		s.versions[key] = v.Version()
+		s.Unlock()
+	}
+
+	return v, err
+}
+
+func (s *store) Watch(key string) (kv.ValueWatch, error) {
+	return s.kv.Watch(key)
+}
+
+func (s *store) History(key string, from, to int) ([]kv.Value, error) {
+	return s.kv.History(key, from, to)
+}
+
+func (s *store) Set(key string, v proto.Message) (int, error) {
+	return s.write(key, opSet, unknownVersion, v, func() (int, error) {
+		return s.kv.Set(key, v)
+	})
+}
+
+func (s *store) SetIfNotExists(key string, v proto.Message) (int, error) {
+	return s.write(key, opSetIfNotExists, 0, v, func() (int, error) {
+		return s.kv.SetIfNotExists(key, v)
+	})
+}
+
+func (s *store) CheckAndSet(key string, version int, v proto.Message) (int, error) {
+	return s.write(key, opCheckAndSet, version, v, func() (int, error) {
+		return s.kv.CheckAndSet(key, version, v)
+	})
+}
+
+// Delete is never queued.  A successful delete discards any write queued for
+// the key, since the write would otherwise resurrect it
+func (s *store) Delete(key string) (kv.Value, error) {
+	s.replayLock.Lock()
+	defer s.replayLock.Unlock()
+
+	v, err := s.kv.Delete(key)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.versions, key)
+	if w, ok := s.queue.get(key); ok {
+		if err := s.queue.remove(w); err != nil {
+			return nil, err
+		}
+		s.metrics.pending.Update(float64(s.queue.len()))
+	}
+
+	return v, nil
+}
+
+func (s *store) write(
+	key string,
+	op writeOp,
+	expectedVersion int,
+	v proto.Message,
+	apply func() (int, error),
+) (int, error) {
+	s.Lock()
+	if s.closed {
+		s.Unlock()
+		return 0, errStoreClosed
+	}
+
+	// Writes to a key with a queued write are queued behind it, otherwise the
+	// replay would overwrite them
+	_, queued := s.queue.get(key)
+	s.Unlock()
+
+	if !queued {
+		version, err := apply()
+		if err == nil {
+			s.Lock()
+			s.versions[key] = version
+			s.Unlock()
+			return version, nil
+		}
+
+		if !s.unavailableFn(err) {
+			return 0, err
+		}
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	data, err := proto.Marshal(v)
+	if err != nil {
+		return 0, err
+	}
+
+	// Plain sets are guarded on the last version of the key seen through this
+	// store, if there is one
+	if op == opSet {
+		if version, ok := s.versions[key]; ok {
+			expectedVersion = version
+		}
+	}
+
+	if err := s.queue.enqueue(queuedWrite{
+		Key:             key,
+		Op:              op,
+		ExpectedVersion: expectedVersion,
+		Value:           data,
+		QueuedAt:        time.Now(),
+	}); err != nil {
+		return 0, fmt.Errorf("could not queue write to %s: %v", key, err)
+	}
+
+	s.metrics.queued.Inc(1)
+	s.metrics.pending.Update(float64(s.queue.len()))
+	return 0, ErrWriteQueued
+}
+
+func (s *store) Pending() int {
+	s.Lock()
+	defer s.Unlock()
+	return s.queue.len()
+}
+
+func (s *store) Replay() error {
+	s.replayLock.Lock()
+	defer s.replayLock.Unlock()
+
+	s.Lock()
+	pending := s.queue.pending()
+	s.Unlock()
+
+	defer func() {
+		s.Lock()
+		s.metrics.pending.Update(float64(s.queue.len()))
+		s.Unlock()
+	}()
+
+	for _, w := range pending {
+		// Writes can be queued behind w while it is replayed, so replay a copy
+		s.Lock()
+		replayed := *w
+		s.Unlock()
+
+		version, err := s.replay(&replayed)
+		if err != nil {
+			if s.unavailableFn(err) {
+				return err
+			}
+
+			// Retrying a write that can never succeed would block the queue
+			s.metrics.replayErrors.Inc(1)
+			s.log.Errorf("dropping queued write to %s that could not be replayed: %v", w.Key, err)
+		}
+
+		s.Lock()
+		err = s.finishReplayWithLock(w, replayed, version)
+		s.Unlock()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// finishReplayWithLock removes a replayed write from the queue.  If another
+// write was queued behind it during the replay, that write stays queued,
+// guarded on the version the replay left the key at
+func (s *store) finishReplayWithLock(w *queuedWrite, replayed queuedWrite, version int) error {
+	if w.QueuedAt.Equal(replayed.QueuedAt) {
+		return s.queue.remove(w)
+	}
+
+	if version == 0 {
+		return nil
+	}
+
+	return s.queue.guard(w, version)
+}
+
+// replay applies a queued write to the underlying store, returning the version
+// it was written at, or 0 if it was dropped
+func (s *store) replay(w *queuedWrite) (int, error) {
+	value := kv.NewRawMessage(w.Value)
+	for {
+		currentVersion := 0
+		current, err := s.kv.Get(w.Key)
+		if err == nil {
+			currentVersion = current.Version()
+		} else if err != kv.ErrNotFound {
+			return 0, err
+		}
+
+		expectedVersion := w.ExpectedVersion
+		if expectedVersion == unknownVersion {
+			expectedVersion = currentVersion
+		}
+
+		if currentVersion != expectedVersion {
+			// Conditional writes only ever apply to the version they were
+			// made against
+			s.metrics.conflicts.Inc(1)
+			if w.Op != opSet || s.opts.ConflictPolicy() == DropOnConflict {
+				s.metrics.dropped.Inc(1)
+				s.log.Warnf("dropping queued %s to %s, expected version %d but found %d",
+					w.Op, w.Key, expectedVersion, currentVersion)
+				return 0, nil
+			}
+
+			expectedVersion = currentVersion
+		}
+
+		var version int
+		if expectedVersion == 0 {
+			version, err = s.kv.SetIfNotExists(w.Key, value)
+		} else {
+			version, err = s.kv.CheckAndSet(w.Key, expectedVersion, value)
+		}
+
+		if err == kv.ErrAlreadyExists || err == kv.ErrVersionMismatch {
+			// The key changed since it was read - check it again
+			continue
+		}
+
+		if err != nil {
+			return 0, err
+		}
+
+		s.Lock()
+		s.versions[w.Key] = version
+		s.Unlock()
+
+		s.metrics.replayed.Inc(1)
+		return version, nil
+	}
+}
+
+func (s *store) Close() error {
+	s.Lock()
+	if s.closed {
+		s.Unlock()
+		return errStoreClosed
+	}
+
+	s.closed = true
+	close(s.doneCh)
+	s.Unlock()
+
+	s.wg.Wait()
+	return nil
+}
+
+func (s *store) replayLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.opts.ReplayInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.doneCh:
+			return
+		case <-ticker.C:
+			if s.Pending() == 0 {
+				continue
+			}
+
+			if err := s.Replay(); err != nil {
+				s.log.Debugf("could not replay queued writes: %v", err)
+			}
+		}
+	}
+}
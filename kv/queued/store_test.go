@@ -0,0 +1,428 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package queued
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/m3db/m3cluster/generated/proto/commonpb"
+	"github.com/m3db/m3cluster/kv"
+	"github.com/m3db/m3cluster/kv/mem"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	errUnavailable error = unavailableError{}
+	errBadWrite          = errors.New("bad write")
+)
+
+func TestOptionsValidate(t *testing.T) {
+	require.Equal(t, errQueueDirNotSet, NewOptions().Validate())
+	require.Equal(t, errInvalidReplayInterval, NewOptions().SetQueueDir("d").SetReplayInterval(0).Validate())
+	require.NoError(t, NewOptions().SetQueueDir("d").Validate())
+}
+
+func TestStoreAvailable(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	inner := newFlakyStore()
+	s := newTestStore(t, inner, dir)
+	defer s.Close()
+
+	version, err := s.Set("k", &commonpb.StringProto{Value: "foo"})
+	require.NoError(t, err)
+	require.Equal(t, 1, version)
+
+	// Conditional failures are returned rather than queued
+	_, err = s.CheckAndSet("k", 5, &commonpb.StringProto{Value: "bar"})
+	require.Equal(t, kv.ErrVersionMismatch, err)
+	_, err = s.SetIfNotExists("k", &commonpb.StringProto{Value: "bar"})
+	require.Equal(t, kv.ErrAlreadyExists, err)
+	require.Equal(t, 0, s.Pending())
+}
+
+func TestStoreQueuesAndReplays(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	inner := newFlakyStore()
+	s := newTestStore(t, inner, dir)
+	defer s.Close()
+
+	inner.setDown(true)
+	_, err := s.Set("k", &commonpb.StringProto{Value: "foo"})
+	require.Equal(t, ErrWriteQueued, err)
+
+	// Later writes to the same key replace the queued value
+	_, err = s.Set("k", &commonpb.StringProto{Value: "bar"})
+	require.Equal(t, ErrWriteQueued, err)
+	require.Equal(t, 1, s.Pending())
+
+	require.Equal(t, errUnavailable, s.Replay())
+	require.Equal(t, 1, s.Pending())
+
+	// Writes to a key with a queued write stay behind it even once the store
+	// is available
+	inner.setDown(false)
+	_, err = s.Set("k", &commonpb.StringProto{Value: "baz"})
+	require.Equal(t, ErrWriteQueued, err)
+
+	require.NoError(t, s.Replay())
+	require.Equal(t, 0, s.Pending())
+	requireString(t, inner, "k", 1, "baz")
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
+
+func TestStoreQueueIsDurable(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	inner := newFlakyStore()
+	s := newTestStore(t, inner, dir)
+
+	inner.setDown(true)
+	_, err := s.Set("k1", &commonpb.StringProto{Value: "foo"})
+	require.Equal(t, ErrWriteQueued, err)
+	_, err = s.SetIfNotExists("k2", &commonpb.StringProto{Value: "bar"})
+	require.Equal(t, ErrWriteQueued, err)
+	require.NoError(t, s.Close())
+
+	inner.setDown(false)
+	s = newTestStore(t, inner, dir)
+	defer s.Close()
+
+	require.Equal(t, 2, s.Pending())
+	require.NoError(t, s.Replay())
+	requireString(t, inner, "k1", 1, "foo")
+	requireString(t, inner, "k2", 1, "bar")
+}
+
+func TestStoreReplayConflict(t *testing.T) {
+	for _, test := range []struct {
+		policy   ConflictPolicy
+		version  int
+		expected string
+	}{
+		{policy: DropOnConflict, version: 2, expected: "other"},
+		{policy: OverwriteOnConflict, version: 3, expected: "queued"},
+	} {
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+
+		inner := newFlakyStore()
+		s := newTestStore(t, inner, dir, func(opts Options) Options {
+			return opts.SetConflictPolicy(test.policy)
+		})
+
+		_, err := s.Set("k", &commonpb.StringProto{Value: "foo"})
+		require.NoError(t, err)
+
+		inner.setDown(true)
+		_, err = s.Set("k", &commonpb.StringProto{Value: "queued"})
+		require.Equal(t, ErrWriteQueued, err)
+		inner.setDown(false)
+
+		// Someone else writes the key before the queued write is replayed
+		_, err = inner.Set("k", &commonpb.StringProto{Value: "other"})
+		require.NoError(t, err)
+
+		require.NoError(t, s.Replay())
+		require.Equal(t, 0, s.Pending())
+		requireString(t, inner, "k", test.version, test.expected)
+		require.NoError(t, s.Close())
+	}
+}
+
+func TestStoreReplayConflictDropsConditionalWrites(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	inner := newFlakyStore()
+	s := newTestStore(t, inner, dir, func(opts Options) Options {
+		return opts.SetConflictPolicy(OverwriteOnConflict)
+	})
+	defer s.Close()
+
+	_, err := s.Set("k1", &commonpb.StringProto{Value: "foo"})
+	require.NoError(t, err)
+
+	inner.setDown(true)
+	_, err = s.CheckAndSet("k1", 1, &commonpb.StringProto{Value: "queued"})
+	require.Equal(t, ErrWriteQueued, err)
+	_, err = s.SetIfNotExists("k2", &commonpb.StringProto{Value: "queued"})
+	require.Equal(t, ErrWriteQueued, err)
+	inner.setDown(false)
+
+	_, err = inner.Set("k1", &commonpb.StringProto{Value: "other"})
+	require.NoError(t, err)
+	_, err = inner.Set("k2", &commonpb.StringProto{Value: "other"})
+	require.NoError(t, err)
+
+	// Only plain sets are overwritten on conflict
+	require.NoError(t, s.Replay())
+	require.Equal(t, 0, s.Pending())
+	requireString(t, inner, "k1", 2, "other")
+	requireString(t, inner, "k2", 1, "other")
+}
+
+func TestStoreReplayDropsFailingWrite(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	inner := newFlakyStore()
+	s := newTestStore(t, inner, dir)
+	defer s.Close()
+
+	inner.setDown(true)
+	_, err := s.Set("k1", &commonpb.StringProto{Value: "foo"})
+	require.Equal(t, ErrWriteQueued, err)
+	_, err = s.Set("k2", &commonpb.StringProto{Value: "bar"})
+	require.Equal(t, ErrWriteQueued, err)
+
+	// A write failing for any reason other than the store being unavailable
+	// does not block the writes queued behind it
+	inner.setDown(false)
+	inner.onWrite = func(key string) error {
+		if key == "k1" {
+			return errBadWrite
+		}
+		return nil
+	}
+	require.NoError(t, s.Replay())
+	require.Equal(t, 0, s.Pending())
+
+	_, err = inner.Get("k1")
+	require.Equal(t, kv.ErrNotFound, err)
+	requireString(t, inner, "k2", 1, "bar")
+}
+
+func TestStoreWriteQueuedDuringReplay(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	inner := newFlakyStore()
+	s := newTestStore(t, inner, dir)
+	defer s.Close()
+
+	_, err := s.Set("k", &commonpb.StringProto{Value: "foo"})
+	require.NoError(t, err)
+
+	inner.setDown(true)
+	_, err = s.Set("k", &commonpb.StringProto{Value: "bar"})
+	require.Equal(t, ErrWriteQueued, err)
+	inner.setDown(false)
+
+	// A write made while the queued write is being replayed is queued behind
+	// it rather than lost, and replays without conflicting
+	inner.onWrite = func(string) error {
+		inner.onWrite = nil
+		_, err := s.Set("k", &commonpb.StringProto{Value: "baz"})
+		require.Equal(t, ErrWriteQueued, err)
+		return nil
+	}
+	require.NoError(t, s.Replay())
+	requireString(t, inner, "k", 2, "bar")
+	require.Equal(t, 1, s.Pending())
+
+	require.NoError(t, s.Replay())
+	require.Equal(t, 0, s.Pending())
+	requireString(t, inner, "k", 3, "baz")
+}
+
+func TestIsUnavailable(t *testing.T) {
+	require.True(t, isUnavailable(context.DeadlineExceeded))
+	require.True(t, isUnavailable(&net.OpError{Op: "dial", Err: errBadWrite}))
+	require.True(t, isUnavailable(errUnavailable))
+	require.False(t, isUnavailable(errBadWrite))
+	require.False(t, isUnavailable(kv.ErrVersionMismatch))
+	require.False(t, isUnavailable(kv.ErrAlreadyExists))
+}
+
+func TestStoreDeleteDiscardsQueuedWrite(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	inner := newFlakyStore()
+	s := newTestStore(t, inner, dir)
+	defer s.Close()
+
+	_, err := s.Set("k", &commonpb.StringProto{Value: "foo"})
+	require.NoError(t, err)
+
+	inner.setDown(true)
+	_, err = s.Set("k", &commonpb.StringProto{Value: "bar"})
+	require.Equal(t, ErrWriteQueued, err)
+	inner.setDown(false)
+
+	_, err = s.Delete("k")
+	require.NoError(t, err)
+	require.Equal(t, 0, s.Pending())
+
+	_, err = inner.Get("k")
+	require.Equal(t, kv.ErrNotFound, err)
+}
+
+func TestStoreReplaysInBackground(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	inner := newFlakyStore()
+	s := newTestStore(t, inner, dir, func(opts Options) Options {
+		return opts.SetReplayInterval(time.Millisecond)
+	})
+	defer s.Close()
+
+	inner.setDown(true)
+	_, err := s.Set("k", &commonpb.StringProto{Value: "foo"})
+	require.Equal(t, ErrWriteQueued, err)
+	inner.setDown(false)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for s.Pending() != 0 {
+		require.True(t, time.Now().Before(deadline), "timed out waiting for replay")
+		time.Sleep(time.Millisecond)
+	}
+
+	requireString(t, inner, "k", 1, "foo")
+}
+
+func TestStoreClose(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	s := newTestStore(t, newFlakyStore(), dir)
+	require.NoError(t, s.Close())
+	require.Equal(t, errStoreClosed, s.Close())
+
+	_, err := s.Set("k", &commonpb.StringProto{Value: "foo"})
+	require.Equal(t, errStoreClosed, err)
+}
+
+// unavailableError is a temporary transport error
+type unavailableError struct{}
+
+func (unavailableError) Error() string   { return "store unavailable" }
+func (unavailableError) Temporary() bool { return true }
+
+// flakyStore is a mem store whose writes and reads fail while it is down.
+// Conditional writes also fail with any error returned by onWrite
+type flakyStore struct {
+	sync.RWMutex
+	kv.Store
+
+	down    bool
+	onWrite func(key string) error
+}
+
+func newFlakyStore() *flakyStore {
+	return &flakyStore{Store: mem.NewStore()}
+}
+
+func (s *flakyStore) setDown(down bool) {
+	s.Lock()
+	s.down = down
+	s.Unlock()
+}
+
+func (s *flakyStore) err() error {
+	s.RLock()
+	defer s.RUnlock()
+	if s.down {
+		return errUnavailable
+	}
+	return nil
+}
+
+func (s *flakyStore) write(key string) error {
+	if s.onWrite != nil {
+		if err := s.onWrite(key); err != nil {
+			return err
+		}
+	}
+	return s.err()
+}
+
+func (s *flakyStore) Get(key string) (kv.Value, error) {
+	if err := s.err(); err != nil {
+		return nil, err
+	}
+	return s.Store.Get(key)
+}
+
+func (s *flakyStore) Set(key string, v proto.Message) (int, error) {
+	if err := s.err(); err != nil {
+		return 0, err
+	}
+	return s.Store.Set(key, v)
+}
+
+func (s *flakyStore) SetIfNotExists(key string, v proto.Message) (int, error) {
+	if err := s.write(key); err != nil {
+		return 0, err
+	}
+	return s.Store.SetIfNotExists(key, v)
+}
+
+func (s *flakyStore) CheckAndSet(key string, version int, v proto.Message) (int, error) {
+	if err := s.write(key); err != nil {
+		return 0, err
+	}
+	return s.Store.CheckAndSet(key, version, v)
+}
+
+func newTestStore(t *testing.T, inner kv.Store, dir string, fns ...func(Options) Options) Store {
+	opts := NewOptions().SetQueueDir(dir).SetReplayInterval(time.Hour)
+	for _, fn := range fns {
+		opts = fn(opts)
+	}
+
+	s, err := NewStore(inner, opts)
+	require.NoError(t, err)
+	return s
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "queued-store")
+	require.NoError(t, err)
+	return dir
+}
+
+func requireString(t *testing.T, store kv.Store, key string, version int, expected string) {
+	v, err := store.Get(key)
+	require.NoError(t, err)
+	require.Equal(t, version, v.Version())
+
+	var s commonpb.StringProto
+	require.NoError(t, v.Unmarshal(&s))
+	require.Equal(t, expected, s.Value)
+}
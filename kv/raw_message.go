@@ -0,0 +1,55 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package kv
+
+import "fmt"
+
+// RawMessage is a proto.Message holding the marshalled form of a value, it
+// allows a value to be copied between stores without knowing its type
+type RawMessage struct {
+	data []byte
+}
+
+// NewRawMessage returns a new RawMessage holding the marshalled value
+func NewRawMessage(data []byte) *RawMessage { return &RawMessage{data: data} }
+
+// Bytes returns the marshalled value
+func (m *RawMessage) Bytes() []byte { return m.data }
+
+// Reset resets the message
+func (m *RawMessage) Reset() { m.data = nil }
+
+// String returns the marshalled value in hex
+func (m *RawMessage) String() string { return fmt.Sprintf("%x", m.data) }
+
+// ProtoMessage marks RawMessage as a proto.Message
+func (m *RawMessage) ProtoMessage() {}
+
+// Marshal returns the marshalled value
+func (m *RawMessage) Marshal() ([]byte, error) {
+	return m.data, nil
+}
+
+// Unmarshal stores a copy of the marshalled value
+func (m *RawMessage) Unmarshal(data []byte) error {
+	m.data = append([]byte(nil), data...)
+	return nil
+}
@@ -0,0 +1,305 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package replication
+
+import (
+	"errors"
+	"time"
+
+	"github.com/m3db/m3x/clock"
+	"github.com/m3db/m3x/instrument"
+	"github.com/m3db/m3x/retry"
+)
+
+const (
+	defaultListKeysInterval = 10 * time.Second
+)
+
+// ListKeysFn lists the keys currently stored under a prefix
+type ListKeysFn func(prefix string) ([]string, error)
+
+// ConflictPolicy determines what happens when a value has been changed in
+// the destination zone since it was last replicated
+type ConflictPolicy int
+
+const (
+	// DestinationWins leaves the destination value in place, so changes made
+	// locally in the destination zone are never overwritten
+	DestinationWins ConflictPolicy = iota
+
+	// SourceWins overwrites the destination value with the source value
+	SourceWins
+)
+
+func (p ConflictPolicy) String() string {
+	switch p {
+	case DestinationWins:
+		return "destination-wins"
+	case SourceWins:
+		return "source-wins"
+	default:
+		return "unknown"
+	}
+}
+
+var (
+	errSourceZoneNotSet      = errors.New("source zone must be specified")
+	errDestinationZoneNotSet = errors.New("destination zone must be specified")
+	errSameZone              = errors.New("source and destination zones must differ")
+	errNoKeys                = errors.New("keys or a key listing must be specified")
+	errInvalidListInterval   = errors.New("key listing interval must be positive")
+)
+
+// Options provide a set of replication options.
+type Options interface {
+	// SetSourceZone sets the zone values are replicated from.
+	SetSourceZone(value string) Options
+
+	// SourceZone returns the zone values are replicated from.
+	SourceZone() string
+
+	// SetDestinationZone sets the zone values are replicated to.
+	SetDestinationZone(value string) Options
+
+	// DestinationZone returns the zone values are replicated to.
+	DestinationZone() string
+
+	// SetSourcePrefix sets the prefix of the replicated keys in the source store.
+	SetSourcePrefix(value string) Options
+
+	// SourcePrefix returns the prefix of the replicated keys in the source store.
+	SourcePrefix() string
+
+	// SetDestinationPrefix sets the prefix of the replicated keys in the
+	// destination store.
+	SetDestinationPrefix(value string) Options
+
+	// DestinationPrefix returns the prefix of the replicated keys in the
+	// destination store.
+	DestinationPrefix() string
+
+	// SetKeys sets a fixed set of keys to replicate, relative to the prefixes.
+	SetKeys(value []string) Options
+
+	// Keys returns the fixed set of keys to replicate, relative to the prefixes.
+	Keys() []string
+
+	// SetListKeysFn sets the function used to discover the keys under the
+	// source prefix. kv.Store cannot list keys itself, so any keys other than
+	// those set with SetKeys are only replicated when a listing is provided.
+	SetListKeysFn(value ListKeysFn) Options
+
+	// ListKeysFn returns the function used to discover the keys under the
+	// source prefix.
+	ListKeysFn() ListKeysFn
+
+	// SetListKeysInterval sets how often the source prefix is listed for new keys.
+	SetListKeysInterval(value time.Duration) Options
+
+	// ListKeysInterval returns how often the source prefix is listed for new keys.
+	ListKeysInterval() time.Duration
+
+	// SetRetryOptions sets the options for retrying failed replications.
+	SetRetryOptions(value retry.Options) Options
+
+	// RetryOptions returns the options for retrying failed replications.
+	RetryOptions() retry.Options
+
+	// SetConflictPolicy sets the conflict policy.
+	SetConflictPolicy(value ConflictPolicy) Options
+
+	// ConflictPolicy returns the conflict policy.
+	ConflictPolicy() ConflictPolicy
+
+	// SetClockOptions sets the clock options.
+	SetClockOptions(value clock.Options) Options
+
+	// ClockOptions returns the clock options.
+	ClockOptions() clock.Options
+
+	// SetInstrumentOptions sets the instrument options.
+	SetInstrumentOptions(value instrument.Options) Options
+
+	// InstrumentOptions returns the instrument options.
+	InstrumentOptions() instrument.Options
+
+	// Validate validates the options.
+	Validate() error
+}
+
+type options struct {
+	sourceZone     string
+	destZone       string
+	sourcePrefix   string
+	destPrefix     string
+	keys           []string
+	listKeysFn     ListKeysFn
+	listInterval   time.Duration
+	retryOpts      retry.Options
+	conflictPolicy ConflictPolicy
+	clockOpts      clock.Options
+	instrumentOpts instrument.Options
+}
+
+// NewOptions creates a new set of options.
+func NewOptions() Options {
+	return &options{
+		listInterval:   defaultListKeysInterval,
+		retryOpts:      retry.NewOptions().SetForever(true),
+		conflictPolicy: DestinationWins,
+		clockOpts:      clock.NewOptions(),
+		instrumentOpts: instrument.NewOptions(),
+	}
+}
+
+func (o *options) SetSourceZone(value string) Options {
+	opts := *o
+	opts.sourceZone = value
+	return &opts
+}
+
+func (o *options) SourceZone() string {
+	return o.sourceZone
+}
+
+func (o *options) SetDestinationZone(value string) Options {
+	opts := *o
+	opts.destZone = value
+	return &opts
+}
+
+func (o *options) DestinationZone() string {
+	return o.destZone
+}
+
+func (o *options) SetSourcePrefix(value string) Options {
+	opts := *o
+	opts.sourcePrefix = value
+	return &opts
+}
+
+func (o *options) SourcePrefix() string {
+	return o.sourcePrefix
+}
+
+func (o *options) SetDestinationPrefix(value string) Options {
+	opts := *o
+	opts.destPrefix = value
+	return &opts
+}
+
+func (o *options) DestinationPrefix() string {
+	return o.destPrefix
+}
+
+func (o *options) SetKeys(value []string) Options {
+	opts := *o
+	opts.keys = value
+	return &opts
+}
+
+func (o *options) Keys() []string {
+	return o.keys
+}
+
+func (o *options) SetListKeysFn(value ListKeysFn) Options {
+	opts := *o
+	opts.listKeysFn = value
+	return &opts
+}
+
+func (o *options) ListKeysFn() ListKeysFn {
+	return o.listKeysFn
+}
+
+func (o *options) SetListKeysInterval(value time.Duration) Options {
+	opts := *o
+	opts.listInterval = value
+	return &opts
+}
+
+func (o *options) ListKeysInterval() time.Duration {
+	return o.listInterval
+}
+
+func (o *options) SetRetryOptions(value retry.Options) Options {
+	opts := *o
+	opts.retryOpts = value
+	return &opts
+}
+
+func (o *options) RetryOptions() retry.Options {
+	return o.retryOpts
+}
+
+func (o *options) SetConflictPolicy(value ConflictPolicy) Options {
+	opts := *o
+	opts.conflictPolicy = value
+	return &opts
+}
+
+func (o *options) ConflictPolicy() ConflictPolicy {
+	return o.conflictPolicy
+}
+
+func (o *options) SetClockOptions(value clock.Options) Options {
+	opts := *o
+	opts.clockOpts = value
+	return &opts
+}
+
+func (o *options) ClockOptions() clock.Options {
+	return o.clockOpts
+}
+
+func (o *options) SetInstrumentOptions(value instrument.Options) Options {
+	opts := *o
+	opts.instrumentOpts = value
+	return &opts
+}
+
+func (o *options) InstrumentOptions() instrument.Options {
+	return o.instrumentOpts
+}
+
+func (o *options) Validate() error {
+	if o.sourceZone == "" {
+		return errSourceZoneNotSet
+	}
+
+	if o.destZone == "" {
+		return errDestinationZoneNotSet
+	}
+
+	if o.sourceZone == o.destZone {
+		return errSameZone
+	}
+
+	if len(o.keys) == 0 && o.listKeysFn == nil {
+		return errNoKeys
+	}
+
+	if o.listKeysFn != nil && o.listInterval <= 0 {
+		return errInvalidListInterval
+	}
+
+	return nil
+}
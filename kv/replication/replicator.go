@@ -0,0 +1,452 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package replication mirrors keys from one zone's kv.Store to another's.
+// Every replicated value is tagged with the zone it originated in, stored
+// alongside the value under <key>/_origin, so that replicators running in
+// both directions between a pair of zones do not send values back to where
+// they came from.
+package replication
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/m3db/m3cluster/generated/proto/replicationpb"
+	"github.com/m3db/m3cluster/kv"
+	"github.com/m3db/m3x/clock"
+	"github.com/m3db/m3x/log"
+	"github.com/m3db/m3x/retry"
+
+	"github.com/uber-go/tally"
+)
+
+const (
+	originSuffix = "/_origin"
+)
+
+var (
+	errAlreadyStarted     = errors.New("replicator already started")
+	errNotStarted         = errors.New("replicator not started")
+	errDestinationChanged = errors.New("destination changed during replication")
+)
+
+// A Replicator applies changes made to a set of keys in one zone's store to
+// another zone's store
+type Replicator interface {
+	// Start starts watching the source keys and replicating their changes
+	Start() error
+
+	// Stop stops replicating changes
+	Stop() error
+}
+
+type replicatorState int
+
+const (
+	replicatorNotStarted replicatorState = iota
+	replicatorStarted
+	replicatorStopped
+)
+
+type replicatorMetrics struct {
+	replicated   tally.Counter
+	deleted      tally.Counter
+	loopsSkipped tally.Counter
+	conflicts    tally.Counter
+	errors       tally.Counter
+	keys         tally.Gauge
+	lag          tally.Timer
+}
+
+func newReplicatorMetrics(scope tally.Scope) replicatorMetrics {
+	return replicatorMetrics{
+		replicated:   scope.Counter("replicated"),
+		deleted:      scope.Counter("deleted"),
+		loopsSkipped: scope.Counter("loops-skipped"),
+		conflicts:    scope.Counter("conflicts"),
+		errors:       scope.Counter("errors"),
+		keys:         scope.Gauge("keys"),
+		lag:          scope.Timer("lag"),
+	}
+}
+
+type replicator struct {
+	sync.Mutex
+
+	source  kv.Store
+	dest    kv.TxnStore
+	opts    Options
+	nowFn   clock.NowFn
+	log     log.Logger
+	retrier retry.Retrier
+	metrics replicatorMetrics
+
+	state   replicatorState
+	watches map[string]kv.ValueWatch
+	doneCh  chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewReplicator creates a new Replicator copying keys from the source store
+// to the destination store. Values are written to the destination together
+// with their origin in a single transaction.
+func NewReplicator(source kv.Store, dest kv.TxnStore, opts Options) (Replicator, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	scope := opts.InstrumentOptions().MetricsScope().
+		SubScope("replication").
+		Tagged(map[string]string{
+			"source-zone":      opts.SourceZone(),
+			"destination-zone": opts.DestinationZone(),
+		})
+
+	return &replicator{
+		source:  source,
+		dest:    dest,
+		opts:    opts,
+		nowFn:   opts.ClockOptions().NowFn(),
+		log:     opts.InstrumentOptions().Logger(),
+		retrier: retry.NewRetrier(opts.RetryOptions()),
+		metrics: newReplicatorMetrics(scope),
+		watches: make(map[string]kv.ValueWatch),
+		doneCh:  make(chan struct{}),
+	}, nil
+}
+
+func (r *replicator) Start() error {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.state != replicatorNotStarted {
+		return errAlreadyStarted
+	}
+
+	watches := make([]kv.ValueWatch, 0, len(r.opts.Keys()))
+	for _, key := range r.opts.Keys() {
+		w, err := r.source.Watch(r.opts.SourcePrefix() + key)
+		if err != nil {
+			for _, w := range watches {
+				w.Close()
+			}
+
+			return err
+		}
+
+		watches = append(watches, w)
+	}
+
+	r.state = replicatorStarted
+	for i, key := range r.opts.Keys() {
+		r.startWatchWithLock(key, watches[i])
+	}
+
+	if r.opts.ListKeysFn() != nil {
+		r.wg.Add(1)
+		go r.discoverKeys()
+	}
+
+	return nil
+}
+
+func (r *replicator) Stop() error {
+	r.Lock()
+	if r.state != replicatorStarted {
+		r.Unlock()
+		return errNotStarted
+	}
+
+	r.state = replicatorStopped
+	close(r.doneCh)
+	for _, w := range r.watches {
+		w.Close()
+	}
+	r.Unlock()
+
+	r.wg.Wait()
+	return nil
+}
+
+func (r *replicator) startWatchWithLock(key string, w kv.ValueWatch) {
+	r.watches[key] = w
+	r.metrics.keys.Update(float64(len(r.watches)))
+
+	r.wg.Add(1)
+	go r.watchKey(key, w)
+}
+
+// discoverKeys periodically lists the source prefix and starts replicating
+// any keys that are not yet being watched
+func (r *replicator) discoverKeys() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.opts.ListKeysInterval())
+	defer ticker.Stop()
+
+	for {
+		if err := r.watchNewKeys(); err != nil {
+			r.metrics.errors.Inc(1)
+			r.log.Errorf("could not list keys under %s in %s: %v",
+				r.opts.SourcePrefix(), r.opts.SourceZone(), err)
+		}
+
+		select {
+		case <-r.doneCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *replicator) watchNewKeys() error {
+	keys, err := r.opts.ListKeysFn()(r.opts.SourcePrefix())
+	if err != nil {
+		return err
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	for _, key := range keys {
+		if r.state != replicatorStarted {
+			return nil
+		}
+
+		if _, ok := r.watches[key]; ok || strings.HasSuffix(key, originSuffix) {
+			continue
+		}
+
+		w, err := r.source.Watch(r.opts.SourcePrefix() + key)
+		if err != nil {
+			return err
+		}
+
+		r.startWatchWithLock(key, w)
+	}
+
+	return nil
+}
+
+func (r *replicator) watchKey(key string, w kv.ValueWatch) {
+	defer r.wg.Done()
+
+	for {
+		select {
+		case <-r.doneCh:
+			return
+		case _, ok := <-w.C():
+			if !ok {
+				return
+			}
+
+			var (
+				sv     = w.Get()
+				seenAt = r.nowFn()
+			)
+			// The retrier backs off between attempts and gives up once the
+			// replicator stops, including when the destination keeps
+			// changing underneath the replication
+			r.retrier.AttemptWhile(r.running, func() error {
+				err := r.replicate(key, sv, seenAt)
+				if err != nil && err != errDestinationChanged {
+					r.metrics.errors.Inc(1)
+					r.log.Errorf("could not replicate %s from %s to %s: %v",
+						key, r.opts.SourceZone(), r.opts.DestinationZone(), err)
+				}
+
+				return err
+			})
+		}
+	}
+}
+
+func (r *replicator) running(int) bool {
+	select {
+	case <-r.doneCh:
+		return false
+	default:
+		return true
+	}
+}
+
+// replicate applies a source value first seen at seenAt to the destination,
+// returning errDestinationChanged if the destination changes underneath it
+func (r *replicator) replicate(key string, sv kv.Value, seenAt time.Time) error {
+	var (
+		sourceKey = r.opts.SourcePrefix() + key
+		destKey   = r.opts.DestinationPrefix() + key
+	)
+
+	// Work out which zone the source value originated in, and when it was
+	// written there. kv.Store does not record write times, so a value written
+	// in the source zone is dated from when its change was first observed.
+	origin := r.opts.SourceZone()
+	writtenAt := seenAt
+	sourceOrigin, err := getOrigin(r.source, sourceKey)
+	if err != nil {
+		return err
+	}
+
+	replicatedIntoSource := sv != nil && sourceOrigin != nil && int(sourceOrigin.Version) == sv.Version()
+	if replicatedIntoSource {
+		if sourceOrigin.Zone == r.opts.DestinationZone() {
+			// The value came from the destination zone in the first place
+			r.metrics.loopsSkipped.Inc(1)
+			return nil
+		}
+
+		origin = sourceOrigin.Zone
+		if sourceOrigin.WrittenAtNanos != 0 {
+			writtenAt = time.Unix(0, sourceOrigin.WrittenAtNanos)
+		}
+	}
+
+	dv, err := r.dest.Get(destKey)
+	if err != nil && err != kv.ErrNotFound {
+		return err
+	}
+
+	if err == kv.ErrNotFound {
+		dv = nil
+	}
+
+	destOrigin, err := getOrigin(r.dest, destKey)
+	if err != nil {
+		return err
+	}
+
+	if sv == nil && dv == nil {
+		return nil
+	}
+
+	// Values written by replication carry an origin matching their version
+	replicatedIntoDest := dv != nil && destOrigin != nil && int(destOrigin.Version) == dv.Version()
+	if sv != nil && replicatedIntoDest && int(destOrigin.SourceVersion) == sv.Version() {
+		// Already replicated
+		return nil
+	}
+
+	// The destination has changed locally since it was last replicated to,
+	// unless the source has already seen that change through replication
+	// in the other direction
+	sourceHasSeen := sourceOrigin != nil &&
+		sourceOrigin.Zone == r.opts.DestinationZone() &&
+		dv != nil && int(sourceOrigin.SourceVersion) == dv.Version()
+	if dv != nil && !replicatedIntoDest && !sourceHasSeen {
+		r.metrics.conflicts.Inc(1)
+		if r.opts.ConflictPolicy() == DestinationWins {
+			r.log.Warnf("not replicating %s to %s, it has been changed in %s",
+				sourceKey, destKey, r.opts.DestinationZone())
+			return nil
+		}
+	}
+
+	if sv == nil {
+		if err := r.delete(destKey); err != nil {
+			return err
+		}
+
+		r.metrics.lag.Record(r.nowFn().Sub(writtenAt))
+		return nil
+	}
+
+	var value kv.RawMessage
+	if err := sv.Unmarshal(&value); err != nil {
+		return err
+	}
+
+	// The value and its origin are written together, conditional on the
+	// destination being unchanged, so the version the value is written at
+	// is known up front. A missing key compares equal to version 0.
+	destVersion := 0
+	if dv != nil {
+		destVersion = dv.Version()
+	}
+
+	if _, err := r.dest.Commit(
+		[]kv.Condition{
+			kv.NewCondition().
+				SetKey(destKey).
+				SetTargetType(kv.TargetVersion).
+				SetCompareType(kv.CompareEqual).
+				SetValue(destVersion),
+		},
+		[]kv.Op{
+			kv.NewSetOp(destKey, &value),
+			kv.NewSetOp(fmtOriginKey(destKey), &replicationpb.Origin{
+				Zone:           origin,
+				SourceVersion:  int32(sv.Version()),
+				Version:        int32(destVersion + 1),
+				WrittenAtNanos: writtenAt.UnixNano(),
+			}),
+		},
+	); err != nil {
+		if err == kv.ErrConditionCheckFailed {
+			return errDestinationChanged
+		}
+
+		return err
+	}
+
+	r.metrics.replicated.Inc(1)
+	r.metrics.lag.Record(r.nowFn().Sub(writtenAt))
+	return nil
+}
+
+func (r *replicator) delete(destKey string) error {
+	if _, err := r.dest.Delete(destKey); err != nil && err != kv.ErrNotFound {
+		return err
+	}
+
+	if _, err := r.dest.Delete(fmtOriginKey(destKey)); err != nil && err != kv.ErrNotFound {
+		return err
+	}
+
+	r.metrics.deleted.Inc(1)
+	return nil
+}
+
+// getOrigin returns the origin recorded for a key, or nil if the key has
+// never been written by replication
+func getOrigin(store kv.Store, key string) (*replicationpb.Origin, error) {
+	v, err := store.Get(fmtOriginKey(key))
+	if err == kv.ErrNotFound {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var origin replicationpb.Origin
+	if err := v.Unmarshal(&origin); err != nil {
+		return nil, err
+	}
+
+	return &origin, nil
+}
+
+func fmtOriginKey(key string) string {
+	return key + originSuffix
+}
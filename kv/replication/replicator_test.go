@@ -0,0 +1,364 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package replication
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/m3db/m3cluster/generated/proto/commonpb"
+	"github.com/m3db/m3cluster/kv"
+	"github.com/m3db/m3cluster/kv/mem"
+	"github.com/m3db/m3x/retry"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptionsValidate(t *testing.T) {
+	opts := NewOptions()
+	require.Equal(t, errSourceZoneNotSet, opts.Validate())
+
+	opts = opts.SetSourceZone("z1")
+	require.Equal(t, errDestinationZoneNotSet, opts.Validate())
+
+	require.Equal(t, errSameZone, opts.SetDestinationZone("z1").Validate())
+
+	opts = opts.SetDestinationZone("z2")
+	require.Equal(t, errNoKeys, opts.Validate())
+
+	require.NoError(t, opts.SetKeys([]string{"k"}).Validate())
+
+	opts = opts.SetListKeysFn(func(string) ([]string, error) { return nil, nil })
+	require.NoError(t, opts.Validate())
+	require.Equal(t, errInvalidListInterval, opts.SetListKeysInterval(0).Validate())
+}
+
+func TestReplicate(t *testing.T) {
+	source, dest := mem.NewStore(), mem.NewStore()
+	r := newTestReplicator(t, source, dest, "z1", "z2")
+
+	_, err := source.Set("src/k", &commonpb.StringProto{Value: "foo"})
+	require.NoError(t, err)
+	require.NoError(t, r.replicate("k", mustGet(t, source, "src/k"), time.Now()))
+	requireString(t, dest, "dst/k", 1, "foo")
+
+	origin, err := getOrigin(dest, "dst/k")
+	require.NoError(t, err)
+	require.Equal(t, "z1", origin.Zone)
+	require.Equal(t, int32(1), origin.SourceVersion)
+	require.Equal(t, int32(1), origin.Version)
+
+	// Replicating the same version again is a no-op
+	require.NoError(t, r.replicate("k", mustGet(t, source, "src/k"), time.Now()))
+	requireString(t, dest, "dst/k", 1, "foo")
+
+	_, err = source.Set("src/k", &commonpb.StringProto{Value: "bar"})
+	require.NoError(t, err)
+	require.NoError(t, r.replicate("k", mustGet(t, source, "src/k"), time.Now()))
+	requireString(t, dest, "dst/k", 2, "bar")
+
+	// Deletes are replicated along with the origin
+	_, err = source.Delete("src/k")
+	require.NoError(t, err)
+	require.NoError(t, r.replicate("k", nil, time.Now()))
+	_, err = dest.Get("dst/k")
+	require.Equal(t, kv.ErrNotFound, err)
+	_, err = dest.Get("dst/k/_origin")
+	require.Equal(t, kv.ErrNotFound, err)
+}
+
+func TestReplicateSkipsLoops(t *testing.T) {
+	z1, z2 := mem.NewStore(), mem.NewStore()
+	forward := newTestReplicator(t, z1, z2, "z1", "z2")
+	backward := newTestReplicator(t, z2, z1, "z2", "z1", withPrefixes("dst/", "src/"))
+
+	_, err := z1.Set("src/k", &commonpb.StringProto{Value: "foo"})
+	require.NoError(t, err)
+	require.NoError(t, forward.replicate("k", mustGet(t, z1, "src/k"), time.Now()))
+
+	// The replicated value is not sent back to where it came from
+	require.NoError(t, backward.replicate("k", mustGet(t, z2, "dst/k"), time.Now()))
+	requireString(t, z1, "src/k", 1, "foo")
+
+	// A change made in the second zone is replicated back to the first, and
+	// then overwritten again from the first without being a conflict
+	_, err = z2.Set("dst/k", &commonpb.StringProto{Value: "bar"})
+	require.NoError(t, err)
+	require.NoError(t, backward.replicate("k", mustGet(t, z2, "dst/k"), time.Now()))
+	requireString(t, z1, "src/k", 2, "bar")
+	require.NoError(t, forward.replicate("k", mustGet(t, z1, "src/k"), time.Now()))
+	requireString(t, z2, "dst/k", 2, "bar")
+
+	_, err = z1.Set("src/k", &commonpb.StringProto{Value: "baz"})
+	require.NoError(t, err)
+	require.NoError(t, forward.replicate("k", mustGet(t, z1, "src/k"), time.Now()))
+	requireString(t, z2, "dst/k", 3, "baz")
+}
+
+func TestReplicateConflict(t *testing.T) {
+	source, dest := mem.NewStore(), mem.NewStore()
+	_, err := source.Set("src/k", &commonpb.StringProto{Value: "foo"})
+	require.NoError(t, err)
+	_, err = dest.Set("dst/k", &commonpb.StringProto{Value: "local"})
+	require.NoError(t, err)
+
+	// By default local changes in the destination are kept
+	r := newTestReplicator(t, source, dest, "z1", "z2")
+	require.NoError(t, r.replicate("k", mustGet(t, source, "src/k"), time.Now()))
+	requireString(t, dest, "dst/k", 1, "local")
+
+	r = newTestReplicator(t, source, dest, "z1", "z2", withConflictPolicy(SourceWins))
+	require.NoError(t, r.replicate("k", mustGet(t, source, "src/k"), time.Now()))
+	requireString(t, dest, "dst/k", 2, "foo")
+}
+
+func TestReplicatorWatchesSource(t *testing.T) {
+	source, dest := mem.NewStore(), mem.NewStore()
+	r := newTestReplicator(t, source, dest, "z1", "z2")
+
+	_, err := source.Set("src/k", &commonpb.StringProto{Value: "foo"})
+	require.NoError(t, err)
+
+	require.NoError(t, r.Start())
+	require.Equal(t, errAlreadyStarted, r.Start())
+
+	waitFor(t, func() bool {
+		_, err := dest.Get("dst/k")
+		return err == nil
+	})
+	requireString(t, dest, "dst/k", 1, "foo")
+
+	_, err = source.Set("src/k", &commonpb.StringProto{Value: "bar"})
+	require.NoError(t, err)
+	waitFor(t, func() bool {
+		v, err := dest.Get("dst/k")
+		return err == nil && v.Version() == 2
+	})
+	requireString(t, dest, "dst/k", 2, "bar")
+
+	require.NoError(t, r.Stop())
+	require.Equal(t, errNotStarted, r.Stop())
+}
+
+func TestReplicateRecordsSourceWriteTime(t *testing.T) {
+	z1, z2, z3 := mem.NewStore(), mem.NewStore(), mem.NewStore()
+	first := newTestReplicator(t, z1, z2, "z1", "z2")
+	second := newTestReplicator(t, z2, z3, "z2", "z3", withPrefixes("dst/", "dst/"))
+
+	_, err := z1.Set("src/k", &commonpb.StringProto{Value: "foo"})
+	require.NoError(t, err)
+
+	// The time a value was written in its original zone is carried along
+	// when it is replicated onwards
+	writtenAt := time.Unix(0, 1000)
+	require.NoError(t, first.replicate("k", mustGet(t, z1, "src/k"), writtenAt))
+	require.NoError(t, second.replicate("k", mustGet(t, z2, "dst/k"), time.Unix(0, 2000)))
+	requireString(t, z3, "dst/k", 1, "foo")
+
+	origin, err := getOrigin(z3, "dst/k")
+	require.NoError(t, err)
+	require.Equal(t, "z1", origin.Zone)
+	require.Equal(t, writtenAt.UnixNano(), origin.WrittenAtNanos)
+}
+
+func TestReplicatorRetriesFailedReplication(t *testing.T) {
+	source := mem.NewStore()
+	dest := &failingStore{TxnStore: mem.NewStore(), failures: 3}
+	r := newTestReplicator(t, source, dest, "z1", "z2")
+
+	_, err := source.Set("src/k", &commonpb.StringProto{Value: "foo"})
+	require.NoError(t, err)
+
+	require.NoError(t, r.Start())
+	waitFor(t, func() bool {
+		_, err := dest.Get("dst/k")
+		return err == nil
+	})
+	requireString(t, dest, "dst/k", 1, "foo")
+	require.NoError(t, r.Stop())
+}
+
+func TestReplicatorStopsWhileDestinationKeepsChanging(t *testing.T) {
+	source := mem.NewStore()
+	dest := &changingStore{TxnStore: mem.NewStore()}
+	r := newTestReplicator(t, source, dest, "z1", "z2")
+
+	_, err := source.Set("src/k", &commonpb.StringProto{Value: "foo"})
+	require.NoError(t, err)
+
+	require.NoError(t, r.Start())
+	waitFor(t, func() bool { return dest.numCommits() > 1 })
+	require.NoError(t, r.Stop())
+}
+
+func TestReplicatorDiscoversKeys(t *testing.T) {
+	var (
+		lock sync.Mutex
+		keys []string
+	)
+	listKeys := func(prefix string) ([]string, error) {
+		require.Equal(t, "src/", prefix)
+
+		lock.Lock()
+		defer lock.Unlock()
+		return keys, nil
+	}
+
+	source, dest := mem.NewStore(), mem.NewStore()
+	r := newTestReplicator(t, source, dest, "z1", "z2", func(opts Options) Options {
+		return opts.
+			SetKeys(nil).
+			SetListKeysFn(listKeys).
+			SetListKeysInterval(time.Millisecond)
+	})
+	require.NoError(t, r.Start())
+
+	_, err := source.Set("src/k1", &commonpb.StringProto{Value: "foo"})
+	require.NoError(t, err)
+	_, err = source.Set("src/k1/_origin", &commonpb.StringProto{Value: "ignored"})
+	require.NoError(t, err)
+
+	lock.Lock()
+	keys = []string{"k1", "k1/_origin"}
+	lock.Unlock()
+
+	waitFor(t, func() bool {
+		_, err := dest.Get("dst/k1")
+		return err == nil
+	})
+	requireString(t, dest, "dst/k1", 1, "foo")
+
+	require.NoError(t, r.Stop())
+
+	r.Lock()
+	defer r.Unlock()
+	require.Len(t, r.watches, 1)
+}
+
+type optionsFn func(Options) Options
+
+func withPrefixes(source, dest string) optionsFn {
+	return func(opts Options) Options {
+		return opts.SetSourcePrefix(source).SetDestinationPrefix(dest)
+	}
+}
+
+func withConflictPolicy(policy ConflictPolicy) optionsFn {
+	return func(opts Options) Options {
+		return opts.SetConflictPolicy(policy)
+	}
+}
+
+func newTestReplicator(
+	t *testing.T,
+	source kv.Store,
+	dest kv.TxnStore,
+	sourceZone, destZone string,
+	fns ...optionsFn,
+) *replicator {
+	opts := NewOptions().
+		SetSourceZone(sourceZone).
+		SetDestinationZone(destZone).
+		SetSourcePrefix("src/").
+		SetDestinationPrefix("dst/").
+		SetKeys([]string{"k"}).
+		SetRetryOptions(retry.NewOptions().
+			SetInitialBackoff(time.Millisecond).
+			SetForever(true))
+	for _, fn := range fns {
+		opts = fn(opts)
+	}
+
+	r, err := NewReplicator(source, dest, opts)
+	require.NoError(t, err)
+	return r.(*replicator)
+}
+
+func mustGet(t *testing.T, store kv.Store, key string) kv.Value {
+	v, err := store.Get(key)
+	require.NoError(t, err)
+	return v
+}
+
+func requireString(t *testing.T, store kv.Store, key string, version int, expected string) {
+	v := mustGet(t, store, key)
+	require.Equal(t, version, v.Version())
+
+	var s commonpb.StringProto
+	require.NoError(t, v.Unmarshal(&s))
+	require.Equal(t, expected, s.Value)
+}
+
+func waitFor(t *testing.T, fn func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !fn() {
+		if time.Now().After(deadline) {
+			require.FailNow(t, "timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+var errTestCommit = errors.New("test commit error")
+
+// failingStore fails the first few transactions committed to it
+type failingStore struct {
+	kv.TxnStore
+
+	sync.Mutex
+	failures int
+}
+
+func (s *failingStore) Commit(conditions []kv.Condition, ops []kv.Op) (kv.Response, error) {
+	s.Lock()
+	if s.failures > 0 {
+		s.failures--
+		s.Unlock()
+		return nil, errTestCommit
+	}
+	s.Unlock()
+
+	return s.TxnStore.Commit(conditions, ops)
+}
+
+// changingStore fails every commit as if the destination changed concurrently
+type changingStore struct {
+	kv.TxnStore
+
+	sync.Mutex
+	commits int
+}
+
+func (s *changingStore) Commit([]kv.Condition, []kv.Op) (kv.Response, error) {
+	s.Lock()
+	s.commits++
+	s.Unlock()
+
+	return nil, kv.ErrConditionCheckFailed
+}
+
+func (s *changingStore) numCommits() int {
+	s.Lock()
+	defer s.Unlock()
+	return s.commits
+}
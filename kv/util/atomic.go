@@ -21,7 +21,7 @@ func WatchAndUpdateAtomicBool(
 	updateFn := func(i interface{}) { property.Store(i.(bool)) }
 
 	return watchAndUpdate(
-		store, key, getBool, updateFn, opts.ValidateFn(), defaultValue, opts.Logger(),
+		store, key, getBool, updateFn, opts.ValidateFn(), opts.Migrator(), defaultValue, opts.Logger(),
 	)
 }
 
@@ -41,7 +41,7 @@ func WatchAndUpdateAtomicFloat64(
 	updateFn := func(i interface{}) { property.Store(i.(float64)) }
 
 	return watchAndUpdate(
-		store, key, getFloat64, updateFn, opts.ValidateFn(), defaultValue, opts.Logger(),
+		store, key, getFloat64, updateFn, opts.ValidateFn(), opts.Migrator(), defaultValue, opts.Logger(),
 	)
 }
 
@@ -61,7 +61,7 @@ func WatchAndUpdateAtomicInt64(
 	updateFn := func(i interface{}) { property.Store(i.(int64)) }
 
 	return watchAndUpdate(
-		store, key, getInt64, updateFn, opts.ValidateFn(), defaultValue, opts.Logger(),
+		store, key, getInt64, updateFn, opts.ValidateFn(), opts.Migrator(), defaultValue, opts.Logger(),
 	)
 }
 
@@ -81,6 +81,6 @@ func WatchAndUpdateAtomicString(
 	updateFn := func(i interface{}) { property.Store(i.(string)) }
 
 	return watchAndUpdate(
-		store, key, getString, updateFn, opts.ValidateFn(), defaultValue, opts.Logger(),
+		store, key, getString, updateFn, opts.ValidateFn(), opts.Migrator(), defaultValue, opts.Logger(),
 	)
 }
@@ -38,7 +38,7 @@ func WatchAndUpdateBool(
 	updateFn := lockedUpdate(func(i interface{}) { *property = i.(bool) }, lock)
 
 	return watchAndUpdate(
-		store, key, getBool, updateFn, opts.ValidateFn(), defaultValue, opts.Logger(),
+		store, key, getBool, updateFn, opts.ValidateFn(), opts.Migrator(), defaultValue, opts.Logger(),
 	)
 }
 
@@ -59,7 +59,7 @@ func WatchAndUpdateFloat64(
 	updateFn := lockedUpdate(func(i interface{}) { *property = i.(float64) }, lock)
 
 	return watchAndUpdate(
-		store, key, getFloat64, updateFn, opts.ValidateFn(), defaultValue, opts.Logger(),
+		store, key, getFloat64, updateFn, opts.ValidateFn(), opts.Migrator(), defaultValue, opts.Logger(),
 	)
 }
 
@@ -80,7 +80,7 @@ func WatchAndUpdateInt64(
 	updateFn := lockedUpdate(func(i interface{}) { *property = i.(int64) }, lock)
 
 	return watchAndUpdate(
-		store, key, getInt64, updateFn, opts.ValidateFn(), defaultValue, opts.Logger(),
+		store, key, getInt64, updateFn, opts.ValidateFn(), opts.Migrator(), defaultValue, opts.Logger(),
 	)
 }
 
@@ -101,7 +101,7 @@ func WatchAndUpdateString(
 	updateFn := lockedUpdate(func(i interface{}) { *property = i.(string) }, lock)
 
 	return watchAndUpdate(
-		store, key, getString, updateFn, opts.ValidateFn(), defaultValue, opts.Logger(),
+		store, key, getString, updateFn, opts.ValidateFn(), opts.Migrator(), defaultValue, opts.Logger(),
 	)
 }
 
@@ -122,7 +122,7 @@ func WatchAndUpdateStringArray(
 	updateFn := lockedUpdate(func(i interface{}) { *property = i.([]string) }, lock)
 
 	return watchAndUpdate(
-		store, key, getStringArray, updateFn, opts.ValidateFn(), defaultValue, opts.Logger(),
+		store, key, getStringArray, updateFn, opts.ValidateFn(), opts.Migrator(), defaultValue, opts.Logger(),
 	)
 }
 
@@ -143,7 +143,7 @@ func WatchAndUpdateStringArrayPointer(
 	updateFn := lockedUpdate(func(i interface{}) { *property = i.(*[]string) }, lock)
 
 	return watchAndUpdate(
-		store, key, getStringArrayPointer, updateFn, opts.ValidateFn(), defaultValue, opts.Logger(),
+		store, key, getStringArrayPointer, updateFn, opts.ValidateFn(), opts.Migrator(), defaultValue, opts.Logger(),
 	)
 }
 
@@ -164,6 +164,6 @@ func WatchAndUpdateTime(
 	updateFn := lockedUpdate(func(i interface{}) { *property = i.(time.Time) }, lock)
 
 	return watchAndUpdate(
-		store, key, getTime, updateFn, opts.ValidateFn(), defaultValue, opts.Logger(),
+		store, key, getTime, updateFn, opts.ValidateFn(), opts.Migrator(), defaultValue, opts.Logger(),
 	)
 }
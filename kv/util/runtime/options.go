@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"github.com/m3db/m3cluster/kv"
+	"github.com/m3db/m3cluster/kv/migration"
 	"github.com/m3db/m3x/instrument"
 )
 
@@ -62,6 +63,16 @@ type Options interface {
 
 	// ProcessFn returns the process function.
 	ProcessFn() ProcessFn
+
+	// SetMigrator sets the migrator used to upgrade values to the latest
+	// schema version before they are unmarshalled. Only the watched key is
+	// read, so values a key suffix schema writes back under a new key are not
+	// seen.
+	SetMigrator(value migration.Migrator) Options
+
+	// Migrator returns the migrator used to upgrade values to the latest
+	// schema version before they are unmarshalled.
+	Migrator() migration.Migrator
 }
 
 type options struct {
@@ -70,6 +81,7 @@ type options struct {
 	kvStore          kv.Store
 	unmarshalFn      UnmarshalFn
 	processFn        ProcessFn
+	migrator         migration.Migrator
 }
 
 // NewOptions creates a new set of options.
@@ -129,3 +141,13 @@ func (o *options) SetProcessFn(value ProcessFn) Options {
 func (o *options) ProcessFn() ProcessFn {
 	return o.processFn
 }
+
+func (o *options) SetMigrator(value migration.Migrator) Options {
+	opts := *o
+	opts.migrator = value
+	return &opts
+}
+
+func (o *options) Migrator() migration.Migrator {
+	return o.migrator
+}
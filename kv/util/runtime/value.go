@@ -27,6 +27,7 @@ import (
 	"time"
 
 	"github.com/m3db/m3cluster/kv"
+	"github.com/m3db/m3cluster/kv/migration"
 	"github.com/m3db/m3x/log"
 )
 
@@ -72,6 +73,7 @@ type value struct {
 	log              log.Logger
 	unmarshalFn      UnmarshalFn
 	processFn        ProcessFn
+	migrator         migration.Migrator
 	updateWithLockFn updateWithLockFn
 
 	status    valueStatus
@@ -91,6 +93,7 @@ func NewValue(
 		log:         opts.InstrumentOptions().Logger(),
 		unmarshalFn: opts.UnmarshalFn(),
 		processFn:   opts.ProcessFn(),
+		migrator:    opts.Migrator(),
 	}
 	v.updateWithLockFn = v.updateWithLock
 	return v
@@ -170,6 +173,14 @@ func (v *value) updateWithLock(update kv.Value) error {
 	if v.currValue != nil && !update.IsNewer(v.currValue) {
 		return nil
 	}
+	if v.migrator != nil {
+		migrated, err := v.migrator.Migrate(v.key, update)
+		if err != nil {
+			err = fmt.Errorf("error migrating value for version %d: %v", update.Version(), err)
+			return err
+		}
+		update = migrated
+	}
 	latest, err := v.unmarshalFn(update)
 	if err != nil {
 		err = fmt.Errorf("error unmarshalling value for version %d: %v", update.Version(), err)
@@ -22,16 +22,20 @@ package runtime
 
 import (
 	"errors"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/m3db/m3cluster/generated/proto/commonpb"
+	"github.com/m3db/m3cluster/generated/proto/migrationtest"
 	"github.com/m3db/m3cluster/kv"
 	"github.com/m3db/m3cluster/kv/mem"
+	"github.com/m3db/m3cluster/kv/migration"
 	"github.com/m3db/m3x/instrument"
 
 	"github.com/golang/mock/gomock"
+	"github.com/golang/protobuf/proto"
 	"github.com/stretchr/testify/require"
 )
 
@@ -283,6 +287,55 @@ func TestValueUpdateSuccess(t *testing.T) {
 	require.Equal(t, input, rv.currValue)
 }
 
+func TestValueUpdateMigrated(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var output *migrationtest.ConfigV2
+	_, rv := testValueWithMockStore(ctrl)
+	rv.migrator = testMigrator(t)
+	rv.unmarshalFn = func(v kv.Value) (interface{}, error) {
+		var cfg migrationtest.ConfigV2
+		err := v.Unmarshal(&cfg)
+		return &cfg, err
+	}
+	rv.processFn = func(v interface{}) error {
+		output = v.(*migrationtest.ConfigV2)
+		return nil
+	}
+
+	input := mem.NewValue(3, &migrationtest.ConfigV1{SchemaVersion: 1, Hosts: "a,b"})
+	require.NoError(t, rv.updateWithLock(input))
+	require.Equal(t, []string{"a", "b"}, output.Hosts)
+	require.Equal(t, 3, rv.currValue.Version())
+}
+
+func TestValueUpdateMigrateError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, rv := testValueWithMockStore(ctrl)
+	rv.migrator = testMigrator(t)
+
+	input := mem.NewValue(3, &migrationtest.ConfigV1{SchemaVersion: 5})
+	require.Error(t, rv.updateWithLock(input))
+	require.Nil(t, rv.currValue)
+}
+
+func testMigrator(t *testing.T) migration.Migrator {
+	m, err := migration.NewMigrator(migration.NewOptions().
+		SetSchema(migration.NewFieldSchema(&migrationtest.ConfigV2{}, "SchemaVersion")))
+	require.NoError(t, err)
+	require.NoError(t, m.Register(1, &migrationtest.ConfigV1{}, nil))
+	require.NoError(t, m.Register(2, &migrationtest.ConfigV2{}, func(prev proto.Message) (proto.Message, error) {
+		return &migrationtest.ConfigV2{
+			SchemaVersion: 2,
+			Hosts:         strings.Split(prev.(*migrationtest.ConfigV1).Hosts, ","),
+		}, nil
+	}))
+	return m
+}
+
 func testValueOptions(store kv.Store) Options {
 	return NewOptions().
 		SetInstrumentOptions(instrument.NewOptions()).
@@ -2,6 +2,7 @@ package util
 
 import (
 	"github.com/m3db/m3cluster/kv"
+	"github.com/m3db/m3cluster/kv/migration"
 	"github.com/m3db/m3x/log"
 )
 
@@ -20,6 +21,15 @@ type Options interface {
 	// ValidateFn returns the validation function applied to kv values.
 	ValidateFn() ValidateFn
 
+	// SetMigrator sets the migrator used to upgrade kv values to the latest
+	// schema version before they are applied. Only the watched key is read, so
+	// values a key suffix schema writes back under a new key are not seen.
+	SetMigrator(val migration.Migrator) Options
+
+	// Migrator returns the migrator used to upgrade kv values to the latest
+	// schema version before they are applied.
+	Migrator() migration.Migrator
+
 	// SetLogger sets the logger.
 	SetLogger(val log.Logger) Options
 
@@ -29,6 +39,7 @@ type Options interface {
 
 type options struct {
 	validateFn ValidateFn
+	migrator   migration.Migrator
 	logger     log.Logger
 }
 
@@ -47,6 +58,16 @@ func (o *options) ValidateFn() ValidateFn {
 	return o.validateFn
 }
 
+func (o *options) SetMigrator(val migration.Migrator) Options {
+	opts := *o
+	opts.migrator = val
+	return &opts
+}
+
+func (o *options) Migrator() migration.Migrator {
+	return o.migrator
+}
+
 func (o *options) SetLogger(val log.Logger) Options {
 	opts := *o
 	opts.logger = val
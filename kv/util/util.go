@@ -27,6 +27,7 @@ import (
 
 	"github.com/m3db/m3cluster/generated/proto/commonpb"
 	"github.com/m3db/m3cluster/kv"
+	"github.com/m3db/m3cluster/kv/migration"
 	"github.com/m3db/m3x/log"
 )
 
@@ -45,7 +46,7 @@ func BoolFromValue(v kv.Value, key string, defaultValue bool, opts Options) (boo
 	updateFn := func(i interface{}) { res = i.(bool) }
 
 	if err := updateWithKV(
-		getBool, updateFn, opts.ValidateFn(), key, v, defaultValue, opts.Logger(),
+		getBool, updateFn, opts.ValidateFn(), opts.Migrator(), key, v, defaultValue, opts.Logger(),
 	); err != nil {
 		return false, err
 	}
@@ -64,7 +65,7 @@ func Float64FromValue(v kv.Value, key string, defaultValue float64, opts Options
 	updateFn := func(i interface{}) { res = i.(float64) }
 
 	if err := updateWithKV(
-		getFloat64, updateFn, opts.ValidateFn(), key, v, defaultValue, opts.Logger(),
+		getFloat64, updateFn, opts.ValidateFn(), opts.Migrator(), key, v, defaultValue, opts.Logger(),
 	); err != nil {
 		return 0, err
 	}
@@ -83,7 +84,7 @@ func Int64FromValue(v kv.Value, key string, defaultValue int64, opts Options) (i
 	updateFn := func(i interface{}) { res = i.(int64) }
 
 	if err := updateWithKV(
-		getInt64, updateFn, opts.ValidateFn(), key, v, defaultValue, opts.Logger(),
+		getInt64, updateFn, opts.ValidateFn(), opts.Migrator(), key, v, defaultValue, opts.Logger(),
 	); err != nil {
 		return 0, err
 	}
@@ -102,7 +103,7 @@ func StringFromValue(v kv.Value, key string, defaultValue string, opts Options)
 	updateFn := func(i interface{}) { res = i.(string) }
 
 	if err := updateWithKV(
-		getString, updateFn, opts.ValidateFn(), key, v, defaultValue, opts.Logger(),
+		getString, updateFn, opts.ValidateFn(), opts.Migrator(), key, v, defaultValue, opts.Logger(),
 	); err != nil {
 		return "", err
 	}
@@ -123,7 +124,7 @@ func StringArrayFromValue(
 	updateFn := func(i interface{}) { res = i.([]string) }
 
 	if err := updateWithKV(
-		getStringArray, updateFn, opts.ValidateFn(), key, v, defaultValue, opts.Logger(),
+		getStringArray, updateFn, opts.ValidateFn(), opts.Migrator(), key, v, defaultValue, opts.Logger(),
 	); err != nil {
 		return nil, err
 	}
@@ -144,7 +145,7 @@ func TimeFromValue(
 	updateFn := func(i interface{}) { res = i.(time.Time) }
 
 	if err := updateWithKV(
-		getTime, updateFn, opts.ValidateFn(), key, v, defaultValue, opts.Logger(),
+		getTime, updateFn, opts.ValidateFn(), opts.Migrator(), key, v, defaultValue, opts.Logger(),
 	); err != nil {
 		return time.Time{}, err
 	}
@@ -215,6 +216,7 @@ func watchAndUpdate(
 	getValue getValueFn,
 	update updateFn,
 	validate ValidateFn,
+	migrator migration.Migrator,
 	defaultValue interface{},
 	logger log.Logger,
 ) (kv.ValueWatch, error) {
@@ -234,7 +236,7 @@ func watchAndUpdate(
 
 	go func() {
 		for range watch.C() {
-			updateWithKV(getValue, update, validate, key, watch.Get(), defaultValue, logger)
+			updateWithKV(getValue, update, validate, migrator, key, watch.Get(), defaultValue, logger)
 		}
 		// The channel for a ValueWatch should never close.
 		getLogger(logger).
@@ -249,11 +251,21 @@ func updateWithKV(
 	getValue getValueFn,
 	update updateFn,
 	validate ValidateFn,
+	migrator migration.Migrator,
 	key string,
 	v kv.Value,
 	defaultValue interface{},
 	logger log.Logger,
 ) error {
+	if migrator != nil {
+		migrated, err := migrator.Migrate(key, v)
+		if err != nil {
+			logMalformedUpdate(logger, key, v.Version(), nil, err)
+			return err
+		}
+		v = migrated
+	}
+
 	if v == nil {
 		// The key is deleted from kv, use the default value.
 		update(defaultValue)
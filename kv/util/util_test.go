@@ -21,12 +21,15 @@
 package util
 
 import (
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/m3db/m3cluster/generated/proto/commonpb"
 	"github.com/m3db/m3cluster/kv"
 	"github.com/m3db/m3cluster/kv/mem"
+	"github.com/m3db/m3cluster/kv/migration"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -320,3 +323,21 @@ func TestStringArrayFromValue(t *testing.T) {
 	)
 	assert.Error(t, err)
 }
+
+func TestStringArrayFromValueMigrated(t *testing.T) {
+	m, err := migration.NewMigrator(migration.NewOptions().SetSchema(migration.NewKeySuffixSchema("/v")))
+	require.NoError(t, err)
+	require.NoError(t, m.Register(1, &commonpb.StringProto{}, nil))
+	require.NoError(t, m.Register(2, &commonpb.StringArrayProto{}, func(prev proto.Message) (proto.Message, error) {
+		return &commonpb.StringArrayProto{Values: strings.Split(prev.(*commonpb.StringProto).Value, ",")}, nil
+	}))
+
+	opts := NewOptions().SetMigrator(m)
+	v := mem.NewValue(1, &commonpb.StringProto{Value: "foo,bar"})
+	res, err := StringArrayFromValue(v, "key/v1", nil, opts)
+	require.NoError(t, err)
+	require.Equal(t, []string{"foo", "bar"}, res)
+
+	_, err = StringArrayFromValue(v, "key/v3", nil, opts)
+	require.Equal(t, migration.ErrUnknownSchemaVersion, err)
+}
@@ -30,9 +30,19 @@ func NewAlgorithm(opts placement.Options) placement.Algorithm {
 		return newMirroredAlgorithm(opts)
 	}
 
-	if opts.IsSharded() {
-		return newShardedAlgorithm(opts)
+	if !opts.IsSharded() {
+		return newNonShardedAlgorithm()
 	}
 
-	return newNonShardedAlgorithm()
+	var algo placement.Algorithm
+	if len(opts.ZoneReplicas()) > 0 {
+		algo = newMultiZoneAlgorithm(opts)
+	} else {
+		algo = newShardedAlgorithm(opts)
+	}
+
+	if opts.MaxInitializingShardsPerInstance() > 0 || opts.MaxInitializingShardsPerPlacement() > 0 {
+		return newThrottledAlgorithm(algo, opts)
+	}
+	return algo
 }
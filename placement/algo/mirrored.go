@@ -23,6 +23,7 @@ package algo
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 
 	"github.com/m3db/m3cluster/placement"
@@ -63,7 +64,7 @@ func (a mirroredAlgorithm) InitialPlacement(
 	shards []uint32,
 	rf int,
 ) (placement.Placement, error) {
-	mirrorInstances, err := groupInstancesByShardSetID(instances, rf)
+	mirrorInstances, err := groupInstancesByShardSetID(instances, rf, a.opts)
 	if err != nil {
 		return nil, err
 	}
@@ -92,6 +93,119 @@ func (a mirroredAlgorithm) AddReplica(p placement.Placement) (placement.Placemen
 	return nil, errors.New("not supported")
 }
 
+func (a mirroredAlgorithm) RemoveReplica(p placement.Placement) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	if p.ReplicaFactor() <= 1 {
+		return nil, errNoReplicaToRemove
+	}
+
+	p, err := markAllShardsAvailable(p, a.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validates the shard sets before dropping a whole instance from each of them.
+	if _, err := mirrorFromPlacement(p, a.opts); err != nil {
+		return nil, err
+	}
+
+	var (
+		level       = a.opts.IsolationLevel()
+		instances   = p.Instances()
+		shardSets   = make(map[uint32][]placement.Instance)
+		domainCount = make(map[string]int, len(instances))
+		ssIDs       []int
+	)
+	sort.Sort(placement.ByIDAscending(instances))
+	for _, instance := range instances {
+		ssID := instance.ShardSetID()
+		if _, ok := shardSets[ssID]; !ok {
+			ssIDs = append(ssIDs, int(ssID))
+		}
+		shardSets[ssID] = append(shardSets[ssID], instance)
+		domainCount[placement.FailureDomain(instance, level)]++
+	}
+	sort.Ints(ssIDs)
+
+	// Drops the instance in the most crowded failure domain from each shard set
+	// so the remaining instances spread as much as possible.
+	cutoffNanos := a.opts.ShardCutoffNanosFn()()
+	for _, ssID := range ssIDs {
+		var (
+			dropFrom       placement.Instance
+			dropFromDomain string
+		)
+		for _, instance := range shardSets[uint32(ssID)] {
+			domain := placement.FailureDomain(instance, level)
+			if dropFrom == nil || domainCount[domain] > domainCount[dropFromDomain] {
+				dropFrom, dropFromDomain = instance, domain
+			}
+		}
+
+		domainCount[dropFromDomain]--
+		for _, s := range dropFrom.Shards().All() {
+			dropShard(p, dropFrom, s.ID(), cutoffNanos)
+		}
+	}
+
+	return p.
+		SetReplicaFactor(p.ReplicaFactor() - 1).
+		SetCutoverNanos(a.opts.PlacementCutoverNanosFn()()), nil
+}
+
+func (a mirroredAlgorithm) SplitShards(p placement.Placement, factor int) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	return splitShards(p, factor, a.opts)
+}
+
+func (a mirroredAlgorithm) Rebalance(
+	p placement.Placement,
+	maxShardMoves int,
+) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	p, err := markAllShardsAvailable(p, a.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	mirrorPlacement, err := mirrorFromPlacement(p, a.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each shard moved in the mirror placement moves the shard set of rf instances.
+	if mirrorPlacement, _, err = rebalance(mirrorPlacement, a.opts, maxShardMoves); err != nil {
+		return nil, err
+	}
+
+	return placementFromMirror(mirrorPlacement, p.Instances(), p.ReplicaFactor())
+}
+
+func (a mirroredAlgorithm) UpdateInstances(
+	p placement.Placement,
+	instances []placement.Instance,
+) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	p, weightChanged, err := updateInstances(p, instances)
+	if err != nil || !weightChanged {
+		return p, err
+	}
+
+	return a.Rebalance(p, 0)
+}
+
 func (a mirroredAlgorithm) RemoveInstances(
 	p placement.Placement,
 	instanceIDs []string,
@@ -121,12 +235,12 @@ func (a mirroredAlgorithm) RemoveInstances(
 		removingInstances = append(removingInstances, instance)
 	}
 
-	mirrorPlacement, err := mirrorFromPlacement(p)
+	mirrorPlacement, err := mirrorFromPlacement(p, a.opts)
 	if err != nil {
 		return nil, err
 	}
 
-	mirrorInstances, err := groupInstancesByShardSetID(removingInstances, p.ReplicaFactor())
+	mirrorInstances, err := groupInstancesByShardSetID(removingInstances, p.ReplicaFactor(), a.opts)
 	if err != nil {
 		return nil, err
 	}
@@ -173,12 +287,12 @@ func (a mirroredAlgorithm) AddInstances(
 		return nil, err
 	}
 
-	mirrorPlacement, err := mirrorFromPlacement(p)
+	mirrorPlacement, err := mirrorFromPlacement(p, a.opts)
 	if err != nil {
 		return nil, err
 	}
 
-	mirrorInstances, err := groupInstancesByShardSetID(addingInstances, p.ReplicaFactor())
+	mirrorInstances, err := groupInstancesByShardSetID(addingInstances, p.ReplicaFactor(), a.opts)
 	if err != nil {
 		return nil, err
 	}
@@ -417,6 +531,7 @@ func validAddingInstances(p placement.Placement, addingInstances []placement.Ins
 func groupInstancesByShardSetID(
 	instances []placement.Instance,
 	rf int,
+	opts placement.Options,
 ) ([]placement.Instance, error) {
 	var (
 		shardSetMap = make(map[uint32]*shardSetMetadata, len(instances))
@@ -426,20 +541,21 @@ func groupInstancesByShardSetID(
 		var (
 			ssID   = instance.ShardSetID()
 			weight = instance.Weight()
-			rack   = instance.Rack()
+			domain = placement.FailureDomain(instance, opts.IsolationLevel())
 			shards = instance.Shards()
 		)
 		meta, ok := shardSetMap[ssID]
 		if !ok {
 			meta = &shardSetMetadata{
-				weight: weight,
-				racks:  make(map[string]struct{}, rf),
-				shards: shards,
+				weight:  weight,
+				domains: make(map[string]struct{}, rf),
+				shards:  shards,
 			}
 			shardSetMap[ssID] = meta
 		}
-		if _, ok := meta.racks[rack]; ok {
-			return nil, fmt.Errorf("found duplicated rack %s for shardset id %d", rack, ssID)
+		_, ok = meta.domains[domain]
+		if ok && opts.IsolationMode() != placement.IsolationModeBestEffort {
+			return nil, fmt.Errorf("found duplicated %s %s for shardset id %d", opts.IsolationLevel(), domain, ssID)
 		}
 
 		if meta.weight != weight {
@@ -450,7 +566,7 @@ func groupInstancesByShardSetID(
 			return nil, fmt.Errorf("found different shards: %v and %v, for shardset id %d", meta.shards, shards, ssID)
 		}
 
-		meta.racks[rack] = struct{}{}
+		meta.domains[domain] = struct{}{}
 		meta.count++
 	}
 
@@ -478,8 +594,8 @@ func groupInstancesByShardSetID(
 
 // mirrorFromPlacement zips all instances with the same shardSetID into a virtual instance
 // and create a placement with those virtual instance and rf=1.
-func mirrorFromPlacement(p placement.Placement) (placement.Placement, error) {
-	mirrorInstances, err := groupInstancesByShardSetID(p.Instances(), p.ReplicaFactor())
+func mirrorFromPlacement(p placement.Placement, opts placement.Options) (placement.Placement, error) {
+	mirrorInstances, err := groupInstancesByShardSetID(p.Instances(), p.ReplicaFactor(), opts)
 	if err != nil {
 		return nil, err
 	}
@@ -570,8 +686,8 @@ func instancesFromMirror(
 }
 
 type shardSetMetadata struct {
-	weight uint32
-	count  int
-	racks  map[string]struct{}
-	shards shard.Shards
+	weight  uint32
+	count   int
+	domains map[string]struct{}
+	shards  shard.Shards
 }
@@ -21,6 +21,7 @@
 package algo
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -1019,7 +1020,7 @@ func TestGroupInstanceByShardSetID(t *testing.T) {
 			shard.NewShard(0).SetState(shard.Available),
 		}))
 
-	res, err := groupInstancesByShardSetID([]placement.Instance{i1, i2}, 2)
+	res, err := groupInstancesByShardSetID([]placement.Instance{i1, i2}, 2, placement.NewOptions())
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(res))
 	assert.Equal(t, placement.NewInstance().
@@ -1031,10 +1032,10 @@ func TestGroupInstanceByShardSetID(t *testing.T) {
 			shard.NewShard(0).SetState(shard.Available),
 		})), res[0])
 
-	_, err = groupInstancesByShardSetID([]placement.Instance{i1, i2.Clone().SetWeight(2)}, 2)
+	_, err = groupInstancesByShardSetID([]placement.Instance{i1, i2.Clone().SetWeight(2)}, 2, placement.NewOptions())
 	assert.Error(t, err)
 
-	_, err = groupInstancesByShardSetID([]placement.Instance{i1, i2.Clone().SetRack("r1")}, 2)
+	_, err = groupInstancesByShardSetID([]placement.Instance{i1, i2.Clone().SetRack("r1")}, 2, placement.NewOptions())
 	assert.Error(t, err)
 }
 
@@ -1236,3 +1237,187 @@ func TestMarkShardAsAvailableWithMirroredAlgo(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NoError(t, placement.Validate(p))
 }
+
+func TestMirrorRebalance(t *testing.T) {
+	i1 := placement.NewInstance().
+		SetID("i1").
+		SetRack("r1").
+		SetEndpoint("endpoint1").
+		SetShardSetID(0).
+		SetWeight(1)
+	i2 := placement.NewInstance().
+		SetID("i2").
+		SetRack("r2").
+		SetEndpoint("endpoint2").
+		SetShardSetID(0).
+		SetWeight(1)
+	i3 := placement.NewInstance().
+		SetID("i3").
+		SetRack("r3").
+		SetEndpoint("endpoint3").
+		SetShardSetID(1).
+		SetWeight(1)
+	i4 := placement.NewInstance().
+		SetID("i4").
+		SetRack("r4").
+		SetEndpoint("endpoint4").
+		SetShardSetID(1).
+		SetWeight(1)
+
+	ids := make([]uint32, 8)
+	for i := 0; i < len(ids); i++ {
+		ids[i] = uint32(i)
+	}
+
+	opts := placement.NewOptions().SetIsMirrored(true)
+	a := NewAlgorithm(opts)
+	p, err := a.InitialPlacement([]placement.Instance{i1, i2, i3, i4}, ids, 2)
+	assert.NoError(t, err)
+	p, err = markAllShardsAvailable(p, opts)
+	assert.NoError(t, err)
+
+	for _, id := range []string{"i3", "i4"} {
+		instance, ok := p.Instance(id)
+		assert.True(t, ok)
+		instance.SetWeight(3)
+	}
+
+	p, err = a.Rebalance(p, 2)
+	assert.NoError(t, err)
+	assert.NoError(t, placement.Validate(p))
+	for _, id := range []string{"i1", "i2"} {
+		instance, _ := p.Instance(id)
+		assert.Equal(t, 2, instance.Shards().NumShardsForState(shard.Leaving))
+	}
+	i3, _ = p.Instance("i3")
+	i4, _ = p.Instance("i4")
+	assert.Equal(t, 2, i3.Shards().NumShardsForState(shard.Initializing))
+	assert.Equal(t, i3.Shards().AllIDs(), i4.Shards().AllIDs())
+}
+
+func TestMirrorUpdateInstances(t *testing.T) {
+	var instances []placement.Instance
+	for i := 1; i <= 4; i++ {
+		instances = append(instances, placement.NewInstance().
+			SetID(fmt.Sprintf("i%d", i)).
+			SetRack(fmt.Sprintf("r%d", i)).
+			SetEndpoint(fmt.Sprintf("endpoint%d", i)).
+			SetShardSetID(uint32((i-1)/2)).
+			SetWeight(1))
+	}
+
+	ids := make([]uint32, 8)
+	for i := 0; i < len(ids); i++ {
+		ids[i] = uint32(i)
+	}
+
+	opts := placement.NewOptions().SetIsMirrored(true)
+	a := NewAlgorithm(opts)
+	p, err := a.InitialPlacement(instances, ids, 2)
+	assert.NoError(t, err)
+	p, err = markAllShardsAvailable(p, opts)
+	assert.NoError(t, err)
+
+	i1, _ := p.Instance("i1")
+	p1, err := a.UpdateInstances(p, []placement.Instance{i1.Clone().SetEndpoint("endpoint11")})
+	assert.NoError(t, err)
+	assert.NoError(t, placement.Validate(p1))
+	i1, _ = p1.Instance("i1")
+	assert.Equal(t, "endpoint11", i1.Endpoint())
+	assert.Equal(t, 4, i1.Shards().NumShardsForState(shard.Available))
+
+	i3, _ := p.Instance("i3")
+	_, err = a.UpdateInstances(p, []placement.Instance{i3.Clone().SetWeight(3)})
+	assert.Error(t, err)
+
+	i4, _ := p.Instance("i4")
+	p2, err := a.UpdateInstances(p, []placement.Instance{i3.Clone().SetWeight(3), i4.Clone().SetWeight(3)})
+	assert.NoError(t, err)
+	assert.NoError(t, placement.Validate(p2))
+	i3, _ = p2.Instance("i3")
+	i4, _ = p2.Instance("i4")
+	assert.Equal(t, 6, loadOnInstance(i3))
+	assert.Equal(t, i3.Shards().AllIDs(), i4.Shards().AllIDs())
+}
+
+func TestMirrorRemoveReplica(t *testing.T) {
+	var instances []placement.Instance
+	for i := 1; i <= 6; i++ {
+		instances = append(instances, placement.NewInstance().
+			SetID(fmt.Sprintf("i%d", i)).
+			SetRack(fmt.Sprintf("r%d", (i-1)%3)).
+			SetEndpoint(fmt.Sprintf("endpoint%d", i)).
+			SetShardSetID(uint32((i-1)/3)).
+			SetWeight(1))
+	}
+
+	ids := make([]uint32, 8)
+	for i := 0; i < len(ids); i++ {
+		ids[i] = uint32(i)
+	}
+
+	opts := placement.NewOptions().SetIsMirrored(true)
+	a := NewAlgorithm(opts)
+	p, err := a.InitialPlacement(instances, ids, 3)
+	assert.NoError(t, err)
+
+	p, err = a.RemoveReplica(p)
+	assert.NoError(t, err)
+	assert.NoError(t, placement.Validate(p))
+	assert.Equal(t, 2, p.ReplicaFactor())
+
+	var dropped []string
+	for _, instance := range p.Instances() {
+		if instance.IsLeaving() {
+			dropped = append(dropped, instance.ID())
+		}
+	}
+	assert.Equal(t, 2, len(dropped))
+
+	p, err = markAllShardsAvailable(p, opts)
+	assert.NoError(t, err)
+	assert.NoError(t, placement.Validate(p))
+	assert.Equal(t, 4, p.NumInstances())
+	for _, id := range dropped {
+		_, ok := p.Instance(id)
+		assert.False(t, ok)
+	}
+	racks := make(map[string]int)
+	for _, instance := range p.Instances() {
+		racks[instance.Rack()]++
+	}
+	assert.Equal(t, 3, len(racks))
+
+	_, err = mirrorFromPlacement(p, opts)
+	assert.NoError(t, err)
+}
+
+func TestMirrorSplitShards(t *testing.T) {
+	var instances []placement.Instance
+	for i := 1; i <= 4; i++ {
+		instances = append(instances, placement.NewInstance().
+			SetID(fmt.Sprintf("i%d", i)).
+			SetRack(fmt.Sprintf("r%d", i)).
+			SetEndpoint(fmt.Sprintf("endpoint%d", i)).
+			SetShardSetID(uint32((i-1)/2)).
+			SetWeight(1))
+	}
+
+	opts := placement.NewOptions().SetIsMirrored(true)
+	a := NewAlgorithm(opts)
+	p, err := a.InitialPlacement(instances, []uint32{0, 1, 2, 3}, 2)
+	assert.NoError(t, err)
+
+	p, err = a.SplitShards(p, 2)
+	assert.NoError(t, err)
+	assert.NoError(t, placement.Validate(p))
+	assert.Equal(t, 8, p.NumShards())
+
+	p, err = markAllShardsAvailable(p, opts)
+	assert.NoError(t, err)
+	_, err = mirrorFromPlacement(p, opts)
+	assert.NoError(t, err)
+	for _, instance := range p.Instances() {
+		assert.Equal(t, 4, instance.Shards().NumShardsForState(shard.Available))
+	}
+}
@@ -0,0 +1,510 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package algo
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/m3db/m3cluster/placement"
+)
+
+var (
+	errIncompatibleWithMultiZoneAlgo = errors.New("could not apply multi-zone algo on the placement")
+	errNoZoneReplicas                = errors.New("no zone replicas configured for the multi-zone algo")
+	errNoZoneReplicasToAdd           = errors.New("zone replicas do not add any replica to the placement")
+	errNoZoneReplicasToRemove        = errors.New("zone replicas do not remove any replica from the placement")
+	errZoneReplicasMismatch          = errors.New("placement zone replicas do not match the configured zone replicas, add or remove replicas to change them")
+)
+
+// multiZoneAlgorithm places the replicas of each shard across zones by
+// the number of replicas configured for each zone. Each zone is placed
+// independently with the sharded algorithm so shards never move across zones.
+type multiZoneAlgorithm struct {
+	opts        placement.Options
+	shardedAlgo placement.Algorithm
+}
+
+func newMultiZoneAlgorithm(opts placement.Options) placement.Algorithm {
+	return multiZoneAlgorithm{
+		opts:        opts,
+		shardedAlgo: newShardedAlgorithm(opts),
+	}
+}
+
+func (a multiZoneAlgorithm) IsCompatibleWith(p placement.Placement) error {
+	if err := a.isCompatibleLayout(p); err != nil {
+		return err
+	}
+
+	if zoneReplicas := p.ZoneReplicas(); len(zoneReplicas) > 0 && !zoneReplicasEqual(zoneReplicas, a.opts.ZoneReplicas()) {
+		return errZoneReplicasMismatch
+	}
+
+	return nil
+}
+
+// isCompatibleLayout checks whether the placement could be placed by zone,
+// regardless of its zone replicas. AddReplica and RemoveReplica only check
+// the layout, since they move the zone replicas to the configured ones.
+func (a multiZoneAlgorithm) isCompatibleLayout(p placement.Placement) error {
+	if !p.IsSharded() || p.IsMirrored() {
+		return errIncompatibleWithMultiZoneAlgo
+	}
+
+	return nil
+}
+
+func (a multiZoneAlgorithm) InitialPlacement(
+	instances []placement.Instance,
+	shards []uint32,
+	rf int,
+) (placement.Placement, error) {
+	zoneReplicas := a.opts.ZoneReplicas()
+	if len(zoneReplicas) == 0 {
+		return nil, errNoZoneReplicas
+	}
+
+	if total := totalReplicas(zoneReplicas); total != rf {
+		return nil, fmt.Errorf("replica factor %d does not match the %d replicas in zone replicas %v", rf, total, zoneReplicas)
+	}
+
+	instancesByZone, err := groupInstancesByZone(instances, zoneReplicas)
+	if err != nil {
+		return nil, err
+	}
+
+	zonePlacements := make(map[string]placement.Placement, len(zoneReplicas))
+	for _, zone := range sortedZones(zoneReplicas) {
+		replicas := zoneReplicas[zone]
+		if replicas == 0 {
+			continue
+		}
+
+		zoneInstances := instancesByZone[zone]
+		if len(zoneInstances) == 0 {
+			return nil, fmt.Errorf("could not place %d replicas in zone %s, no instances in the zone", replicas, zone)
+		}
+
+		zp, err := a.shardedAlgo.InitialPlacement(zoneInstances, shards, replicas)
+		if err != nil {
+			return nil, fmt.Errorf("could not place replicas in zone %s: %v", zone, err)
+		}
+		zonePlacements[zone] = zp
+	}
+
+	return a.mergeZonePlacements(shards, zonePlacements, zoneReplicas), nil
+}
+
+func (a multiZoneAlgorithm) AddReplica(p placement.Placement) (placement.Placement, error) {
+	if err := a.isCompatibleLayout(p); err != nil {
+		return nil, err
+	}
+
+	target := a.opts.ZoneReplicas()
+	if len(target) == 0 {
+		return nil, errNoZoneReplicas
+	}
+
+	current := a.zoneReplicas(p)
+	for zone := range current {
+		if _, ok := target[zone]; !ok {
+			return nil, fmt.Errorf("zone %s is missing from the zone replicas %v", zone, target)
+		}
+	}
+
+	zonePlacements := splitPlacementByZone(p, current)
+	added := false
+	for _, zone := range sortedZones(target) {
+		toAdd := target[zone] - current[zone]
+		if toAdd < 0 {
+			return nil, fmt.Errorf("could not reduce the replicas in zone %s from %d to %d by adding replicas", zone, current[zone], target[zone])
+		}
+		if toAdd == 0 {
+			continue
+		}
+
+		zp, ok := zonePlacements[zone]
+		if !ok || zp.NumInstances() == 0 {
+			return nil, fmt.Errorf("could not add replicas to zone %s, no instances in the zone", zone)
+		}
+
+		var err error
+		for i := 0; i < toAdd; i++ {
+			if zp, err = a.shardedAlgo.AddReplica(zp); err != nil {
+				return nil, fmt.Errorf("could not add replica to zone %s: %v", zone, err)
+			}
+		}
+		zonePlacements[zone] = zp
+		added = true
+	}
+
+	if !added {
+		return nil, errNoZoneReplicasToAdd
+	}
+
+	return a.mergeZonePlacements(p.Shards(), zonePlacements, target), nil
+}
+
+func (a multiZoneAlgorithm) RemoveReplica(p placement.Placement) (placement.Placement, error) {
+	if err := a.isCompatibleLayout(p); err != nil {
+		return nil, err
+	}
+
+	target := a.opts.ZoneReplicas()
+	if len(target) == 0 {
+		return nil, errNoZoneReplicas
+	}
+
+	current := a.zoneReplicas(p)
+	for zone := range current {
+		if _, ok := target[zone]; !ok {
+			return nil, fmt.Errorf("zone %s is missing from the zone replicas %v", zone, target)
+		}
+	}
+
+	zonePlacements := splitPlacementByZone(p, current)
+	removed := false
+	for _, zone := range sortedZones(current) {
+		toRemove := current[zone] - target[zone]
+		if toRemove < 0 {
+			return nil, fmt.Errorf("could not increase the replicas in zone %s from %d to %d by removing replicas", zone, current[zone], target[zone])
+		}
+		if toRemove == 0 {
+			continue
+		}
+
+		zp, ok := zonePlacements[zone]
+		if !ok {
+			continue
+		}
+
+		var err error
+		for i := 0; i < toRemove; i++ {
+			if zp, err = a.shardedAlgo.RemoveReplica(zp); err != nil {
+				return nil, fmt.Errorf("could not remove replica from zone %s: %v", zone, err)
+			}
+		}
+		zonePlacements[zone] = zp
+		removed = true
+	}
+
+	if !removed {
+		return nil, errNoZoneReplicasToRemove
+	}
+
+	return a.mergeZonePlacements(p.Shards(), zonePlacements, target), nil
+}
+
+func (a multiZoneAlgorithm) SplitShards(p placement.Placement, factor int) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	return splitShards(p, factor, a.opts)
+}
+
+func (a multiZoneAlgorithm) Rebalance(
+	p placement.Placement,
+	maxShardMoves int,
+) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	zoneReplicas := a.zoneReplicas(p)
+	zonePlacements := splitPlacementByZone(p, zoneReplicas)
+	for _, zone := range sortedZones(zoneReplicas) {
+		zp, ok := zonePlacements[zone]
+		if !ok {
+			continue
+		}
+
+		zp, moves, err := rebalance(zp, a.opts, maxShardMoves)
+		if err != nil {
+			return nil, fmt.Errorf("could not rebalance zone %s: %v", zone, err)
+		}
+		zonePlacements[zone] = zp
+
+		if maxShardMoves > 0 {
+			if maxShardMoves -= moves; maxShardMoves <= 0 {
+				break
+			}
+		}
+	}
+
+	return a.mergeZonePlacements(p.Shards(), zonePlacements, zoneReplicas), nil
+}
+
+func (a multiZoneAlgorithm) UpdateInstances(
+	p placement.Placement,
+	instances []placement.Instance,
+) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	p, weightChanged, err := updateInstances(p, instances)
+	if err != nil || !weightChanged {
+		return p, err
+	}
+
+	return a.Rebalance(p, 0)
+}
+
+func (a multiZoneAlgorithm) RemoveInstances(
+	p placement.Placement,
+	instanceIDs []string,
+) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	zoneReplicas := a.zoneReplicas(p)
+	zonePlacements := splitPlacementByZone(p, zoneReplicas)
+	idsByZone := make(map[string][]string)
+	for _, id := range instanceIDs {
+		instance, ok := p.Instance(id)
+		if !ok {
+			return nil, fmt.Errorf("instance %s does not exist in placement", id)
+		}
+		idsByZone[instance.Zone()] = append(idsByZone[instance.Zone()], id)
+	}
+
+	for _, zone := range sortedZones(zoneReplicas) {
+		ids, ok := idsByZone[zone]
+		if !ok {
+			continue
+		}
+
+		zp, err := a.shardedAlgo.RemoveInstances(zonePlacements[zone], ids)
+		if err != nil {
+			return nil, fmt.Errorf("could not remove instances from zone %s: %v", zone, err)
+		}
+		zonePlacements[zone] = zp
+	}
+
+	return a.mergeZonePlacements(p.Shards(), zonePlacements, zoneReplicas), nil
+}
+
+func (a multiZoneAlgorithm) AddInstances(
+	p placement.Placement,
+	instances []placement.Instance,
+) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	zoneReplicas := a.zoneReplicas(p)
+	instancesByZone, err := groupInstancesByZone(instances, zoneReplicas)
+	if err != nil {
+		return nil, err
+	}
+
+	zonePlacements := splitPlacementByZone(p, zoneReplicas)
+	for _, zone := range sortedZones(zoneReplicas) {
+		addingInstances, ok := instancesByZone[zone]
+		if !ok {
+			continue
+		}
+
+		if zoneReplicas[zone] == 0 {
+			return nil, fmt.Errorf("could not add instances to zone %s, no replicas in the zone", zone)
+		}
+
+		zp, err := a.shardedAlgo.AddInstances(zonePlacements[zone], addingInstances)
+		if err != nil {
+			return nil, fmt.Errorf("could not add instances to zone %s: %v", zone, err)
+		}
+		zonePlacements[zone] = zp
+	}
+
+	return a.mergeZonePlacements(p.Shards(), zonePlacements, zoneReplicas), nil
+}
+
+func (a multiZoneAlgorithm) ReplaceInstances(
+	p placement.Placement,
+	leavingInstanceIDs []string,
+	addingInstances []placement.Instance,
+) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	zoneReplicas := a.zoneReplicas(p)
+	leavingByZone := make(map[string][]string)
+	for _, id := range leavingInstanceIDs {
+		instance, ok := p.Instance(id)
+		if !ok {
+			return nil, fmt.Errorf("instance %s does not exist in placement", id)
+		}
+		leavingByZone[instance.Zone()] = append(leavingByZone[instance.Zone()], id)
+	}
+
+	addingByZone, err := groupInstancesByZone(addingInstances, zoneReplicas)
+	if err != nil {
+		return nil, err
+	}
+
+	for zone, instances := range addingByZone {
+		if _, ok := leavingByZone[zone]; !ok {
+			return nil, fmt.Errorf("could not replace with instance %s, no leaving instances in zone %s", instances[0].ID(), zone)
+		}
+	}
+
+	zonePlacements := splitPlacementByZone(p, zoneReplicas)
+	for _, zone := range sortedZones(zoneReplicas) {
+		leavingIDs, ok := leavingByZone[zone]
+		if !ok {
+			continue
+		}
+
+		zp, err := a.shardedAlgo.ReplaceInstances(zonePlacements[zone], leavingIDs, addingByZone[zone])
+		if err != nil {
+			return nil, fmt.Errorf("could not replace instances in zone %s: %v", zone, err)
+		}
+		zonePlacements[zone] = zp
+	}
+
+	return a.mergeZonePlacements(p.Shards(), zonePlacements, zoneReplicas), nil
+}
+
+func (a multiZoneAlgorithm) MarkShardAvailable(
+	p placement.Placement,
+	instanceID string,
+	shardID uint32,
+) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	return markShardAvailable(p.Clone(), instanceID, shardID, a.opts)
+}
+
+// zoneReplicas returns the zone replicas of the placement. Placements
+// without zone replicas in a single zone are treated as having all their
+// replicas in that zone, which allows AddReplica to extend them to more zones.
+func (a multiZoneAlgorithm) zoneReplicas(p placement.Placement) map[string]int {
+	if zoneReplicas := p.ZoneReplicas(); len(zoneReplicas) > 0 {
+		return zoneReplicas
+	}
+
+	zones := make(map[string]struct{})
+	for _, instance := range p.Instances() {
+		zones[instance.Zone()] = struct{}{}
+	}
+	if len(zones) == 1 {
+		for zone := range zones {
+			return map[string]int{zone: p.ReplicaFactor()}
+		}
+	}
+
+	return a.opts.ZoneReplicas()
+}
+
+func (a multiZoneAlgorithm) mergeZonePlacements(
+	shards []uint32,
+	zonePlacements map[string]placement.Placement,
+	zoneReplicas map[string]int,
+) placement.Placement {
+	var instances []placement.Instance
+	for _, zp := range zonePlacements {
+		instances = append(instances, zp.Instances()...)
+	}
+
+	replicas := make(map[string]int, len(zoneReplicas))
+	for zone, r := range zoneReplicas {
+		replicas[zone] = r
+	}
+
+	return placement.NewPlacement().
+		SetInstances(instances).
+		SetShards(shards).
+		SetReplicaFactor(totalReplicas(zoneReplicas)).
+		SetIsSharded(true).
+		SetZoneReplicas(replicas).
+		SetCutoverNanos(a.opts.PlacementCutoverNanosFn()())
+}
+
+// splitPlacementByZone splits the placement into one sharded placement per zone,
+// each with the replicas of the zone as its replica factor.
+func splitPlacementByZone(p placement.Placement, zoneReplicas map[string]int) map[string]placement.Placement {
+	instancesByZone := make(map[string][]placement.Instance, len(zoneReplicas))
+	for _, instance := range p.Clone().Instances() {
+		instancesByZone[instance.Zone()] = append(instancesByZone[instance.Zone()], instance)
+	}
+
+	res := make(map[string]placement.Placement, len(instancesByZone))
+	for zone, instances := range instancesByZone {
+		res[zone] = placement.NewPlacement().
+			SetInstances(instances).
+			SetShards(p.Shards()).
+			SetReplicaFactor(zoneReplicas[zone]).
+			SetIsSharded(true).
+			SetCutoverNanos(p.CutoverNanos())
+	}
+	return res
+}
+
+func groupInstancesByZone(
+	instances []placement.Instance,
+	zoneReplicas map[string]int,
+) (map[string][]placement.Instance, error) {
+	res := make(map[string][]placement.Instance, len(zoneReplicas))
+	for _, instance := range instances {
+		zone := instance.Zone()
+		if _, ok := zoneReplicas[zone]; !ok {
+			return nil, fmt.Errorf("instance %s is in zone %s without zone replicas", instance.ID(), zone)
+		}
+		res[zone] = append(res[zone], instance)
+	}
+	return res, nil
+}
+
+func zoneReplicasEqual(a, b map[string]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for zone, replicas := range a {
+		if other, ok := b[zone]; !ok || other != replicas {
+			return false
+		}
+	}
+	return true
+}
+
+func totalReplicas(zoneReplicas map[string]int) int {
+	total := 0
+	for _, replicas := range zoneReplicas {
+		total += replicas
+	}
+	return total
+}
+
+func sortedZones(zoneReplicas map[string]int) []string {
+	zones := make([]string, 0, len(zoneReplicas))
+	for zone := range zoneReplicas {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+	return zones
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package algo
+
+import (
+	"testing"
+
+	"github.com/m3db/m3cluster/placement"
+	"github.com/m3db/m3cluster/shard"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiZoneInitialPlacement(t *testing.T) {
+	instances := []placement.Instance{
+		placement.NewEmptyInstance("a1", "r1", "z1", "e1", 1),
+		placement.NewEmptyInstance("a2", "r2", "z1", "e2", 1),
+		placement.NewEmptyInstance("b1", "r3", "z2", "e3", 1),
+	}
+	ids := []uint32{0, 1, 2, 3, 4, 5}
+
+	a := newMultiZoneAlgorithm(placement.NewOptions())
+	_, err := a.InitialPlacement(instances, ids, 3)
+	require.Equal(t, errNoZoneReplicas, err)
+
+	opts := placement.NewOptions().SetZoneReplicas(map[string]int{"z1": 2, "z2": 1})
+	a = newMultiZoneAlgorithm(opts)
+	_, err = a.InitialPlacement(instances, ids, 2)
+	require.Error(t, err)
+
+	p, err := a.InitialPlacement(instances, ids, 3)
+	require.NoError(t, err)
+	require.NoError(t, placement.Validate(p))
+	require.Equal(t, 3, p.ReplicaFactor())
+
+	// Instances outside the configured zones are rejected.
+	_, err = a.AddInstances(p, []placement.Instance{placement.NewEmptyInstance("c1", "r4", "z3", "e4", 1)})
+	require.Error(t, err)
+
+	// Replacements must be in the zone of the leaving instance.
+	_, err = a.ReplaceInstances(p, []string{"b1"}, []placement.Instance{placement.NewEmptyInstance("a3", "r5", "z1", "e5", 1)})
+	require.Error(t, err)
+
+	p, err = a.ReplaceInstances(p, []string{"b1"}, []placement.Instance{placement.NewEmptyInstance("b2", "r6", "z2", "e6", 1)})
+	require.NoError(t, err)
+	require.NoError(t, placement.Validate(p))
+	b2, ok := p.Instance("b2")
+	require.True(t, ok)
+	require.Equal(t, len(ids), b2.Shards().NumShards())
+
+	// Placements with different zone replicas are only changed by adding or
+	// removing replicas.
+	a = newMultiZoneAlgorithm(opts.SetZoneReplicas(map[string]int{"z1": 1, "z2": 1}))
+	require.Equal(t, errZoneReplicasMismatch, a.IsCompatibleWith(p))
+	_, err = a.AddInstances(p, []placement.Instance{placement.NewEmptyInstance("a3", "r7", "z1", "e7", 1)})
+	require.Equal(t, errZoneReplicasMismatch, err)
+	_, err = a.RemoveReplica(p)
+	require.NoError(t, err)
+}
+
+func TestMultiZoneRemoveReplica(t *testing.T) {
+	instances := []placement.Instance{
+		placement.NewEmptyInstance("a1", "r1", "z1", "e1", 1),
+		placement.NewEmptyInstance("a2", "r2", "z1", "e2", 1),
+		placement.NewEmptyInstance("b1", "r3", "z2", "e3", 1),
+		placement.NewEmptyInstance("b2", "r4", "z2", "e4", 1),
+	}
+	ids := []uint32{0, 1, 2, 3, 4, 5}
+
+	opts := placement.NewOptions().SetZoneReplicas(map[string]int{"z1": 2, "z2": 2})
+	p, err := newMultiZoneAlgorithm(opts).InitialPlacement(instances, ids, 4)
+	require.NoError(t, err)
+	p, err = markAllShardsAvailable(p, opts)
+	require.NoError(t, err)
+
+	_, err = newMultiZoneAlgorithm(opts).RemoveReplica(p)
+	require.Equal(t, errNoZoneReplicasToRemove, err)
+
+	opts = opts.SetZoneReplicas(map[string]int{"z1": 2, "z2": 1})
+	p, err = newMultiZoneAlgorithm(opts).RemoveReplica(p)
+	require.NoError(t, err)
+	require.NoError(t, placement.Validate(p))
+	require.Equal(t, 3, p.ReplicaFactor())
+	require.Equal(t, map[string]int{"z1": 2, "z2": 1}, p.ZoneReplicas())
+	for _, id := range []string{"b1", "b2"} {
+		instance, ok := p.Instance(id)
+		require.True(t, ok)
+		require.Equal(t, 3, loadOnInstance(instance))
+		require.Equal(t, 3, instance.Shards().NumShardsForState(shard.Leaving))
+	}
+}
+
+func TestMultiZoneAddReplicaToSingleZonePlacement(t *testing.T) {
+	instances := []placement.Instance{
+		placement.NewEmptyInstance("a1", "r1", "z1", "e1", 1),
+		placement.NewEmptyInstance("a2", "r2", "z1", "e2", 1),
+		placement.NewEmptyInstance("a3", "r3", "z1", "e3", 1),
+	}
+	ids := []uint32{0, 1, 2, 3, 4, 5}
+
+	p, err := newShardedAlgorithm(placement.NewOptions()).InitialPlacement(instances, ids, 2)
+	require.NoError(t, err)
+	require.Empty(t, p.ZoneReplicas())
+
+	// The placement has no instances in z2 to take the new replica.
+	opts := placement.NewOptions().SetZoneReplicas(map[string]int{"z1": 2, "z2": 1})
+	_, err = newMultiZoneAlgorithm(opts).AddReplica(p)
+	require.Error(t, err)
+
+	// Zones could not be dropped from the zone replicas.
+	_, err = newMultiZoneAlgorithm(opts.SetZoneReplicas(map[string]int{"z2": 2})).AddReplica(p)
+	require.Error(t, err)
+
+	_, err = newMultiZoneAlgorithm(opts.SetZoneReplicas(map[string]int{"z1": 2})).AddReplica(p)
+	require.Equal(t, errNoZoneReplicasToAdd, err)
+
+	p, err = newMultiZoneAlgorithm(opts.SetZoneReplicas(map[string]int{"z1": 3})).AddReplica(p)
+	require.NoError(t, err)
+	require.NoError(t, placement.Validate(p))
+	require.Equal(t, 3, p.ReplicaFactor())
+	require.Equal(t, map[string]int{"z1": 3}, p.ZoneReplicas())
+}
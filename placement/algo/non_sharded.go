@@ -74,6 +74,51 @@ func (a nonShardedAlgorithm) AddReplica(p placement.Placement) (placement.Placem
 	return p.Clone().SetReplicaFactor(p.ReplicaFactor() + 1), nil
 }
 
+func (a nonShardedAlgorithm) RemoveReplica(p placement.Placement) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	if p.ReplicaFactor() <= 1 {
+		return nil, errNoReplicaToRemove
+	}
+
+	return p.Clone().SetReplicaFactor(p.ReplicaFactor() - 1), nil
+}
+
+func (a nonShardedAlgorithm) SplitShards(p placement.Placement, factor int) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	return nil, errShardsOnNonShardedAlgo
+}
+
+func (a nonShardedAlgorithm) Rebalance(
+	p placement.Placement,
+	maxShardMoves int,
+) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	// There is no shards to move in non-sharded algorithm.
+	return p.Clone(), nil
+}
+
+func (a nonShardedAlgorithm) UpdateInstances(
+	p placement.Placement,
+	instances []placement.Instance,
+) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	// There is no shards to move in non-sharded algorithm when the weight changes.
+	p, _, err := updateInstances(p, instances)
+	return p, err
+}
+
 func (a nonShardedAlgorithm) RemoveInstances(
 	p placement.Placement,
 	instanceIDs []string,
@@ -100,6 +100,35 @@ func TestNonShardedAlgo(t *testing.T) {
 	assert.False(t, p.IsSharded())
 }
 
+func TestNonShardedUpdateInstances(t *testing.T) {
+	a := newNonShardedAlgorithm()
+
+	i1 := placement.NewInstance().SetID("i1").SetEndpoint("e1").SetWeight(1)
+	i2 := placement.NewInstance().SetID("i2").SetEndpoint("e2").SetWeight(1)
+	p, err := a.InitialPlacement([]placement.Instance{i1, i2}, []uint32{}, 1)
+	assert.NoError(t, err)
+
+	p, err = a.UpdateInstances(p, []placement.Instance{i1.Clone().SetEndpoint("e11").SetWeight(3)})
+	assert.NoError(t, err)
+	assert.NoError(t, placement.Validate(p))
+	i1, ok := p.Instance("i1")
+	assert.True(t, ok)
+	assert.Equal(t, "e11", i1.Endpoint())
+	assert.Equal(t, uint32(3), i1.Weight())
+
+	_, err = a.UpdateInstances(p, []placement.Instance{placement.NewInstance().SetID("i3")})
+	assert.Error(t, err)
+}
+
+func TestNonShardedSplitShards(t *testing.T) {
+	a := newNonShardedAlgorithm()
+	p, err := a.InitialPlacement([]placement.Instance{placement.NewInstance().SetID("i1").SetEndpoint("e1")}, []uint32{}, 1)
+	assert.NoError(t, err)
+
+	_, err = a.SplitShards(p, 2)
+	assert.Equal(t, errShardsOnNonShardedAlgo, err)
+}
+
 func TestIncompatibleWithNonShardedAlgo(t *testing.T) {
 	i1 := placement.NewInstance().SetID("i1").SetEndpoint("e1").SetWeight(1)
 	i2 := placement.NewInstance().SetID("i2").SetEndpoint("e2").SetWeight(1)
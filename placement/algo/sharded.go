@@ -28,8 +28,11 @@ import (
 )
 
 var (
-	errNotEnoughRacks              = errors.New("not enough racks to take shards, please make sure RF is less than number of racks")
+	errNotEnoughFailureDomains     = errors.New("not enough failure domains to take shards, please make sure RF is less than number of failure domains at the isolation level")
 	errIncompatibleWithShardedAlgo = errors.New("could not apply sharded algo on the placement")
+	errZoneReplicasWithShardedAlgo = errors.New("could not apply sharded algo on a placement with zone replicas, the multi-zone algo is required")
+	errNoReplicaToRemove           = errors.New("could not remove the last replica from the placement")
+	errNonContiguousShards         = errors.New("could not split shards, the shards are not numbered from 0 to the number of shards")
 )
 
 type rackAwarePlacementAlgorithm struct {
@@ -45,6 +48,12 @@ func (a rackAwarePlacementAlgorithm) IsCompatibleWith(p placement.Placement) err
 		return errIncompatibleWithShardedAlgo
 	}
 
+	// Placing the replicas of a multi-zone placement without regard to zones
+	// would break its zone replicas.
+	if len(p.ZoneReplicas()) > 0 {
+		return errZoneReplicasWithShardedAlgo
+	}
+
 	return nil
 }
 
@@ -96,6 +105,54 @@ func (a rackAwarePlacementAlgorithm) AddReplica(p placement.Placement) (placemen
 	return ph.GeneratePlacement(), nil
 }
 
+func (a rackAwarePlacementAlgorithm) RemoveReplica(p placement.Placement) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	if p.ReplicaFactor() <= 1 {
+		return nil, errNoReplicaToRemove
+	}
+
+	return removeReplica(p.Clone(), a.opts), nil
+}
+
+func (a rackAwarePlacementAlgorithm) SplitShards(p placement.Placement, factor int) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	return splitShards(p, factor, a.opts)
+}
+
+func (a rackAwarePlacementAlgorithm) Rebalance(
+	p placement.Placement,
+	maxShardMoves int,
+) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	p, _, err := rebalance(p.Clone(), a.opts, maxShardMoves)
+	return p, err
+}
+
+func (a rackAwarePlacementAlgorithm) UpdateInstances(
+	p placement.Placement,
+	instances []placement.Instance,
+) (placement.Placement, error) {
+	if err := a.IsCompatibleWith(p); err != nil {
+		return nil, err
+	}
+
+	p, weightChanged, err := updateInstances(p, instances)
+	if err != nil || !weightChanged {
+		return p, err
+	}
+
+	return a.Rebalance(p, 0)
+}
+
 func (a rackAwarePlacementAlgorithm) RemoveInstances(
 	p placement.Placement,
 	instanceIDs []string,
@@ -168,9 +225,9 @@ func (a rackAwarePlacementAlgorithm) ReplaceInstances(
 
 	for _, leavingInstance := range leavingInstances {
 		err = ph.PlaceShards(leavingInstance.Shards().All(), leavingInstance, addingInstances)
-		if err != nil && err != errNotEnoughRacks {
-			// errNotEnoughRacks means the adding instances do not have enough racks to take all the shards,
-			// but the rest instances might have more racks to take all the shards.
+		if err != nil && err != errNotEnoughFailureDomains {
+			// errNotEnoughFailureDomains means the adding instances do not have enough failure domains
+			// to take all the shards, but the rest instances might have more to take all the shards.
 			return nil, err
 		}
 		load := loadOnInstance(leavingInstance)
@@ -213,3 +270,19 @@ func (a rackAwarePlacementAlgorithm) MarkShardAvailable(
 
 	return markShardAvailable(p.Clone(), instanceID, shardID, a.opts)
 }
+
+// rebalance optimizes the load distribution of the placement, allowing shards
+// to move with the usual Initializing and Leaving handoff, and returns the
+// number of shards moved.
+func rebalance(
+	p placement.Placement,
+	opts placement.Options,
+	maxShardMoves int,
+) (placement.Placement, int, error) {
+	ph := newRebalanceHelper(p, opts, maxShardMoves)
+	if err := ph.Optimize(unsafe); err != nil {
+		return nil, 0, err
+	}
+
+	return ph.GeneratePlacement(), ph.shardMoves, nil
+}
@@ -25,6 +25,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"sort"
 
 	"github.com/m3db/m3cluster/placement"
 	"github.com/m3db/m3cluster/shard"
@@ -59,12 +60,23 @@ const (
 
 type assignLoadFn func(instance placement.Instance) error
 
+type assignCheckFn func(shardID uint32, from, to placement.Instance) bool
+
 // PlacementHelper helps the algorithm to place shards.
 type PlacementHelper interface {
 	// Instances returns the list of instances managed by the PlacementHelper.
 	Instances() []placement.Instance
 
+	// FailureDomain returns the failure domain of the instance at the configured isolation level.
+	FailureDomain(instance placement.Instance) string
+
+	// HasIsolationConflict checks if the isolation constraint is violated when moving the shard
+	// to the target failure domain.
+	HasIsolationConflict(shard uint32, from placement.Instance, toDomain string) bool
+
 	// HasRackConflict checks if the rack constraint is violated when moving the shard to the target rack.
+	//
+	// Deprecated: Use HasIsolationConflict, which this is equivalent to at the default rack isolation level.
 	HasRackConflict(shard uint32, from placement.Instance, toRack string) bool
 
 	// PlaceShards distributes shards to the instances in the helper, with aware of where are the shards coming from.
@@ -89,16 +101,20 @@ type PlacementHelper interface {
 }
 
 type placementHelper struct {
-	targetLoad         map[string]int
-	shardToInstanceMap map[uint32]map[placement.Instance]struct{}
-	rackToInstancesMap map[string]map[placement.Instance]struct{}
-	rackToWeightMap    map[string]uint32
-	totalWeight        uint32
-	rf                 int
-	uniqueShards       []uint32
-	instances          map[string]placement.Instance
-	log                log.Logger
-	opts               placement.Options
+	targetLoad           map[string]int
+	shardToInstanceMap   map[uint32]map[placement.Instance]struct{}
+	domainToInstancesMap map[string]map[placement.Instance]struct{}
+	domainToWeightMap    map[string]uint32
+	instanceToDomainMap  map[string]string
+	totalWeight          uint32
+	maxShardMoves        int
+	shardMoves           int
+	rf                   int
+	uniqueShards         []uint32
+	zoneReplicas         map[string]int
+	instances            map[string]placement.Instance
+	log                  log.Logger
+	opts                 placement.Options
 }
 
 // NewPlacementHelper returns a placement helper
@@ -120,6 +136,14 @@ func newAddReplicaHelper(p placement.Placement, opts placement.Options) Placemen
 	return newHelper(p, p.ReplicaFactor()+1, opts)
 }
 
+// newRebalanceHelper returns a helper that moves at most maxShardMoves shards
+// when optimizing, or any number of shards when maxShardMoves is not positive.
+func newRebalanceHelper(p placement.Placement, opts placement.Options, maxShardMoves int) *placementHelper {
+	ph := newHelper(p, p.ReplicaFactor(), opts).(*placementHelper)
+	ph.maxShardMoves = maxShardMoves
+	return ph
+}
+
 func newAddInstanceHelper(
 	p placement.Placement,
 	instance placement.Instance,
@@ -192,6 +216,7 @@ func newHelper(p placement.Placement, targetRF int, opts placement.Options) Plac
 		rf:           targetRF,
 		instances:    make(map[string]placement.Instance, p.NumInstances()),
 		uniqueShards: p.Shards(),
+		zoneReplicas: p.ZoneReplicas(),
 		log:          opts.InstrumentOptions().Logger(),
 		opts:         opts,
 	}
@@ -207,21 +232,24 @@ func newHelper(p placement.Placement, targetRF int, opts placement.Options) Plac
 
 func (ph *placementHelper) scanCurrentLoad() {
 	ph.shardToInstanceMap = make(map[uint32]map[placement.Instance]struct{}, len(ph.uniqueShards))
-	ph.rackToInstancesMap = make(map[string]map[placement.Instance]struct{})
-	ph.rackToWeightMap = make(map[string]uint32)
+	ph.domainToInstancesMap = make(map[string]map[placement.Instance]struct{})
+	ph.domainToWeightMap = make(map[string]uint32)
+	ph.instanceToDomainMap = make(map[string]string, len(ph.instances))
 	totalWeight := uint32(0)
 	for _, instance := range ph.instances {
-		if _, exist := ph.rackToInstancesMap[instance.Rack()]; !exist {
-			ph.rackToInstancesMap[instance.Rack()] = make(map[placement.Instance]struct{})
+		domain := placement.FailureDomain(instance, ph.opts.IsolationLevel())
+		ph.instanceToDomainMap[instance.ID()] = domain
+		if _, exist := ph.domainToInstancesMap[domain]; !exist {
+			ph.domainToInstancesMap[domain] = make(map[placement.Instance]struct{})
 		}
-		ph.rackToInstancesMap[instance.Rack()][instance] = struct{}{}
+		ph.domainToInstancesMap[domain][instance] = struct{}{}
 
 		if instance.IsLeaving() {
 			// Leaving instances are not counted as usable capacities in the placement.
 			continue
 		}
 
-		ph.rackToWeightMap[instance.Rack()] = ph.rackToWeightMap[instance.Rack()] + instance.Weight()
+		ph.domainToWeightMap[domain] = ph.domainToWeightMap[domain] + instance.Weight()
 		totalWeight += instance.Weight()
 
 		for _, s := range instance.Shards().All() {
@@ -235,11 +263,11 @@ func (ph *placementHelper) scanCurrentLoad() {
 }
 
 func (ph *placementHelper) buildTargetLoad() {
-	overWeightedRack := 0
+	overWeightedDomain := 0
 	overWeight := uint32(0)
-	for _, weight := range ph.rackToWeightMap {
-		if isRackOverWeight(weight, ph.totalWeight, ph.rf) {
-			overWeightedRack++
+	for _, weight := range ph.domainToWeightMap {
+		if isDomainOverWeight(weight, ph.totalWeight, ph.rf) {
+			overWeightedDomain++
 			overWeight += weight
 		}
 	}
@@ -250,13 +278,13 @@ func (ph *placementHelper) buildTargetLoad() {
 			// We should not set a target load for leaving instances.
 			continue
 		}
-		rackWeight := ph.rackToWeightMap[instance.Rack()]
-		if isRackOverWeight(rackWeight, ph.totalWeight, ph.rf) {
-			// if the instance is on a over-sized rack, the target load is topped at shardLen / rackSize
-			targetLoad[instance.ID()] = int(math.Ceil(float64(ph.getShardLen()) * float64(instance.Weight()) / float64(rackWeight)))
+		domainWeight := ph.domainToWeightMap[ph.FailureDomain(instance)]
+		if isDomainOverWeight(domainWeight, ph.totalWeight, ph.rf) {
+			// if the instance is in a over-sized failure domain, the target load is topped at shardLen / domainSize
+			targetLoad[instance.ID()] = int(math.Ceil(float64(ph.getShardLen()) * float64(instance.Weight()) / float64(domainWeight)))
 		} else {
-			// if the instance is on a normal rack, get the target load with aware of other over-sized rack
-			targetLoad[instance.ID()] = ph.getShardLen() * (ph.rf - overWeightedRack) * int(instance.Weight()) / int(ph.totalWeight-overWeight)
+			// if the instance is in a normal failure domain, get the target load with aware of other over-sized domains
+			targetLoad[instance.ID()] = ph.getShardLen() * (ph.rf - overWeightedDomain) * int(instance.Weight()) / int(ph.totalWeight-overWeight)
 		}
 	}
 	ph.targetLoad = targetLoad
@@ -299,8 +327,16 @@ func (ph *placementHelper) moveOneShardInState(from, to placement.Instance, stat
 }
 
 func (ph *placementHelper) moveShard(candidateShard shard.Shard, from, to placement.Instance) bool {
+	return ph.moveShardWithCheck(candidateShard, from, to, ph.canAssignInstance)
+}
+
+func (ph *placementHelper) moveShardWithCheck(
+	candidateShard shard.Shard,
+	from, to placement.Instance,
+	canAssignFn assignCheckFn,
+) bool {
 	shardID := candidateShard.ID()
-	if !ph.canAssignInstance(shardID, from, to) {
+	if !canAssignFn(shardID, from, to) {
 		return false
 	}
 
@@ -316,7 +352,11 @@ func (ph *placementHelper) moveShard(candidateShard shard.Shard, from, to placem
 		switch candidateShard.State() {
 		case shard.Unknown, shard.Initializing:
 			from.Shards().Remove(shardID)
-			newShard.SetSourceID(candidateShard.SourceID())
+			// NB: a shard split from a parent shard on the instance has no
+			// Leaving shard to take over from once it is moved away.
+			if sourceID := candidateShard.SourceID(); sourceID != from.ID() {
+				newShard.SetSourceID(sourceID)
+			}
 		case shard.Available:
 			candidateShard.
 				SetState(shard.Leaving).
@@ -348,22 +388,33 @@ func (ph *placementHelper) moveShard(candidateShard shard.Shard, from, to placem
 	return true
 }
 
-func (ph *placementHelper) HasRackConflict(shard uint32, from placement.Instance, toRack string) bool {
+func (ph *placementHelper) FailureDomain(instance placement.Instance) string {
+	if domain, ok := ph.instanceToDomainMap[instance.ID()]; ok {
+		return domain
+	}
+	return placement.FailureDomain(instance, ph.opts.IsolationLevel())
+}
+
+func (ph *placementHelper) HasIsolationConflict(shard uint32, from placement.Instance, toDomain string) bool {
 	if from != nil {
-		if from.Rack() == toRack {
+		if ph.FailureDomain(from) == toDomain {
 			return false
 		}
 	}
 	for instance := range ph.shardToInstanceMap[shard] {
-		if instance.Rack() == toRack {
+		if ph.FailureDomain(instance) == toDomain {
 			return true
 		}
 	}
 	return false
 }
 
+func (ph *placementHelper) HasRackConflict(shard uint32, from placement.Instance, toRack string) bool {
+	return ph.HasIsolationConflict(shard, from, toRack)
+}
+
 func (ph *placementHelper) buildInstanceHeap(instances []placement.Instance, availableCapacityAscending bool) (heap.Interface, error) {
-	return newHeap(instances, availableCapacityAscending, ph.targetLoad, ph.rackToWeightMap)
+	return newHeap(instances, availableCapacityAscending, ph.targetLoad, ph.domainToWeightMap, ph.FailureDomain)
 }
 
 func (ph *placementHelper) GeneratePlacement() placement.Placement {
@@ -391,6 +442,7 @@ func (ph *placementHelper) GeneratePlacement() placement.Placement {
 		SetReplicaFactor(ph.rf).
 		SetIsSharded(true).
 		SetIsMirrored(ph.opts.IsMirrored()).
+		SetZoneReplicas(ph.zoneReplicas).
 		SetCutoverNanos(ph.opts.PlacementCutoverNanosFn()())
 }
 
@@ -426,9 +478,19 @@ func (ph *placementHelper) PlaceShards(
 				break
 			}
 		}
+		if !moved && ph.opts.IsolationMode() == placement.IsolationModeBestEffort {
+			// NB: in best effort mode, fall back to the most preferred instance
+			// that does not own the shard yet, regardless of its failure domain.
+			for _, tryInstance := range triedInstances {
+				if ph.moveShardWithCheck(s, from, tryInstance, ph.canTakeShard) {
+					moved = true
+					break
+				}
+			}
+		}
 		if !moved {
-			// this should only happen when RF > number of racks
-			return errNotEnoughRacks
+			// this should only happen when RF > number of failure domains
+			return errNotEnoughFailureDomains
 		}
 		for _, triedInstance := range triedInstances {
 			heap.Push(instanceHeap, triedInstance)
@@ -572,16 +634,29 @@ func (ph *placementHelper) assignTargetLoad(
 	if err != nil {
 		return err
 	}
-	for targetInstance.Shards().NumShards() < targetLoad && instanceHeap.Len() > 0 {
+	for targetInstance.Shards().NumShards() < targetLoad && instanceHeap.Len() > 0 && !ph.reachedMaxShardMoves() {
 		fromInstance := heap.Pop(instanceHeap).(placement.Instance)
 		if moved := moveOneShardFn(fromInstance, targetInstance); moved {
+			ph.shardMoves++
 			heap.Push(instanceHeap, fromInstance)
 		}
 	}
 	return nil
 }
 
+func (ph *placementHelper) reachedMaxShardMoves() bool {
+	return ph.maxShardMoves > 0 && ph.shardMoves >= ph.maxShardMoves
+}
+
 func (ph *placementHelper) canAssignInstance(shardID uint32, from, to placement.Instance) bool {
+	if !ph.canTakeShard(shardID, from, to) {
+		return false
+	}
+	return ph.opts.LooseRackCheck() || !ph.HasIsolationConflict(shardID, from, ph.FailureDomain(to))
+}
+
+// canTakeShard checks whether the instance could own the shard, regardless of isolation.
+func (ph *placementHelper) canTakeShard(shardID uint32, _, to placement.Instance) bool {
 	s, ok := to.Shards().Shard(shardID)
 	if ok && s.State() != shard.Leaving {
 		// NB(cw): a Leaving shard is not counted to the load of the instance
@@ -592,7 +667,7 @@ func (ph *placementHelper) canAssignInstance(shardID uint32, from, to placement.
 		// and i1 should be able to take it and mark it as "Available"
 		return false
 	}
-	return ph.opts.LooseRackCheck() || !ph.HasRackConflict(shardID, from, to.Rack())
+	return true
 }
 
 func (ph *placementHelper) assignShardToInstance(s shard.Shard, to placement.Instance) {
@@ -607,7 +682,8 @@ func (ph *placementHelper) assignShardToInstance(s shard.Shard, to placement.Ins
 // instanceHeap provides an easy way to get best candidate instance to assign/steal a shard
 type instanceHeap struct {
 	instances         []placement.Instance
-	rackToWeightMap   map[string]uint32
+	domainToWeightMap map[string]uint32
+	domainFn          func(placement.Instance) string
 	targetLoad        map[string]int
 	capacityAscending bool
 }
@@ -616,13 +692,15 @@ func newHeap(
 	instances []placement.Instance,
 	capacityAscending bool,
 	targetLoad map[string]int,
-	rackToWeightMap map[string]uint32,
+	domainToWeightMap map[string]uint32,
+	domainFn func(placement.Instance) string,
 ) (*instanceHeap, error) {
 	h := &instanceHeap{
 		capacityAscending: capacityAscending,
 		instances:         instances,
 		targetLoad:        targetLoad,
-		rackToWeightMap:   rackToWeightMap,
+		domainToWeightMap: domainToWeightMap,
+		domainFn:          domainFn,
 	}
 	heap.Init(h)
 	return h, nil
@@ -641,11 +719,12 @@ func (h *instanceHeap) Less(i, j int) bool {
 	instanceJ := h.instances[j]
 	leftLoadOnI := h.targetLoadForInstance(instanceI.ID()) - loadOnInstance(instanceI)
 	leftLoadOnJ := h.targetLoadForInstance(instanceJ.ID()) - loadOnInstance(instanceJ)
-	// if both instance has tokens to be filled, prefer the one on a bigger rack
+	// if both instance has tokens to be filled, prefer the one in a bigger failure domain
 	// since it tends to be more picky in accepting shards
 	if leftLoadOnI > 0 && leftLoadOnJ > 0 {
-		if instanceI.Rack() != instanceJ.Rack() {
-			return h.rackToWeightMap[instanceI.Rack()] > h.rackToWeightMap[instanceJ.Rack()]
+		domainI, domainJ := h.domainFn(instanceI), h.domainFn(instanceJ)
+		if domainI != domainJ {
+			return h.domainToWeightMap[domainI] > h.domainToWeightMap[domainJ]
 		}
 	}
 	// compare left capacity on both instances
@@ -671,8 +750,8 @@ func (h *instanceHeap) Pop() interface{} {
 	return instance
 }
 
-func isRackOverWeight(rackWeight, totalWeight uint32, rf int) bool {
-	return float64(rackWeight)/float64(totalWeight) >= 1.0/float64(rf)
+func isDomainOverWeight(domainWeight, totalWeight uint32, rf int) bool {
+	return float64(domainWeight)/float64(totalWeight) >= 1.0/float64(rf)
 }
 
 func addInstanceToPlacement(
@@ -762,6 +841,10 @@ func markShardAvailable(p placement.Placement, instanceID string, shardID uint32
 		return nil, fmt.Errorf("shard %d does not exist in instance %s", shardID, instanceID)
 	}
 
+	if placement.IsDroppedReplica(instance, s) {
+		return removeDroppedReplica(p, instance, s, opts)
+	}
+
 	if s.State() != shard.Initializing {
 		return nil, fmt.Errorf("could not mark shard %d as available, it's not in Initializing state", s.ID())
 	}
@@ -776,8 +859,9 @@ func markShardAvailable(p placement.Placement, instanceID string, shardID uint32
 	sourceID := s.SourceID()
 	shards.Add(shard.NewShard(shardID).SetState(shard.Available))
 
-	// There could be no source for cases like initial placement.
-	if sourceID == "" {
+	// There could be no source for cases like initial placement, or the source
+	// could be the instance itself for shards split from a parent shard.
+	if sourceID == "" || sourceID == instanceID {
 		return p, nil
 	}
 
@@ -810,12 +894,34 @@ func markShardAvailable(p placement.Placement, instanceID string, shardID uint32
 	return p, nil
 }
 
+// removeDroppedReplica removes the dropped replica from the instance once it
+// could be cut off, the instance is removed when it owns no more shards.
+func removeDroppedReplica(
+	p placement.Placement,
+	instance placement.Instance,
+	s shard.Shard,
+	opts placement.Options,
+) (placement.Placement, error) {
+	isCutoffFn := opts.IsShardCutoffFn()
+	if isCutoffFn != nil {
+		if err := isCutoffFn(s); err != nil {
+			return nil, err
+		}
+	}
+
+	instance.Shards().Remove(s.ID())
+	if instance.Shards().NumShards() == 0 {
+		return p.SetInstances(removeInstanceFromList(p.Instances(), instance.ID())), nil
+	}
+	return p, nil
+}
+
 func markAllShardsAvailable(p placement.Placement, opts placement.Options) (placement.Placement, error) {
 	p = p.Clone()
 	var err error
 	for _, instance := range p.Instances() {
 		for _, s := range instance.Shards().All() {
-			if s.State() == shard.Initializing {
+			if s.State() == shard.Initializing || placement.IsDroppedReplica(instance, s) {
 				p, err = markShardAvailable(p, instance.ID(), s.ID(), opts)
 				if err != nil {
 					return nil, err
@@ -825,3 +931,220 @@ func markAllShardsAvailable(p placement.Placement, opts placement.Options) (plac
 	}
 	return p, nil
 }
+
+// updateInstances returns a copy of the placement with the weight, endpoint,
+// hostname and port of the given instances updated, and whether any weight
+// has changed.
+func updateInstances(
+	p placement.Placement,
+	updates []placement.Instance,
+) (placement.Placement, bool, error) {
+	p = p.Clone()
+	weightChanged := false
+	for _, update := range updates {
+		instance, ok := p.Instance(update.ID())
+		if !ok {
+			return nil, false, fmt.Errorf("instance %s does not exist in placement", update.ID())
+		}
+		if instance.Rack() != update.Rack() || instance.Zone() != update.Zone() || instance.Region() != update.Region() {
+			return nil, false, fmt.Errorf("could not change the rack, zone or region of instance %s", update.ID())
+		}
+		if instance.ShardSetID() != update.ShardSetID() {
+			return nil, false, fmt.Errorf("could not change the shard set id of instance %s", update.ID())
+		}
+
+		if instance.Weight() != update.Weight() {
+			weightChanged = true
+		}
+		instance.
+			SetWeight(update.Weight()).
+			SetEndpoint(update.Endpoint()).
+			SetHostname(update.Hostname()).
+			SetPort(update.Port())
+	}
+	return p, weightChanged, nil
+}
+
+// removeReplica drops one replica of each shard in the placement. Replicas
+// sharing a failure domain with another replica of the same shard are dropped
+// first, then the rest are dropped from the instances most loaded over their
+// target load with the reduced replica factor.
+func removeReplica(p placement.Placement, opts placement.Options) placement.Placement {
+	var (
+		rf              = p.ReplicaFactor() - 1
+		level           = opts.IsolationLevel()
+		instances       = p.Instances()
+		shardOwners     = make(map[uint32][]placement.Instance, p.NumShards())
+		loads           = make(map[string]int, len(instances))
+		numToDrop       = make(map[string]int, len(instances))
+		totalWeight     uint32
+		cutoffNanos     = opts.ShardCutoffNanosFn()()
+		remainingShards = make(map[uint32]struct{}, p.NumShards())
+	)
+	sort.Sort(placement.ByIDAscending(instances))
+	for _, instance := range instances {
+		load := loadOnInstance(instance)
+		if load == 0 {
+			continue
+		}
+		loads[instance.ID()] = load
+		numToDrop[instance.ID()] = load
+		totalWeight += instance.Weight()
+		for _, s := range instance.Shards().All() {
+			if s.State() != shard.Leaving {
+				shardOwners[s.ID()] = append(shardOwners[s.ID()], instance)
+				remainingShards[s.ID()] = struct{}{}
+			}
+		}
+	}
+
+	overTarget := func(instance placement.Instance) float64 {
+		target := float64(instance.Weight()) * float64(rf*p.NumShards()) / float64(totalWeight)
+		return float64(loads[instance.ID()]) - target
+	}
+	drop := func(instance placement.Instance, shardID uint32) {
+		dropShard(p, instance, shardID, cutoffNanos)
+		loads[instance.ID()]--
+		for _, owner := range shardOwners[shardID] {
+			numToDrop[owner.ID()]--
+		}
+		delete(remainingShards, shardID)
+	}
+
+	for _, shardID := range p.Shards() {
+		domainCount := make(map[string]int, len(shardOwners[shardID]))
+		for _, owner := range shardOwners[shardID] {
+			domainCount[placement.FailureDomain(owner, level)]++
+		}
+
+		var dropFrom placement.Instance
+		for _, owner := range shardOwners[shardID] {
+			if domainCount[placement.FailureDomain(owner, level)] < 2 {
+				continue
+			}
+			if dropFrom == nil || overTarget(owner) > overTarget(dropFrom) {
+				dropFrom = owner
+			}
+		}
+		if dropFrom != nil {
+			drop(dropFrom, shardID)
+		}
+	}
+
+	for len(remainingShards) > 0 {
+		var dropFrom placement.Instance
+		for _, instance := range instances {
+			if numToDrop[instance.ID()] <= 0 {
+				continue
+			}
+			if dropFrom == nil || overTarget(instance) > overTarget(dropFrom) {
+				dropFrom = instance
+			}
+		}
+
+		// Prefers dropping an Initializing replica which has not taken all the data yet.
+		var candidate shard.Shard
+		for _, s := range dropFrom.Shards().All() {
+			if _, ok := remainingShards[s.ID()]; !ok || s.State() == shard.Leaving {
+				continue
+			}
+			isInit := s.State() == shard.Initializing
+			if candidate == nil ||
+				(isInit && candidate.State() != shard.Initializing) ||
+				(isInit == (candidate.State() == shard.Initializing) && s.ID() < candidate.ID()) {
+				candidate = s
+			}
+		}
+		drop(dropFrom, candidate.ID())
+	}
+
+	remaining := make([]placement.Instance, 0, len(instances))
+	for _, instance := range instances {
+		if instance.Shards().NumShards() > 0 {
+			remaining = append(remaining, instance)
+		}
+	}
+
+	return p.
+		SetInstances(remaining).
+		SetReplicaFactor(rf).
+		SetCutoverNanos(opts.PlacementCutoverNanosFn()())
+}
+
+// dropShard drops the replica of the shard from the instance. An Available
+// replica is marked as a Leaving dropped replica, an Initializing replica is
+// removed right away, handing the dropped replica over to its source instance.
+func dropShard(p placement.Placement, instance placement.Instance, shardID uint32, cutoffNanos int64) {
+	s, ok := instance.Shards().Shard(shardID)
+	if !ok {
+		return
+	}
+
+	if s.State() == shard.Available {
+		s.SetState(shard.Leaving).
+			SetSourceID(instance.ID()).
+			SetCutoffNanos(cutoffNanos)
+		return
+	}
+
+	instance.Shards().Remove(shardID)
+	if s.SourceID() == "" {
+		return
+	}
+
+	if source, ok := p.Instance(s.SourceID()); ok {
+		if leavingShard, ok := source.Shards().Shard(shardID); ok && leavingShard.State() == shard.Leaving {
+			leavingShard.SetSourceID(source.ID())
+		}
+	}
+}
+
+// splitShards splits each shard in the placement into factor child shards. The
+// placement must own shards 0 to n-1, the children of shard i are i+k*n for k
+// from 0 to factor-1 so keys hashing to shard i still hash to one of its children
+// with the new shard count. Shard i itself remains as its first child, the other
+// children are placed as Initializing on the instances owning shard i, with the
+// instance itself as the source.
+func splitShards(p placement.Placement, factor int, opts placement.Options) (placement.Placement, error) {
+	if factor < 2 {
+		return nil, fmt.Errorf("could not split shards by factor %d, expecting at least 2", factor)
+	}
+
+	numShards := p.NumShards()
+	for i, shardID := range placement.SortedShardIDs(p.Shards()) {
+		if shardID != uint32(i) {
+			return nil, errNonContiguousShards
+		}
+	}
+
+	p = p.Clone()
+	for _, instance := range p.Instances() {
+		for _, s := range instance.Shards().All() {
+			if s.State() != shard.Available {
+				return nil, fmt.Errorf("could not split shards, shard %d on instance %s is %v", s.ID(), instance.ID(), s.State())
+			}
+		}
+	}
+
+	cutoverNanos := opts.ShardCutoverNanosFn()()
+	for _, instance := range p.Instances() {
+		for _, s := range instance.Shards().All() {
+			for k := 1; k < factor; k++ {
+				instance.Shards().Add(shard.NewShard(s.ID() + uint32(k*numShards)).
+					SetState(shard.Initializing).
+					SetSourceID(instance.ID()).
+					SetCutoverNanos(cutoverNanos))
+			}
+		}
+	}
+
+	shards := make([]uint32, numShards*factor)
+	for i := range shards {
+		shards[i] = uint32(i)
+	}
+	// Resets the instances to index the child shards.
+	return p.
+		SetInstances(p.Instances()).
+		SetShards(shards).
+		SetCutoverNanos(opts.PlacementCutoverNanosFn()()), nil
+}
@@ -174,6 +174,11 @@ func TestAssignShard(t *testing.T) {
 	assert.False(t, ph.canAssignInstance(2, i6, i3))
 	ph = newHelper(p, 3, placement.NewOptions().SetLooseRackCheck(true)).(*placementHelper)
 	assert.True(t, ph.canAssignInstance(2, i6, i3))
+
+	// The deprecated rack check matches the isolation check at rack level
+	assert.True(t, ph.HasRackConflict(2, i6, "r2"))
+	assert.False(t, ph.HasRackConflict(2, i6, "r3"))
+	assert.Equal(t, ph.HasIsolationConflict(2, i1, "r2"), ph.HasRackConflict(2, i1, "r2"))
 }
 
 func TestNonLeavingInstances(t *testing.T) {
@@ -294,6 +299,9 @@ func TestGeneratePlacement(t *testing.T) {
 
 	p := ph.GeneratePlacement()
 	assert.Equal(t, 2, p.NumInstances())
+
+	ph = NewPlacementHelper(p.SetZoneReplicas(map[string]int{"z1": 1}), placement.NewOptions())
+	assert.Equal(t, map[string]int{"z1": 1}, ph.GeneratePlacement().ZoneReplicas())
 }
 
 func TestReturnInitShardToSource_RackConflict(t *testing.T) {
@@ -576,7 +576,7 @@ func TestLooseRackCheckAlgorithm(t *testing.T) {
 	require.NoError(t, placement.Validate(p))
 
 	p1, err := a.AddReplica(p)
-	assert.Equal(t, errNotEnoughRacks, err)
+	assert.Equal(t, errNotEnoughFailureDomains, err)
 	assert.Nil(t, p1)
 	p = mustMarkAllShardsAsAvailable(t, p, opts)
 	require.NoError(t, placement.Validate(p))
@@ -588,7 +588,7 @@ func TestLooseRackCheckAlgorithm(t *testing.T) {
 	require.NoError(t, placement.Validate(p))
 
 	p1, err = a.AddReplica(p)
-	assert.Equal(t, errNotEnoughRacks, err)
+	assert.Equal(t, errNotEnoughFailureDomains, err)
 	assert.Nil(t, p1)
 	p = mustMarkAllShardsAsAvailable(t, p, opts)
 	require.NoError(t, placement.Validate(p))
@@ -794,6 +794,306 @@ func TestAddReplica(t *testing.T) {
 	}
 }
 
+func TestRemoveReplica(t *testing.T) {
+	var instances []placement.Instance
+	for i := 0; i < 6; i++ {
+		instances = append(instances, placement.NewEmptyInstance(
+			fmt.Sprintf("i%d", i),
+			fmt.Sprintf("r%d", i%3),
+			"",
+			fmt.Sprintf("e%d", i),
+			1,
+		))
+	}
+
+	ids := make([]uint32, 12)
+	for i := 0; i < len(ids); i++ {
+		ids[i] = uint32(i)
+	}
+
+	opts := placement.NewOptions()
+	a := newShardedAlgorithm(opts)
+	p, err := a.InitialPlacement(instances, ids, 3)
+	require.NoError(t, err)
+
+	p2, err := a.RemoveReplica(p)
+	require.NoError(t, err)
+	require.NoError(t, placement.Validate(p2))
+	assert.Equal(t, 2, p2.ReplicaFactor())
+
+	numDropped := 0
+	racksByShard := make(map[uint32]map[string]struct{})
+	for _, instance := range p2.Instances() {
+		for _, s := range instance.Shards().All() {
+			if s.State() == shard.Leaving {
+				assert.True(t, placement.IsDroppedReplica(instance, s))
+				numDropped++
+				continue
+			}
+			if racksByShard[s.ID()] == nil {
+				racksByShard[s.ID()] = make(map[string]struct{})
+			}
+			racksByShard[s.ID()][instance.Rack()] = struct{}{}
+		}
+	}
+	assert.Equal(t, len(ids), numDropped)
+	for _, racks := range racksByShard {
+		assert.Equal(t, 2, len(racks))
+	}
+
+	p2, err = markAllShardsAvailable(p2, opts)
+	require.NoError(t, err)
+	validateDistribution(t, p2, 1.01, "TestRemoveReplica available")
+	for _, instance := range p2.Instances() {
+		assert.Equal(t, instance.Shards().NumShards(), instance.Shards().NumShardsForState(shard.Available))
+	}
+
+	p2, err = a.RemoveReplica(p2)
+	require.NoError(t, err)
+	p2, err = markAllShardsAvailable(p2, opts)
+	require.NoError(t, err)
+	_, err = a.RemoveReplica(p2)
+	assert.Equal(t, errNoReplicaToRemove, err)
+}
+
+func TestRemoveReplicaUpdatesShardIndex(t *testing.T) {
+	i1 := placement.NewEmptyInstance("i1", "r1", "", "e1", 1)
+	i1.Shards().Add(shard.NewShard(0).SetState(shard.Leaving))
+	i1.Shards().Add(shard.NewShard(1).SetState(shard.Available))
+	i2 := placement.NewEmptyInstance("i2", "r2", "", "e2", 1)
+	i2.Shards().Add(shard.NewShard(0).SetState(shard.Available))
+	i3 := placement.NewEmptyInstance("i3", "r2", "", "e3", 1)
+	i3.Shards().Add(shard.NewShard(0).SetState(shard.Initializing).SetSourceID("i1"))
+	i3.Shards().Add(shard.NewShard(2).SetState(shard.Available))
+	i4 := placement.NewEmptyInstance("i4", "r4", "", "e4", 1)
+	i4.Shards().Add(shard.NewShard(1).SetState(shard.Available))
+	i4.Shards().Add(shard.NewShard(2).SetState(shard.Available))
+
+	p := placement.NewPlacement().
+		SetInstances([]placement.Instance{i1, i2, i3, i4}).
+		SetShards([]uint32{0, 1, 2}).
+		SetReplicaFactor(2).
+		SetIsSharded(true)
+	require.NoError(t, placement.Validate(p))
+
+	a := newShardedAlgorithm(placement.NewOptions())
+	p, err := a.RemoveReplica(p)
+	require.NoError(t, err)
+	require.NoError(t, placement.Validate(p))
+
+	// i2 and i3 share a rack, so the Initializing replica of shard 0 is dropped
+	// from i3 while i3 keeps shard 2.
+	for _, shardID := range p.Shards() {
+		var expected []string
+		for _, instance := range p.Instances() {
+			if instance.Shards().Contains(shardID) {
+				expected = append(expected, instance.ID())
+			}
+		}
+		var actual []string
+		for _, instance := range p.InstancesForShard(shardID) {
+			actual = append(actual, instance.ID())
+		}
+		assert.Equal(t, expected, actual, "shard %d", shardID)
+	}
+	i3, ok := p.Instance("i3")
+	require.True(t, ok)
+	assert.False(t, i3.Shards().Contains(0))
+}
+
+func TestRemoveReplicaWithHandoff(t *testing.T) {
+	i1 := placement.NewEmptyInstance("i1", "r1", "", "e1", 1)
+	i1.Shards().Add(shard.NewShard(0).SetState(shard.Leaving))
+	i2 := placement.NewEmptyInstance("i2", "r2", "", "e2", 2)
+	i2.Shards().Add(shard.NewShard(0).SetState(shard.Available))
+	i3 := placement.NewEmptyInstance("i3", "r3", "", "e3", 1)
+	i3.Shards().Add(shard.NewShard(0).SetState(shard.Initializing).SetSourceID("i1"))
+
+	p := placement.NewPlacement().
+		SetInstances([]placement.Instance{i1, i2, i3}).
+		SetShards([]uint32{0}).
+		SetReplicaFactor(2).
+		SetIsSharded(true)
+	require.NoError(t, placement.Validate(p))
+
+	a := newShardedAlgorithm(placement.NewOptions())
+	p, err := a.RemoveReplica(p)
+	require.NoError(t, err)
+	require.NoError(t, placement.Validate(p))
+
+	// The Initializing replica on i3 is dropped and i1 keeps a dropped replica of shard 0.
+	_, ok := p.Instance("i3")
+	assert.False(t, ok)
+	i1, _ = p.Instance("i1")
+	s, ok := i1.Shards().Shard(0)
+	require.True(t, ok)
+	assert.True(t, placement.IsDroppedReplica(i1, s))
+
+	p, err = a.MarkShardAvailable(p, "i1", 0)
+	require.NoError(t, err)
+	require.NoError(t, placement.Validate(p))
+	_, ok = p.Instance("i1")
+	assert.False(t, ok)
+	assert.Equal(t, 1, p.NumInstances())
+}
+
+func TestSplitShards(t *testing.T) {
+	i1 := placement.NewEmptyInstance("i1", "r1", "", "e1", 1)
+	i2 := placement.NewEmptyInstance("i2", "r2", "", "e2", 1)
+	i3 := placement.NewEmptyInstance("i3", "r3", "", "e3", 1)
+
+	opts := placement.NewOptions().
+		SetPlacementCutoverNanosFn(timeNanosGen(1)).
+		SetShardCutoverNanosFn(timeNanosGen(2))
+	a := newShardedAlgorithm(opts)
+	p, err := a.InitialPlacement([]placement.Instance{i1, i2, i3}, []uint32{0, 1, 2, 3}, 2)
+	require.NoError(t, err)
+
+	_, err = a.SplitShards(p, 1)
+	assert.Error(t, err)
+
+	p1, err := a.SplitShards(p, 3)
+	require.NoError(t, err)
+	require.NoError(t, placement.Validate(p1))
+	assert.Equal(t, 12, p1.NumShards())
+	assert.Equal(t, 2, p1.ReplicaFactor())
+	assert.Equal(t, int64(1), p1.CutoverNanos())
+
+	for _, instance := range p1.Instances() {
+		before, ok := p.Instance(instance.ID())
+		require.True(t, ok)
+		assert.Equal(t, 3*before.Shards().NumShards(), instance.Shards().NumShards())
+		for _, parent := range before.Shards().All() {
+			s, ok := instance.Shards().Shard(parent.ID())
+			require.True(t, ok)
+			assert.Equal(t, shard.Available, s.State())
+			for _, childID := range []uint32{parent.ID() + 4, parent.ID() + 8} {
+				s, ok := instance.Shards().Shard(childID)
+				require.True(t, ok)
+				assert.Equal(t, shard.Initializing, s.State())
+				assert.Equal(t, instance.ID(), s.SourceID())
+				assert.Equal(t, int64(2), s.CutoverNanos())
+			}
+		}
+	}
+
+	assert.Equal(t, 2, len(p1.InstancesForShard(11)))
+
+	// The split shards could be marked as available in place.
+	p1, err = markAllShardsAvailable(p1, opts)
+	require.NoError(t, err)
+	require.NoError(t, placement.Validate(p1))
+	validateDistribution(t, p1, 1.01, "TestSplitShards")
+
+	// Shards with pending movements could not be split.
+	p2, err := a.AddInstances(p, []placement.Instance{placement.NewEmptyInstance("i4", "r4", "", "e4", 1)})
+	require.NoError(t, err)
+	_, err = a.SplitShards(p2, 2)
+	assert.Error(t, err)
+
+	_, err = a.SplitShards(p.Clone().SetShards([]uint32{1, 2, 3, 4}), 2)
+	assert.Equal(t, errNonContiguousShards, err)
+}
+
+func TestRebalance(t *testing.T) {
+	i1 := placement.NewEmptyInstance("i1", "r1", "", "e1", 1)
+	for i := 0; i < 8; i++ {
+		i1.Shards().Add(shard.NewShard(uint32(i)).SetState(shard.Available))
+	}
+	i2 := placement.NewEmptyInstance("i2", "r2", "", "e2", 1)
+	i3 := placement.NewEmptyInstance("i3", "r3", "", "e3", 2)
+
+	ids := make([]uint32, 8)
+	for i := 0; i < len(ids); i++ {
+		ids[i] = uint32(i)
+	}
+	p := placement.NewPlacement().
+		SetInstances([]placement.Instance{i1, i2, i3}).
+		SetShards(ids).
+		SetReplicaFactor(1).
+		SetIsSharded(true)
+
+	a := newShardedAlgorithm(placement.NewOptions())
+	p1, err := a.Rebalance(p, 3)
+	require.NoError(t, err)
+	require.NoError(t, placement.Validate(p1))
+
+	i1, _ = p1.Instance("i1")
+	assert.Equal(t, 3, i1.Shards().NumShardsForState(shard.Leaving))
+	assert.Equal(t, 5, loadOnInstance(i1))
+	numInitializing := 0
+	for _, instance := range p1.Instances() {
+		for _, s := range instance.Shards().ShardsForState(shard.Initializing) {
+			assert.Equal(t, "i1", s.SourceID())
+			numInitializing++
+		}
+	}
+	assert.Equal(t, 3, numInitializing)
+
+	// The original placement is not modified.
+	i1, _ = p.Instance("i1")
+	assert.Equal(t, 8, i1.Shards().NumShardsForState(shard.Available))
+
+	p2, err := a.Rebalance(p, 0)
+	require.NoError(t, err)
+	validateDistribution(t, p2, 1.01, "TestRebalance")
+	i3, _ = p2.Instance("i3")
+	assert.Equal(t, 4, loadOnInstance(i3))
+
+	_, err = a.Rebalance(p.Clone().SetIsSharded(false), 0)
+	assert.Equal(t, errIncompatibleWithShardedAlgo, err)
+}
+
+func TestUpdateInstances(t *testing.T) {
+	i1 := placement.NewEmptyInstance("i1", "r1", "", "e1", 1)
+	i2 := placement.NewEmptyInstance("i2", "r2", "", "e2", 1)
+	i3 := placement.NewEmptyInstance("i3", "r3", "", "e3", 1)
+
+	ids := make([]uint32, 12)
+	for i := 0; i < len(ids); i++ {
+		ids[i] = uint32(i)
+	}
+
+	a := newShardedAlgorithm(placement.NewOptions())
+	p, err := a.InitialPlacement([]placement.Instance{i1, i2, i3}, ids, 1)
+	require.NoError(t, err)
+	p, err = markAllShardsAvailable(p, placement.NewOptions())
+	require.NoError(t, err)
+
+	i1, _ = p.Instance("i1")
+	update := i1.Clone().SetEndpoint("e11").SetHostname("h11").SetPort(9000)
+	p1, err := a.UpdateInstances(p, []placement.Instance{update})
+	require.NoError(t, err)
+	require.NoError(t, placement.Validate(p1))
+	i1, _ = p1.Instance("i1")
+	assert.Equal(t, "e11", i1.Endpoint())
+	assert.Equal(t, "h11", i1.Hostname())
+	assert.Equal(t, uint32(9000), i1.Port())
+	for _, instance := range p1.Instances() {
+		assert.Equal(t, 4, instance.Shards().NumShardsForState(shard.Available))
+		assert.Equal(t, instance.Shards().NumShards(), instance.Shards().NumShardsForState(shard.Available))
+	}
+
+	i3, _ = p.Instance("i3")
+	p2, err := a.UpdateInstances(p, []placement.Instance{i3.Clone().SetWeight(4)})
+	require.NoError(t, err)
+	validateDistribution(t, p2, 1.01, "TestUpdateInstances")
+	i3, _ = p2.Instance("i3")
+	assert.Equal(t, uint32(4), i3.Weight())
+	assert.Equal(t, 8, loadOnInstance(i3))
+	assert.Equal(t, 4, i3.Shards().NumShardsForState(shard.Initializing))
+
+	_, err = a.UpdateInstances(p, []placement.Instance{placement.NewEmptyInstance("i4", "r4", "", "e4", 1)})
+	assert.Error(t, err)
+
+	_, err = a.UpdateInstances(p, []placement.Instance{placement.NewEmptyInstance("i1", "r4", "", "e1", 1)})
+	assert.Error(t, err)
+
+	i1, _ = p.Instance("i1")
+	_, err = a.UpdateInstances(p, []placement.Instance{i1.Clone().SetRegion("region2")})
+	assert.Error(t, err)
+}
+
 func TestAddInstance(t *testing.T) {
 	i1 := placement.NewEmptyInstance("i1", "r1", "", "e1", 1)
 	i1.Shards().Add(shard.NewShard(0).SetState(shard.Available))
@@ -1087,6 +1387,82 @@ func TestReplaceInstance_Backout(t *testing.T) {
 	assert.Equal(t, "e2", i2.Endpoint())
 }
 
+func TestIsolationLevelAlgorithm(t *testing.T) {
+	var instances []placement.Instance
+	for i := 0; i < 6; i++ {
+		instances = append(instances, placement.NewEmptyInstance(
+			fmt.Sprintf("i%d", i),
+			fmt.Sprintf("r%d", i),
+			fmt.Sprintf("z%d", i%2),
+			fmt.Sprintf("e%d", i),
+			1,
+		))
+	}
+
+	ids := make([]uint32, 12)
+	for i := 0; i < len(ids); i++ {
+		ids[i] = uint32(i)
+	}
+
+	// Six racks but only two zones.
+	opts := placement.NewOptions().SetIsolationLevel(placement.IsolationLevelRack)
+	p, err := newShardedAlgorithm(opts).InitialPlacement(instances, ids, 3)
+	require.NoError(t, err)
+	require.NoError(t, placement.Validate(p))
+
+	opts = placement.NewOptions().SetIsolationLevel(placement.IsolationLevelZone)
+	a := newShardedAlgorithm(opts)
+	_, err = a.InitialPlacement(instances, ids, 3)
+	assert.Equal(t, errNotEnoughFailureDomains, err)
+
+	p, err = a.InitialPlacement(instances, ids, 2)
+	require.NoError(t, err)
+	require.NoError(t, placement.Validate(p))
+	validateIsolation(t, p, placement.IsolationLevelZone, 2)
+
+	_, err = a.AddReplica(p)
+	assert.Equal(t, errNotEnoughFailureDomains, err)
+
+	b := newShardedAlgorithm(opts.SetIsolationMode(placement.IsolationModeBestEffort))
+	p, err = b.AddReplica(p)
+	require.NoError(t, err)
+	p = mustMarkAllShardsAsAvailable(t, p, opts)
+	require.NoError(t, placement.Validate(p))
+	validateIsolation(t, p, placement.IsolationLevelZone, 2)
+}
+
+func TestIsolationLevelHost(t *testing.T) {
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "e1", 1).SetHostname("h1")
+	i2 := placement.NewEmptyInstance("i2", "r1", "z1", "e2", 1).SetHostname("h1")
+	i3 := placement.NewEmptyInstance("i3", "r1", "z1", "e3", 1).SetHostname("h2")
+	instances := []placement.Instance{i1, i2, i3}
+
+	ids := make([]uint32, 6)
+	for i := 0; i < len(ids); i++ {
+		ids[i] = uint32(i)
+	}
+
+	// All instances are on the same rack, so rack isolation fails.
+	_, err := newShardedAlgorithm(placement.NewOptions()).InitialPlacement(instances, ids, 2)
+	assert.Equal(t, errNotEnoughFailureDomains, err)
+
+	opts := placement.NewOptions().SetIsolationLevel(placement.IsolationLevelHost)
+	p, err := newShardedAlgorithm(opts).InitialPlacement(instances, ids, 2)
+	require.NoError(t, err)
+	require.NoError(t, placement.Validate(p))
+	validateIsolation(t, p, placement.IsolationLevelHost, 2)
+}
+
+func validateIsolation(t *testing.T, p placement.Placement, level placement.IsolationLevel, minDomains int) {
+	for _, id := range p.Shards() {
+		domains := make(map[string]struct{})
+		for _, instance := range p.InstancesForShard(id) {
+			domains[placement.FailureDomain(instance, level)] = struct{}{}
+		}
+		assert.True(t, len(domains) >= minDomains, "shard %d is in %d failure domains", id, len(domains))
+	}
+}
+
 func TestIncompatibleWithShardedAlgo(t *testing.T) {
 	i1 := placement.NewInstance().SetID("i1").SetEndpoint("e1")
 	i2 := placement.NewInstance().SetID("i2").SetEndpoint("e2")
@@ -1116,6 +1492,15 @@ func TestIncompatibleWithShardedAlgo(t *testing.T) {
 	_, err = a.MarkShardAvailable(p, "i2", 0)
 	assert.Error(t, err)
 	assert.Equal(t, errIncompatibleWithShardedAlgo, err)
+
+	p = placement.NewPlacement().
+		SetInstances([]placement.Instance{i1, i2}).
+		SetShards([]uint32{}).
+		SetReplicaFactor(1).
+		SetIsSharded(true).
+		SetZoneReplicas(map[string]int{"z1": 1})
+	_, err = a.AddInstances(p, []placement.Instance{i3})
+	assert.Equal(t, errZoneReplicasWithShardedAlgo, err)
 }
 
 func TestMarkShardAsAvailableWithShardedAlgo(t *testing.T) {
@@ -0,0 +1,304 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package algo
+
+import (
+	"github.com/m3db/m3cluster/placement"
+	"github.com/m3db/m3cluster/shard"
+)
+
+// throttledAlgorithm limits the number of shards moving at the same time in
+// placements changed by the wrapped algorithm. Shard moves over the limits are
+// staged as pending shard moves in the placement, and they are started as the
+// Initializing shards are marked as available.
+type throttledAlgorithm struct {
+	opts placement.Options
+	algo placement.Algorithm
+}
+
+func newThrottledAlgorithm(algo placement.Algorithm, opts placement.Options) placement.Algorithm {
+	return throttledAlgorithm{opts: opts, algo: algo}
+}
+
+func (a throttledAlgorithm) IsCompatibleWith(p placement.Placement) error {
+	return a.algo.IsCompatibleWith(p)
+}
+
+func (a throttledAlgorithm) InitialPlacement(
+	instances []placement.Instance,
+	shards []uint32,
+	rf int,
+) (placement.Placement, error) {
+	return a.algo.InitialPlacement(instances, shards, rf)
+}
+
+func (a throttledAlgorithm) AddReplica(p placement.Placement) (placement.Placement, error) {
+	return a.apply(p, a.algo.AddReplica)
+}
+
+func (a throttledAlgorithm) RemoveReplica(p placement.Placement) (placement.Placement, error) {
+	return a.apply(p, a.algo.RemoveReplica)
+}
+
+func (a throttledAlgorithm) SplitShards(p placement.Placement, factor int) (placement.Placement, error) {
+	return a.apply(p, func(p placement.Placement) (placement.Placement, error) {
+		return a.algo.SplitShards(p, factor)
+	})
+}
+
+func (a throttledAlgorithm) Rebalance(p placement.Placement, maxShardMoves int) (placement.Placement, error) {
+	return a.apply(p, func(p placement.Placement) (placement.Placement, error) {
+		return a.algo.Rebalance(p, maxShardMoves)
+	})
+}
+
+func (a throttledAlgorithm) UpdateInstances(
+	p placement.Placement,
+	instances []placement.Instance,
+) (placement.Placement, error) {
+	return a.apply(p, func(p placement.Placement) (placement.Placement, error) {
+		return a.algo.UpdateInstances(p, instances)
+	})
+}
+
+func (a throttledAlgorithm) AddInstances(
+	p placement.Placement,
+	instances []placement.Instance,
+) (placement.Placement, error) {
+	return a.apply(p, func(p placement.Placement) (placement.Placement, error) {
+		return a.algo.AddInstances(p, instances)
+	})
+}
+
+func (a throttledAlgorithm) RemoveInstances(
+	p placement.Placement,
+	leavingInstanceIDs []string,
+) (placement.Placement, error) {
+	return a.apply(p, func(p placement.Placement) (placement.Placement, error) {
+		return a.algo.RemoveInstances(p, leavingInstanceIDs)
+	})
+}
+
+func (a throttledAlgorithm) ReplaceInstances(
+	p placement.Placement,
+	leavingInstanceIDs []string,
+	addingInstances []placement.Instance,
+) (placement.Placement, error) {
+	return a.apply(p, func(p placement.Placement) (placement.Placement, error) {
+		return a.algo.ReplaceInstances(p, leavingInstanceIDs, addingInstances)
+	})
+}
+
+func (a throttledAlgorithm) MarkShardAvailable(
+	p placement.Placement,
+	instanceID string,
+	shardID uint32,
+) (placement.Placement, error) {
+	p, err := a.algo.MarkShardAvailable(p, instanceID, shardID)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.startPendingShardMoves(p, false), nil
+}
+
+// apply starts all the pending shard moves before changing the placement so
+// the algorithm sees the complete target of the previous changes, then stages
+// the new shard moves over the limits.
+func (a throttledAlgorithm) apply(
+	p placement.Placement,
+	fn func(p placement.Placement) (placement.Placement, error),
+) (placement.Placement, error) {
+	newPlacement, err := fn(a.startPendingShardMoves(p.Clone(), true))
+	if err != nil {
+		return nil, err
+	}
+
+	return a.stageShardMoves(p, newPlacement), nil
+}
+
+// startPendingShardMoves starts the pending shard moves allowed by the limits,
+// or all of them when unlimited. Pending shard moves that could no longer
+// start are dropped.
+func (a throttledAlgorithm) startPendingShardMoves(p placement.Placement, unlimited bool) placement.Placement {
+	moves := p.PendingShardMoves()
+	if len(moves) == 0 {
+		return p
+	}
+
+	limiter := newInitializingShardLimiter(a.opts)
+	limiter.acquireInitializing(p)
+	var pending []placement.PendingShardMove
+	for _, move := range moves {
+		source, ok := p.Instance(move.SourceID)
+		if !ok {
+			continue
+		}
+		s, ok := source.Shards().Shard(move.ShardID)
+		if !ok || s.State() != shard.Available {
+			continue
+		}
+		target, ok := p.Instance(move.TargetID)
+		if !ok || target.Shards().Contains(move.ShardID) {
+			continue
+		}
+
+		if !unlimited && !limiter.tryAcquire(target.ID()) {
+			pending = append(pending, move)
+			continue
+		}
+
+		s.SetState(shard.Leaving).SetCutoffNanos(a.opts.ShardCutoffNanosFn()())
+		target.Shards().Add(shard.NewShard(move.ShardID).
+			SetState(shard.Initializing).
+			SetSourceID(source.ID()).
+			SetCutoverNanos(a.opts.ShardCutoverNanosFn()()))
+	}
+
+	return p.
+		SetInstances(p.Instances()).
+		SetPendingShardMoves(pending)
+}
+
+// stageShardMoves stages the shard moves started in the new placement over the
+// limits. Shard moves already in progress in the old placement and shards
+// initializing without a source instance are never staged.
+func (a throttledAlgorithm) stageShardMoves(old, p placement.Placement) placement.Placement {
+	limiter := newInitializingShardLimiter(a.opts)
+	var (
+		instances = p.Instances()
+		newMoves  = make(map[string][]shard.Shard, len(instances))
+	)
+	for _, instance := range instances {
+		oldInstance, _ := old.Instance(instance.ID())
+		for _, s := range instance.Shards().All() {
+			if s.State() != shard.Initializing || s.SourceID() == instance.ID() {
+				continue
+			}
+			if s.SourceID() != "" && !isMoveInProgress(oldInstance, s) {
+				if source, ok := p.Instance(s.SourceID()); ok && source.Shards().Contains(s.ID()) {
+					newMoves[instance.ID()] = append(newMoves[instance.ID()], s)
+					continue
+				}
+			}
+			limiter.acquire(instance.ID())
+		}
+	}
+
+	// Starts the new shard moves round robin across the target instances so no
+	// instance is starved by the placement limit.
+	var pending []placement.PendingShardMove
+	for round := 0; len(newMoves) > 0; round++ {
+		for _, instance := range instances {
+			shards, ok := newMoves[instance.ID()]
+			if !ok {
+				continue
+			}
+			if round >= len(shards) {
+				delete(newMoves, instance.ID())
+				continue
+			}
+
+			s := shards[round]
+			if limiter.tryAcquire(instance.ID()) {
+				continue
+			}
+			stageShardMove(p, instance, s)
+			pending = append(pending, placement.PendingShardMove{
+				ShardID:  s.ID(),
+				SourceID: s.SourceID(),
+				TargetID: instance.ID(),
+			})
+		}
+	}
+
+	return p.
+		SetInstances(instances).
+		SetPendingShardMoves(pending)
+}
+
+func isMoveInProgress(oldInstance placement.Instance, s shard.Shard) bool {
+	if oldInstance == nil {
+		return false
+	}
+	oldShard, ok := oldInstance.Shards().Shard(s.ID())
+	return ok && oldShard.State() == shard.Initializing && oldShard.SourceID() == s.SourceID()
+}
+
+// stageShardMove reverts the shard move onto the instance, returning the shard
+// to the source instance as Available.
+func stageShardMove(p placement.Placement, instance placement.Instance, s shard.Shard) {
+	instance.Shards().Remove(s.ID())
+	source, _ := p.Instance(s.SourceID())
+	leavingShard, ok := source.Shards().Shard(s.ID())
+	if !ok {
+		return
+	}
+	source.Shards().Add(shard.NewShard(s.ID()).
+		SetState(shard.Available).
+		SetCutoverNanos(leavingShard.CutoverNanos()))
+}
+
+// initializingShardLimiter tracks the number of Initializing shards against the
+// configured limits.
+type initializingShardLimiter struct {
+	perInstance     map[string]int
+	total           int
+	maxPerInstance  int
+	maxPerPlacement int
+}
+
+func newInitializingShardLimiter(opts placement.Options) *initializingShardLimiter {
+	return &initializingShardLimiter{
+		perInstance:     make(map[string]int),
+		maxPerInstance:  opts.MaxInitializingShardsPerInstance(),
+		maxPerPlacement: opts.MaxInitializingShardsPerPlacement(),
+	}
+}
+
+// acquireInitializing counts the Initializing shards in the placement, except
+// for the shards split from a parent shard on the same instance which do not
+// stream any data.
+func (l *initializingShardLimiter) acquireInitializing(p placement.Placement) {
+	for _, instance := range p.Instances() {
+		for _, s := range instance.Shards().ShardsForState(shard.Initializing) {
+			if s.SourceID() != instance.ID() {
+				l.acquire(instance.ID())
+			}
+		}
+	}
+}
+
+func (l *initializingShardLimiter) acquire(instanceID string) {
+	l.perInstance[instanceID]++
+	l.total++
+}
+
+func (l *initializingShardLimiter) tryAcquire(instanceID string) bool {
+	if l.maxPerInstance > 0 && l.perInstance[instanceID] >= l.maxPerInstance {
+		return false
+	}
+	if l.maxPerPlacement > 0 && l.total >= l.maxPerPlacement {
+		return false
+	}
+	l.acquire(instanceID)
+	return true
+}
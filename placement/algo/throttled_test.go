@@ -0,0 +1,143 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package algo
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/m3db/m3cluster/placement"
+	"github.com/m3db/m3cluster/shard"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestThrottledAlgorithmPerInstanceLimit(t *testing.T) {
+	opts := placement.NewOptions().SetMaxInitializingShardsPerInstance(1)
+	a := NewAlgorithm(opts)
+	_, ok := a.(throttledAlgorithm)
+	require.True(t, ok)
+
+	p, err := a.InitialPlacement(newThrottleTestInstances(0, 4), newThrottleTestShards(16), 1)
+	require.NoError(t, err)
+
+	p, err = a.AddInstances(p, newThrottleTestInstances(4, 5))
+	require.NoError(t, err)
+	require.NoError(t, placement.Validate(p))
+
+	i4, ok := p.Instance("i4")
+	require.True(t, ok)
+	require.Equal(t, 1, i4.Shards().NumShardsForState(shard.Initializing))
+	require.Equal(t, 2, len(p.PendingShardMoves()))
+	for _, move := range p.PendingShardMoves() {
+		require.Equal(t, "i4", move.TargetID)
+	}
+
+	// Pending shard moves start one by one as shards are marked as available.
+	for i := 0; i < 3; i++ {
+		i4, _ = p.Instance("i4")
+		initShards := i4.Shards().ShardsForState(shard.Initializing)
+		require.Equal(t, 1, len(initShards))
+		require.Equal(t, 2-i, len(p.PendingShardMoves()))
+
+		p, err = a.MarkShardAvailable(p, "i4", initShards[0].ID())
+		require.NoError(t, err)
+		require.NoError(t, placement.Validate(p))
+	}
+	require.Empty(t, p.PendingShardMoves())
+	i4, _ = p.Instance("i4")
+	require.Equal(t, 3, i4.Shards().NumShardsForState(shard.Available))
+}
+
+func TestThrottledAlgorithmPerPlacementLimit(t *testing.T) {
+	opts := placement.NewOptions().SetMaxInitializingShardsPerPlacement(2)
+	a := NewAlgorithm(opts)
+
+	p, err := a.InitialPlacement(newThrottleTestInstances(0, 3), newThrottleTestShards(12), 2)
+	require.NoError(t, err)
+
+	p, err = a.AddInstances(p, newThrottleTestInstances(3, 6))
+	require.NoError(t, err)
+	require.NoError(t, placement.Validate(p))
+
+	numInitializing := 0
+	for _, instance := range p.Instances() {
+		numInitializing += instance.Shards().NumShardsForState(shard.Initializing)
+	}
+	require.Equal(t, 2, numInitializing)
+	require.Equal(t, 10, len(p.PendingShardMoves()))
+
+	// The instances without any started shard move are kept for the pending moves.
+	for _, id := range []string{"i3", "i4", "i5"} {
+		_, ok := p.Instance(id)
+		require.True(t, ok)
+	}
+
+	// Changing the placement again restages all the pending shard moves.
+	p, err = a.Rebalance(p, 0)
+	require.NoError(t, err)
+	require.NoError(t, placement.Validate(p))
+	require.Equal(t, 10, len(p.PendingShardMoves()))
+
+	for len(p.PendingShardMoves()) > 0 {
+		instanceID, shardID := firstInitializingShard(t, p)
+		p, err = a.MarkShardAvailable(p, instanceID, shardID)
+		require.NoError(t, err)
+		require.NoError(t, placement.Validate(p))
+	}
+	p, err = markAllShardsAvailable(p, opts)
+	require.NoError(t, err)
+	validateDistribution(t, p, 1.01, "TestThrottledAlgorithmPerPlacementLimit")
+}
+
+func firstInitializingShard(t *testing.T, p placement.Placement) (string, uint32) {
+	for _, instance := range p.Instances() {
+		for _, s := range instance.Shards().All() {
+			if s.State() == shard.Initializing {
+				return instance.ID(), s.ID()
+			}
+		}
+	}
+	require.FailNow(t, "no initializing shard in the placement")
+	return "", 0
+}
+
+func newThrottleTestInstances(from, to int) []placement.Instance {
+	var instances []placement.Instance
+	for i := from; i < to; i++ {
+		instances = append(instances, placement.NewEmptyInstance(
+			fmt.Sprintf("i%d", i),
+			fmt.Sprintf("r%d", i),
+			"",
+			fmt.Sprintf("e%d", i),
+			1,
+		))
+	}
+	return instances
+}
+
+func newThrottleTestShards(numShards int) []uint32 {
+	ids := make([]uint32, numShards)
+	for i := range ids {
+		ids[i] = uint32(i)
+	}
+	return ids
+}
@@ -0,0 +1,427 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/m3db/m3cluster/generated/proto/commonpb"
+	"github.com/m3db/m3cluster/generated/proto/controllerpb"
+	"github.com/m3db/m3cluster/kv"
+	"github.com/m3db/m3cluster/placement"
+	"github.com/m3db/m3cluster/services/leader/elected"
+	"github.com/m3db/m3cluster/shard"
+	"github.com/m3db/m3x/clock"
+	xwatch "github.com/m3db/m3x/watch"
+
+	"github.com/uber-go/tally"
+)
+
+const (
+	replacementWindow     = time.Hour
+	replacementsKeyFormat = "%s/replacements"
+)
+
+var (
+	errOptsNotSet = errors.New("options must be specified")
+)
+
+// A Controller replaces the instances that stopped heartbeating in a placement.
+// Any number of controllers may run against the same placement; only the
+// elected leader replaces instances. An instance is replaced once it has not
+// heartbeated for the grace period, one instance at a time and no more than
+// the configured number of instances per hour. Replacements are skipped while
+// the pause switch in KV is on. The replacement times are kept in KV next to
+// the pause switch, so the hourly limit holds across leaders
+type Controller interface {
+	// Start campaigns for leadership and begins watching the heartbeats
+	Start() error
+
+	// Stop resigns leadership and stops watching the heartbeats
+	Stop() error
+}
+
+type controllerMetrics struct {
+	deadInstances        tally.Gauge
+	replacements         tally.Counter
+	replacementErrors    tally.Counter
+	replacementsInFlight tally.Counter
+	rateLimited          tally.Counter
+	reserveErrors        tally.Counter
+	paused               tally.Counter
+	pauseErrors          tally.Counter
+	placementErrors      tally.Counter
+}
+
+func newControllerMetrics(scope tally.Scope) controllerMetrics {
+	return controllerMetrics{
+		deadInstances:        scope.Gauge("dead-instances"),
+		replacements:         scope.Counter("replacements"),
+		replacementErrors:    scope.Counter("replacement-errors"),
+		replacementsInFlight: scope.Counter("replacements-in-flight"),
+		rateLimited:          scope.Counter("rate-limited"),
+		reserveErrors:        scope.Counter("reserve-errors"),
+		paused:               scope.Counter("paused"),
+		pauseErrors:          scope.Counter("pause-errors"),
+		placementErrors:      scope.Counter("placement-errors"),
+	}
+}
+
+type controller struct {
+	sync.RWMutex
+
+	opts    Options
+	loop    elected.Loop
+	nowFn   clock.NowFn
+	metrics controllerMetrics
+	doneCh  chan struct{}
+	wg      sync.WaitGroup
+
+	// alive is the latest set of heartbeating instances and lastSeen is when
+	// an instance was last known to be heartbeating
+	alive    map[string]struct{}
+	lastSeen map[string]time.Time
+}
+
+// NewController creates a new Controller
+func NewController(opts Options) (Controller, error) {
+	if opts == nil {
+		return nil, errOptsNotSet
+	}
+
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	instrumentOpts := opts.InstrumentOptions()
+	scope := instrumentOpts.MetricsScope().SubScope("placement-controller")
+	c := &controller{
+		opts:     opts,
+		nowFn:    opts.ClockOptions().NowFn(),
+		metrics:  newControllerMetrics(scope),
+		doneCh:   make(chan struct{}),
+		alive:    make(map[string]struct{}),
+		lastSeen: make(map[string]time.Time),
+	}
+
+	loop, err := elected.NewLoop(elected.NewOptions().
+		SetLeaderService(opts.LeaderService()).
+		SetElectionID(opts.ElectionID()).
+		SetCampaignOptions(opts.CampaignOptions()).
+		SetInterval(opts.CheckInterval()).
+		SetLeaderFn(c.check).
+		SetInstrumentOptions(instrumentOpts.SetMetricsScope(scope)))
+	if err != nil {
+		return nil, err
+	}
+
+	c.loop = loop
+	return c, nil
+}
+
+func (c *controller) Start() error {
+	w, err := c.opts.HeartbeatService().Watch()
+	if err != nil {
+		return err
+	}
+
+	if err := c.loop.Start(); err != nil {
+		w.Close()
+		return err
+	}
+
+	c.wg.Add(1)
+	go c.watchHeartbeats(w)
+	return nil
+}
+
+func (c *controller) Stop() error {
+	// The heartbeat watcher is stopped even if resigning fails
+	err := c.loop.Stop()
+	if err == elected.ErrNotStarted {
+		return err
+	}
+
+	close(c.doneCh)
+	c.wg.Wait()
+	return err
+}
+
+func (c *controller) watchHeartbeats(w xwatch.Watch) {
+	defer c.wg.Done()
+	defer w.Close()
+
+	for {
+		select {
+		case <-c.doneCh:
+			return
+		case _, ok := <-w.C():
+			if !ok {
+				return
+			}
+			ids, ok := w.Get().([]string)
+			if !ok {
+				continue
+			}
+			c.updateAlive(ids)
+		}
+	}
+}
+
+// updateAlive records the heartbeating instances whether or not the controller
+// is the leader, so a new leader knows when the instances that stopped
+// heartbeating were last alive
+func (c *controller) updateAlive(ids []string) {
+	now := c.nowFn()
+	alive := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		alive[id] = struct{}{}
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	// The instances that were alive until this update were last seen now
+	for id := range c.alive {
+		c.lastSeen[id] = now
+	}
+	for id := range alive {
+		c.lastSeen[id] = now
+	}
+	c.alive = alive
+}
+
+func (c *controller) check() {
+	log := c.opts.InstrumentOptions().Logger()
+
+	paused, err := c.paused()
+	if err != nil {
+		c.metrics.pauseErrors.Inc(1)
+		log.Errorf("could not read placement controller pause switch: %v", err)
+		return
+	}
+	if paused {
+		c.metrics.paused.Inc(1)
+		return
+	}
+
+	p, _, err := c.opts.PlacementService().Placement()
+	if err != nil {
+		c.metrics.placementErrors.Inc(1)
+		log.Errorf("could not read placement: %v", err)
+		return
+	}
+
+	now := c.nowFn()
+	dead := c.deadInstances(p, now)
+	c.metrics.deadInstances.Update(float64(len(dead)))
+	if len(dead) == 0 {
+		return
+	}
+
+	// Only one replacement may be in flight, a replacement completes once all
+	// the shards of the replacement instance are available
+	if replacementInFlight(p) {
+		c.metrics.replacementsInFlight.Inc(1)
+		return
+	}
+
+	// The replacement is recorded before it is made, so a failed replacement
+	// still counts towards the hourly limit
+	id := dead[0]
+	reserved, err := c.reserveReplacement(now)
+	if err != nil {
+		c.metrics.reserveErrors.Inc(1)
+		log.Errorf("could not record replacement of instance %s: %v", id, err)
+		return
+	}
+	if !reserved {
+		c.metrics.rateLimited.Inc(1)
+		log.Warnf("not replacing instance %s, reached %d replacements in the last hour",
+			id, c.opts.MaxReplacementsPerHour())
+		return
+	}
+
+	_, used, err := c.opts.PlacementService().ReplaceInstances([]string{id}, c.opts.Candidates())
+	if err != nil {
+		c.metrics.replacementErrors.Inc(1)
+		log.Errorf("could not replace instance %s: %v", id, err)
+		return
+	}
+
+	c.Lock()
+	delete(c.lastSeen, id)
+	c.Unlock()
+
+	c.metrics.replacements.Inc(1)
+	for _, instance := range used {
+		log.Infof("replaced instance %s with instance %s", id, instance.ID())
+	}
+}
+
+func (c *controller) paused() (bool, error) {
+	v, err := c.opts.KVStore().Get(c.opts.PauseKey())
+	if err == kv.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var value commonpb.BoolProto
+	if err := v.Unmarshal(&value); err != nil {
+		return false, err
+	}
+	return value.Value, nil
+}
+
+// deadInstances returns the instances in the placement that have not
+// heartbeated for the grace period, longest dead first. Instances are given
+// the full grace period from the first time the controller sees them
+func (c *controller) deadInstances(p placement.Placement, now time.Time) []string {
+	c.Lock()
+	defer c.Unlock()
+
+	inPlacement := make(map[string]struct{}, p.NumInstances())
+	var dead []deadInstance
+	for _, instance := range p.Instances() {
+		id := instance.ID()
+		inPlacement[id] = struct{}{}
+
+		if _, ok := c.alive[id]; ok {
+			c.lastSeen[id] = now
+			continue
+		}
+
+		lastSeen, ok := c.lastSeen[id]
+		if !ok {
+			c.lastSeen[id] = now
+			continue
+		}
+
+		if instance.IsLeaving() {
+			continue
+		}
+
+		if now.Sub(lastSeen) >= c.opts.GracePeriod() {
+			dead = append(dead, deadInstance{id: id, lastSeen: lastSeen})
+		}
+	}
+
+	for id := range c.lastSeen {
+		if _, ok := inPlacement[id]; !ok {
+			delete(c.lastSeen, id)
+		}
+	}
+
+	sort.Sort(deadInstancesByLastSeen(dead))
+	ids := make([]string, len(dead))
+	for i, instance := range dead {
+		ids[i] = instance.id
+	}
+	return ids
+}
+
+// reserveReplacement records a replacement at the given time unless the
+// hourly limit has been reached. The times are stored in KV with a
+// check-and-set, so two controllers cannot both take the last replacement
+func (c *controller) reserveReplacement(now time.Time) (bool, error) {
+	store := c.opts.KVStore()
+	key := fmt.Sprintf(replacementsKeyFormat, c.opts.PauseKey())
+	var (
+		replacements controllerpb.Replacements
+		version      int
+	)
+	v, err := store.Get(key)
+	if err != nil && err != kv.ErrNotFound {
+		return false, err
+	}
+	if err == nil {
+		if err := v.Unmarshal(&replacements); err != nil {
+			return false, err
+		}
+		version = v.Version()
+	}
+
+	replacedAt := make([]time.Time, len(replacements.ReplacedAtNanos))
+	for i, nanos := range replacements.ReplacedAtNanos {
+		replacedAt[i] = time.Unix(0, nanos)
+	}
+	replacedAt = recentReplacements(replacedAt, now)
+	if len(replacedAt) >= c.opts.MaxReplacementsPerHour() {
+		return false, nil
+	}
+	replacedAt = append(replacedAt, now)
+
+	nanos := make([]int64, len(replacedAt))
+	for i, t := range replacedAt {
+		nanos[i] = t.UnixNano()
+	}
+	replacements = controllerpb.Replacements{ReplacedAtNanos: nanos}
+	if version == 0 {
+		_, err = store.SetIfNotExists(key, &replacements)
+	} else {
+		_, err = store.CheckAndSet(key, version, &replacements)
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func recentReplacements(replacedAt []time.Time, now time.Time) []time.Time {
+	var recent []time.Time
+	for _, t := range replacedAt {
+		if now.Sub(t) < replacementWindow {
+			recent = append(recent, t)
+		}
+	}
+	return recent
+}
+
+func replacementInFlight(p placement.Placement) bool {
+	for _, instance := range p.Instances() {
+		if instance.Shards().NumShardsForState(shard.Initializing) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+type deadInstance struct {
+	id       string
+	lastSeen time.Time
+}
+
+type deadInstancesByLastSeen []deadInstance
+
+func (d deadInstancesByLastSeen) Len() int      { return len(d) }
+func (d deadInstancesByLastSeen) Swap(i, j int) { d[i], d[j] = d[j], d[i] }
+func (d deadInstancesByLastSeen) Less(i, j int) bool {
+	if d[i].lastSeen.Equal(d[j].lastSeen) {
+		return d[i].id < d[j].id
+	}
+	return d[i].lastSeen.Before(d[j].lastSeen)
+}
@@ -0,0 +1,387 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package controller
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/m3db/m3cluster/generated/proto/commonpb"
+	"github.com/m3db/m3cluster/generated/proto/controllerpb"
+	"github.com/m3db/m3cluster/kv"
+	"github.com/m3db/m3cluster/kv/mem"
+	"github.com/m3db/m3cluster/placement"
+	"github.com/m3db/m3cluster/placement/service"
+	"github.com/m3db/m3cluster/placement/storage"
+	"github.com/m3db/m3cluster/services"
+	"github.com/m3db/m3cluster/services/leader/campaign"
+	"github.com/m3db/m3cluster/services/leader/elected"
+	"github.com/m3db/m3x/clock"
+	xwatch "github.com/m3db/m3x/watch"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestControllerReplacesDeadInstanceWhenLeader(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ps := newTestPlacementService(t)
+	clk := newTestClock()
+
+	hb := xwatch.NewWatchable()
+	require.NoError(t, hb.Update([]string{"i1", "i2"}))
+	hbService := services.NewMockHeartbeatService(ctrl)
+	hbService.EXPECT().Watch().DoAndReturn(func() (xwatch.Watch, error) {
+		_, w, err := hb.Watch()
+		return w, err
+	}).Times(2)
+
+	statusCh := make(chan campaign.Status, 2)
+	leaderSvc := services.NewMockLeaderService(ctrl)
+	leaderSvc.EXPECT().Campaign("e1", gomock.Any()).Return((<-chan campaign.Status)(statusCh), nil)
+	leaderSvc.EXPECT().Resign("e1").DoAndReturn(func(string) error {
+		close(statusCh)
+		return nil
+	})
+
+	c, err := NewController(testOptions(t, ps).
+		SetHeartbeatService(hbService).
+		SetLeaderService(leaderSvc).
+		SetClockOptions(clock.NewOptions().SetNowFn(clk.now)))
+	require.NoError(t, err)
+	require.NoError(t, c.Start())
+	require.Equal(t, elected.ErrAlreadyStarted, c.Start())
+
+	// Followers never replace instances
+	statusCh <- campaign.NewStatus(campaign.Follower)
+	clk.advance(time.Hour)
+	time.Sleep(20 * time.Millisecond)
+	p, _, err := ps.Placement()
+	require.NoError(t, err)
+	require.Equal(t, 3, p.NumInstances())
+
+	statusCh <- campaign.NewStatus(campaign.Leader)
+	for {
+		c.(*controller).RLock()
+		_, seen := c.(*controller).lastSeen["i3"]
+		c.(*controller).RUnlock()
+		if seen {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	clk.advance(time.Hour)
+	for {
+		p, _, err = ps.Placement()
+		require.NoError(t, err)
+		if p.NumInstances() == 4 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	i3, ok := p.Instance("i3")
+	require.True(t, ok)
+	require.True(t, i3.IsLeaving())
+	_, ok = p.Instance("i4")
+	require.True(t, ok)
+
+	require.NoError(t, c.Stop())
+	require.Equal(t, elected.ErrNotStarted, c.Stop())
+}
+
+func TestControllerCheckGracePeriod(t *testing.T) {
+	ps := newTestPlacementService(t)
+	clk := newTestClock()
+	c := newTestController(t, testOptions(t, ps).SetClockOptions(clock.NewOptions().SetNowFn(clk.now)))
+	c.alive = map[string]struct{}{"i1": {}, "i2": {}}
+
+	// Instances are given the full grace period from when they are first seen
+	c.check()
+	clk.advance(time.Minute - time.Second)
+	c.check()
+	p, _, err := ps.Placement()
+	require.NoError(t, err)
+	require.Equal(t, 3, p.NumInstances())
+
+	// Heartbeating again restarts the grace period
+	c.alive = map[string]struct{}{"i1": {}, "i2": {}, "i3": {}}
+	c.check()
+	c.alive = map[string]struct{}{"i1": {}, "i2": {}}
+	clk.advance(time.Minute - time.Second)
+	c.check()
+	p, _, err = ps.Placement()
+	require.NoError(t, err)
+	require.Equal(t, 3, p.NumInstances())
+
+	clk.advance(time.Second)
+	c.check()
+	p, _, err = ps.Placement()
+	require.NoError(t, err)
+	require.Equal(t, 4, p.NumInstances())
+	i3, ok := p.Instance("i3")
+	require.True(t, ok)
+	require.True(t, i3.IsLeaving())
+}
+
+func TestControllerCheckPaused(t *testing.T) {
+	ps := newTestPlacementService(t)
+	clk := newTestClock()
+	store := mem.NewStore()
+	c := newTestController(t, testOptions(t, ps).
+		SetClockOptions(clock.NewOptions().SetNowFn(clk.now)).
+		SetKVStore(store))
+	c.alive = map[string]struct{}{"i1": {}, "i2": {}}
+
+	c.check()
+	_, err := store.Set("pause", &commonpb.BoolProto{Value: true})
+	require.NoError(t, err)
+	clk.advance(time.Hour)
+	c.check()
+	p, _, err := ps.Placement()
+	require.NoError(t, err)
+	require.Equal(t, 3, p.NumInstances())
+
+	_, err = store.Set("pause", &commonpb.BoolProto{Value: false})
+	require.NoError(t, err)
+	c.check()
+	p, _, err = ps.Placement()
+	require.NoError(t, err)
+	require.Equal(t, 4, p.NumInstances())
+}
+
+func TestControllerCheckOneReplacementAtATime(t *testing.T) {
+	ps := newTestPlacementService(t)
+	clk := newTestClock()
+	store := mem.NewStore()
+	c := newTestController(t, testOptions(t, ps).
+		SetClockOptions(clock.NewOptions().SetNowFn(clk.now)).
+		SetKVStore(store).
+		SetMaxReplacementsPerHour(2))
+	c.alive = map[string]struct{}{}
+
+	c.check()
+	clk.advance(time.Hour)
+	c.check()
+	p, _, err := ps.Placement()
+	require.NoError(t, err)
+	require.Equal(t, 4, p.NumInstances())
+
+	// The next dead instance waits for the replacement to complete
+	c.check()
+	p, _, err = ps.Placement()
+	require.NoError(t, err)
+	require.Equal(t, 4, p.NumInstances())
+	require.Len(t, replacedAtNanos(t, store), 1)
+
+	require.NoError(t, ps.MarkInstanceAvailable("i4"))
+	c.check()
+	p, _, err = ps.Placement()
+	require.NoError(t, err)
+	require.Len(t, replacedAtNanos(t, store), 2)
+	require.Equal(t, 4, p.NumInstances())
+	_, ok := p.Instance("i5")
+	require.True(t, ok)
+}
+
+func TestControllerCheckMaxReplacementsPerHour(t *testing.T) {
+	ps := newTestPlacementService(t)
+	clk := newTestClock()
+	store := mem.NewStore()
+	c := newTestController(t, testOptions(t, ps).
+		SetClockOptions(clock.NewOptions().SetNowFn(clk.now)).
+		SetKVStore(store))
+	c.alive = map[string]struct{}{"i1": {}}
+
+	c.check()
+	clk.advance(time.Hour)
+	c.check()
+	require.NoError(t, ps.MarkInstanceAvailable("i4"))
+	p, _, err := ps.Placement()
+	require.NoError(t, err)
+	require.Equal(t, 3, p.NumInstances())
+
+	// The limit allows no other replacement within the hour
+	clk.advance(time.Hour - time.Second)
+	c.check()
+	p, _, err = ps.Placement()
+	require.NoError(t, err)
+	require.Equal(t, 3, p.NumInstances())
+
+	clk.advance(time.Second)
+	c.check()
+	p, _, err = ps.Placement()
+	require.NoError(t, err)
+	require.Equal(t, 4, p.NumInstances())
+	require.Len(t, replacedAtNanos(t, store), 1)
+}
+
+func TestControllerCheckMaxReplacementsPerHourAcrossLeaders(t *testing.T) {
+	ps := newTestPlacementService(t)
+	clk := newTestClock()
+	store := mem.NewStore()
+	opts := testOptions(t, ps).
+		SetClockOptions(clock.NewOptions().SetNowFn(clk.now)).
+		SetKVStore(store)
+	c1 := newTestController(t, opts)
+	c1.alive = map[string]struct{}{"i1": {}, "i2": {}}
+
+	c1.check()
+	clk.advance(time.Hour)
+	c1.check()
+	require.NoError(t, ps.MarkInstanceAvailable("i4"))
+	p, _, err := ps.Placement()
+	require.NoError(t, err)
+	require.Equal(t, 3, p.NumInstances())
+	require.Len(t, replacedAtNanos(t, store), 1)
+
+	// A new leader knows about the replacements made by the previous one
+	c2 := newTestController(t, opts)
+	c2.alive = map[string]struct{}{"i1": {}}
+	c2.check()
+	clk.advance(time.Hour - time.Second)
+	c2.check()
+	p, _, err = ps.Placement()
+	require.NoError(t, err)
+	require.Equal(t, 3, p.NumInstances())
+
+	clk.advance(time.Second)
+	c2.check()
+	p, _, err = ps.Placement()
+	require.NoError(t, err)
+	require.Equal(t, 4, p.NumInstances())
+	i2, ok := p.Instance("i2")
+	require.True(t, ok)
+	require.True(t, i2.IsLeaving())
+}
+
+func TestControllerCheckLeaderRegained(t *testing.T) {
+	ps := newTestPlacementService(t)
+	clk := newTestClock()
+	c := newTestController(t, testOptions(t, ps).SetClockOptions(clock.NewOptions().SetNowFn(clk.now)))
+	c.updateAlive([]string{"i1", "i2", "i3"})
+	c.check()
+
+	// While following, i3 heartbeats for a long time before it stops
+	clk.advance(time.Hour)
+	c.updateAlive([]string{"i1", "i2"})
+
+	// On regaining leadership i3 is still given the full grace period
+	c.check()
+	clk.advance(time.Minute - time.Second)
+	c.check()
+	p, _, err := ps.Placement()
+	require.NoError(t, err)
+	require.Equal(t, 3, p.NumInstances())
+
+	clk.advance(time.Second)
+	c.check()
+	p, _, err = ps.Placement()
+	require.NoError(t, err)
+	require.Equal(t, 4, p.NumInstances())
+	i3, ok := p.Instance("i3")
+	require.True(t, ok)
+	require.True(t, i3.IsLeaving())
+}
+
+func newTestPlacementService(t *testing.T) placement.Service {
+	opts := placement.NewOptions().SetValidZone("z1")
+	ps := service.NewPlacementService(storage.NewPlacementStorage(mem.NewStore(), "placement", opts), opts)
+	_, err := ps.BuildInitialPlacement([]placement.Instance{
+		placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1),
+		placement.NewEmptyInstance("i2", "r2", "z1", "endpoint", 1),
+		placement.NewEmptyInstance("i3", "r3", "z1", "endpoint", 1),
+	}, 6, 1)
+	require.NoError(t, err)
+	return ps
+}
+
+func testOptions(t *testing.T, ps placement.Service) Options {
+	return NewOptions().
+		SetPlacementService(ps).
+		SetHeartbeatService(noopHeartbeatService{}).
+		SetLeaderService(noopLeaderService{}).
+		SetElectionID("e1").
+		SetCampaignOptions(testCampaignOptions(t)).
+		SetCandidates([]placement.Instance{
+			placement.NewEmptyInstance("i4", "r4", "z1", "endpoint", 1),
+			placement.NewEmptyInstance("i5", "r5", "z1", "endpoint", 1),
+		}).
+		SetGracePeriod(time.Minute).
+		SetCheckInterval(time.Millisecond).
+		SetKVStore(mem.NewStore()).
+		SetPauseKey("pause")
+}
+
+func testCampaignOptions(t *testing.T) services.CampaignOptions {
+	opts, err := services.NewCampaignOptions()
+	require.NoError(t, err)
+	return opts
+}
+
+func replacedAtNanos(t *testing.T, store kv.Store) []int64 {
+	v, err := store.Get("pause/replacements")
+	require.NoError(t, err)
+
+	var replacements controllerpb.Replacements
+	require.NoError(t, v.Unmarshal(&replacements))
+	return replacements.ReplacedAtNanos
+}
+
+func newTestController(t *testing.T, opts Options) *controller {
+	c, err := NewController(opts)
+	require.NoError(t, err)
+	return c.(*controller)
+}
+
+type testClock struct {
+	sync.Mutex
+
+	t time.Time
+}
+
+func newTestClock() *testClock {
+	return &testClock{t: time.Unix(1000, 0)}
+}
+
+func (c *testClock) now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.t
+}
+
+func (c *testClock) advance(d time.Duration) {
+	c.Lock()
+	c.t = c.t.Add(d)
+	c.Unlock()
+}
+
+type noopHeartbeatService struct {
+	services.HeartbeatService
+}
+
+type noopLeaderService struct {
+	services.LeaderService
+}
@@ -0,0 +1,319 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package controller
+
+import (
+	"errors"
+	"time"
+
+	"github.com/m3db/m3cluster/kv"
+	"github.com/m3db/m3cluster/placement"
+	"github.com/m3db/m3cluster/services"
+	"github.com/m3db/m3x/clock"
+	"github.com/m3db/m3x/instrument"
+)
+
+const (
+	defaultGracePeriod            = 10 * time.Minute
+	defaultCheckInterval          = 10 * time.Second
+	defaultMaxReplacementsPerHour = 1
+)
+
+var (
+	errPlacementServiceNotSet        = errors.New("placement service must be specified")
+	errHeartbeatServiceNotSet        = errors.New("heartbeat service must be specified")
+	errLeaderServiceNotSet           = errors.New("leader service must be specified")
+	errElectionIDNotSet              = errors.New("election id must be specified")
+	errCampaignOptionsNotSet         = errors.New("campaign options must be specified")
+	errCandidatesNotSet              = errors.New("candidates must be specified")
+	errKVStoreNotSet                 = errors.New("kv store must be specified")
+	errPauseKeyNotSet                = errors.New("pause key must be specified")
+	errInvalidGracePeriod            = errors.New("grace period must be positive")
+	errInvalidCheckInterval          = errors.New("check interval must be positive")
+	errInvalidMaxReplacementsPerHour = errors.New("max replacements per hour must be positive")
+)
+
+// Options are the options for the replacement controller
+type Options interface {
+	// PlacementService is the service used to replace the dead instances
+	PlacementService() placement.Service
+	SetPlacementService(value placement.Service) Options
+
+	// HeartbeatService is the service watched for the heartbeating instances
+	HeartbeatService() services.HeartbeatService
+	SetHeartbeatService(value services.HeartbeatService) Options
+
+	// LeaderService is the service used to elect the controller that replaces
+	// the dead instances
+	LeaderService() services.LeaderService
+	SetLeaderService(value services.LeaderService) Options
+
+	// ElectionID is the election the controllers campaign in
+	ElectionID() string
+	SetElectionID(value string) Options
+
+	// CampaignOptions are the options used when campaigning for leadership
+	CampaignOptions() services.CampaignOptions
+	SetCampaignOptions(value services.CampaignOptions) Options
+
+	// Candidates is the pool of instances the replacements are selected from
+	Candidates() []placement.Instance
+	SetCandidates(value []placement.Instance) Options
+
+	// GracePeriod is how long an instance may not heartbeat before it is replaced
+	GracePeriod() time.Duration
+	SetGracePeriod(value time.Duration) Options
+
+	// CheckInterval is how often the leader checks for dead instances
+	CheckInterval() time.Duration
+	SetCheckInterval(value time.Duration) Options
+
+	// MaxReplacementsPerHour is the maximum number of instances replaced in an hour
+	MaxReplacementsPerHour() int
+	SetMaxReplacementsPerHour(value int) Options
+
+	// KVStore is the store holding the pause switch and the times of the recent
+	// replacements
+	KVStore() kv.Store
+	SetKVStore(value kv.Store) Options
+
+	// PauseKey is the key of the pause switch, a commonpb.BoolProto which pauses
+	// the replacements when true. The replacement times are kept at
+	// PauseKey/replacements
+	PauseKey() string
+	SetPauseKey(value string) Options
+
+	// ClockOptions are the clock options
+	ClockOptions() clock.Options
+	SetClockOptions(value clock.Options) Options
+
+	// InstrumentOptions are the instrument options
+	InstrumentOptions() instrument.Options
+	SetInstrumentOptions(value instrument.Options) Options
+
+	// Validate validates the options
+	Validate() error
+}
+
+type options struct {
+	placementService       placement.Service
+	hbService              services.HeartbeatService
+	leaderService          services.LeaderService
+	electionID             string
+	campaignOpts           services.CampaignOptions
+	candidates             []placement.Instance
+	gracePeriod            time.Duration
+	checkInterval          time.Duration
+	maxReplacementsPerHour int
+	store                  kv.Store
+	pauseKey               string
+	clockOpts              clock.Options
+	instrumentOpts         instrument.Options
+}
+
+// NewOptions creates a new set of options for the replacement controller
+func NewOptions() Options {
+	return &options{
+		gracePeriod:            defaultGracePeriod,
+		checkInterval:          defaultCheckInterval,
+		maxReplacementsPerHour: defaultMaxReplacementsPerHour,
+		clockOpts:              clock.NewOptions(),
+		instrumentOpts:         instrument.NewOptions(),
+	}
+}
+
+func (o *options) PlacementService() placement.Service {
+	return o.placementService
+}
+
+func (o *options) SetPlacementService(value placement.Service) Options {
+	opts := *o
+	opts.placementService = value
+	return &opts
+}
+
+func (o *options) HeartbeatService() services.HeartbeatService {
+	return o.hbService
+}
+
+func (o *options) SetHeartbeatService(value services.HeartbeatService) Options {
+	opts := *o
+	opts.hbService = value
+	return &opts
+}
+
+func (o *options) LeaderService() services.LeaderService {
+	return o.leaderService
+}
+
+func (o *options) SetLeaderService(value services.LeaderService) Options {
+	opts := *o
+	opts.leaderService = value
+	return &opts
+}
+
+func (o *options) ElectionID() string {
+	return o.electionID
+}
+
+func (o *options) SetElectionID(value string) Options {
+	opts := *o
+	opts.electionID = value
+	return &opts
+}
+
+func (o *options) CampaignOptions() services.CampaignOptions {
+	return o.campaignOpts
+}
+
+func (o *options) SetCampaignOptions(value services.CampaignOptions) Options {
+	opts := *o
+	opts.campaignOpts = value
+	return &opts
+}
+
+func (o *options) Candidates() []placement.Instance {
+	return o.candidates
+}
+
+func (o *options) SetCandidates(value []placement.Instance) Options {
+	opts := *o
+	opts.candidates = value
+	return &opts
+}
+
+func (o *options) GracePeriod() time.Duration {
+	return o.gracePeriod
+}
+
+func (o *options) SetGracePeriod(value time.Duration) Options {
+	opts := *o
+	opts.gracePeriod = value
+	return &opts
+}
+
+func (o *options) CheckInterval() time.Duration {
+	return o.checkInterval
+}
+
+func (o *options) SetCheckInterval(value time.Duration) Options {
+	opts := *o
+	opts.checkInterval = value
+	return &opts
+}
+
+func (o *options) MaxReplacementsPerHour() int {
+	return o.maxReplacementsPerHour
+}
+
+func (o *options) SetMaxReplacementsPerHour(value int) Options {
+	opts := *o
+	opts.maxReplacementsPerHour = value
+	return &opts
+}
+
+func (o *options) KVStore() kv.Store {
+	return o.store
+}
+
+func (o *options) SetKVStore(value kv.Store) Options {
+	opts := *o
+	opts.store = value
+	return &opts
+}
+
+func (o *options) PauseKey() string {
+	return o.pauseKey
+}
+
+func (o *options) SetPauseKey(value string) Options {
+	opts := *o
+	opts.pauseKey = value
+	return &opts
+}
+
+func (o *options) ClockOptions() clock.Options {
+	return o.clockOpts
+}
+
+func (o *options) SetClockOptions(value clock.Options) Options {
+	opts := *o
+	opts.clockOpts = value
+	return &opts
+}
+
+func (o *options) InstrumentOptions() instrument.Options {
+	return o.instrumentOpts
+}
+
+func (o *options) SetInstrumentOptions(value instrument.Options) Options {
+	opts := *o
+	opts.instrumentOpts = value
+	return &opts
+}
+
+func (o *options) Validate() error {
+	if o.placementService == nil {
+		return errPlacementServiceNotSet
+	}
+
+	if o.hbService == nil {
+		return errHeartbeatServiceNotSet
+	}
+
+	if o.leaderService == nil {
+		return errLeaderServiceNotSet
+	}
+
+	if o.electionID == "" {
+		return errElectionIDNotSet
+	}
+
+	if o.campaignOpts == nil {
+		return errCampaignOptionsNotSet
+	}
+
+	if len(o.candidates) == 0 {
+		return errCandidatesNotSet
+	}
+
+	if o.store == nil {
+		return errKVStoreNotSet
+	}
+
+	if o.pauseKey == "" {
+		return errPauseKeyNotSet
+	}
+
+	if o.gracePeriod <= 0 {
+		return errInvalidGracePeriod
+	}
+
+	if o.checkInterval <= 0 {
+		return errInvalidCheckInterval
+	}
+
+	if o.maxReplacementsPerHour <= 0 {
+		return errInvalidMaxReplacementsPerHour
+	}
+
+	return nil
+}
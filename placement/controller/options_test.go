@@ -0,0 +1,72 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package controller
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/m3db/m3cluster/kv/mem"
+	"github.com/m3db/m3cluster/placement"
+	"github.com/m3db/m3cluster/services"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptionsValidate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	opts := NewOptions()
+	require.Equal(t, errPlacementServiceNotSet, opts.Validate())
+
+	opts = opts.SetPlacementService(placement.NewMockService(ctrl))
+	require.Equal(t, errHeartbeatServiceNotSet, opts.Validate())
+
+	opts = opts.SetHeartbeatService(services.NewMockHeartbeatService(ctrl))
+	require.Equal(t, errLeaderServiceNotSet, opts.Validate())
+
+	opts = opts.SetLeaderService(services.NewMockLeaderService(ctrl))
+	require.Equal(t, errElectionIDNotSet, opts.Validate())
+
+	opts = opts.SetElectionID("e1")
+	require.Equal(t, errCampaignOptionsNotSet, opts.Validate())
+
+	campaignOpts, err := services.NewCampaignOptions()
+	require.NoError(t, err)
+	opts = opts.SetCampaignOptions(campaignOpts)
+	require.Equal(t, errCandidatesNotSet, opts.Validate())
+
+	opts = opts.SetCandidates([]placement.Instance{
+		placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1),
+	})
+	require.Equal(t, errKVStoreNotSet, opts.Validate())
+
+	opts = opts.SetKVStore(mem.NewStore())
+	require.Equal(t, errPauseKeyNotSet, opts.Validate())
+
+	opts = opts.SetPauseKey("pause")
+	require.NoError(t, opts.Validate())
+
+	require.Equal(t, errInvalidGracePeriod, opts.SetGracePeriod(0).Validate())
+	require.Equal(t, errInvalidCheckInterval, opts.SetCheckInterval(0).Validate())
+	require.Equal(t, errInvalidMaxReplacementsPerHour, opts.SetMaxReplacementsPerHour(0).Validate())
+}
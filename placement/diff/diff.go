@@ -0,0 +1,596 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package diff computes what changed between two placements, in a form that
+// can be serialized for reviews, alerting and change logs.
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/m3db/m3cluster/placement"
+	"github.com/m3db/m3cluster/shard"
+)
+
+// Int64Change is a change to an integer value
+type Int64Change struct {
+	From int64 `json:"from"`
+	To   int64 `json:"to"`
+}
+
+// BoolChange is a change to a boolean value
+type BoolChange struct {
+	From bool `json:"from"`
+	To   bool `json:"to"`
+}
+
+// ZoneReplicasChange is a change to the number of replicas placed in a zone
+type ZoneReplicasChange struct {
+	Zone string `json:"zone"`
+	From int    `json:"from"`
+	To   int    `json:"to"`
+}
+
+// FieldChange is a change to an attribute of an instance
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// InstanceInfo describes an instance added to or removed from a placement
+type InstanceInfo struct {
+	ID         string   `json:"id"`
+	Endpoint   string   `json:"endpoint"`
+	Hostname   string   `json:"hostname,omitempty"`
+	Port       uint32   `json:"port,omitempty"`
+	Region     string   `json:"region,omitempty"`
+	Zone       string   `json:"zone"`
+	Rack       string   `json:"rack"`
+	Weight     uint32   `json:"weight"`
+	ShardSetID uint32   `json:"shardSetID,omitempty"`
+	Shards     []uint32 `json:"shards,omitempty"`
+}
+
+// InstanceChange describes the attributes of an instance that changed
+type InstanceChange struct {
+	ID      string        `json:"id"`
+	Changes []FieldChange `json:"changes"`
+}
+
+// ShardMove describes a shard replica moving between instances.  From is
+// empty if the replica is new, and To is empty if the replica was dropped
+type ShardMove struct {
+	Shard    uint32 `json:"shard"`
+	From     string `json:"from,omitempty"`
+	To       string `json:"to,omitempty"`
+	SourceID string `json:"sourceID,omitempty"`
+}
+
+// ShardStateChange describes the state of a shard on an instance changing.
+// From is empty if the instance did not own the shard before, and To is
+// empty if the instance no longer owns it
+type ShardStateChange struct {
+	Instance string `json:"instance"`
+	Shard    uint32 `json:"shard"`
+	From     string `json:"from,omitempty"`
+	To       string `json:"to,omitempty"`
+}
+
+// ShardCutoverChange describes the cutover or cutoff time of a shard on an
+// instance changing
+type ShardCutoverChange struct {
+	Instance     string       `json:"instance"`
+	Shard        uint32       `json:"shard"`
+	CutoverNanos *Int64Change `json:"cutoverNanos,omitempty"`
+	CutoffNanos  *Int64Change `json:"cutoffNanos,omitempty"`
+}
+
+// PlacementDiff describes the changes between two placements.  Instances
+// and shards are listed in ascending order so that diffs are stable
+type PlacementDiff struct {
+	FromVersion         int                  `json:"fromVersion"`
+	ToVersion           int                  `json:"toVersion"`
+	ReplicaFactor       *Int64Change         `json:"replicaFactor,omitempty"`
+	NumShards           *Int64Change         `json:"numShards,omitempty"`
+	IsSharded           *BoolChange          `json:"isSharded,omitempty"`
+	IsMirrored          *BoolChange          `json:"isMirrored,omitempty"`
+	CutoverNanos        *Int64Change         `json:"cutoverNanos,omitempty"`
+	ZoneReplicas        []ZoneReplicasChange `json:"zoneReplicas,omitempty"`
+	InstancesAdded      []InstanceInfo       `json:"instancesAdded,omitempty"`
+	InstancesRemoved    []InstanceInfo       `json:"instancesRemoved,omitempty"`
+	InstancesChanged    []InstanceChange     `json:"instancesChanged,omitempty"`
+	ShardMoves          []ShardMove          `json:"shardMoves,omitempty"`
+	ShardStateChanges   []ShardStateChange   `json:"shardStateChanges,omitempty"`
+	ShardCutoverChanges []ShardCutoverChange `json:"shardCutoverChanges,omitempty"`
+
+	// PendingShardMovesAdded and PendingShardMovesRemoved are the shard moves
+	// queued by a throttled placement that were added or removed, either
+	// because they started or because they were dropped
+	PendingShardMovesAdded   []ShardMove `json:"pendingShardMovesAdded,omitempty"`
+	PendingShardMovesRemoved []ShardMove `json:"pendingShardMovesRemoved,omitempty"`
+}
+
+// Placements computes the changes going from one placement to another. A
+// nil placement is treated as an empty placement
+func Placements(from, to placement.Placement) PlacementDiff {
+	if from == nil {
+		from = placement.NewPlacement()
+	}
+
+	if to == nil {
+		to = placement.NewPlacement()
+	}
+
+	d := PlacementDiff{
+		FromVersion:   from.GetVersion(),
+		ToVersion:     to.GetVersion(),
+		ReplicaFactor: int64Change(int64(from.ReplicaFactor()), int64(to.ReplicaFactor())),
+		NumShards:     int64Change(int64(from.NumShards()), int64(to.NumShards())),
+		IsSharded:     boolChange(from.IsSharded(), to.IsSharded()),
+		IsMirrored:    boolChange(from.IsMirrored(), to.IsMirrored()),
+		CutoverNanos:  int64Change(from.CutoverNanos(), to.CutoverNanos()),
+	}
+
+	d.diffZoneReplicas(from.ZoneReplicas(), to.ZoneReplicas())
+	d.diffInstances(from, to)
+	d.diffShards(from, to)
+	d.diffPendingShardMoves(from.PendingShardMoves(), to.PendingShardMoves())
+	return d
+}
+
+// Versions computes the changes going from one version of the placement in
+// the storage to another
+func Versions(s placement.Storage, fromVersion, toVersion int) (PlacementDiff, error) {
+	from, err := placementForVersion(s, fromVersion)
+	if err != nil {
+		return PlacementDiff{}, err
+	}
+
+	to, err := placementForVersion(s, toVersion)
+	if err != nil {
+		return PlacementDiff{}, err
+	}
+
+	return Placements(from, to), nil
+}
+
+// IsEmpty returns true if nothing changed between the placements, other
+// than their versions
+func (d PlacementDiff) IsEmpty() bool {
+	return d.ReplicaFactor == nil &&
+		d.NumShards == nil &&
+		d.IsSharded == nil &&
+		d.IsMirrored == nil &&
+		d.CutoverNanos == nil &&
+		len(d.ZoneReplicas) == 0 &&
+		len(d.InstancesAdded) == 0 &&
+		len(d.InstancesRemoved) == 0 &&
+		len(d.InstancesChanged) == 0 &&
+		len(d.ShardMoves) == 0 &&
+		len(d.ShardStateChanges) == 0 &&
+		len(d.ShardCutoverChanges) == 0 &&
+		len(d.PendingShardMovesAdded) == 0 &&
+		len(d.PendingShardMovesRemoved) == 0
+}
+
+// String returns a human readable summary of the diff, one change per line
+func (d PlacementDiff) String() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "placement version %d -> %d\n", d.FromVersion, d.ToVersion)
+
+	if d.ReplicaFactor != nil {
+		fmt.Fprintf(&buf, "~ replica factor: %d -> %d\n", d.ReplicaFactor.From, d.ReplicaFactor.To)
+	}
+	if d.NumShards != nil {
+		fmt.Fprintf(&buf, "~ shards: %d -> %d\n", d.NumShards.From, d.NumShards.To)
+	}
+	if d.IsSharded != nil {
+		fmt.Fprintf(&buf, "~ sharded: %v -> %v\n", d.IsSharded.From, d.IsSharded.To)
+	}
+	if d.IsMirrored != nil {
+		fmt.Fprintf(&buf, "~ mirrored: %v -> %v\n", d.IsMirrored.From, d.IsMirrored.To)
+	}
+	if d.CutoverNanos != nil {
+		fmt.Fprintf(&buf, "~ cutover nanos: %d -> %d\n", d.CutoverNanos.From, d.CutoverNanos.To)
+	}
+	for _, c := range d.ZoneReplicas {
+		fmt.Fprintf(&buf, "~ zone %s replicas: %d -> %d\n", c.Zone, c.From, c.To)
+	}
+	for _, i := range d.InstancesAdded {
+		fmt.Fprintf(&buf, "+ instance %s (%s, zone %s, rack %s, weight %d)\n",
+			i.ID, i.Endpoint, i.Zone, i.Rack, i.Weight)
+	}
+	for _, i := range d.InstancesRemoved {
+		fmt.Fprintf(&buf, "- instance %s (%s, zone %s, rack %s, weight %d)\n",
+			i.ID, i.Endpoint, i.Zone, i.Rack, i.Weight)
+	}
+	for _, i := range d.InstancesChanged {
+		for _, c := range i.Changes {
+			fmt.Fprintf(&buf, "~ instance %s %s: %q -> %q\n", i.ID, c.Field, c.From, c.To)
+		}
+	}
+	for _, m := range d.ShardMoves {
+		from, to := m.From, m.To
+		if from == "" {
+			from = "(new)"
+		}
+		if to == "" {
+			to = "(dropped)"
+		}
+		fmt.Fprintf(&buf, "> shard %d: %s -> %s\n", m.Shard, from, to)
+	}
+	for _, c := range d.ShardStateChanges {
+		fmt.Fprintf(&buf, "~ shard %d on %s: %s -> %s\n", c.Shard, c.Instance, stateOrNone(c.From), stateOrNone(c.To))
+	}
+	for _, c := range d.ShardCutoverChanges {
+		if c.CutoverNanos != nil {
+			fmt.Fprintf(&buf, "~ shard %d on %s cutover nanos: %d -> %d\n",
+				c.Shard, c.Instance, c.CutoverNanos.From, c.CutoverNanos.To)
+		}
+		if c.CutoffNanos != nil {
+			fmt.Fprintf(&buf, "~ shard %d on %s cutoff nanos: %d -> %d\n",
+				c.Shard, c.Instance, c.CutoffNanos.From, c.CutoffNanos.To)
+		}
+	}
+	for _, m := range d.PendingShardMovesAdded {
+		fmt.Fprintf(&buf, "+ pending shard %d: %s -> %s\n", m.Shard, m.From, m.To)
+	}
+	for _, m := range d.PendingShardMovesRemoved {
+		fmt.Fprintf(&buf, "- pending shard %d: %s -> %s\n", m.Shard, m.From, m.To)
+	}
+
+	return buf.String()
+}
+
+func (d *PlacementDiff) diffZoneReplicas(from, to map[string]int) {
+	seen := make(map[string]struct{}, len(from)+len(to))
+	for zone := range from {
+		seen[zone] = struct{}{}
+	}
+	for zone := range to {
+		seen[zone] = struct{}{}
+	}
+
+	zones := make([]string, 0, len(seen))
+	for zone := range seen {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+
+	for _, zone := range zones {
+		if from[zone] != to[zone] {
+			d.ZoneReplicas = append(d.ZoneReplicas, ZoneReplicasChange{Zone: zone, From: from[zone], To: to[zone]})
+		}
+	}
+}
+
+func (d *PlacementDiff) diffInstances(from, to placement.Placement) {
+	for _, instance := range to.Instances() {
+		prev, ok := from.Instance(instance.ID())
+		if !ok {
+			d.InstancesAdded = append(d.InstancesAdded, newInstanceInfo(instance))
+			continue
+		}
+
+		if changes := diffInstanceFields(prev, instance); len(changes) > 0 {
+			d.InstancesChanged = append(d.InstancesChanged, InstanceChange{
+				ID:      instance.ID(),
+				Changes: changes,
+			})
+		}
+	}
+
+	for _, instance := range from.Instances() {
+		if _, ok := to.Instance(instance.ID()); !ok {
+			d.InstancesRemoved = append(d.InstancesRemoved, newInstanceInfo(instance))
+		}
+	}
+}
+
+func diffInstanceFields(from, to placement.Instance) []FieldChange {
+	var changes []FieldChange
+	add := func(field, fromValue, toValue string) {
+		if fromValue != toValue {
+			changes = append(changes, FieldChange{Field: field, From: fromValue, To: toValue})
+		}
+	}
+
+	add("endpoint", from.Endpoint(), to.Endpoint())
+	add("hostname", from.Hostname(), to.Hostname())
+	add("port", formatUint(from.Port()), formatUint(to.Port()))
+	add("region", from.Region(), to.Region())
+	add("zone", from.Zone(), to.Zone())
+	add("rack", from.Rack(), to.Rack())
+	add("weight", formatUint(from.Weight()), formatUint(to.Weight()))
+	add("shardSetID", formatUint(from.ShardSetID()), formatUint(to.ShardSetID()))
+	return changes
+}
+
+func (d *PlacementDiff) diffShards(from, to placement.Placement) {
+	var (
+		fromOwners = shardOwners(from)
+		toOwners   = shardOwners(to)
+	)
+
+	for _, id := range shardIDs(fromOwners, toOwners) {
+		var (
+			before = fromOwners[id]
+			after  = toOwners[id]
+		)
+
+		// Instances that gained a replica of the shard, and those that stopped
+		// serving one, either by dropping it or by starting to hand it off
+		var gained, lost []string
+		for _, instanceID := range instanceIDs(before, after) {
+			prev, hadShard := before[instanceID]
+			curr, hasShard := after[instanceID]
+
+			switch {
+			case hasShard && !hadShard:
+				d.ShardStateChanges = append(d.ShardStateChanges, ShardStateChange{
+					Instance: instanceID,
+					Shard:    id,
+					To:       curr.State().String(),
+				})
+				if curr.State() != shard.Leaving {
+					gained = append(gained, instanceID)
+				}
+			case hadShard && !hasShard:
+				d.ShardStateChanges = append(d.ShardStateChanges, ShardStateChange{
+					Instance: instanceID,
+					Shard:    id,
+					From:     prev.State().String(),
+				})
+				if prev.State() != shard.Leaving {
+					lost = append(lost, instanceID)
+				}
+			default:
+				if prev.State() != curr.State() {
+					d.ShardStateChanges = append(d.ShardStateChanges, ShardStateChange{
+						Instance: instanceID,
+						Shard:    id,
+						From:     prev.State().String(),
+						To:       curr.State().String(),
+					})
+					if curr.State() == shard.Leaving {
+						lost = append(lost, instanceID)
+					}
+				}
+
+				d.diffShardCutover(instanceID, prev, curr)
+			}
+		}
+
+		d.pairMoves(id, gained, lost, after)
+	}
+}
+
+// pairMoves matches the instances that gained a replica of a shard with the
+// instances that lost one.  An explicit source is used where the placement
+// records one, and any remaining instances are paired in ascending order
+func (d *PlacementDiff) pairMoves(id uint32, gained, lost []string, after map[string]shard.Shard) {
+	unmatched := make(map[string]bool, len(lost))
+	for _, instanceID := range lost {
+		unmatched[instanceID] = true
+	}
+
+	var unsourced []string
+	for _, instanceID := range gained {
+		sourceID := after[instanceID].SourceID()
+		if sourceID != "" && unmatched[sourceID] {
+			delete(unmatched, sourceID)
+			d.ShardMoves = append(d.ShardMoves, ShardMove{Shard: id, From: sourceID, To: instanceID, SourceID: sourceID})
+			continue
+		}
+
+		unsourced = append(unsourced, instanceID)
+	}
+
+	var remaining []string
+	for _, instanceID := range lost {
+		if unmatched[instanceID] {
+			remaining = append(remaining, instanceID)
+		}
+	}
+
+	for _, instanceID := range unsourced {
+		move := ShardMove{Shard: id, To: instanceID, SourceID: after[instanceID].SourceID()}
+		if len(remaining) > 0 {
+			move.From = remaining[0]
+			remaining = remaining[1:]
+		}
+		d.ShardMoves = append(d.ShardMoves, move)
+	}
+
+	for _, instanceID := range remaining {
+		d.ShardMoves = append(d.ShardMoves, ShardMove{Shard: id, From: instanceID})
+	}
+}
+
+func (d *PlacementDiff) diffPendingShardMoves(from, to []placement.PendingShardMove) {
+	var (
+		before = pendingMoveSet(from)
+		after  = pendingMoveSet(to)
+	)
+
+	for move := range after {
+		if _, ok := before[move]; !ok {
+			d.PendingShardMovesAdded = append(d.PendingShardMovesAdded, move)
+		}
+	}
+	for move := range before {
+		if _, ok := after[move]; !ok {
+			d.PendingShardMovesRemoved = append(d.PendingShardMovesRemoved, move)
+		}
+	}
+
+	sort.Sort(shardMovesAscending(d.PendingShardMovesAdded))
+	sort.Sort(shardMovesAscending(d.PendingShardMovesRemoved))
+}
+
+func pendingMoveSet(moves []placement.PendingShardMove) map[ShardMove]struct{} {
+	set := make(map[ShardMove]struct{}, len(moves))
+	for _, m := range moves {
+		set[ShardMove{Shard: m.ShardID, From: m.SourceID, To: m.TargetID}] = struct{}{}
+	}
+	return set
+}
+
+func (d *PlacementDiff) diffShardCutover(instanceID string, from, to shard.Shard) {
+	var (
+		cutover = int64Change(from.CutoverNanos(), to.CutoverNanos())
+		cutoff  = int64Change(from.CutoffNanos(), to.CutoffNanos())
+	)
+
+	if cutover == nil && cutoff == nil {
+		return
+	}
+
+	d.ShardCutoverChanges = append(d.ShardCutoverChanges, ShardCutoverChange{
+		Instance:     instanceID,
+		Shard:        to.ID(),
+		CutoverNanos: cutover,
+		CutoffNanos:  cutoff,
+	})
+}
+
+func placementForVersion(s placement.Storage, version int) (placement.Placement, error) {
+	ps, err := s.History(version, version+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(ps) == 0 {
+		return nil, fmt.Errorf("could not find placement of version %d", version)
+	}
+	return ps[0], nil
+}
+
+func newInstanceInfo(instance placement.Instance) InstanceInfo {
+	return InstanceInfo{
+		ID:         instance.ID(),
+		Endpoint:   instance.Endpoint(),
+		Hostname:   instance.Hostname(),
+		Port:       instance.Port(),
+		Region:     instance.Region(),
+		Zone:       instance.Zone(),
+		Rack:       instance.Rack(),
+		Weight:     instance.Weight(),
+		ShardSetID: instance.ShardSetID(),
+		Shards:     instance.Shards().AllIDs(),
+	}
+}
+
+// shardOwners maps each shard to the instances owning it, keyed by instance id
+func shardOwners(p placement.Placement) map[uint32]map[string]shard.Shard {
+	owners := make(map[uint32]map[string]shard.Shard)
+	for _, instance := range p.Instances() {
+		for _, s := range instance.Shards().All() {
+			byInstance, ok := owners[s.ID()]
+			if !ok {
+				byInstance = make(map[string]shard.Shard)
+				owners[s.ID()] = byInstance
+			}
+			byInstance[instance.ID()] = s
+		}
+	}
+
+	return owners
+}
+
+func shardIDs(a, b map[uint32]map[string]shard.Shard) []uint32 {
+	seen := make(map[uint32]struct{}, len(a)+len(b))
+	for id := range a {
+		seen[id] = struct{}{}
+	}
+	for id := range b {
+		seen[id] = struct{}{}
+	}
+
+	ids := make([]uint32, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Sort(placement.ShardIDsAscending(ids))
+	return ids
+}
+
+func instanceIDs(a, b map[string]shard.Shard) []string {
+	seen := make(map[string]struct{}, len(a)+len(b))
+	for id := range a {
+		seen[id] = struct{}{}
+	}
+	for id := range b {
+		seen[id] = struct{}{}
+	}
+
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func int64Change(from, to int64) *Int64Change {
+	if from == to {
+		return nil
+	}
+
+	return &Int64Change{From: from, To: to}
+}
+
+func boolChange(from, to bool) *BoolChange {
+	if from == to {
+		return nil
+	}
+
+	return &BoolChange{From: from, To: to}
+}
+
+func formatUint(v uint32) string {
+	return strconv.FormatUint(uint64(v), 10)
+}
+
+func stateOrNone(state string) string {
+	if state == "" {
+		return "(none)"
+	}
+
+	return state
+}
+
+type shardMovesAscending []ShardMove
+
+func (s shardMovesAscending) Len() int      { return len(s) }
+func (s shardMovesAscending) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+func (s shardMovesAscending) Less(i, j int) bool {
+	if s[i].Shard != s[j].Shard {
+		return s[i].Shard < s[j].Shard
+	}
+	if s[i].From != s[j].From {
+		return s[i].From < s[j].From
+	}
+	return s[i].To < s[j].To
+}
@@ -0,0 +1,284 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package diff
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/m3db/m3cluster/placement"
+	"github.com/m3db/m3cluster/shard"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlacementsIdentical(t *testing.T) {
+	p := testPlacement(
+		testInstance("i1", shard.NewShard(0).SetState(shard.Available)),
+		testInstance("i2", shard.NewShard(1).SetState(shard.Available)),
+	)
+
+	d := Placements(p, p.Clone())
+	require.True(t, d.IsEmpty())
+}
+
+func TestPlacementsFromNil(t *testing.T) {
+	to := testPlacement(
+		testInstance("i1", shard.NewShard(0).SetState(shard.Initializing)),
+	).SetVersion(1)
+
+	d := Placements(nil, to)
+	require.False(t, d.IsEmpty())
+	require.Equal(t, 1, d.ToVersion)
+	require.Equal(t, &Int64Change{From: 0, To: 1}, d.ReplicaFactor)
+	require.Equal(t, &Int64Change{From: 0, To: 1}, d.NumShards)
+	require.Equal(t, &BoolChange{From: false, To: true}, d.IsSharded)
+	require.Len(t, d.InstancesAdded, 1)
+	require.Equal(t, "i1", d.InstancesAdded[0].ID)
+	require.Equal(t, []uint32{0}, d.InstancesAdded[0].Shards)
+	require.Equal(t, []ShardMove{{Shard: 0, To: "i1"}}, d.ShardMoves)
+	require.Equal(t, []ShardStateChange{
+		{Instance: "i1", Shard: 0, To: shard.Initializing.String()},
+	}, d.ShardStateChanges)
+}
+
+func TestVersions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	from := testPlacement(
+		testInstance("i1", shard.NewShard(0).SetState(shard.Available)),
+	).SetVersion(1)
+	to := testPlacement(
+		testInstance("i1", shard.NewShard(0).SetState(shard.Leaving)),
+		testInstance("i2", shard.NewShard(0).SetState(shard.Initializing).SetSourceID("i1")),
+	).SetVersion(2)
+
+	s := placement.NewMockStorage(ctrl)
+	s.EXPECT().History(1, 2).Return([]placement.Placement{from}, nil).AnyTimes()
+	s.EXPECT().History(2, 3).Return([]placement.Placement{to}, nil).AnyTimes()
+	s.EXPECT().History(3, 4).Return(nil, nil).AnyTimes()
+	s.EXPECT().History(4, 5).Return(nil, errors.New("bad request")).AnyTimes()
+
+	d, err := Versions(s, 1, 2)
+	require.NoError(t, err)
+	require.Equal(t, Placements(from, to), d)
+	require.Equal(t, 1, d.FromVersion)
+	require.Equal(t, 2, d.ToVersion)
+
+	_, err = Versions(s, 1, 3)
+	require.Error(t, err)
+
+	_, err = Versions(s, 4, 1)
+	require.Error(t, err)
+}
+
+func TestPlacementsShardMoveWithSourceID(t *testing.T) {
+	from := testPlacement(
+		testInstance("i1", shard.NewShard(0).SetState(shard.Available), shard.NewShard(1).SetState(shard.Available)),
+		testInstance("i2", shard.NewShard(2).SetState(shard.Available)),
+	)
+	to := testPlacement(
+		testInstance("i1", shard.NewShard(0).SetState(shard.Available), shard.NewShard(1).SetState(shard.Leaving)),
+		testInstance("i2", shard.NewShard(2).SetState(shard.Available)),
+		testInstance("i3", shard.NewShard(1).SetState(shard.Initializing).SetSourceID("i1")),
+	)
+
+	d := Placements(from, to)
+	require.Len(t, d.InstancesAdded, 1)
+	require.Equal(t, "i3", d.InstancesAdded[0].ID)
+	require.Empty(t, d.InstancesRemoved)
+	require.Equal(t, []ShardMove{
+		{Shard: 1, From: "i1", To: "i3", SourceID: "i1"},
+	}, d.ShardMoves)
+	require.Equal(t, []ShardStateChange{
+		{Instance: "i1", Shard: 1, From: shard.Available.String(), To: shard.Leaving.String()},
+		{Instance: "i3", Shard: 1, To: shard.Initializing.String()},
+	}, d.ShardStateChanges)
+
+	// Completing the move should not be reported as another move
+	done := testPlacement(
+		testInstance("i1", shard.NewShard(0).SetState(shard.Available)),
+		testInstance("i2", shard.NewShard(2).SetState(shard.Available)),
+		testInstance("i3", shard.NewShard(1).SetState(shard.Available)),
+	)
+
+	d = Placements(to, done)
+	require.Empty(t, d.ShardMoves)
+	require.Equal(t, []ShardStateChange{
+		{Instance: "i1", Shard: 1, From: shard.Leaving.String()},
+		{Instance: "i3", Shard: 1, From: shard.Initializing.String(), To: shard.Available.String()},
+	}, d.ShardStateChanges)
+}
+
+func TestPlacementsInstanceRemoved(t *testing.T) {
+	from := testPlacement(
+		testInstance("i1", shard.NewShard(0).SetState(shard.Available)),
+		testInstance("i2", shard.NewShard(1).SetState(shard.Available)),
+	)
+	to := testPlacement(
+		testInstance("i1", shard.NewShard(0).SetState(shard.Available), shard.NewShard(1).SetState(shard.Available)),
+	)
+
+	d := Placements(from, to)
+	require.Empty(t, d.InstancesAdded)
+	require.Len(t, d.InstancesRemoved, 1)
+	require.Equal(t, "i2", d.InstancesRemoved[0].ID)
+	require.Equal(t, []ShardMove{{Shard: 1, From: "i2", To: "i1"}}, d.ShardMoves)
+}
+
+func TestPlacementsInstanceChanged(t *testing.T) {
+	from := testPlacement(testInstance("i1", shard.NewShard(0).SetState(shard.Available)))
+	to := testPlacement(testInstance("i1", shard.NewShard(0).SetState(shard.Available)))
+	instance, ok := to.Instance("i1")
+	require.True(t, ok)
+	instance.SetEndpoint("i1:2").SetRegion("reg2").SetWeight(2)
+
+	d := Placements(from, to)
+	require.Equal(t, []InstanceChange{
+		{
+			ID: "i1",
+			Changes: []FieldChange{
+				{Field: "endpoint", From: "i1:1", To: "i1:2"},
+				{Field: "region", From: "", To: "reg2"},
+				{Field: "weight", From: "1", To: "2"},
+			},
+		},
+	}, d.InstancesChanged)
+	require.Empty(t, d.ShardMoves)
+}
+
+func TestPlacementsCutoverChanges(t *testing.T) {
+	from := testPlacement(
+		testInstance("i1", shard.NewShard(0).SetState(shard.Initializing).SetCutoverNanos(100)),
+	).SetCutoverNanos(100)
+	to := testPlacement(
+		testInstance("i1", shard.NewShard(0).SetState(shard.Initializing).SetCutoverNanos(200).SetCutoffNanos(300)),
+	).SetCutoverNanos(200)
+
+	d := Placements(from, to)
+	require.Equal(t, &Int64Change{From: 100, To: 200}, d.CutoverNanos)
+	require.Len(t, d.ShardCutoverChanges, 1)
+	c := d.ShardCutoverChanges[0]
+	require.Equal(t, "i1", c.Instance)
+	require.Equal(t, uint32(0), c.Shard)
+	require.Equal(t, &Int64Change{From: 100, To: 200}, c.CutoverNanos)
+	require.NotNil(t, c.CutoffNanos)
+	require.Equal(t, int64(300), c.CutoffNanos.To)
+}
+
+func TestPlacementsReplicaFactorChange(t *testing.T) {
+	from := testPlacement(
+		testInstance("i1", shard.NewShard(0).SetState(shard.Available)),
+		testInstance("i2"),
+	)
+	to := testPlacement(
+		testInstance("i1", shard.NewShard(0).SetState(shard.Available)),
+		testInstance("i2", shard.NewShard(0).SetState(shard.Initializing)),
+	).SetReplicaFactor(2)
+
+	d := Placements(from, to)
+	require.Equal(t, &Int64Change{From: 1, To: 2}, d.ReplicaFactor)
+	require.Equal(t, []ShardMove{{Shard: 0, To: "i2"}}, d.ShardMoves)
+}
+
+func TestPlacementsZoneReplicasChange(t *testing.T) {
+	from := testPlacement(
+		testInstance("i1", shard.NewShard(0).SetState(shard.Available)),
+	).SetZoneReplicas(map[string]int{"z1": 1})
+	to := testPlacement(
+		testInstance("i1", shard.NewShard(0).SetState(shard.Available)),
+	).SetZoneReplicas(map[string]int{"z1": 1, "z2": 1})
+
+	d := Placements(from, to)
+	require.False(t, d.IsEmpty())
+	require.Equal(t, []ZoneReplicasChange{{Zone: "z2", From: 0, To: 1}}, d.ZoneReplicas)
+	require.Contains(t, d.String(), "~ zone z2 replicas: 0 -> 1")
+	require.True(t, Placements(to, to.Clone()).IsEmpty())
+}
+
+func TestPlacementsPendingShardMoves(t *testing.T) {
+	from := testPlacement(
+		testInstance("i1", shard.NewShard(0).SetState(shard.Available), shard.NewShard(1).SetState(shard.Available)),
+		testInstance("i2"),
+	).SetPendingShardMoves([]placement.PendingShardMove{
+		{ShardID: 1, SourceID: "i1", TargetID: "i2"},
+		{ShardID: 0, SourceID: "i1", TargetID: "i2"},
+	})
+	to := from.Clone().SetPendingShardMoves([]placement.PendingShardMove{
+		{ShardID: 1, SourceID: "i1", TargetID: "i2"},
+		{ShardID: 2, SourceID: "i1", TargetID: "i2"},
+	})
+
+	d := Placements(from, to)
+	require.False(t, d.IsEmpty())
+	require.Equal(t, []ShardMove{{Shard: 2, From: "i1", To: "i2"}}, d.PendingShardMovesAdded)
+	require.Equal(t, []ShardMove{{Shard: 0, From: "i1", To: "i2"}}, d.PendingShardMovesRemoved)
+	require.Contains(t, d.String(), "+ pending shard 2: i1 -> i2")
+	require.Contains(t, d.String(), "- pending shard 0: i1 -> i2")
+	require.True(t, Placements(to, to.Clone()).IsEmpty())
+}
+
+func TestPlacementDiffSerialization(t *testing.T) {
+	from := testPlacement(
+		testInstance("i1", shard.NewShard(0).SetState(shard.Available), shard.NewShard(1).SetState(shard.Available)),
+	)
+	to := testPlacement(
+		testInstance("i1", shard.NewShard(0).SetState(shard.Available), shard.NewShard(1).SetState(shard.Leaving)),
+		testInstance("i2", shard.NewShard(1).SetState(shard.Initializing).SetSourceID("i1")),
+	).SetVersion(2)
+
+	d := Placements(from, to)
+	b, err := json.Marshal(d)
+	require.NoError(t, err)
+
+	var decoded PlacementDiff
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	require.Equal(t, d, decoded)
+	require.Contains(t, d.String(), "> shard 1: i1 -> i2")
+	require.Contains(t, d.String(), "+ instance i2")
+}
+
+func testInstance(id string, shards ...shard.Shard) placement.Instance {
+	return placement.NewEmptyInstance(id, "r-"+id, "z1", id+":1", 1).
+		SetShards(shard.NewShards(shards))
+}
+
+func testPlacement(instances ...placement.Instance) placement.Placement {
+	var ids []uint32
+	seen := make(map[uint32]bool)
+	for _, instance := range instances {
+		for _, id := range instance.Shards().AllIDs() {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	return placement.NewPlacement().
+		SetInstances(instances).
+		SetShards(ids).
+		SetReplicaFactor(1).
+		SetIsSharded(true)
+}
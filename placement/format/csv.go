@@ -0,0 +1,94 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package format
+
+import (
+	"bytes"
+	"encoding/csv"
+	"sort"
+	"strconv"
+
+	"github.com/m3db/m3cluster/placement"
+)
+
+var csvHeader = []string{
+	"shard", "instance", "state", "source_id", "zone", "rack", "endpoint", "cutover_nanos", "cutoff_nanos",
+}
+
+// PlacementToCSV exports the placement as a table with a row for each shard
+// owned by each instance, sorted by shard and instance id.
+func PlacementToCSV(p placement.Placement) ([]byte, error) {
+	canonical, err := newPlacement(p)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows shardRows
+	for _, instance := range canonical.Instances {
+		for _, s := range instance.Shards {
+			rows = append(rows, shardRow{instance: instance, shard: s})
+		}
+	}
+	sort.Sort(rows)
+
+	var (
+		buf bytes.Buffer
+		w   = csv.NewWriter(&buf)
+	)
+	if err := w.Write(csvHeader); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if err := w.Write([]string{
+			strconv.FormatUint(uint64(row.shard.ID), 10),
+			row.instance.ID,
+			row.shard.State,
+			row.shard.SourceID,
+			row.instance.Zone,
+			row.instance.Rack,
+			row.instance.Endpoint,
+			strconv.FormatInt(row.shard.CutoverNanos, 10),
+			strconv.FormatInt(row.shard.CutoffNanos, 10),
+		}); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+type shardRow struct {
+	instance Instance
+	shard    Shard
+}
+
+type shardRows []shardRow
+
+func (s shardRows) Len() int { return len(s) }
+
+func (s shardRows) Less(i, j int) bool {
+	if s[i].shard.ID != s[j].shard.ID {
+		return s[i].shard.ID < s[j].shard.ID
+	}
+	return s[i].instance.ID < s[j].instance.ID
+}
+
+func (s shardRows) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
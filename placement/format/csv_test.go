@@ -0,0 +1,43 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package format
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlacementToCSV(t *testing.T) {
+	b, err := PlacementToCSV(testPlacement())
+	require.NoError(t, err)
+
+	expected := `shard,instance,state,source_id,zone,rack,endpoint,cutover_nanos,cutoff_nanos
+0,i1,AVAILABLE,,z1,r1,endpoint1,0,0
+0,i2,AVAILABLE,,z1,r2,endpoint2,0,0
+1,i1,LEAVING,,z1,r1,endpoint1,0,200
+1,i2,INITIALIZING,i1,z1,r2,endpoint2,100,0
+1,i3,AVAILABLE,,z1,r3,endpoint3,0,0
+2,i1,AVAILABLE,,z1,r1,endpoint1,0,0
+2,i2,AVAILABLE,,z1,r2,endpoint2,0,0
+`
+	require.Equal(t, expected, string(b))
+}
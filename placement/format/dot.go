@@ -0,0 +1,159 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/m3db/m3cluster/generated/proto/placementpb"
+	"github.com/m3db/m3cluster/placement"
+)
+
+var dotEdgeStyles = map[string]string{
+	placementpb.ShardState_INITIALIZING.String(): "dashed",
+	placementpb.ShardState_AVAILABLE.String():    "solid",
+	placementpb.ShardState_LEAVING.String():      "dotted",
+}
+
+// PlacementToDOT exports the placement as a Graphviz graph. Instances are grouped
+// by zone and rack, and shards owned by the same instances in the same states are
+// grouped into a single node with edges to their owners, dashed for Initializing,
+// solid for Available and dotted for Leaving shards.
+func PlacementToDOT(p placement.Placement) ([]byte, error) {
+	canonical, err := newPlacement(p)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("digraph placement {\n")
+	buf.WriteString("  rankdir=LR;\n")
+	writeDOTInstances(&buf, canonical.Instances)
+	for i, group := range newShardGroups(canonical.Instances) {
+		node := "shards" + strconv.Itoa(i)
+		fmt.Fprintf(&buf, "  %s [shape=ellipse, label=%s];\n", strconv.Quote(node), strconv.Quote(group.label()))
+		for _, owner := range group.owners {
+			fmt.Fprintf(&buf, "  %s -> %s [style=%s];\n",
+				strconv.Quote(node), strconv.Quote(owner.instanceID), dotEdgeStyles[owner.state])
+		}
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes(), nil
+}
+
+func writeDOTInstances(buf *bytes.Buffer, instances []Instance) {
+	var (
+		domains  []string
+		byDomain = make(map[string][]Instance)
+	)
+	for _, instance := range instances {
+		domain := instance.Zone + "/" + instance.Rack
+		if _, ok := byDomain[domain]; !ok {
+			domains = append(domains, domain)
+		}
+		byDomain[domain] = append(byDomain[domain], instance)
+	}
+	sort.Strings(domains)
+
+	for _, domain := range domains {
+		fmt.Fprintf(buf, "  subgraph %s {\n", strconv.Quote("cluster_"+domain))
+		fmt.Fprintf(buf, "    label=%s;\n", strconv.Quote(domain))
+		for _, instance := range byDomain[domain] {
+			fmt.Fprintf(buf, "    %s [shape=box, label=%s];\n",
+				strconv.Quote(instance.ID), strconv.Quote(fmt.Sprintf("%s\n%s", instance.ID, instance.Endpoint)))
+		}
+		buf.WriteString("  }\n")
+	}
+}
+
+type shardOwner struct {
+	instanceID string
+	state      string
+}
+
+// shardGroup is a group of shards owned by the same instances in the same states.
+type shardGroup struct {
+	shards []uint32
+	owners []shardOwner
+}
+
+// label returns the shards in the group as ranges, e.g. "0-3,7".
+func (g shardGroup) label() string {
+	var ranges []string
+	for i := 0; i < len(g.shards); {
+		j := i
+		for j+1 < len(g.shards) && g.shards[j+1] == g.shards[j]+1 {
+			j++
+		}
+		if i == j {
+			ranges = append(ranges, strconv.FormatUint(uint64(g.shards[i]), 10))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", g.shards[i], g.shards[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(ranges, ",")
+}
+
+// newShardGroups returns the shard groups ordered by their smallest shard.
+func newShardGroups(instances []Instance) []shardGroup {
+	owners := make(map[uint32][]shardOwner)
+	for _, instance := range instances {
+		for _, s := range instance.Shards {
+			owners[s.ID] = append(owners[s.ID], shardOwner{instanceID: instance.ID, state: s.State})
+		}
+	}
+
+	shardIDs := make([]uint32, 0, len(owners))
+	for id := range owners {
+		shardIDs = append(shardIDs, id)
+	}
+	sort.Sort(placement.ShardIDsAscending(shardIDs))
+
+	var (
+		groups  []shardGroup
+		byOwner = make(map[string]int)
+	)
+	for _, id := range shardIDs {
+		key := ownersKey(owners[id])
+		idx, ok := byOwner[key]
+		if !ok {
+			idx = len(groups)
+			byOwner[key] = idx
+			groups = append(groups, shardGroup{owners: owners[id]})
+		}
+		groups[idx].shards = append(groups[idx].shards, id)
+	}
+	return groups
+}
+
+// ownersKey relies on the instances being sorted by id.
+func ownersKey(owners []shardOwner) string {
+	parts := make([]string, len(owners))
+	for i, owner := range owners {
+		parts[i] = owner.instanceID + ":" + owner.state
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package format
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlacementToDOT(t *testing.T) {
+	b, err := PlacementToDOT(testPlacement())
+	require.NoError(t, err)
+
+	expected := `digraph placement {
+  rankdir=LR;
+  subgraph "cluster_z1/r1" {
+    label="z1/r1";
+    "i1" [shape=box, label="i1\nendpoint1"];
+  }
+  subgraph "cluster_z1/r2" {
+    label="z1/r2";
+    "i2" [shape=box, label="i2\nendpoint2"];
+  }
+  subgraph "cluster_z1/r3" {
+    label="z1/r3";
+    "i3" [shape=box, label="i3\nendpoint3"];
+  }
+  "shards0" [shape=ellipse, label="0,2"];
+  "shards0" -> "i1" [style=solid];
+  "shards0" -> "i2" [style=solid];
+  "shards1" [shape=ellipse, label="1"];
+  "shards1" -> "i1" [style=dotted];
+  "shards1" -> "i2" [style=dashed];
+  "shards1" -> "i3" [style=solid];
+}
+`
+	require.Equal(t, expected, string(b))
+}
+
+func TestShardGroupLabel(t *testing.T) {
+	require.Equal(t, "0-3,5,7-8", shardGroup{shards: []uint32{0, 1, 2, 3, 5, 7, 8}}.label())
+	require.Equal(t, "", shardGroup{}.label())
+}
@@ -0,0 +1,359 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package format exports placements and staged placements in human readable
+// formats so they can be reviewed and versioned outside of kv. The JSON and
+// YAML formats are canonical, with instances and shards sorted by id, and
+// round trip to placementpb. The CSV and DOT formats are views for review.
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/m3db/m3cluster/generated/proto/placementpb"
+	"github.com/m3db/m3cluster/placement"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Placement is the canonical form of placementpb.Placement.
+type Placement struct {
+	Instances         []Instance         `json:"instances" yaml:"instances"`
+	ReplicaFactor     uint32             `json:"replicaFactor" yaml:"replicaFactor"`
+	NumShards         uint32             `json:"numShards" yaml:"numShards"`
+	IsSharded         bool               `json:"isSharded" yaml:"isSharded"`
+	CutoverTime       int64              `json:"cutoverTime,omitempty" yaml:"cutoverTime,omitempty"`
+	IsMirrored        bool               `json:"isMirrored,omitempty" yaml:"isMirrored,omitempty"`
+	ZoneReplicas      map[string]uint32  `json:"zoneReplicas,omitempty" yaml:"zoneReplicas,omitempty"`
+	PendingShardMoves []PendingShardMove `json:"pendingShardMoves,omitempty" yaml:"pendingShardMoves,omitempty"`
+}
+
+// Instance is the canonical form of placementpb.Instance.
+type Instance struct {
+	ID         string  `json:"id" yaml:"id"`
+	Rack       string  `json:"rack" yaml:"rack"`
+	Zone       string  `json:"zone" yaml:"zone"`
+	Region     string  `json:"region,omitempty" yaml:"region,omitempty"`
+	Weight     uint32  `json:"weight" yaml:"weight"`
+	Endpoint   string  `json:"endpoint" yaml:"endpoint"`
+	Hostname   string  `json:"hostname,omitempty" yaml:"hostname,omitempty"`
+	Port       uint32  `json:"port,omitempty" yaml:"port,omitempty"`
+	ShardSetID uint32  `json:"shardSetID,omitempty" yaml:"shardSetID,omitempty"`
+	Shards     []Shard `json:"shards" yaml:"shards"`
+}
+
+// Shard is the canonical form of placementpb.Shard, the state is the name
+// of the placementpb.ShardState.
+type Shard struct {
+	ID           uint32 `json:"id" yaml:"id"`
+	State        string `json:"state" yaml:"state"`
+	SourceID     string `json:"sourceID,omitempty" yaml:"sourceID,omitempty"`
+	CutoverNanos int64  `json:"cutoverNanos,omitempty" yaml:"cutoverNanos,omitempty"`
+	CutoffNanos  int64  `json:"cutoffNanos,omitempty" yaml:"cutoffNanos,omitempty"`
+}
+
+// PendingShardMove is the canonical form of placementpb.PendingShardMove.
+type PendingShardMove struct {
+	ShardID  uint32 `json:"shardID" yaml:"shardID"`
+	SourceID string `json:"sourceID" yaml:"sourceID"`
+	TargetID string `json:"targetID" yaml:"targetID"`
+}
+
+// PlacementSnapshots is the canonical form of placementpb.PlacementSnapshots.
+type PlacementSnapshots struct {
+	Snapshots []Placement `json:"snapshots" yaml:"snapshots"`
+}
+
+// NewPlacementFromProto returns the canonical form of the placement proto.
+func NewPlacementFromProto(pb *placementpb.Placement) Placement {
+	p := Placement{
+		Instances:     make([]Instance, 0, len(pb.Instances)),
+		ReplicaFactor: pb.ReplicaFactor,
+		NumShards:     pb.NumShards,
+		IsSharded:     pb.IsSharded,
+		CutoverTime:   pb.CutoverTime,
+		IsMirrored:    pb.IsMirrored,
+	}
+
+	for _, instance := range pb.Instances {
+		p.Instances = append(p.Instances, newInstanceFromProto(instance))
+	}
+	sort.Sort(instancesByIDAscending(p.Instances))
+
+	if len(pb.ZoneReplicas) > 0 {
+		p.ZoneReplicas = make(map[string]uint32, len(pb.ZoneReplicas))
+		for zone, rf := range pb.ZoneReplicas {
+			p.ZoneReplicas[zone] = rf
+		}
+	}
+
+	for _, move := range pb.PendingShardMoves {
+		p.PendingShardMoves = append(p.PendingShardMoves, PendingShardMove{
+			ShardID:  move.ShardId,
+			SourceID: move.SourceId,
+			TargetID: move.TargetId,
+		})
+	}
+	return p
+}
+
+func newInstanceFromProto(pb *placementpb.Instance) Instance {
+	instance := Instance{
+		ID:         pb.Id,
+		Rack:       pb.Rack,
+		Zone:       pb.Zone,
+		Region:     pb.Region,
+		Weight:     pb.Weight,
+		Endpoint:   pb.Endpoint,
+		Hostname:   pb.Hostname,
+		Port:       pb.Port,
+		ShardSetID: pb.ShardSetId,
+		Shards:     make([]Shard, 0, len(pb.Shards)),
+	}
+
+	for _, s := range pb.Shards {
+		instance.Shards = append(instance.Shards, Shard{
+			ID:           s.Id,
+			State:        s.State.String(),
+			SourceID:     s.SourceId,
+			CutoverNanos: s.CutoverNanos,
+			CutoffNanos:  s.CutoffNanos,
+		})
+	}
+	sort.Sort(shardsByIDAscending(instance.Shards))
+	return instance
+}
+
+// Proto returns the placement proto of the canonical form.
+func (p Placement) Proto() (*placementpb.Placement, error) {
+	pb := &placementpb.Placement{
+		Instances:     make(map[string]*placementpb.Instance, len(p.Instances)),
+		ReplicaFactor: p.ReplicaFactor,
+		NumShards:     p.NumShards,
+		IsSharded:     p.IsSharded,
+		CutoverTime:   p.CutoverTime,
+		IsMirrored:    p.IsMirrored,
+	}
+
+	for _, instance := range p.Instances {
+		if _, ok := pb.Instances[instance.ID]; ok {
+			return nil, fmt.Errorf("duplicated instance %s", instance.ID)
+		}
+		pi, err := instance.proto()
+		if err != nil {
+			return nil, err
+		}
+		pb.Instances[instance.ID] = pi
+	}
+
+	if len(p.ZoneReplicas) > 0 {
+		pb.ZoneReplicas = make(map[string]uint32, len(p.ZoneReplicas))
+		for zone, rf := range p.ZoneReplicas {
+			pb.ZoneReplicas[zone] = rf
+		}
+	}
+
+	for _, move := range p.PendingShardMoves {
+		pb.PendingShardMoves = append(pb.PendingShardMoves, &placementpb.PendingShardMove{
+			ShardId:  move.ShardID,
+			SourceId: move.SourceID,
+			TargetId: move.TargetID,
+		})
+	}
+	return pb, nil
+}
+
+func (instance Instance) proto() (*placementpb.Instance, error) {
+	pb := &placementpb.Instance{
+		Id:         instance.ID,
+		Rack:       instance.Rack,
+		Zone:       instance.Zone,
+		Region:     instance.Region,
+		Weight:     instance.Weight,
+		Endpoint:   instance.Endpoint,
+		Hostname:   instance.Hostname,
+		Port:       instance.Port,
+		ShardSetId: instance.ShardSetID,
+		Shards:     make([]*placementpb.Shard, 0, len(instance.Shards)),
+	}
+
+	for _, s := range instance.Shards {
+		state, ok := placementpb.ShardState_value[s.State]
+		if !ok {
+			return nil, fmt.Errorf("invalid state %s for shard %d on instance %s", s.State, s.ID, instance.ID)
+		}
+		pb.Shards = append(pb.Shards, &placementpb.Shard{
+			Id:           s.ID,
+			State:        placementpb.ShardState(state),
+			SourceId:     s.SourceID,
+			CutoverNanos: s.CutoverNanos,
+			CutoffNanos:  s.CutoffNanos,
+		})
+	}
+	return pb, nil
+}
+
+// NewPlacementSnapshotsFromProto returns the canonical form of the placement snapshots proto.
+func NewPlacementSnapshotsFromProto(pb *placementpb.PlacementSnapshots) PlacementSnapshots {
+	ps := PlacementSnapshots{Snapshots: make([]Placement, 0, len(pb.Snapshots))}
+	for _, snapshot := range pb.Snapshots {
+		ps.Snapshots = append(ps.Snapshots, NewPlacementFromProto(snapshot))
+	}
+	return ps
+}
+
+// Proto returns the placement snapshots proto of the canonical form.
+func (ps PlacementSnapshots) Proto() (*placementpb.PlacementSnapshots, error) {
+	pb := &placementpb.PlacementSnapshots{Snapshots: make([]*placementpb.Placement, 0, len(ps.Snapshots))}
+	for _, snapshot := range ps.Snapshots {
+		p, err := snapshot.Proto()
+		if err != nil {
+			return nil, err
+		}
+		pb.Snapshots = append(pb.Snapshots, p)
+	}
+	return pb, nil
+}
+
+// PlacementToJSON exports the placement as canonical JSON.
+func PlacementToJSON(p placement.Placement) ([]byte, error) {
+	canonical, err := newPlacement(p)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(canonical, "", "  ")
+}
+
+// PlacementFromJSON imports the placement from canonical JSON.
+func PlacementFromJSON(b []byte) (placement.Placement, error) {
+	var canonical Placement
+	if err := json.Unmarshal(b, &canonical); err != nil {
+		return nil, err
+	}
+	return canonical.placement()
+}
+
+// PlacementToYAML exports the placement as canonical YAML.
+func PlacementToYAML(p placement.Placement) ([]byte, error) {
+	canonical, err := newPlacement(p)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(canonical)
+}
+
+// PlacementFromYAML imports the placement from canonical YAML.
+func PlacementFromYAML(b []byte) (placement.Placement, error) {
+	var canonical Placement
+	if err := yaml.Unmarshal(b, &canonical); err != nil {
+		return nil, err
+	}
+	return canonical.placement()
+}
+
+// StagedPlacementToJSON exports the placements in the staged placement as canonical JSON.
+func StagedPlacementToJSON(sp placement.StagedPlacement) ([]byte, error) {
+	canonical, err := newPlacementSnapshots(sp)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(canonical, "", "  ")
+}
+
+// StagedPlacementFromJSON imports the staged placement from canonical JSON.
+func StagedPlacementFromJSON(
+	b []byte,
+	opts placement.ActiveStagedPlacementOptions,
+) (placement.StagedPlacement, error) {
+	var canonical PlacementSnapshots
+	if err := json.Unmarshal(b, &canonical); err != nil {
+		return nil, err
+	}
+	return canonical.stagedPlacement(opts)
+}
+
+// StagedPlacementToYAML exports the placements in the staged placement as canonical YAML.
+func StagedPlacementToYAML(sp placement.StagedPlacement) ([]byte, error) {
+	canonical, err := newPlacementSnapshots(sp)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(canonical)
+}
+
+// StagedPlacementFromYAML imports the staged placement from canonical YAML.
+func StagedPlacementFromYAML(
+	b []byte,
+	opts placement.ActiveStagedPlacementOptions,
+) (placement.StagedPlacement, error) {
+	var canonical PlacementSnapshots
+	if err := yaml.Unmarshal(b, &canonical); err != nil {
+		return nil, err
+	}
+	return canonical.stagedPlacement(opts)
+}
+
+func newPlacement(p placement.Placement) (Placement, error) {
+	pb, err := p.Proto()
+	if err != nil {
+		return Placement{}, err
+	}
+	return NewPlacementFromProto(pb), nil
+}
+
+func (p Placement) placement() (placement.Placement, error) {
+	pb, err := p.Proto()
+	if err != nil {
+		return nil, err
+	}
+	return placement.NewPlacementFromProto(pb)
+}
+
+func newPlacementSnapshots(sp placement.StagedPlacement) (PlacementSnapshots, error) {
+	pb, err := sp.Proto()
+	if err != nil {
+		return PlacementSnapshots{}, err
+	}
+	return NewPlacementSnapshotsFromProto(pb), nil
+}
+
+func (ps PlacementSnapshots) stagedPlacement(
+	opts placement.ActiveStagedPlacementOptions,
+) (placement.StagedPlacement, error) {
+	pb, err := ps.Proto()
+	if err != nil {
+		return nil, err
+	}
+	return placement.NewStagedPlacementFromProto(0, pb, opts)
+}
+
+type instancesByIDAscending []Instance
+
+func (s instancesByIDAscending) Len() int           { return len(s) }
+func (s instancesByIDAscending) Less(i, j int) bool { return s[i].ID < s[j].ID }
+func (s instancesByIDAscending) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+type shardsByIDAscending []Shard
+
+func (s shardsByIDAscending) Len() int           { return len(s) }
+func (s shardsByIDAscending) Less(i, j int) bool { return s[i].ID < s[j].ID }
+func (s shardsByIDAscending) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,139 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package format
+
+import (
+	"testing"
+
+	"github.com/m3db/m3cluster/placement"
+	"github.com/m3db/m3cluster/shard"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlacementJSONRoundTrip(t *testing.T) {
+	p := testPlacement()
+	b, err := PlacementToJSON(p)
+	require.NoError(t, err)
+
+	imported, err := PlacementFromJSON(b)
+	require.NoError(t, err)
+	requireSamePlacement(t, p, imported)
+
+	again, err := PlacementToJSON(imported)
+	require.NoError(t, err)
+	require.Equal(t, string(b), string(again))
+}
+
+func TestPlacementYAMLRoundTrip(t *testing.T) {
+	p := testPlacement()
+	b, err := PlacementToYAML(p)
+	require.NoError(t, err)
+
+	imported, err := PlacementFromYAML(b)
+	require.NoError(t, err)
+	requireSamePlacement(t, p, imported)
+
+	again, err := PlacementToYAML(imported)
+	require.NoError(t, err)
+	require.Equal(t, string(b), string(again))
+}
+
+func TestPlacementCanonicalOrder(t *testing.T) {
+	canonical, err := newPlacement(testPlacement())
+	require.NoError(t, err)
+	require.Equal(t, 3, len(canonical.Instances))
+	for i, id := range []string{"i1", "i2", "i3"} {
+		require.Equal(t, id, canonical.Instances[i].ID)
+	}
+	require.Equal(t, []Shard{
+		{ID: 0, State: "AVAILABLE"},
+		{ID: 1, State: "LEAVING", CutoffNanos: 200},
+		{ID: 2, State: "AVAILABLE"},
+	}, canonical.Instances[0].Shards)
+	require.Equal(t, []PendingShardMove{{ShardID: 2, SourceID: "i1", TargetID: "i3"}}, canonical.PendingShardMoves)
+}
+
+func TestPlacementFromJSONErrors(t *testing.T) {
+	_, err := PlacementFromJSON([]byte("{"))
+	require.Error(t, err)
+
+	_, err = PlacementFromJSON([]byte(`{"instances": [{"id": "i1", "shards": [{"id": 0, "state": "UNKNOWN"}]}]}`))
+	require.Error(t, err)
+
+	_, err = PlacementFromJSON([]byte(`{"instances": [{"id": "i1"}, {"id": "i1"}]}`))
+	require.Error(t, err)
+}
+
+func TestStagedPlacementRoundTrip(t *testing.T) {
+	p1 := testPlacement()
+	p2 := testPlacement().SetCutoverNanos(2000)
+	sp := placement.NewStagedPlacement().SetPlacements([]placement.Placement{p1, p2})
+	opts := placement.NewActiveStagedPlacementOptions()
+
+	b, err := StagedPlacementToJSON(sp)
+	require.NoError(t, err)
+	imported, err := StagedPlacementFromJSON(b, opts)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(imported.Placements()))
+	requireSamePlacement(t, p1, imported.Placements()[0])
+	requireSamePlacement(t, p2, imported.Placements()[1])
+	require.Equal(t, opts, imported.ActiveStagedPlacementOptions())
+
+	b, err = StagedPlacementToYAML(sp)
+	require.NoError(t, err)
+	imported, err = StagedPlacementFromYAML(b, opts)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(imported.Placements()))
+	requireSamePlacement(t, p1, imported.Placements()[0])
+	requireSamePlacement(t, p2, imported.Placements()[1])
+}
+
+func requireSamePlacement(t *testing.T, expected, actual placement.Placement) {
+	expectedProto, err := expected.Proto()
+	require.NoError(t, err)
+	actualProto, err := actual.Proto()
+	require.NoError(t, err)
+	require.Equal(t, expectedProto, actualProto)
+}
+
+func testPlacement() placement.Placement {
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint1", 1).
+		SetHostname("host1").
+		SetPort(1)
+	i1.Shards().Add(shard.NewShard(2).SetState(shard.Available))
+	i1.Shards().Add(shard.NewShard(0).SetState(shard.Available))
+	i1.Shards().Add(shard.NewShard(1).SetState(shard.Leaving).SetCutoffNanos(200))
+	i2 := placement.NewEmptyInstance("i2", "r2", "z1", "endpoint2", 2)
+	i2.Shards().Add(shard.NewShard(0).SetState(shard.Available))
+	i2.Shards().Add(shard.NewShard(1).SetState(shard.Initializing).SetSourceID("i1").SetCutoverNanos(100))
+	i2.Shards().Add(shard.NewShard(2).SetState(shard.Available))
+	i3 := placement.NewEmptyInstance("i3", "r3", "z1", "endpoint3", 1)
+	i3.Shards().Add(shard.NewShard(1).SetState(shard.Available))
+
+	return placement.NewPlacement().
+		SetInstances([]placement.Instance{i3, i1, i2}).
+		SetShards([]uint32{0, 1, 2}).
+		SetReplicaFactor(2).
+		SetIsSharded(true).
+		SetCutoverNanos(1000).
+		SetPendingShardMoves([]placement.PendingShardMove{{ShardID: 2, SourceID: "i1", TargetID: "i3"}})
+}
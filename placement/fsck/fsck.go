@@ -0,0 +1,258 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package fsck checks a placement against the instances heartbeating for the
+// service, reporting the shards and instances that need attention.
+package fsck
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/m3db/m3cluster/placement"
+	"github.com/m3db/m3cluster/services"
+	"github.com/m3db/m3cluster/shard"
+	"github.com/m3db/m3x/clock"
+
+	"github.com/uber-go/tally"
+)
+
+// Checker checks placements against live heartbeats.
+type Checker interface {
+	// Check checks the placement against the instances heartbeating for the service.
+	Check(p placement.Placement) (Report, error)
+}
+
+// Report is the result of a placement check.
+type Report struct {
+	// UnderReplicatedShards are the shards with fewer healthy Available replicas
+	// than the replica factor, sorted by shard id.
+	UnderReplicatedShards []UnderReplicatedShard `json:"underReplicatedShards,omitempty"`
+
+	// DeadInstances are the instances in the placement that are not heartbeating, sorted.
+	DeadInstances []string `json:"deadInstances,omitempty"`
+
+	// UnknownInstances are the heartbeating instances missing from the placement, sorted.
+	UnknownInstances []string `json:"unknownInstances,omitempty"`
+
+	// StuckShards are the shards Initializing for longer than the deadline,
+	// sorted by shard and instance id. Shards with a cutover time are measured
+	// from it. Shards without one are measured from the first check by the
+	// same checker that saw them Initializing, so they are only reported by a
+	// checker that is kept across checks.
+	StuckShards []StuckShard `json:"stuckShards,omitempty"`
+}
+
+// IsHealthy returns true if the check found nothing to report.
+func (r Report) IsHealthy() bool {
+	return len(r.UnderReplicatedShards) == 0 &&
+		len(r.DeadInstances) == 0 &&
+		len(r.UnknownInstances) == 0 &&
+		len(r.StuckShards) == 0
+}
+
+// UnderReplicatedShard is a shard with fewer healthy Available replicas than
+// the replica factor. A replica is healthy if its instance is heartbeating.
+type UnderReplicatedShard struct {
+	ID              uint32 `json:"id"`
+	HealthyReplicas int    `json:"healthyReplicas"`
+	ReplicaFactor   int    `json:"replicaFactor"`
+}
+
+// StuckShard is a shard Initializing on an instance for longer than the deadline.
+type StuckShard struct {
+	ID           uint32        `json:"id"`
+	InstanceID   string        `json:"instanceID"`
+	Initializing time.Duration `json:"initializing"`
+}
+
+type checkerMetrics struct {
+	checks                tally.Counter
+	checkErrors           tally.Counter
+	underReplicatedShards tally.Gauge
+	deadInstances         tally.Gauge
+	unknownInstances      tally.Gauge
+	stuckShards           tally.Gauge
+}
+
+func newCheckerMetrics(scope tally.Scope) checkerMetrics {
+	return checkerMetrics{
+		checks:                scope.Counter("checks"),
+		checkErrors:           scope.Counter("check-errors"),
+		underReplicatedShards: scope.Gauge("under-replicated-shards"),
+		deadInstances:         scope.Gauge("dead-instances"),
+		unknownInstances:      scope.Gauge("unknown-instances"),
+		stuckShards:           scope.Gauge("stuck-shards"),
+	}
+}
+
+type checker struct {
+	sync.Mutex
+
+	hbService services.HeartbeatService
+	opts      Options
+	nowFn     clock.NowFn
+	metrics   checkerMetrics
+
+	initializingSince map[placement.InstanceShard]time.Time
+}
+
+// NewChecker creates a new Checker using the heartbeats in the heartbeat service.
+func NewChecker(hbService services.HeartbeatService, opts Options) (Checker, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	scope := opts.InstrumentOptions().MetricsScope().SubScope("placement-fsck")
+	return &checker{
+		hbService: hbService,
+		opts:      opts,
+		nowFn:     opts.ClockOptions().NowFn(),
+		metrics:   newCheckerMetrics(scope),
+
+		initializingSince: make(map[placement.InstanceShard]time.Time),
+	}, nil
+}
+
+func (c *checker) Check(p placement.Placement) (Report, error) {
+	c.metrics.checks.Inc(1)
+	instances, err := c.hbService.GetInstances()
+	if err != nil {
+		c.metrics.checkErrors.Inc(1)
+		return Report{}, err
+	}
+
+	alive := make(map[string]bool, len(instances))
+	for _, instance := range instances {
+		alive[instance.ID()] = true
+	}
+
+	report := Report{
+		UnderReplicatedShards: underReplicatedShards(p, alive),
+		DeadInstances:         deadInstances(p, alive),
+		UnknownInstances:      unknownInstances(p, instances),
+		StuckShards:           c.stuckShards(p, c.nowFn(), c.opts.InitializingDeadline()),
+	}
+
+	c.metrics.underReplicatedShards.Update(float64(len(report.UnderReplicatedShards)))
+	c.metrics.deadInstances.Update(float64(len(report.DeadInstances)))
+	c.metrics.unknownInstances.Update(float64(len(report.UnknownInstances)))
+	c.metrics.stuckShards.Update(float64(len(report.StuckShards)))
+	return report, nil
+}
+
+func underReplicatedShards(p placement.Placement, alive map[string]bool) []UnderReplicatedShard {
+	healthy := make(map[uint32]int, p.NumShards())
+	for _, instance := range p.Instances() {
+		if !alive[instance.ID()] {
+			continue
+		}
+		for _, s := range instance.Shards().ShardsForState(shard.Available) {
+			healthy[s.ID()]++
+		}
+	}
+
+	var (
+		rf  = p.ReplicaFactor()
+		res []UnderReplicatedShard
+	)
+	for _, id := range placement.SortedShardIDs(p.Shards()) {
+		if healthy[id] >= rf {
+			continue
+		}
+		res = append(res, UnderReplicatedShard{ID: id, HealthyReplicas: healthy[id], ReplicaFactor: rf})
+	}
+	return res
+}
+
+func deadInstances(p placement.Placement, alive map[string]bool) []string {
+	var res []string
+	for _, instance := range p.Instances() {
+		if !alive[instance.ID()] {
+			res = append(res, instance.ID())
+		}
+	}
+	return res
+}
+
+func unknownInstances(p placement.Placement, instances []placement.Instance) []string {
+	var res []string
+	for _, instance := range instances {
+		if _, ok := p.Instance(instance.ID()); !ok {
+			res = append(res, instance.ID())
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// stuckShards measures how long each shard has been Initializing from its
+// cutover time, or from the first check that saw it Initializing if it has
+// none, as cutover times are not set by default. Shards that are no longer
+// Initializing are forgotten, so a shard that starts Initializing again
+// starts afresh.
+func (c *checker) stuckShards(p placement.Placement, now time.Time, deadline time.Duration) []StuckShard {
+	c.Lock()
+	defer c.Unlock()
+
+	var (
+		since = make(map[placement.InstanceShard]time.Time, len(c.initializingSince))
+		res   []StuckShard
+	)
+	for _, instance := range p.Instances() {
+		for _, s := range instance.Shards().ShardsForState(shard.Initializing) {
+			key := placement.InstanceShard{InstanceID: instance.ID(), ShardID: s.ID()}
+			firstSeen, ok := c.initializingSince[key]
+			if !ok {
+				firstSeen = now
+				if cutover := s.CutoverNanos(); cutover != shard.DefaultShardCutoverNanos {
+					if cutoverTime := time.Unix(0, cutover); cutoverTime.Before(now) {
+						firstSeen = cutoverTime
+					}
+				}
+			}
+			since[key] = firstSeen
+
+			initializing := now.Sub(firstSeen)
+			if initializing <= deadline {
+				continue
+			}
+			res = append(res, StuckShard{ID: s.ID(), InstanceID: instance.ID(), Initializing: initializing})
+		}
+	}
+	c.initializingSince = since
+
+	sort.Sort(stuckShardsByIDAscending(res))
+	return res
+}
+
+type stuckShardsByIDAscending []StuckShard
+
+func (s stuckShardsByIDAscending) Len() int { return len(s) }
+
+func (s stuckShardsByIDAscending) Less(i, j int) bool {
+	if s[i].ID != s[j].ID {
+		return s[i].ID < s[j].ID
+	}
+	return s[i].InstanceID < s[j].InstanceID
+}
+
+func (s stuckShardsByIDAscending) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,247 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package fsck
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/m3db/m3cluster/placement"
+	"github.com/m3db/m3cluster/services"
+	"github.com/m3db/m3cluster/shard"
+	"github.com/m3db/m3x/clock"
+	"github.com/m3db/m3x/instrument"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally"
+)
+
+func TestCheck(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Unix(0, 0).Add(10 * time.Hour)
+	hbService := services.NewMockHeartbeatService(ctrl)
+	hbService.EXPECT().GetInstances().Return([]placement.Instance{
+		placement.NewInstance().SetID("i4"),
+		placement.NewInstance().SetID("i1"),
+		placement.NewInstance().SetID("i2"),
+	}, nil).Times(2)
+
+	scope := tally.NewTestScope("", nil)
+	c, err := NewChecker(hbService, NewOptions().
+		SetInitializingDeadline(time.Hour).
+		SetClockOptions(clock.NewOptions().SetNowFn(func() time.Time { return now })).
+		SetInstrumentOptions(instrument.NewOptions().SetMetricsScope(scope)))
+	require.NoError(t, err)
+
+	// Shard 2 started Initializing on i1 two hours before it did on i2.
+	p := testPlacement()
+	before := p.Clone()
+	i2, ok := before.Instance("i2")
+	require.True(t, ok)
+	i2.Shards().Remove(2)
+
+	report, err := c.Check(before)
+	require.NoError(t, err)
+	require.Empty(t, report.StuckShards)
+
+	now = now.Add(2 * time.Hour)
+	report, err = c.Check(p)
+	require.NoError(t, err)
+	require.False(t, report.IsHealthy())
+	require.Equal(t, []UnderReplicatedShard{
+		{ID: 1, HealthyReplicas: 1, ReplicaFactor: 2},
+		{ID: 2, HealthyReplicas: 0, ReplicaFactor: 2},
+	}, report.UnderReplicatedShards)
+	require.Equal(t, []string{"i3"}, report.DeadInstances)
+	require.Equal(t, []string{"i4"}, report.UnknownInstances)
+	require.Equal(t, []StuckShard{
+		{ID: 2, InstanceID: "i1", Initializing: 2 * time.Hour},
+	}, report.StuckShards)
+
+	gauges := make(map[string]float64)
+	for _, g := range scope.Snapshot().Gauges() {
+		gauges[g.Name()] = g.Value()
+	}
+	require.Equal(t, map[string]float64{
+		"placement-fsck.under-replicated-shards": 2,
+		"placement-fsck.dead-instances":          1,
+		"placement-fsck.unknown-instances":       1,
+		"placement-fsck.stuck-shards":            1,
+	}, gauges)
+}
+
+func TestCheckHealthy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint1", 1)
+	i1.Shards().Add(shard.NewShard(0).SetState(shard.Available))
+	p := placement.NewPlacement().
+		SetInstances([]placement.Instance{i1}).
+		SetShards([]uint32{0}).
+		SetReplicaFactor(1).
+		SetIsSharded(true)
+
+	hbService := services.NewMockHeartbeatService(ctrl)
+	hbService.EXPECT().GetInstances().Return([]placement.Instance{i1}, nil)
+	c, err := NewChecker(hbService, NewOptions())
+	require.NoError(t, err)
+
+	report, err := c.Check(p)
+	require.NoError(t, err)
+	require.True(t, report.IsHealthy())
+}
+
+func TestCheckStuckShardsWithDefaultOptions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// The shard has the default cutover time, as set by the default placement
+	// options, which must not make it look Initializing since the epoch.
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint1", 1)
+	i1.Shards().Add(shard.NewShard(0).SetState(shard.Initializing))
+	p := placement.NewPlacement().
+		SetInstances([]placement.Instance{i1}).
+		SetShards([]uint32{0}).
+		SetReplicaFactor(1).
+		SetIsSharded(true)
+
+	hbService := services.NewMockHeartbeatService(ctrl)
+	hbService.EXPECT().GetInstances().Return([]placement.Instance{i1}, nil).AnyTimes()
+
+	now := time.Now()
+	c, err := NewChecker(hbService, NewOptions().
+		SetClockOptions(clock.NewOptions().SetNowFn(func() time.Time { return now })))
+	require.NoError(t, err)
+
+	report, err := c.Check(p)
+	require.NoError(t, err)
+	require.Empty(t, report.StuckShards)
+
+	now = now.Add(defaultInitializingDeadline)
+	report, err = c.Check(p)
+	require.NoError(t, err)
+	require.Empty(t, report.StuckShards)
+
+	now = now.Add(time.Minute)
+	report, err = c.Check(p)
+	require.NoError(t, err)
+	require.Equal(t, []StuckShard{
+		{ID: 0, InstanceID: "i1", Initializing: defaultInitializingDeadline + time.Minute},
+	}, report.StuckShards)
+
+	// A shard that starts Initializing again is measured afresh.
+	available := p.Clone()
+	i1, _ = available.Instance("i1")
+	i1.Shards().Add(shard.NewShard(0).SetState(shard.Available))
+	_, err = c.Check(available)
+	require.NoError(t, err)
+
+	now = now.Add(time.Minute)
+	report, err = c.Check(p)
+	require.NoError(t, err)
+	require.Empty(t, report.StuckShards)
+}
+
+func TestCheckStuckShardsFromCutover(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Unix(0, 0).Add(10 * time.Hour)
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint1", 1)
+	i1.Shards().Add(shard.NewShard(0).
+		SetState(shard.Initializing).
+		SetCutoverNanos(now.Add(-3 * time.Hour).UnixNano()))
+	i1.Shards().Add(shard.NewShard(1).
+		SetState(shard.Initializing).
+		SetCutoverNanos(now.Add(time.Hour).UnixNano()))
+	p := placement.NewPlacement().
+		SetInstances([]placement.Instance{i1}).
+		SetShards([]uint32{0, 1}).
+		SetReplicaFactor(1).
+		SetIsSharded(true)
+
+	hbService := services.NewMockHeartbeatService(ctrl)
+	hbService.EXPECT().GetInstances().Return([]placement.Instance{i1}, nil)
+	c, err := NewChecker(hbService, NewOptions().
+		SetInitializingDeadline(time.Hour).
+		SetClockOptions(clock.NewOptions().SetNowFn(func() time.Time { return now })))
+	require.NoError(t, err)
+
+	// A fresh checker reports the shard that cut over long ago.
+	report, err := c.Check(p)
+	require.NoError(t, err)
+	require.Equal(t, []StuckShard{
+		{ID: 0, InstanceID: "i1", Initializing: 3 * time.Hour},
+	}, report.StuckShards)
+}
+
+func TestCheckHeartbeatError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	hbService := services.NewMockHeartbeatService(ctrl)
+	hbService.EXPECT().GetInstances().Return(nil, errors.New("mock error"))
+
+	scope := tally.NewTestScope("", nil)
+	c, err := NewChecker(hbService, NewOptions().
+		SetInstrumentOptions(instrument.NewOptions().SetMetricsScope(scope)))
+	require.NoError(t, err)
+
+	_, err = c.Check(placement.NewPlacement())
+	require.Error(t, err)
+
+	counters := scope.Snapshot().Counters()
+	require.Equal(t, int64(1), counters["placement-fsck.check-errors+"].Value())
+}
+
+func TestNewCheckerInvalidOptions(t *testing.T) {
+	_, err := NewChecker(nil, NewOptions().SetInitializingDeadline(0))
+	require.Equal(t, errInvalidInitializingDeadline, err)
+}
+
+// testPlacement returns a placement with rf 2 where i3 is not heartbeating:
+// shard 0 is healthy, shard 1 has a replica on i3, and shard 2 is moving from
+// i3 to i1 and Initializing on i2.
+func testPlacement() placement.Placement {
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint1", 1)
+	i1.Shards().Add(shard.NewShard(0).SetState(shard.Available))
+	i1.Shards().Add(shard.NewShard(1).SetState(shard.Available))
+	i1.Shards().Add(shard.NewShard(2).
+		SetState(shard.Initializing).
+		SetSourceID("i3"))
+	i2 := placement.NewEmptyInstance("i2", "r2", "z1", "endpoint2", 1)
+	i2.Shards().Add(shard.NewShard(0).SetState(shard.Available))
+	i2.Shards().Add(shard.NewShard(2).SetState(shard.Initializing))
+	i3 := placement.NewEmptyInstance("i3", "r3", "z1", "endpoint3", 1)
+	i3.Shards().Add(shard.NewShard(1).SetState(shard.Available))
+	i3.Shards().Add(shard.NewShard(2).SetState(shard.Leaving))
+
+	return placement.NewPlacement().
+		SetInstances([]placement.Instance{i1, i2, i3}).
+		SetShards([]uint32{0, 1, 2}).
+		SetReplicaFactor(2).
+		SetIsSharded(true)
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package fsck
+
+import (
+	"errors"
+	"time"
+
+	"github.com/m3db/m3x/clock"
+	"github.com/m3db/m3x/instrument"
+)
+
+const (
+	defaultInitializingDeadline = time.Hour
+)
+
+var (
+	errInvalidInitializingDeadline = errors.New("initializing deadline must be positive")
+)
+
+// Options are the options for the placement checker.
+type Options interface {
+	// InitializingDeadline is how long a shard may stay Initializing before it
+	// is reported as stuck.
+	InitializingDeadline() time.Duration
+	SetInitializingDeadline(value time.Duration) Options
+
+	// ClockOptions are the clock options.
+	ClockOptions() clock.Options
+	SetClockOptions(value clock.Options) Options
+
+	// InstrumentOptions are the instrument options.
+	InstrumentOptions() instrument.Options
+	SetInstrumentOptions(value instrument.Options) Options
+
+	// Validate validates the options.
+	Validate() error
+}
+
+type options struct {
+	initializingDeadline time.Duration
+	clockOpts            clock.Options
+	instrumentOpts       instrument.Options
+}
+
+// NewOptions creates a new set of options for the placement checker.
+func NewOptions() Options {
+	return options{
+		initializingDeadline: defaultInitializingDeadline,
+		clockOpts:            clock.NewOptions(),
+		instrumentOpts:       instrument.NewOptions(),
+	}
+}
+
+func (o options) InitializingDeadline() time.Duration {
+	return o.initializingDeadline
+}
+
+func (o options) SetInitializingDeadline(value time.Duration) Options {
+	o.initializingDeadline = value
+	return o
+}
+
+func (o options) ClockOptions() clock.Options {
+	return o.clockOpts
+}
+
+func (o options) SetClockOptions(value clock.Options) Options {
+	o.clockOpts = value
+	return o
+}
+
+func (o options) InstrumentOptions() instrument.Options {
+	return o.instrumentOpts
+}
+
+func (o options) SetInstrumentOptions(value instrument.Options) Options {
+	o.instrumentOpts = value
+	return o
+}
+
+func (o options) Validate() error {
+	if o.initializingDeadline <= 0 {
+		return errInvalidInitializingDeadline
+	}
+	return nil
+}
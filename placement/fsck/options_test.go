@@ -0,0 +1,50 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package fsck
+
+import (
+	"testing"
+	"time"
+
+	"github.com/m3db/m3x/clock"
+	"github.com/m3db/m3x/instrument"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptions(t *testing.T) {
+	opts := NewOptions()
+	require.Equal(t, defaultInitializingDeadline, opts.InitializingDeadline())
+	require.NoError(t, opts.Validate())
+
+	opts = opts.SetInitializingDeadline(time.Minute)
+	require.Equal(t, time.Minute, opts.InitializingDeadline())
+
+	clockOpts := clock.NewOptions()
+	opts = opts.SetClockOptions(clockOpts)
+	require.Equal(t, clockOpts, opts.ClockOptions())
+
+	instrumentOpts := instrument.NewOptions()
+	opts = opts.SetInstrumentOptions(instrumentOpts)
+	require.Equal(t, instrumentOpts, opts.InstrumentOptions())
+
+	require.Equal(t, errInvalidInitializingDeadline, opts.SetInitializingDeadline(-time.Second).Validate())
+}
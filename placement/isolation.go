@@ -0,0 +1,107 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package placement
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IsolationLevel is a level in the failure domain hierarchy of
+// region > zone > rack > host that replicas of a shard are spread across.
+type IsolationLevel int
+
+// List of isolation levels, from the narrowest to the widest.
+const (
+	IsolationLevelHost IsolationLevel = iota
+	IsolationLevelRack
+	IsolationLevelZone
+	IsolationLevelRegion
+)
+
+var isolationLevelNames = map[IsolationLevel]string{
+	IsolationLevelHost:   "host",
+	IsolationLevelRack:   "rack",
+	IsolationLevelZone:   "zone",
+	IsolationLevelRegion: "region",
+}
+
+func (l IsolationLevel) String() string {
+	if name, ok := isolationLevelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown isolation level %d", int(l))
+}
+
+// ParseIsolationLevel parses an isolation level from its name.
+func ParseIsolationLevel(str string) (IsolationLevel, error) {
+	for level, name := range isolationLevelNames {
+		if strings.EqualFold(str, name) {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid isolation level %q", str)
+}
+
+// IsolationMode describes how strictly the isolation level is enforced.
+type IsolationMode int
+
+// List of isolation modes.
+const (
+	// IsolationModeStrict fails the placement operation if the replicas
+	// of a shard can not be spread across distinct failure domains.
+	IsolationModeStrict IsolationMode = iota
+
+	// IsolationModeBestEffort prefers distinct failure domains for the
+	// replicas of a shard, but places replicas in the same failure domain
+	// when there are not enough failure domains to go around.
+	IsolationModeBestEffort
+)
+
+func (m IsolationMode) String() string {
+	switch m {
+	case IsolationModeStrict:
+		return "strict"
+	case IsolationModeBestEffort:
+		return "best-effort"
+	}
+	return fmt.Sprintf("unknown isolation mode %d", int(m))
+}
+
+// FailureDomain returns the failure domain of the instance at the given
+// isolation level. Like racks, the names of regions, zones and hosts are
+// expected to be unique across the cluster. Instances without a hostname
+// are treated as being on their own host.
+func FailureDomain(instance Instance, level IsolationLevel) string {
+	switch level {
+	case IsolationLevelHost:
+		if host := instance.Hostname(); host != "" {
+			return host
+		}
+		return instance.ID()
+	case IsolationLevelZone:
+		return instance.Zone()
+	case IsolationLevelRegion:
+		return instance.Region()
+	default:
+		return instance.Rack()
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package placement
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailureDomain(t *testing.T) {
+	i := NewEmptyInstance("i1", "r1", "z1", "e1", 1).SetRegion("us-east")
+	require.Equal(t, "i1", FailureDomain(i, IsolationLevelHost))
+	require.Equal(t, "r1", FailureDomain(i, IsolationLevelRack))
+	require.Equal(t, "z1", FailureDomain(i, IsolationLevelZone))
+	require.Equal(t, "us-east", FailureDomain(i, IsolationLevelRegion))
+
+	i.SetHostname("h1")
+	require.Equal(t, "h1", FailureDomain(i, IsolationLevelHost))
+}
+
+func TestIsolationLevel(t *testing.T) {
+	for _, level := range []IsolationLevel{
+		IsolationLevelHost,
+		IsolationLevelRack,
+		IsolationLevelZone,
+		IsolationLevelRegion,
+	} {
+		parsed, err := ParseIsolationLevel(level.String())
+		require.NoError(t, err)
+		require.Equal(t, level, parsed)
+	}
+
+	parsed, err := ParseIsolationLevel("Zone")
+	require.NoError(t, err)
+	require.Equal(t, IsolationLevelZone, parsed)
+
+	_, err = ParseIsolationLevel("datacenter")
+	require.Error(t, err)
+}
+
+func TestIsolationMode(t *testing.T) {
+	require.Equal(t, "strict", IsolationModeStrict.String())
+	require.Equal(t, "best-effort", IsolationModeBestEffort.String())
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/m3db/m3cluster/shard"
 	"github.com/m3db/m3x/clock"
 	"github.com/m3db/m3x/instrument"
+	"github.com/m3db/m3x/retry"
 )
 
 const (
@@ -33,6 +34,8 @@ const (
 	defaultIsSharded   = true
 	// By default partial replace should be allowed for better distribution.
 	defaultAllowPartialReplace = true
+	defaultIsolationLevel      = IsolationLevelRack
+	defaultIsolationMode       = IsolationModeStrict
 )
 
 type deploymentOptions struct {
@@ -58,12 +61,18 @@ func defaultShardValidationFn(s shard.Shard) error { return nil }
 
 type options struct {
 	looseRackCheck      bool
+	isolationLevel      IsolationLevel
+	isolationMode       IsolationMode
 	allowPartialReplace bool
 	isSharded           bool
 	isMirrored          bool
 	isStaged            bool
 	iopts               instrument.Options
 	validZone           string
+	zoneReplicas        map[string]int
+	maxInitPerInstance  int
+	maxInitPerPlacement int
+	conflictRetryOpts   retry.Options
 	dryrun              bool
 	placementCutOverFn  TimeNanosFn
 	shardCutOverFn      TimeNanosFn
@@ -77,6 +86,8 @@ type options struct {
 func NewOptions() Options {
 	return options{
 		allowPartialReplace: defaultAllowPartialReplace,
+		isolationLevel:      defaultIsolationLevel,
+		isolationMode:       defaultIsolationMode,
 		isSharded:           defaultIsSharded,
 		iopts:               instrument.NewOptions(),
 		placementCutOverFn:  defaultTimeNanosFn,
@@ -97,6 +108,24 @@ func (o options) SetLooseRackCheck(looseRackCheck bool) Options {
 	return o
 }
 
+func (o options) IsolationLevel() IsolationLevel {
+	return o.isolationLevel
+}
+
+func (o options) SetIsolationLevel(level IsolationLevel) Options {
+	o.isolationLevel = level
+	return o
+}
+
+func (o options) IsolationMode() IsolationMode {
+	return o.isolationMode
+}
+
+func (o options) SetIsolationMode(mode IsolationMode) Options {
+	o.isolationMode = mode
+	return o
+}
+
 func (o options) AllowPartialReplace() bool {
 	return o.allowPartialReplace
 }
@@ -160,6 +189,42 @@ func (o options) SetValidZone(z string) Options {
 	return o
 }
 
+func (o options) ZoneReplicas() map[string]int {
+	return o.zoneReplicas
+}
+
+func (o options) SetZoneReplicas(zoneReplicas map[string]int) Options {
+	o.zoneReplicas = zoneReplicas
+	return o
+}
+
+func (o options) MaxInitializingShardsPerInstance() int {
+	return o.maxInitPerInstance
+}
+
+func (o options) SetMaxInitializingShardsPerInstance(value int) Options {
+	o.maxInitPerInstance = value
+	return o
+}
+
+func (o options) MaxInitializingShardsPerPlacement() int {
+	return o.maxInitPerPlacement
+}
+
+func (o options) SetMaxInitializingShardsPerPlacement(value int) Options {
+	o.maxInitPerPlacement = value
+	return o
+}
+
+func (o options) ConflictRetryOptions() retry.Options {
+	return o.conflictRetryOpts
+}
+
+func (o options) SetConflictRetryOptions(value retry.Options) Options {
+	o.conflictRetryOpts = value
+	return o
+}
+
 func (o options) PlacementCutoverNanosFn() TimeNanosFn {
 	return o.placementCutOverFn
 }
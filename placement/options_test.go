@@ -26,6 +26,7 @@ import (
 
 	"github.com/m3db/m3cluster/shard"
 	"github.com/m3db/m3x/instrument"
+	"github.com/m3db/m3x/retry"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -40,10 +41,15 @@ func TestDeploymentOptions(t *testing.T) {
 func TestPlacementOptions(t *testing.T) {
 	o := NewOptions()
 	assert.False(t, o.LooseRackCheck())
+	assert.Equal(t, IsolationLevelRack, o.IsolationLevel())
+	assert.Equal(t, IsolationModeStrict, o.IsolationMode())
 	assert.True(t, o.AllowPartialReplace())
 	assert.True(t, o.IsSharded())
 	assert.False(t, o.Dryrun())
 	assert.False(t, o.IsMirrored())
+	assert.Equal(t, 0, o.MaxInitializingShardsPerInstance())
+	assert.Equal(t, 0, o.MaxInitializingShardsPerPlacement())
+	assert.Nil(t, o.ConflictRetryOptions())
 	assert.False(t, o.IsStaged())
 	assert.Equal(t, instrument.NewOptions(), o.InstrumentOptions())
 	assert.Equal(t, int64(0), o.PlacementCutoverNanosFn()())
@@ -53,6 +59,20 @@ func TestPlacementOptions(t *testing.T) {
 	o = o.SetLooseRackCheck(true)
 	assert.True(t, o.LooseRackCheck())
 
+	o = o.SetIsolationLevel(IsolationLevelZone)
+	assert.Equal(t, IsolationLevelZone, o.IsolationLevel())
+
+	o = o.SetIsolationMode(IsolationModeBestEffort)
+	assert.Equal(t, IsolationModeBestEffort, o.IsolationMode())
+
+	o = o.SetMaxInitializingShardsPerInstance(2).SetMaxInitializingShardsPerPlacement(10)
+	assert.Equal(t, 2, o.MaxInitializingShardsPerInstance())
+	assert.Equal(t, 10, o.MaxInitializingShardsPerPlacement())
+
+	retryOpts := retry.NewOptions()
+	o = o.SetConflictRetryOptions(retryOpts)
+	assert.Equal(t, retryOpts, o.ConflictRetryOptions())
+
 	o = o.SetAllowPartialReplace(false)
 	assert.False(t, o.AllowPartialReplace())
 
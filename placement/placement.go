@@ -37,6 +37,7 @@ var (
 	errDuplicatedShards           = errors.New("invalid placement, there are duplicated shards in one replica")
 	errUnexpectedShards           = errors.New("invalid placement, there are unexpected shard ids on instance")
 	errMirrorNotSharded           = errors.New("invalid placement, mirrored placement must be sharded")
+	errZoneReplicasNotSharded     = errors.New("invalid placement, zone replicas require a sharded placement")
 )
 
 type placement struct {
@@ -47,6 +48,8 @@ type placement struct {
 	isSharded        bool
 	isMirrored       bool
 	cutoverNanos     int64
+	zoneReplicas     map[string]int
+	pendingMoves     []PendingShardMove
 	version          int
 }
 
@@ -80,7 +83,9 @@ func NewPlacementFromProto(p *placementpb.Placement) (Placement, error) {
 		SetReplicaFactor(int(p.ReplicaFactor)).
 		SetIsSharded(p.IsSharded).
 		SetCutoverNanos(p.CutoverTime).
-		SetIsMirrored(p.IsMirrored), nil
+		SetIsMirrored(p.IsMirrored).
+		SetZoneReplicas(zoneReplicasFromProto(p.ZoneReplicas)).
+		SetPendingShardMoves(pendingShardMovesFromProto(p.PendingShardMoves)), nil
 }
 
 func (p *placement) InstancesForShard(shard uint32) []Instance {
@@ -168,6 +173,15 @@ func (p *placement) SetIsMirrored(v bool) Placement {
 	return p
 }
 
+func (p *placement) ZoneReplicas() map[string]int {
+	return p.zoneReplicas
+}
+
+func (p *placement) SetZoneReplicas(zoneReplicas map[string]int) Placement {
+	p.zoneReplicas = zoneReplicas
+	return p
+}
+
 func (p *placement) CutoverNanos() int64 {
 	return p.cutoverNanos
 }
@@ -186,6 +200,15 @@ func (p *placement) SetVersion(v int) Placement {
 	return p
 }
 
+func (p *placement) PendingShardMoves() []PendingShardMove {
+	return p.pendingMoves
+}
+
+func (p *placement) SetPendingShardMoves(moves []PendingShardMove) Placement {
+	p.pendingMoves = moves
+	return p
+}
+
 func (p *placement) String() string {
 	return fmt.Sprintf(
 		"Placement[Instances=%s, NumShards=%d, ReplicaFactor=%d, IsSharded=%v, IsMirrored=%v]",
@@ -204,12 +227,14 @@ func (p *placement) Proto() (*placementpb.Placement, error) {
 	}
 
 	return &placementpb.Placement{
-		Instances:     instances,
-		ReplicaFactor: uint32(p.ReplicaFactor()),
-		NumShards:     uint32(p.NumShards()),
-		IsSharded:     p.IsSharded(),
-		CutoverTime:   p.CutoverNanos(),
-		IsMirrored:    p.IsMirrored(),
+		Instances:         instances,
+		ReplicaFactor:     uint32(p.ReplicaFactor()),
+		NumShards:         uint32(p.NumShards()),
+		IsSharded:         p.IsSharded(),
+		CutoverTime:       p.CutoverNanos(),
+		IsMirrored:        p.IsMirrored(),
+		ZoneReplicas:      zoneReplicasToProto(p.ZoneReplicas()),
+		PendingShardMoves: pendingShardMovesToProto(p.PendingShardMoves()),
 	}, nil
 }
 
@@ -220,7 +245,81 @@ func (p *placement) Clone() Placement {
 		SetReplicaFactor(p.ReplicaFactor()).
 		SetIsSharded(p.IsSharded()).
 		SetIsMirrored(p.IsMirrored()).
-		SetCutoverNanos(p.CutoverNanos())
+		SetCutoverNanos(p.CutoverNanos()).
+		SetZoneReplicas(cloneZoneReplicas(p.ZoneReplicas())).
+		SetPendingShardMoves(clonePendingShardMoves(p.PendingShardMoves()))
+}
+
+func pendingShardMovesFromProto(moves []*placementpb.PendingShardMove) []PendingShardMove {
+	if len(moves) == 0 {
+		return nil
+	}
+	res := make([]PendingShardMove, len(moves))
+	for i, move := range moves {
+		res[i] = PendingShardMove{
+			ShardID:  move.ShardId,
+			SourceID: move.SourceId,
+			TargetID: move.TargetId,
+		}
+	}
+	return res
+}
+
+func pendingShardMovesToProto(moves []PendingShardMove) []*placementpb.PendingShardMove {
+	if len(moves) == 0 {
+		return nil
+	}
+	res := make([]*placementpb.PendingShardMove, len(moves))
+	for i, move := range moves {
+		res[i] = &placementpb.PendingShardMove{
+			ShardId:  move.ShardID,
+			SourceId: move.SourceID,
+			TargetId: move.TargetID,
+		}
+	}
+	return res
+}
+
+func clonePendingShardMoves(moves []PendingShardMove) []PendingShardMove {
+	if moves == nil {
+		return nil
+	}
+	res := make([]PendingShardMove, len(moves))
+	copy(res, moves)
+	return res
+}
+
+func zoneReplicasFromProto(zoneReplicas map[string]uint32) map[string]int {
+	if len(zoneReplicas) == 0 {
+		return nil
+	}
+	res := make(map[string]int, len(zoneReplicas))
+	for zone, replicas := range zoneReplicas {
+		res[zone] = int(replicas)
+	}
+	return res
+}
+
+func zoneReplicasToProto(zoneReplicas map[string]int) map[string]uint32 {
+	if len(zoneReplicas) == 0 {
+		return nil
+	}
+	res := make(map[string]uint32, len(zoneReplicas))
+	for zone, replicas := range zoneReplicas {
+		res[zone] = uint32(replicas)
+	}
+	return res
+}
+
+func cloneZoneReplicas(zoneReplicas map[string]int) map[string]int {
+	if zoneReplicas == nil {
+		return nil
+	}
+	res := make(map[string]int, len(zoneReplicas))
+	for zone, replicas := range zoneReplicas {
+		res[zone] = replicas
+	}
+	return res
 }
 
 // Placements represents a list of placements.
@@ -271,6 +370,14 @@ func (placements Placements) ActiveIndex(timeNanos int64) int {
 	return idx
 }
 
+// IsDroppedReplica returns whether the shard is a replica dropped from the
+// instance when the replica factor was reduced. A dropped replica is Leaving
+// with the instance itself as the source, as there is no Initializing replica
+// taking it over.
+func IsDroppedReplica(instance Instance, s shard.Shard) bool {
+	return s.State() == shard.Leaving && s.SourceID() == instance.ID()
+}
+
 // Validate validates a placement
 func Validate(p Placement) error {
 	if p.IsMirrored() && !p.IsSharded() {
@@ -282,6 +389,11 @@ func Validate(p Placement) error {
 		return errDuplicatedShards
 	}
 
+	pendingTargets := make(map[string]struct{}, len(p.PendingShardMoves()))
+	for _, move := range p.PendingShardMoves() {
+		pendingTargets[move.TargetID] = struct{}{}
+	}
+
 	expectedTotal := len(p.Shards()) * p.ReplicaFactor()
 	totalCapacity := 0
 	totalLeaving := 0
@@ -291,7 +403,7 @@ func Validate(p Placement) error {
 		if instance.Endpoint() == "" {
 			return fmt.Errorf("instance %s does not contain valid endpoint", instance.String())
 		}
-		if instance.Shards().NumShards() == 0 && p.IsSharded() {
+		if _, ok := pendingTargets[instance.ID()]; !ok && instance.Shards().NumShards() == 0 && p.IsSharded() {
 			return fmt.Errorf("instance %s contains no shard in a sharded placement", instance.String())
 		}
 		if instance.Shards().NumShards() != 0 && !p.IsSharded() {
@@ -310,11 +422,15 @@ func Validate(p Placement) error {
 				totalInit++
 				shardCountMap[s.ID()] = count + 1
 				totalCapacity++
-				if s.SourceID() != "" {
+				// Shards split from a parent shard on the same instance have no
+				// Leaving shard to take over from.
+				if s.SourceID() != "" && s.SourceID() != instance.ID() {
 					totalInitWithSourceID++
 				}
 			case shard.Leaving:
-				totalLeaving++
+				if !IsDroppedReplica(instance, s) {
+					totalLeaving++
+				}
 			default:
 				return fmt.Errorf("invalid shard state %v for shard %d", s.State(), s.ID())
 			}
@@ -343,6 +459,81 @@ func Validate(p Placement) error {
 			return fmt.Errorf("invalid shard count for shard %d: expected %d, actual %d", shard, p.ReplicaFactor(), c)
 		}
 	}
+	if err := validatePendingShardMoves(p); err != nil {
+		return err
+	}
+
+	return validateZoneReplicas(p)
+}
+
+// validatePendingShardMoves validates that each pending shard move could start
+// by moving an Available shard from the source instance to the target instance.
+func validatePendingShardMoves(p Placement) error {
+	for _, move := range p.PendingShardMoves() {
+		source, ok := p.Instance(move.SourceID)
+		if !ok {
+			return fmt.Errorf("invalid pending shard move, source instance %s does not exist", move.SourceID)
+		}
+		if s, ok := source.Shards().Shard(move.ShardID); !ok || s.State() != shard.Available {
+			return fmt.Errorf("invalid pending shard move, shard %d is not available on source instance %s", move.ShardID, move.SourceID)
+		}
+
+		target, ok := p.Instance(move.TargetID)
+		if !ok {
+			return fmt.Errorf("invalid pending shard move, target instance %s does not exist", move.TargetID)
+		}
+		if target.Shards().Contains(move.ShardID) {
+			return fmt.Errorf("invalid pending shard move, shard %d already exists on target instance %s", move.ShardID, move.TargetID)
+		}
+	}
+	return nil
+}
+
+// validateZoneReplicas validates that each shard has the expected number of
+// replicas in each zone for placements spanning multiple zones.
+func validateZoneReplicas(p Placement) error {
+	zoneReplicas := p.ZoneReplicas()
+	if len(zoneReplicas) == 0 {
+		return nil
+	}
+
+	if !p.IsSharded() {
+		return errZoneReplicasNotSharded
+	}
+
+	total := 0
+	for _, replicas := range zoneReplicas {
+		total += replicas
+	}
+	if total != p.ReplicaFactor() {
+		return fmt.Errorf("invalid placement, the zone replicas add up to %d, expecting replica factor %d", total, p.ReplicaFactor())
+	}
+
+	zoneShardCounts := make(map[string]map[uint32]int, len(zoneReplicas))
+	for _, instance := range p.Instances() {
+		zone := instance.Zone()
+		if _, ok := zoneReplicas[zone]; !ok {
+			return fmt.Errorf("invalid placement, instance %s is in zone %s without zone replicas", instance.ID(), zone)
+		}
+		shardCounts, ok := zoneShardCounts[zone]
+		if !ok {
+			shardCounts = make(map[uint32]int, p.NumShards())
+			zoneShardCounts[zone] = shardCounts
+		}
+		for _, s := range instance.Shards().All() {
+			if s.State() != shard.Leaving {
+				shardCounts[s.ID()]++
+			}
+		}
+	}
+
+	for zone, replicas := range zoneReplicas {
+		for _, id := range p.Shards() {
+			if c := zoneShardCounts[zone][id]; c != replicas {
+				return fmt.Errorf("invalid shard count for shard %d in zone %s: expected %d, actual %d", id, zone, replicas, c)
+			}
+		}
+	}
 	return nil
 }
 
@@ -386,6 +577,7 @@ func NewInstanceFromProto(instance *placementpb.Instance) (Instance, error) {
 		SetRack(instance.Rack).
 		SetWeight(instance.Weight).
 		SetZone(instance.Zone).
+		SetRegion(instance.Region).
 		SetEndpoint(instance.Endpoint).
 		SetShards(shards).
 		SetShardSetID(instance.ShardSetId).
@@ -397,6 +589,7 @@ type instance struct {
 	id         string
 	rack       string
 	zone       string
+	region     string
 	weight     uint32
 	endpoint   string
 	hostname   string
@@ -406,9 +599,13 @@ type instance struct {
 }
 
 func (i *instance) String() string {
+	var region string
+	if i.region != "" {
+		region = fmt.Sprintf(", Region=%s", i.region)
+	}
 	return fmt.Sprintf(
-		"Instance[ID=%s, Rack=%s, Zone=%s, Weight=%d, Endpoint=%s, Hostname=%s, Port=%d, ShardSetID=%d, Shards=%s]",
-		i.id, i.rack, i.zone, i.weight, i.endpoint, i.hostname, i.port, i.shardSetID, i.shards.String(),
+		"Instance[ID=%s, Rack=%s, Zone=%s%s, Weight=%d, Endpoint=%s, Hostname=%s, Port=%d, ShardSetID=%d, Shards=%s]",
+		i.id, i.rack, i.zone, region, i.weight, i.endpoint, i.hostname, i.port, i.shardSetID, i.shards.String(),
 	)
 }
 
@@ -439,6 +636,15 @@ func (i *instance) SetZone(z string) Instance {
 	return i
 }
 
+func (i *instance) Region() string {
+	return i.region
+}
+
+func (i *instance) SetRegion(r string) Instance {
+	i.region = r
+	return i
+}
+
 func (i *instance) Weight() uint32 {
 	return i.weight
 }
@@ -503,6 +709,7 @@ func (i *instance) Proto() (*placementpb.Instance, error) {
 		Id:         i.ID(),
 		Rack:       i.Rack(),
 		Zone:       i.Zone(),
+		Region:     i.Region(),
 		Weight:     i.Weight(),
 		Endpoint:   i.Endpoint(),
 		Shards:     ss,
@@ -534,6 +741,7 @@ func (i *instance) Clone() Instance {
 		SetID(i.ID()).
 		SetRack(i.Rack()).
 		SetZone(i.Zone()).
+		SetRegion(i.Region()).
 		SetWeight(i.Weight()).
 		SetEndpoint(i.Endpoint()).
 		SetHostname(i.Hostname()).
@@ -583,3 +791,26 @@ func (s ByIDAscending) Less(i, j int) bool {
 func (s ByIDAscending) Swap(i, j int) {
 	s[i], s[j] = s[j], s[i]
 }
+
+// ShardIDsAscending sorts shard IDs ascending
+type ShardIDsAscending []uint32
+
+func (s ShardIDsAscending) Len() int {
+	return len(s)
+}
+
+func (s ShardIDsAscending) Less(i, j int) bool {
+	return s[i] < s[j]
+}
+
+func (s ShardIDsAscending) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+// SortedShardIDs returns a copy of the shard IDs sorted ascending
+func SortedShardIDs(ids []uint32) []uint32 {
+	sorted := make([]uint32, len(ids))
+	copy(sorted, ids)
+	sort.Sort(ShardIDsAscending(sorted))
+	return sorted
+}
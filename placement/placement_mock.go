@@ -31,6 +31,7 @@ import (
 	shard "github.com/m3db/m3cluster/shard"
 	clock "github.com/m3db/m3x/clock"
 	instrument "github.com/m3db/m3x/instrument"
+	retry "github.com/m3db/m3x/retry"
 	time "time"
 )
 
@@ -125,6 +126,26 @@ func (_mr *_MockInstanceRecorder) SetZone(arg0 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "SetZone", arg0)
 }
 
+func (_m *MockInstance) Region() string {
+	ret := _m.ctrl.Call(_m, "Region")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+func (_mr *_MockInstanceRecorder) Region() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Region")
+}
+
+func (_m *MockInstance) SetRegion(r string) Instance {
+	ret := _m.ctrl.Call(_m, "SetRegion", r)
+	ret0, _ := ret[0].(Instance)
+	return ret0
+}
+
+func (_mr *_MockInstanceRecorder) SetRegion(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "SetRegion", arg0)
+}
+
 func (_m *MockInstance) Weight() uint32 {
 	ret := _m.ctrl.Call(_m, "Weight")
 	ret0, _ := ret[0].(uint32)
@@ -468,6 +489,46 @@ func (_mr *_MockPlacementRecorder) SetIsMirrored(arg0 interface{}) *gomock.Call
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "SetIsMirrored", arg0)
 }
 
+func (_m *MockPlacement) ZoneReplicas() map[string]int {
+	ret := _m.ctrl.Call(_m, "ZoneReplicas")
+	ret0, _ := ret[0].(map[string]int)
+	return ret0
+}
+
+func (_mr *_MockPlacementRecorder) ZoneReplicas() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "ZoneReplicas")
+}
+
+func (_m *MockPlacement) SetZoneReplicas(zoneReplicas map[string]int) Placement {
+	ret := _m.ctrl.Call(_m, "SetZoneReplicas", zoneReplicas)
+	ret0, _ := ret[0].(Placement)
+	return ret0
+}
+
+func (_mr *_MockPlacementRecorder) SetZoneReplicas(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "SetZoneReplicas", arg0)
+}
+
+func (_m *MockPlacement) PendingShardMoves() []PendingShardMove {
+	ret := _m.ctrl.Call(_m, "PendingShardMoves")
+	ret0, _ := ret[0].([]PendingShardMove)
+	return ret0
+}
+
+func (_mr *_MockPlacementRecorder) PendingShardMoves() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "PendingShardMoves")
+}
+
+func (_m *MockPlacement) SetPendingShardMoves(moves []PendingShardMove) Placement {
+	ret := _m.ctrl.Call(_m, "SetPendingShardMoves", moves)
+	ret0, _ := ret[0].(Placement)
+	return ret0
+}
+
+func (_mr *_MockPlacementRecorder) SetPendingShardMoves(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "SetPendingShardMoves", arg0)
+}
+
 func (_m *MockPlacement) String() string {
 	ret := _m.ctrl.Call(_m, "String")
 	ret0, _ := ret[0].(string)
@@ -980,6 +1041,46 @@ func (_mr *_MockOptionsRecorder) SetLooseRackCheck(arg0 interface{}) *gomock.Cal
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "SetLooseRackCheck", arg0)
 }
 
+func (_m *MockOptions) IsolationLevel() IsolationLevel {
+	ret := _m.ctrl.Call(_m, "IsolationLevel")
+	ret0, _ := ret[0].(IsolationLevel)
+	return ret0
+}
+
+func (_mr *_MockOptionsRecorder) IsolationLevel() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "IsolationLevel")
+}
+
+func (_m *MockOptions) SetIsolationLevel(level IsolationLevel) Options {
+	ret := _m.ctrl.Call(_m, "SetIsolationLevel", level)
+	ret0, _ := ret[0].(Options)
+	return ret0
+}
+
+func (_mr *_MockOptionsRecorder) SetIsolationLevel(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "SetIsolationLevel", arg0)
+}
+
+func (_m *MockOptions) IsolationMode() IsolationMode {
+	ret := _m.ctrl.Call(_m, "IsolationMode")
+	ret0, _ := ret[0].(IsolationMode)
+	return ret0
+}
+
+func (_mr *_MockOptionsRecorder) IsolationMode() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "IsolationMode")
+}
+
+func (_m *MockOptions) SetIsolationMode(mode IsolationMode) Options {
+	ret := _m.ctrl.Call(_m, "SetIsolationMode", mode)
+	ret0, _ := ret[0].(Options)
+	return ret0
+}
+
+func (_mr *_MockOptionsRecorder) SetIsolationMode(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "SetIsolationMode", arg0)
+}
+
 func (_m *MockOptions) AllowPartialReplace() bool {
 	ret := _m.ctrl.Call(_m, "AllowPartialReplace")
 	ret0, _ := ret[0].(bool)
@@ -1120,6 +1221,86 @@ func (_mr *_MockOptionsRecorder) SetValidZone(arg0 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "SetValidZone", arg0)
 }
 
+func (_m *MockOptions) ZoneReplicas() map[string]int {
+	ret := _m.ctrl.Call(_m, "ZoneReplicas")
+	ret0, _ := ret[0].(map[string]int)
+	return ret0
+}
+
+func (_mr *_MockOptionsRecorder) ZoneReplicas() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "ZoneReplicas")
+}
+
+func (_m *MockOptions) SetZoneReplicas(zoneReplicas map[string]int) Options {
+	ret := _m.ctrl.Call(_m, "SetZoneReplicas", zoneReplicas)
+	ret0, _ := ret[0].(Options)
+	return ret0
+}
+
+func (_mr *_MockOptionsRecorder) SetZoneReplicas(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "SetZoneReplicas", arg0)
+}
+
+func (_m *MockOptions) MaxInitializingShardsPerInstance() int {
+	ret := _m.ctrl.Call(_m, "MaxInitializingShardsPerInstance")
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+func (_mr *_MockOptionsRecorder) MaxInitializingShardsPerInstance() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "MaxInitializingShardsPerInstance")
+}
+
+func (_m *MockOptions) SetMaxInitializingShardsPerInstance(value int) Options {
+	ret := _m.ctrl.Call(_m, "SetMaxInitializingShardsPerInstance", value)
+	ret0, _ := ret[0].(Options)
+	return ret0
+}
+
+func (_mr *_MockOptionsRecorder) SetMaxInitializingShardsPerInstance(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "SetMaxInitializingShardsPerInstance", arg0)
+}
+
+func (_m *MockOptions) MaxInitializingShardsPerPlacement() int {
+	ret := _m.ctrl.Call(_m, "MaxInitializingShardsPerPlacement")
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+func (_mr *_MockOptionsRecorder) MaxInitializingShardsPerPlacement() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "MaxInitializingShardsPerPlacement")
+}
+
+func (_m *MockOptions) SetMaxInitializingShardsPerPlacement(value int) Options {
+	ret := _m.ctrl.Call(_m, "SetMaxInitializingShardsPerPlacement", value)
+	ret0, _ := ret[0].(Options)
+	return ret0
+}
+
+func (_mr *_MockOptionsRecorder) SetMaxInitializingShardsPerPlacement(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "SetMaxInitializingShardsPerPlacement", arg0)
+}
+
+func (_m *MockOptions) ConflictRetryOptions() retry.Options {
+	ret := _m.ctrl.Call(_m, "ConflictRetryOptions")
+	ret0, _ := ret[0].(retry.Options)
+	return ret0
+}
+
+func (_mr *_MockOptionsRecorder) ConflictRetryOptions() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "ConflictRetryOptions")
+}
+
+func (_m *MockOptions) SetConflictRetryOptions(value retry.Options) Options {
+	ret := _m.ctrl.Call(_m, "SetConflictRetryOptions", value)
+	ret0, _ := ret[0].(Options)
+	return ret0
+}
+
+func (_mr *_MockOptionsRecorder) SetConflictRetryOptions(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "SetConflictRetryOptions", arg0)
+}
+
 func (_m *MockOptions) PlacementCutoverNanosFn() TimeNanosFn {
 	ret := _m.ctrl.Call(_m, "PlacementCutoverNanosFn")
 	ret0, _ := ret[0].(TimeNanosFn)
@@ -1303,6 +1484,17 @@ func (_mr *_MockStorageRecorder) Placement() *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "Placement")
 }
 
+func (_m *MockStorage) History(from int, to int) ([]Placement, error) {
+	ret := _m.ctrl.Call(_m, "History", from, to)
+	ret0, _ := ret[0].([]Placement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockStorageRecorder) History(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "History", arg0, arg1)
+}
+
 func (_m *MockStorage) Delete() error {
 	ret := _m.ctrl.Call(_m, "Delete")
 	ret0, _ := ret[0].(error)
@@ -1408,6 +1600,17 @@ func (_mr *_MockServiceRecorder) Placement() *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "Placement")
 }
 
+func (_m *MockService) History(from int, to int) ([]Placement, error) {
+	ret := _m.ctrl.Call(_m, "History", from, to)
+	ret0, _ := ret[0].([]Placement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockServiceRecorder) History(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "History", arg0, arg1)
+}
+
 func (_m *MockService) Delete() error {
 	ret := _m.ctrl.Call(_m, "Delete")
 	ret0, _ := ret[0].(error)
@@ -1472,6 +1675,50 @@ func (_mr *_MockServiceRecorder) AddReplica() *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "AddReplica")
 }
 
+func (_m *MockService) Rollback(version int) (Placement, error) {
+	ret := _m.ctrl.Call(_m, "Rollback", version)
+	ret0, _ := ret[0].(Placement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockServiceRecorder) Rollback(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Rollback", arg0)
+}
+
+func (_m *MockService) RemoveReplica() (Placement, error) {
+	ret := _m.ctrl.Call(_m, "RemoveReplica")
+	ret0, _ := ret[0].(Placement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockServiceRecorder) RemoveReplica() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "RemoveReplica")
+}
+
+func (_m *MockService) SplitShards(factor int) (Placement, error) {
+	ret := _m.ctrl.Call(_m, "SplitShards", factor)
+	ret0, _ := ret[0].(Placement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockServiceRecorder) SplitShards(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "SplitShards", arg0)
+}
+
+func (_m *MockService) Rebalance(maxShardMoves int) (Placement, error) {
+	ret := _m.ctrl.Call(_m, "Rebalance", maxShardMoves)
+	ret0, _ := ret[0].(Placement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockServiceRecorder) Rebalance(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Rebalance", arg0)
+}
+
 func (_m *MockService) AddInstances(candidates []Instance) (Placement, []Instance, error) {
 	ret := _m.ctrl.Call(_m, "AddInstances", candidates)
 	ret0, _ := ret[0].(Placement)
@@ -1507,6 +1754,17 @@ func (_mr *_MockServiceRecorder) ReplaceInstances(arg0, arg1 interface{}) *gomoc
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "ReplaceInstances", arg0, arg1)
 }
 
+func (_m *MockService) UpdateInstances(instances []Instance) (Placement, error) {
+	ret := _m.ctrl.Call(_m, "UpdateInstances", instances)
+	ret0, _ := ret[0].(Placement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockServiceRecorder) UpdateInstances(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "UpdateInstances", arg0)
+}
+
 func (_m *MockService) MarkShardAvailable(instanceID string, shardID uint32) error {
 	ret := _m.ctrl.Call(_m, "MarkShardAvailable", instanceID, shardID)
 	ret0, _ := ret[0].(error)
@@ -1527,6 +1785,17 @@ func (_mr *_MockServiceRecorder) MarkInstanceAvailable(arg0 interface{}) *gomock
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "MarkInstanceAvailable", arg0)
 }
 
+func (_m *MockService) MarkShardsAvailable(shards []InstanceShard) ([]MarkShardResult, error) {
+	ret := _m.ctrl.Call(_m, "MarkShardsAvailable", shards)
+	ret0, _ := ret[0].([]MarkShardResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockServiceRecorder) MarkShardsAvailable(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "MarkShardsAvailable", arg0)
+}
+
 // Mock of Algorithm interface
 type MockAlgorithm struct {
 	ctrl     *gomock.Controller
@@ -1570,6 +1839,39 @@ func (_mr *_MockAlgorithmRecorder) AddReplica(arg0 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "AddReplica", arg0)
 }
 
+func (_m *MockAlgorithm) RemoveReplica(p Placement) (Placement, error) {
+	ret := _m.ctrl.Call(_m, "RemoveReplica", p)
+	ret0, _ := ret[0].(Placement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockAlgorithmRecorder) RemoveReplica(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "RemoveReplica", arg0)
+}
+
+func (_m *MockAlgorithm) SplitShards(p Placement, factor int) (Placement, error) {
+	ret := _m.ctrl.Call(_m, "SplitShards", p, factor)
+	ret0, _ := ret[0].(Placement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockAlgorithmRecorder) SplitShards(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "SplitShards", arg0, arg1)
+}
+
+func (_m *MockAlgorithm) Rebalance(p Placement, maxShardMoves int) (Placement, error) {
+	ret := _m.ctrl.Call(_m, "Rebalance", p, maxShardMoves)
+	ret0, _ := ret[0].(Placement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockAlgorithmRecorder) Rebalance(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Rebalance", arg0, arg1)
+}
+
 func (_m *MockAlgorithm) AddInstances(p Placement, instances []Instance) (Placement, error) {
 	ret := _m.ctrl.Call(_m, "AddInstances", p, instances)
 	ret0, _ := ret[0].(Placement)
@@ -1603,6 +1905,17 @@ func (_mr *_MockAlgorithmRecorder) ReplaceInstances(arg0, arg1, arg2 interface{}
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "ReplaceInstances", arg0, arg1, arg2)
 }
 
+func (_m *MockAlgorithm) UpdateInstances(p Placement, instances []Instance) (Placement, error) {
+	ret := _m.ctrl.Call(_m, "UpdateInstances", p, instances)
+	ret0, _ := ret[0].(Placement)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockAlgorithmRecorder) UpdateInstances(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "UpdateInstances", arg0, arg1)
+}
+
 func (_m *MockAlgorithm) IsCompatibleWith(p Placement) error {
 	ret := _m.ctrl.Call(_m, "IsCompatibleWith", p)
 	ret0, _ := ret[0].(error)
@@ -276,6 +276,52 @@ func TestValidateLeavingNotMatchInitializingWithSourceID(t *testing.T) {
 	assert.Equal(t, err.Error(), "invalid placement, 2 shards in Leaving state, not equal 1 in Initializing state with source id")
 }
 
+func TestValidateDroppedReplica(t *testing.T) {
+	i1 := NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i1.Shards().Add(shard.NewShard(1).SetState(shard.Available))
+	i1.Shards().Add(shard.NewShard(2).SetState(shard.Leaving).SetSourceID("i1"))
+
+	i2 := NewEmptyInstance("i2", "r2", "z1", "endpoint", 1)
+	i2.Shards().Add(shard.NewShard(1).SetState(shard.Leaving).SetSourceID("i2"))
+	i2.Shards().Add(shard.NewShard(2).SetState(shard.Available))
+
+	s, _ := i1.Shards().Shard(2)
+	assert.True(t, IsDroppedReplica(i1, s))
+	assert.False(t, IsDroppedReplica(i2, s))
+	s, _ = i1.Shards().Shard(1)
+	assert.False(t, IsDroppedReplica(i1, s))
+
+	p := NewPlacement().
+		SetInstances([]Instance{i1, i2}).
+		SetShards([]uint32{1, 2}).
+		SetReplicaFactor(1).
+		SetIsSharded(true)
+	assert.NoError(t, Validate(p))
+
+	i2.Shards().Add(shard.NewShard(1).SetState(shard.Leaving))
+	assert.Error(t, Validate(p))
+}
+
+func TestValidateSplitShards(t *testing.T) {
+	i1 := NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i1.Shards().Add(shard.NewShard(0).SetState(shard.Available))
+	i1.Shards().Add(shard.NewShard(2).SetState(shard.Initializing).SetSourceID("i1"))
+
+	i2 := NewEmptyInstance("i2", "r2", "z1", "endpoint", 1)
+	i2.Shards().Add(shard.NewShard(1).SetState(shard.Available))
+	i2.Shards().Add(shard.NewShard(3).SetState(shard.Initializing).SetSourceID("i2"))
+
+	p := NewPlacement().
+		SetInstances([]Instance{i1, i2}).
+		SetShards([]uint32{0, 1, 2, 3}).
+		SetReplicaFactor(1).
+		SetIsSharded(true)
+	assert.NoError(t, Validate(p))
+
+	i2.Shards().Add(shard.NewShard(3).SetState(shard.Initializing).SetSourceID("i1"))
+	assert.Error(t, Validate(p))
+}
+
 func TestValidateNoEndpoint(t *testing.T) {
 	i1 := NewEmptyInstance("i1", "r1", "z1", "", 1)
 	i1.Shards().Add(shard.NewShard(1).SetState(shard.Available))
@@ -309,6 +355,78 @@ func TestValidateInstanceWithNoShard(t *testing.T) {
 	assert.Contains(t, err.Error(), "contains no shard")
 }
 
+func TestValidateZoneReplicas(t *testing.T) {
+	i1 := NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i1.Shards().Add(shard.NewShard(1).SetState(shard.Available))
+	i1.Shards().Add(shard.NewShard(2).SetState(shard.Available))
+
+	i2 := NewEmptyInstance("i2", "r2", "z1", "endpoint", 1)
+	i2.Shards().Add(shard.NewShard(1).SetState(shard.Available))
+	i2.Shards().Add(shard.NewShard(2).SetState(shard.Available))
+
+	i3 := NewEmptyInstance("i3", "r3", "z2", "endpoint", 1)
+	i3.Shards().Add(shard.NewShard(1).SetState(shard.Available))
+	i3.Shards().Add(shard.NewShard(2).SetState(shard.Available))
+
+	p := NewPlacement().
+		SetInstances([]Instance{i1, i2, i3}).
+		SetShards([]uint32{1, 2}).
+		SetReplicaFactor(3).
+		SetIsSharded(true).
+		SetZoneReplicas(map[string]int{"z1": 2, "z2": 1})
+	assert.NoError(t, Validate(p))
+
+	pb, err := p.Proto()
+	assert.NoError(t, err)
+	fromProto, err := NewPlacementFromProto(pb)
+	assert.NoError(t, err)
+	assert.Equal(t, p.ZoneReplicas(), fromProto.ZoneReplicas())
+	assert.Equal(t, p.ZoneReplicas(), p.Clone().ZoneReplicas())
+
+	// Replicas in the wrong zones.
+	p.SetZoneReplicas(map[string]int{"z1": 1, "z2": 2})
+	assert.Error(t, Validate(p))
+
+	// Zone replicas not adding up to the replica factor.
+	p.SetZoneReplicas(map[string]int{"z1": 2, "z2": 2})
+	assert.Error(t, Validate(p))
+
+	// Instances in a zone without replicas.
+	p.SetZoneReplicas(map[string]int{"z1": 3})
+	assert.Error(t, Validate(p))
+}
+
+func TestValidatePendingShardMoves(t *testing.T) {
+	i1 := NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i1.Shards().Add(shard.NewShard(1).SetState(shard.Available))
+	i1.Shards().Add(shard.NewShard(2).SetState(shard.Available))
+
+	i2 := NewEmptyInstance("i2", "r2", "z1", "endpoint", 1)
+
+	p := NewPlacement().
+		SetInstances([]Instance{i1, i2}).
+		SetShards([]uint32{1, 2}).
+		SetReplicaFactor(1).
+		SetIsSharded(true)
+	assert.Error(t, Validate(p))
+
+	p.SetPendingShardMoves([]PendingShardMove{{ShardID: 2, SourceID: "i1", TargetID: "i2"}})
+	assert.NoError(t, Validate(p))
+
+	pb, err := p.Proto()
+	assert.NoError(t, err)
+	fromProto, err := NewPlacementFromProto(pb)
+	assert.NoError(t, err)
+	assert.Equal(t, p.PendingShardMoves(), fromProto.PendingShardMoves())
+	assert.Equal(t, p.PendingShardMoves(), p.Clone().PendingShardMoves())
+
+	p.SetPendingShardMoves([]PendingShardMove{{ShardID: 2, SourceID: "i2", TargetID: "i1"}})
+	assert.Error(t, Validate(p))
+
+	p.SetPendingShardMoves([]PendingShardMove{{ShardID: 2, SourceID: "i1", TargetID: "i3"}})
+	assert.Error(t, Validate(p))
+}
+
 func TestInstance(t *testing.T) {
 	i1 := NewInstance().
 		SetID("id").
@@ -346,6 +464,9 @@ func TestInstance(t *testing.T) {
 	assert.Equal(t, 2, i1.Shards().NumShards())
 	assert.Equal(t, "id", i1.ID())
 	assert.Equal(t, "rack", i1.Rack())
+
+	i1.SetRegion("region")
+	assert.Contains(t, i1.String(), "Zone=zone, Region=region, Weight=1")
 }
 
 func TestInstanceIsLeaving(t *testing.T) {
@@ -392,6 +513,12 @@ func TestSortInstanceByID(t *testing.T) {
 	assert.Equal(t, []Instance{i1, i2, i3, i4, i5, i6}, i)
 }
 
+func TestSortedShardIDs(t *testing.T) {
+	ids := []uint32{3, 1, 4, 2}
+	assert.Equal(t, []uint32{1, 2, 3, 4}, SortedShardIDs(ids))
+	assert.Equal(t, []uint32{3, 1, 4, 2}, ids)
+}
+
 func TestClonePlacement(t *testing.T) {
 	i1 := NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
 	i1.Shards().Add(shard.NewShard(1).SetState(shard.Available))
@@ -502,6 +629,7 @@ func TestPlacementInstanceFromProto(t *testing.T) {
 		Id:       "i1",
 		Rack:     "r1",
 		Zone:     "z1",
+		Region:   "g1",
 		Endpoint: "e1",
 		Weight:   1,
 		Shards:   protoShardsUnsorted,
@@ -526,6 +654,7 @@ func TestPlacementInstanceFromProto(t *testing.T) {
 		SetID("i1").
 		SetRack("r1").
 		SetZone("z1").
+		SetRegion("g1").
 		SetEndpoint("e1").
 		SetWeight(1)
 
@@ -66,6 +66,10 @@ func filterZones(
 		return []placement.Instance{}
 	}
 
+	if opts != nil && len(opts.ZoneReplicas()) > 0 {
+		return filterZoneReplicas(candidates, opts.ZoneReplicas())
+	}
+
 	var validZone string
 	if opts != nil {
 		validZone = opts.ValidZone()
@@ -82,3 +86,17 @@ func filterZones(
 	}
 	return validInstances
 }
+
+// filterZoneReplicas finds the instances in the zones that hold replicas.
+func filterZoneReplicas(
+	candidates []placement.Instance,
+	zoneReplicas map[string]int,
+) []placement.Instance {
+	validInstances := make([]placement.Instance, 0, len(candidates))
+	for _, instance := range candidates {
+		if zoneReplicas[instance.Zone()] > 0 {
+			validInstances = append(validInstances, instance)
+		}
+	}
+	return validInstances
+}
@@ -57,17 +57,17 @@ func (f *mirroredFilter) SelectInitialInstances(
 		return nil, err
 	}
 
-	weightToHostMap, err := groupHostsByWeight(candidates)
+	weightToHostMap, err := groupHostsByWeight(candidates, f.opts.IsolationLevel())
 	if err != nil {
 		return nil, err
 	}
 
 	var groups = make([][]placement.Instance, 0, len(candidates))
 	for _, hosts := range weightToHostMap {
-		groupedHosts, ungrouped := groupHostsWithRackCheck(hosts, rf)
+		groupedHosts, ungrouped := f.groupHosts(hosts, rf)
 		if len(ungrouped) != 0 {
 			for _, host := range ungrouped {
-				f.logger.Warnf("could not group host %s, rack %s, weight %d", host.name, host.rack, host.weight)
+				f.logger.Warnf("could not group host %s, failure domain %s, weight %d", host.name, host.domain, host.weight)
 			}
 		}
 		if len(groupedHosts) == 0 {
@@ -100,14 +100,14 @@ func (f *mirroredFilter) SelectAddingInstances(
 		return nil, err
 	}
 
-	weightToHostMap, err := groupHostsByWeight(candidates)
+	weightToHostMap, err := groupHostsByWeight(candidates, f.opts.IsolationLevel())
 	if err != nil {
 		return nil, err
 	}
 
 	var groups = make([][]placement.Instance, 0, len(candidates))
 	for _, hosts := range weightToHostMap {
-		groupedHosts, _ := groupHostsWithRackCheck(hosts, p.ReplicaFactor())
+		groupedHosts, _ := f.groupHosts(hosts, p.ReplicaFactor())
 		if len(groupedHosts) == 0 {
 			continue
 		}
@@ -157,7 +157,12 @@ func (f *mirroredFilter) SelectReplaceInstances(
 	)
 	for _, instance := range leavingInstances {
 		if h.name == "" {
-			h = newHost(instance.Hostname(), instance.Rack(), instance.Weight())
+			h = newHost(
+				instance.Hostname(),
+				instance.Rack(),
+				placement.FailureDomain(instance, f.opts.IsolationLevel()),
+				instance.Weight(),
+			)
 		}
 
 		err := h.addInstance(instance.Port(), instance)
@@ -167,7 +172,7 @@ func (f *mirroredFilter) SelectReplaceInstances(
 		ssIDs[instance.ShardSetID()] = struct{}{}
 	}
 
-	weightToHostMap, err := groupHostsByWeight(candidates)
+	weightToHostMap, err := groupHostsByWeight(candidates, f.opts.IsolationLevel())
 	if err != nil {
 		return nil, err
 	}
@@ -177,8 +182,8 @@ func (f *mirroredFilter) SelectReplaceInstances(
 		return nil, fmt.Errorf("could not find instances with weight %d in the candidate list", h.weight)
 	}
 
-	// Find out the racks that are already in the same shard set id with the leaving instances.
-	var conflictRacks = make(map[string]struct{})
+	// Find out the failure domains that are already in the same shard set id with the leaving instances.
+	var conflictDomains = make(map[string]struct{})
 	for _, instance := range p.Instances() {
 		if _, ok := ssIDs[instance.ShardSetID()]; !ok {
 			continue
@@ -190,27 +195,12 @@ func (f *mirroredFilter) SelectReplaceInstances(
 			continue
 		}
 
-		conflictRacks[instance.Rack()] = struct{}{}
+		conflictDomains[placement.FailureDomain(instance, f.opts.IsolationLevel())] = struct{}{}
 	}
 
-	var groups [][]placement.Instance
-	for _, candidateHost := range hosts {
-		if candidateHost.name == h.name {
-			continue
-		}
-
-		if _, ok := conflictRacks[candidateHost.rack]; ok {
-			continue
-		}
-
-		groups, err = groupInstancesByHostPort([][]host{[]host{h, candidateHost}})
-		if err != nil {
-			f.logger.Warnf("could not match up candidate host %s with target host %s: %v", candidateHost.name, h.name, err)
-			continue
-		}
-
-		// Successfully grouped candidate with the host in placement.
-		break
+	groups := f.matchReplaceHost(h, hosts, conflictDomains, false)
+	if len(groups) == 0 && f.opts.IsolationMode() == placement.IsolationModeBestEffort {
+		groups = f.matchReplaceHost(h, hosts, conflictDomains, true)
 	}
 
 	if len(groups) == 0 {
@@ -237,6 +227,52 @@ func (f *mirroredFilter) SelectReplaceInstances(
 	return res, nil
 }
 
+// matchReplaceHost finds the first candidate host that could replace the
+// given host, skipping candidates in the conflicting failure domains unless
+// allowConflict is set.
+func (f *mirroredFilter) matchReplaceHost(
+	h host,
+	candidates []host,
+	conflictDomains map[string]struct{},
+	allowConflict bool,
+) [][]placement.Instance {
+	for _, candidateHost := range candidates {
+		if candidateHost.name == h.name {
+			continue
+		}
+
+		if _, ok := conflictDomains[candidateHost.domain]; ok && !allowConflict {
+			continue
+		}
+
+		groups, err := groupInstancesByHostPort([][]host{[]host{h, candidateHost}})
+		if err != nil {
+			f.logger.Warnf("could not match up candidate host %s with target host %s: %v", candidateHost.name, h.name, err)
+			continue
+		}
+
+		// Successfully grouped candidate with the host in placement.
+		return groups
+	}
+	return nil
+}
+
+// groupHosts groups the hosts with the isolation check, in best effort
+// mode the hosts that could not be isolated are grouped regardless of
+// their failure domains.
+func (f *mirroredFilter) groupHosts(hosts []host, rf int) ([][]host, []host) {
+	groups, ungrouped := groupHostsWithIsolationCheck(hosts, rf)
+	if f.opts.IsolationMode() != placement.IsolationModeBestEffort {
+		return groups, ungrouped
+	}
+
+	for len(ungrouped) >= rf {
+		groups = append(groups, ungrouped[:rf])
+		ungrouped = ungrouped[rf:]
+	}
+	return groups, ungrouped
+}
+
 func findIndex(ids []string, id string) int {
 	for i := range ids {
 		if ids[i] == id {
@@ -247,7 +283,10 @@ func findIndex(ids []string, id string) int {
 	return -1
 }
 
-func groupHostsByWeight(candidates []placement.Instance) (map[uint32][]host, error) {
+func groupHostsByWeight(
+	candidates []placement.Instance,
+	level placement.IsolationLevel,
+) (map[uint32][]host, error) {
 	var (
 		uniqueHosts      = make(map[string]host, len(candidates))
 		weightToHostsMap = make(map[uint32][]host, len(candidates))
@@ -257,7 +296,7 @@ func groupHostsByWeight(candidates []placement.Instance) (map[uint32][]host, err
 		weight := instance.Weight()
 		h, ok := uniqueHosts[hostname]
 		if !ok {
-			h = newHost(hostname, instance.Rack(), weight)
+			h = newHost(hostname, instance.Rack(), placement.FailureDomain(instance, level), weight)
 			uniqueHosts[hostname] = h
 			weightToHostsMap[weight] = append(weightToHostsMap[weight], h)
 		}
@@ -269,64 +308,64 @@ func groupHostsByWeight(candidates []placement.Instance) (map[uint32][]host, err
 	return weightToHostsMap, nil
 }
 
-// groupHostsWithRackCheck looks at the racks of the given hosts
-// and try to make as many groups as possible. The hosts in each group
-// must come from different racks.
-func groupHostsWithRackCheck(hosts []host, rf int) ([][]host, []host) {
+// groupHostsWithIsolationCheck looks at the failure domains of the given
+// hosts and try to make as many groups as possible. The hosts in each group
+// must come from different failure domains.
+func groupHostsWithIsolationCheck(hosts []host, rf int) ([][]host, []host) {
 	if len(hosts) < rf {
 		// When the number of hosts is less than rf, no groups can be made.
 		return nil, hosts
 	}
 
 	var (
-		uniqRacks = make(map[string]*rack, len(hosts))
-		rh        = racksByNumHost(make([]*rack, 0, len(hosts)))
+		uniqDomains = make(map[string]*failureDomain, len(hosts))
+		dh          = domainsByNumHost(make([]*failureDomain, 0, len(hosts)))
 	)
 	for _, h := range hosts {
-		r, ok := uniqRacks[h.rack]
+		d, ok := uniqDomains[h.domain]
 		if !ok {
-			r = &rack{
-				rack:  h.rack,
-				hosts: make([]host, 0, rf),
+			d = &failureDomain{
+				domain: h.domain,
+				hosts:  make([]host, 0, rf),
 			}
 
-			uniqRacks[h.rack] = r
-			rh = append(rh, r)
+			uniqDomains[h.domain] = d
+			dh = append(dh, d)
 		}
-		r.hosts = append(r.hosts, h)
+		d.hosts = append(d.hosts, h)
 	}
 
-	heap.Init(&rh)
+	heap.Init(&dh)
 
-	// For each group, always prefer to find one host from the largest rack
-	// in the heap. After a group is filled, push all the checked racks back
-	// to the heap so they can be used for the next group.
+	// For each group, always prefer to find one host from the largest failure
+	// domain in the heap. After a group is filled, push all the checked domains
+	// back to the heap so they can be used for the next group.
 	res := make([][]host, 0, int(math.Ceil(float64(len(hosts))/float64(rf))))
-	for rh.Len() >= rf {
-		// When there are more than rf racks available, try to make a group.
-		seenRacks := make(map[string]*rack, rf)
+	for dh.Len() >= rf {
+		// When there are more than rf failure domains available, try to make a group.
+		seenDomains := make(map[string]*failureDomain, rf)
 		groups := make([]host, 0, rf)
 		for i := 0; i < rf; i++ {
-			r := heap.Pop(&rh).(*rack)
-			// Move the host from the rack to the group. The racks in the heap
-			// always have at least one host.
-			groups = append(groups, r.hosts[len(r.hosts)-1])
-			r.hosts = r.hosts[:len(r.hosts)-1]
-			seenRacks[r.rack] = r
+			d := heap.Pop(&dh).(*failureDomain)
+			// Move the host from the failure domain to the group. The domains
+			// in the heap always have at least one host.
+			groups = append(groups, d.hosts[len(d.hosts)-1])
+			d.hosts = d.hosts[:len(d.hosts)-1]
+			seenDomains[d.domain] = d
 		}
 		if len(groups) == rf {
 			res = append(res, groups)
 		}
-		for _, r := range seenRacks {
-			if len(r.hosts) > 0 {
-				heap.Push(&rh, r)
+		for _, d := range seenDomains {
+			if len(d.hosts) > 0 {
+				heap.Push(&dh, d)
 			}
 		}
 	}
 
-	ungrouped := make([]host, 0, rh.Len())
-	for _, r := range rh {
-		ungrouped = append(ungrouped, r.hosts...)
+	ungrouped := make([]host, 0, dh.Len())
+	for _, d := range dh {
+		ungrouped = append(ungrouped, d.hosts...)
 	}
 	return res, ungrouped
 }
@@ -398,14 +437,16 @@ func nextNShardSetIDs(p placement.Placement, n int) []uint32 {
 type host struct {
 	name           string
 	rack           string
+	domain         string
 	weight         uint32
 	portToInstance map[uint32]placement.Instance
 }
 
-func newHost(name, rack string, weight uint32) host {
+func newHost(name, rack, domain string, weight uint32) host {
 	return host{
 		name:           name,
 		rack:           rack,
+		domain:         domain,
 		weight:         weight,
 		portToInstance: make(map[uint32]placement.Instance),
 	}
@@ -424,34 +465,34 @@ func (h host) addInstance(port uint32, instance placement.Instance) error {
 	return nil
 }
 
-type rack struct {
-	rack  string
-	hosts []host
+type failureDomain struct {
+	domain string
+	hosts  []host
 }
 
-type racksByNumHost []*rack
+type domainsByNumHost []*failureDomain
 
-func (h racksByNumHost) Len() int {
+func (h domainsByNumHost) Len() int {
 	return len(h)
 }
 
-func (h racksByNumHost) Less(i, j int) bool {
+func (h domainsByNumHost) Less(i, j int) bool {
 	return len(h[i].hosts) > len(h[j].hosts)
 }
 
-func (h racksByNumHost) Swap(i, j int) {
+func (h domainsByNumHost) Swap(i, j int) {
 	h[i], h[j] = h[j], h[i]
 }
 
-func (h *racksByNumHost) Push(i interface{}) {
-	r := i.(*rack)
-	*h = append(*h, r)
+func (h *domainsByNumHost) Push(i interface{}) {
+	d := i.(*failureDomain)
+	*h = append(*h, d)
 }
 
-func (h *racksByNumHost) Pop() interface{} {
+func (h *domainsByNumHost) Pop() interface{} {
 	old := *h
 	n := len(old)
-	rack := old[n-1]
+	d := old[n-1]
 	*h = old[0 : n-1]
-	return rack
+	return d
 }
@@ -370,6 +370,51 @@ func TestSelectInitialInstancesForMirrorRF3(t *testing.T) {
 		require.Equal(t, 0, count)
 	}
 }
+func TestSelectInitialInstancesForMirrorIsolation(t *testing.T) {
+	newInstance := func(host, rack, zone string) placement.Instance {
+		return placement.NewInstance().
+			SetID(host + "p1").
+			SetHostname(host).
+			SetPort(1).
+			SetRack(rack).
+			SetZone(zone).
+			SetEndpoint(host + "p1e").
+			SetWeight(1)
+	}
+	instances := []placement.Instance{
+		newInstance("h1", "r1", "z1"),
+		newInstance("h2", "r2", "z1"),
+		newInstance("h3", "r3", "z1"),
+		newInstance("h4", "r4", "z1"),
+	}
+
+	// Four racks make two groups with rack isolation.
+	selector := newMirroredSelector(placement.NewOptions().SetValidZone("z1"))
+	res, err := selector.SelectInitialInstances(instances, 2)
+	require.NoError(t, err)
+	require.Equal(t, 4, len(res))
+
+	// A single zone could not make any group with strict zone isolation.
+	opts := placement.NewOptions().SetValidZone("z1").SetIsolationLevel(placement.IsolationLevelZone)
+	selector = newMirroredSelector(opts)
+	_, err = selector.SelectInitialInstances(instances, 2)
+	require.Equal(t, errNoValidMirrorInstance, err)
+
+	selector = newMirroredSelector(opts.SetIsolationMode(placement.IsolationModeBestEffort))
+	res, err = selector.SelectInitialInstances(instances, 2)
+	require.NoError(t, err)
+	require.Equal(t, 4, len(res))
+
+	shardSets := make(map[uint32]int)
+	for _, instance := range res {
+		shardSets[instance.ShardSetID()]++
+	}
+	require.Equal(t, 2, len(shardSets))
+	for _, count := range shardSets {
+		require.Equal(t, 2, count)
+	}
+}
+
 func TestSelectReplaceInstanceForMirror(t *testing.T) {
 	h1p1 := placement.NewInstance().
 		SetID("h1p1").
@@ -58,33 +58,33 @@ func (f *nonMirroredFilter) SelectAddingInstances(
 		return nil, err
 	}
 
-	// build rack-instance map for candidate instances
-	candidateRackMap := buildRackMap(candidates)
+	// build domain-instance map for candidate instances
+	candidateDomainMap := buildDomainMap(candidates, f.opts.IsolationLevel())
 
-	// build rack-instance map for current placement
-	placementRackMap := buildRackMap(p.Instances())
+	// build domain-instance map for current placement
+	placementDomainMap := buildDomainMap(p.Instances(), f.opts.IsolationLevel())
 
-	// if there is a rack not in the current placement, prefer that rack
-	for r, instances := range candidateRackMap {
-		if _, exist := placementRackMap[r]; !exist {
-			// All the racks in the candidateRackMap have at least 1 instance.
+	// if there is a failure domain not in the current placement, prefer that domain
+	for d, instances := range candidateDomainMap {
+		if _, exist := placementDomainMap[d]; !exist {
+			// All the domains in the candidateDomainMap have at least 1 instance.
 			return instances[:1], nil
 		}
 	}
 
-	// otherwise sort the racks in the current placement by capacity and find a instance from least sized rack
-	racks := make(sortableValues, 0, len(placementRackMap))
-	for rack, instances := range placementRackMap {
+	// otherwise sort the domains in the current placement by capacity and find a instance from least sized domain
+	domains := make(sortableValues, 0, len(placementDomainMap))
+	for domain, instances := range placementDomainMap {
 		weight := 0
 		for _, i := range instances {
 			weight += int(i.Weight())
 		}
-		racks = append(racks, sortableValue{value: rack, weight: weight})
+		domains = append(domains, sortableValue{value: domain, weight: weight})
 	}
-	sort.Sort(racks)
+	sort.Sort(domains)
 
-	for _, rackLen := range racks {
-		if i, exist := candidateRackMap[rackLen.value.(string)]; exist {
+	for _, domainLen := range domains {
+		if i, exist := candidateDomainMap[domainLen.value.(string)]; exist {
 			for _, instance := range i {
 				return []placement.Instance{instance}, nil
 			}
@@ -113,27 +113,36 @@ func (f *nonMirroredFilter) SelectReplaceInstances(
 		leavingInstances = append(leavingInstances, leavingInstance)
 	}
 
-	// map rack to instances
-	rackMap := buildRackMap(candidates)
+	if len(f.opts.ZoneReplicas()) > 0 {
+		// shards do not move across zones in a multi-zone placement,
+		// so the replacements must come from the zones of the leaving instances
+		if candidates = filterLeavingZones(candidates, leavingInstances); len(candidates) == 0 {
+			return nil, errNoValidInstance
+		}
+	}
+
+	// map failure domain to instances
+	domainMap := buildDomainMap(candidates, f.opts.IsolationLevel())
 
 	// otherwise sort the candidate instances by the number of conflicts
 	ph := algo.NewPlacementHelper(p, f.opts)
-	instances := make([]sortableValue, 0, len(rackMap))
-	for rack, instancesInRack := range rackMap {
+	instances := make([]sortableValue, 0, len(domainMap))
+	for domain, instancesInDomain := range domainMap {
 		conflicts := 0
 		for _, leaving := range leavingInstances {
 			for _, s := range leaving.Shards().All() {
-				if ph.HasRackConflict(s.ID(), leaving, rack) {
+				if ph.HasIsolationConflict(s.ID(), leaving, domain) {
 					conflicts++
 				}
 			}
 		}
-		for _, instance := range instancesInRack {
+		for _, instance := range instancesInDomain {
 			instances = append(instances, sortableValue{value: instance, weight: conflicts})
 		}
 	}
 
-	groups := groupInstancesByConflict(instances, f.opts.LooseRackCheck())
+	allowConflict := f.opts.LooseRackCheck() || f.opts.IsolationMode() == placement.IsolationModeBestEffort
+	groups := groupInstancesByConflict(instances, allowConflict)
 	if len(groups) == 0 {
 		return nil, errNoValidInstance
 	}
@@ -231,13 +240,32 @@ func knapsack(instances []placement.Instance, targetWeight int) ([]placement.Ins
 	panic("should never reach here")
 }
 
-func buildRackMap(candidates []placement.Instance) map[string][]placement.Instance {
-	result := make(map[string][]placement.Instance, len(candidates))
+func filterLeavingZones(
+	candidates []placement.Instance,
+	leavingInstances []placement.Instance,
+) []placement.Instance {
+	zones := make(map[string]struct{}, len(leavingInstances))
+	for _, instance := range leavingInstances {
+		zones[instance.Zone()] = struct{}{}
+	}
+
+	result := make([]placement.Instance, 0, len(candidates))
 	for _, instance := range candidates {
-		if _, exist := result[instance.Rack()]; !exist {
-			result[instance.Rack()] = make([]placement.Instance, 0)
+		if _, ok := zones[instance.Zone()]; ok {
+			result = append(result, instance)
 		}
-		result[instance.Rack()] = append(result[instance.Rack()], instance)
+	}
+	return result
+}
+
+func buildDomainMap(
+	candidates []placement.Instance,
+	level placement.IsolationLevel,
+) map[string][]placement.Instance {
+	result := make(map[string][]placement.Instance, len(candidates))
+	for _, instance := range candidates {
+		domain := placement.FailureDomain(instance, level)
+		result[domain] = append(result[domain], instance)
 	}
 	return result
 }
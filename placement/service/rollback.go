@@ -0,0 +1,220 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package service
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/m3db/m3cluster/placement"
+	"github.com/m3db/m3cluster/shard"
+)
+
+var (
+	errRollbackShardingChanged = errors.New("could not roll back between sharded and non-sharded placements")
+	errRollbackShardsChanged   = errors.New("could not roll back to a placement with different shards")
+)
+
+// rollback returns a placement that moves the shards in the current placement back
+// to their owners in the target placement. Shards leave their current owners through
+// the usual Leaving and Initializing states so the rollback is as safe as any other
+// shard movement, and instance metadata is kept from the current placement, other
+// than the shard set ids of instances in the target placement, which have to match
+// the shards the instances are moved back to.
+func rollback(current, target placement.Placement, opts placement.Options) (placement.Placement, error) {
+	if current.IsSharded() != target.IsSharded() {
+		return nil, errRollbackShardingChanged
+	}
+
+	if !target.IsSharded() {
+		return target.Clone().
+			SetPendingShardMoves(nil).
+			SetCutoverNanos(opts.PlacementCutoverNanosFn()()), nil
+	}
+
+	if !sameShards(current.Shards(), target.Shards()) {
+		return nil, errRollbackShardsChanged
+	}
+
+	p := current.Clone()
+	instances := p.Instances()
+	for _, instance := range target.Instances() {
+		if existing, ok := p.Instance(instance.ID()); ok {
+			existing.SetShardSetID(instance.ShardSetID())
+			continue
+		}
+		instances = append(instances, instance.Clone().SetShards(shard.NewShards(nil)))
+	}
+	p = p.SetInstances(instances)
+
+	var (
+		cutoverNanos = opts.ShardCutoverNanosFn()()
+		cutoffNanos  = opts.ShardCutoffNanosFn()()
+	)
+	for _, shardID := range target.Shards() {
+		var (
+			targetOwners  = ownersOfShard(target, shardID)
+			currentOwners = ownersOfShard(p, shardID)
+			sources       []string
+		)
+
+		for _, id := range currentOwners {
+			if containsString(targetOwners, id) {
+				continue
+			}
+			instance, _ := p.Instance(id)
+			s, _ := instance.Shards().Shard(shardID)
+			switch s.State() {
+			case shard.Initializing:
+				// NB: the Leaving shard on the source, if any, is either taken back
+				// below or becomes a dropped replica.
+				instance.Shards().Remove(shardID)
+			case shard.Available:
+				s.SetState(shard.Leaving).SetCutoffNanos(cutoffNanos)
+				sources = append(sources, id)
+			}
+		}
+
+		for _, id := range targetOwners {
+			if containsString(currentOwners, id) {
+				continue
+			}
+			instance, _ := p.Instance(id)
+			if s, ok := instance.Shards().Shard(shardID); ok && s.State() == shard.Leaving {
+				// The instance still owns the shard, take it back and break the
+				// link with any instance taking over from it.
+				instance.Shards().Add(shard.NewShard(shardID).
+					SetState(shard.Available).
+					SetCutoverNanos(s.CutoverNanos()))
+				unlinkSource(p, shardID, id)
+				continue
+			}
+
+			newShard := shard.NewShard(shardID).
+				SetState(shard.Initializing).
+				SetCutoverNanos(cutoverNanos)
+			if len(sources) > 0 {
+				newShard.SetSourceID(sources[0])
+				sources = sources[1:]
+			}
+			instance.Shards().Add(newShard)
+		}
+
+		dropUnpairedLeavingShards(p, shardID)
+	}
+
+	instances = instances[:0]
+	for _, instance := range p.Instances() {
+		if instance.Shards().NumShards() > 0 {
+			instances = append(instances, instance)
+		}
+	}
+
+	var zoneReplicas map[string]int
+	if zr := target.ZoneReplicas(); len(zr) > 0 {
+		zoneReplicas = make(map[string]int, len(zr))
+		for zone, rf := range zr {
+			zoneReplicas[zone] = rf
+		}
+	}
+
+	return p.
+		SetInstances(instances).
+		SetReplicaFactor(target.ReplicaFactor()).
+		SetZoneReplicas(zoneReplicas).
+		SetIsMirrored(target.IsMirrored()).
+		SetPendingShardMoves(nil).
+		SetCutoverNanos(opts.PlacementCutoverNanosFn()()), nil
+}
+
+// ownersOfShard returns the sorted ids of the instances owning the shard
+// in Initializing or Available state.
+func ownersOfShard(p placement.Placement, shardID uint32) []string {
+	var owners []string
+	for _, instance := range p.InstancesForShard(shardID) {
+		s, ok := instance.Shards().Shard(shardID)
+		if !ok || s.State() == shard.Leaving {
+			continue
+		}
+		owners = append(owners, instance.ID())
+	}
+	sort.Strings(owners)
+	return owners
+}
+
+// unlinkSource clears the source of the Initializing shards taking over
+// the shard from the given instance.
+func unlinkSource(p placement.Placement, shardID uint32, sourceID string) {
+	for _, instance := range p.Instances() {
+		s, ok := instance.Shards().Shard(shardID)
+		if ok && s.State() == shard.Initializing && s.SourceID() == sourceID {
+			s.SetSourceID("")
+		}
+	}
+}
+
+// dropUnpairedLeavingShards turns the Leaving shards no Initializing shard is
+// taking over from into dropped replicas.
+func dropUnpairedLeavingShards(p placement.Placement, shardID uint32) {
+	var (
+		instances = p.Instances()
+		paired    = make(map[string]bool, len(instances))
+	)
+	for _, instance := range instances {
+		s, ok := instance.Shards().Shard(shardID)
+		if ok && s.State() == shard.Initializing && s.SourceID() != "" {
+			paired[s.SourceID()] = true
+		}
+	}
+
+	for _, instance := range instances {
+		s, ok := instance.Shards().Shard(shardID)
+		if !ok || s.State() != shard.Leaving || paired[instance.ID()] {
+			continue
+		}
+		s.SetSourceID(instance.ID())
+	}
+}
+
+func sameShards(a, b []uint32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := make(map[uint32]struct{}, len(a))
+	for _, id := range a {
+		set[id] = struct{}{}
+	}
+	for _, id := range b {
+		if _, ok := set[id]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
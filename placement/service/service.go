@@ -23,30 +23,52 @@ package service
 import (
 	"fmt"
 
+	"github.com/m3db/m3cluster/kv"
 	"github.com/m3db/m3cluster/placement"
 	"github.com/m3db/m3cluster/placement/algo"
 	"github.com/m3db/m3cluster/placement/selector"
 	"github.com/m3db/m3cluster/shard"
+	xerrors "github.com/m3db/m3x/errors"
 	"github.com/m3db/m3x/log"
+	"github.com/m3db/m3x/retry"
+)
+
+var (
+	// defaultMarkShardsRetryOptions are the options for retrying marking shards
+	// available in a batch on version conflicts if ConflictRetryOptions is not set.
+	defaultMarkShardsRetryOptions = retry.NewOptions().SetMaxRetries(4)
 )
 
 type placementService struct {
 	placement.Storage
 
-	opts     placement.Options
-	algo     placement.Algorithm
-	selector placement.InstanceSelector
-	logger   log.Logger
+	opts              placement.Options
+	algo              placement.Algorithm
+	selector          placement.InstanceSelector
+	retrier           retry.Retrier
+	markShardsRetrier retry.Retrier
+	logger            log.Logger
 }
 
 // NewPlacementService returns an instance of placement service.
 func NewPlacementService(s placement.Storage, opts placement.Options) placement.Service {
+	var (
+		retrier           retry.Retrier
+		markShardsRetrier = retry.NewRetrier(defaultMarkShardsRetryOptions)
+	)
+	if retryOpts := opts.ConflictRetryOptions(); retryOpts != nil {
+		retrier = retry.NewRetrier(retryOpts)
+		markShardsRetrier = retrier
+	}
+
 	return &placementService{
-		Storage:  s,
-		opts:     opts,
-		algo:     algo.NewAlgorithm(opts),
-		selector: selector.NewInstanceSelector(opts),
-		logger:   opts.InstrumentOptions().Logger(),
+		Storage:           s,
+		opts:              opts,
+		algo:              algo.NewAlgorithm(opts),
+		selector:          selector.NewInstanceSelector(opts),
+		retrier:           retrier,
+		markShardsRetrier: markShardsRetrier,
+		logger:            opts.InstrumentOptions().Logger(),
 	}
 }
 
@@ -86,146 +108,327 @@ func (ps *placementService) BuildInitialPlacement(
 }
 
 func (ps *placementService) AddReplica() (placement.Placement, error) {
-	p, v, err := ps.Placement()
+	return ps.update(ps.algo.AddReplica, func(initial, current placement.Placement) bool {
+		return current.ReplicaFactor() > initial.ReplicaFactor()
+	})
+}
+
+func (ps *placementService) RemoveReplica() (placement.Placement, error) {
+	return ps.update(ps.algo.RemoveReplica, func(initial, current placement.Placement) bool {
+		return current.ReplicaFactor() < initial.ReplicaFactor()
+	})
+}
+
+func (ps *placementService) SplitShards(factor int) (placement.Placement, error) {
+	return ps.update(func(p placement.Placement) (placement.Placement, error) {
+		return ps.algo.SplitShards(p, factor)
+	}, func(initial, current placement.Placement) bool {
+		return current.NumShards() > initial.NumShards()
+	})
+}
+
+func (ps *placementService) Rebalance(maxShardMoves int) (placement.Placement, error) {
+	return ps.update(func(p placement.Placement) (placement.Placement, error) {
+		return ps.algo.Rebalance(p, maxShardMoves)
+	}, nil)
+}
+
+func (ps *placementService) Rollback(version int) (placement.Placement, error) {
+	_, v, err := ps.Placement()
 	if err != nil {
 		return nil, err
 	}
 
-	if p, err = ps.algo.AddReplica(p); err != nil {
-		return nil, err
+	if version <= 0 || version >= v {
+		return nil, fmt.Errorf("could not roll back to version %d, current version is %d", version, v)
 	}
 
-	if err := placement.Validate(p); err != nil {
+	history, err := ps.History(version, version+1)
+	if err != nil {
 		return nil, err
 	}
+	if len(history) == 0 {
+		return nil, fmt.Errorf("could not find placement of version %d", version)
+	}
+
+	return ps.update(func(p placement.Placement) (placement.Placement, error) {
+		return rollback(p, history[0], ps.opts)
+	}, nil)
+}
 
-	return p, ps.CheckAndSet(p, v)
+func (ps *placementService) UpdateInstances(instances []placement.Instance) (placement.Placement, error) {
+	return ps.update(func(p placement.Placement) (placement.Placement, error) {
+		return ps.algo.UpdateInstances(p, instances)
+	}, nil)
 }
 
 func (ps *placementService) AddInstances(
 	candidates []placement.Instance,
 ) (placement.Placement, []placement.Instance, error) {
-	p, v, err := ps.Placement()
+	var addingInstances []placement.Instance
+	p, err := ps.update(func(p placement.Placement) (placement.Placement, error) {
+		// NB: the algorithm assigns shards to the instances it is given, so each
+		// attempt starts from a fresh copy of the candidates.
+		var err error
+		if addingInstances, err = ps.selector.SelectAddingInstances(placement.Instances(candidates).Clone(), p); err != nil {
+			return nil, err
+		}
+		return ps.algo.AddInstances(p, addingInstances)
+	}, func(initial, current placement.Placement) bool {
+		addingInstances = newInstances(initial, current, candidates)
+		return len(addingInstances) > 0
+	})
 	if err != nil {
 		return nil, nil, err
 	}
 
-	addingInstances, err := ps.selector.SelectAddingInstances(candidates, p)
+	addedInstances, err := instancesInPlacement(p, addingInstances)
 	if err != nil {
 		return nil, nil, err
 	}
-
-	if p, err = ps.algo.AddInstances(p, addingInstances); err != nil {
-		return nil, nil, err
-	}
-
-	if err := placement.Validate(p); err != nil {
-		return nil, nil, err
-	}
-
-	for i, instance := range addingInstances {
-		addingInstance, ok := p.Instance(instance.ID())
-		if !ok {
-			return nil, nil, fmt.Errorf("unable to find added instance [%s] in new placement", instance.ID())
-		}
-		addingInstances[i] = addingInstance
-	}
-
-	return p, addingInstances, ps.CheckAndSet(p, v)
+	return p, addedInstances, nil
 }
 
 func (ps *placementService) RemoveInstances(instanceIDs []string) (placement.Placement, error) {
-	p, v, err := ps.Placement()
-	if err != nil {
-		return nil, err
-	}
-
-	if p, err = ps.algo.RemoveInstances(p, instanceIDs); err != nil {
-		return nil, err
-	}
-
-	if err := placement.Validate(p); err != nil {
-		return nil, err
-	}
-
-	return p, ps.CheckAndSet(p, v)
+	return ps.update(func(p placement.Placement) (placement.Placement, error) {
+		return ps.algo.RemoveInstances(p, instanceIDs)
+	}, func(_, current placement.Placement) bool {
+		return allLeaving(current, instanceIDs)
+	})
 }
 
 func (ps *placementService) ReplaceInstances(
 	leavingInstanceIDs []string,
 	candidates []placement.Instance,
 ) (placement.Placement, []placement.Instance, error) {
-	p, v, err := ps.Placement()
+	var addingInstances []placement.Instance
+	p, err := ps.update(func(p placement.Placement) (placement.Placement, error) {
+		// NB: the algorithm assigns shards to the instances it is given, so each
+		// attempt starts from a fresh copy of the candidates.
+		var err error
+		if addingInstances, err = ps.selector.SelectReplaceInstances(
+			placement.Instances(candidates).Clone(),
+			leavingInstanceIDs,
+			p,
+		); err != nil {
+			return nil, err
+		}
+		return ps.algo.ReplaceInstances(p, leavingInstanceIDs, addingInstances)
+	}, func(initial, current placement.Placement) bool {
+		addingInstances = newInstances(initial, current, candidates)
+		return len(addingInstances) > 0 && allLeaving(current, leavingInstanceIDs)
+	})
 	if err != nil {
 		return nil, nil, err
 	}
 
-	addingInstances, err := ps.selector.SelectReplaceInstances(candidates, leavingInstanceIDs, p)
+	addedInstances, err := instancesInPlacement(p, addingInstances)
 	if err != nil {
 		return nil, nil, err
 	}
+	return p, addedInstances, nil
+}
 
-	if p, err = ps.algo.ReplaceInstances(p, leavingInstanceIDs, addingInstances); err != nil {
-		return nil, nil, err
-	}
+func (ps *placementService) MarkShardAvailable(instanceID string, shardID uint32) error {
+	_, err := ps.update(func(p placement.Placement) (placement.Placement, error) {
+		return ps.algo.MarkShardAvailable(p, instanceID, shardID)
+	}, func(_, current placement.Placement) bool {
+		return isShardAvailable(current, instanceID, shardID)
+	})
+	return err
+}
 
-	if err := placement.Validate(p); err != nil {
-		return nil, nil, err
-	}
+func (ps *placementService) MarkInstanceAvailable(instanceID string) error {
+	_, err := ps.update(func(p placement.Placement) (placement.Placement, error) {
+		instance, exist := p.Instance(instanceID)
+		if !exist {
+			return nil, fmt.Errorf("could not find instance %s in placement", instanceID)
+		}
 
-	addedInstances := make([]placement.Instance, 0, len(addingInstances))
-	for _, inst := range addingInstances {
-		addedInstance, ok := p.Instance(inst.ID())
-		if !ok {
-			return nil, nil, fmt.Errorf("unable to find added instance [%+v] in new placement [%+v]", inst, p)
+		var err error
+		for _, s := range instance.Shards().All() {
+			if s.State() != shard.Initializing && !placement.IsDroppedReplica(instance, s) {
+				continue
+			}
+			if p, err = ps.algo.MarkShardAvailable(p, instanceID, s.ID()); err != nil {
+				return nil, err
+			}
 		}
-		addedInstances = append(addedInstances, addedInstance)
+		return p, nil
+	}, nil)
+	return err
+}
+
+func (ps *placementService) MarkShardsAvailable(
+	shards []placement.InstanceShard,
+) ([]placement.MarkShardResult, error) {
+	var (
+		results []placement.MarkShardResult
+		retried bool
+	)
+	_, err := ps.updateWithRetrier(ps.markShardsRetrier, func(p placement.Placement) (placement.Placement, error) {
+		results = make([]placement.MarkShardResult, 0, len(shards))
+		var marked int
+		for _, s := range shards {
+			// NB: a shard marked available by an earlier attempt that hit a
+			// version conflict is not marked again.
+			if retried && isShardAvailable(p, s.InstanceID, s.ShardID) {
+				results = append(results, placement.MarkShardResult{InstanceShard: s})
+				continue
+			}
+
+			// NB: the algorithm marks the shard on a clone of the placement, so a
+			// shard that could not be marked leaves the placement unchanged.
+			newPlacement, err := ps.algo.MarkShardAvailable(p, s.InstanceID, s.ShardID)
+			if err == nil {
+				p = newPlacement
+				marked++
+			}
+			results = append(results, placement.MarkShardResult{InstanceShard: s, Err: err})
+		}
+		retried = true
+
+		if marked == 0 {
+			return nil, nil
+		}
+		return p, nil
+	}, nil)
+	if err != nil {
+		return nil, err
 	}
+	return results, nil
+}
 
-	return p, addedInstances, ps.CheckAndSet(p, v)
+// update runs the operation on the placement and writes the result. When the
+// ConflictRetryOptions are set, the placement is read again and the operation
+// is run again on version conflicts.
+func (ps *placementService) update(
+	op func(p placement.Placement) (placement.Placement, error),
+	applied func(initial, current placement.Placement) bool,
+) (placement.Placement, error) {
+	return ps.updateWithRetrier(ps.retrier, op, applied)
 }
 
-func (ps *placementService) MarkShardAvailable(instanceID string, shardID uint32) error {
-	p, v, err := ps.Placement()
-	if err != nil {
-		return err
+// updateWithRetrier runs the operation on the placement and writes the result,
+// retrying with the retrier on version conflicts. Before running the operation
+// again, applied reports whether the placement read already reflects the
+// operation compared to the placement read in the first attempt, e.g. when an
+// operator ran the same operation concurrently, in which case the placement is
+// returned without running the operation again. The operation returns a nil
+// placement if there is nothing to write.
+func (ps *placementService) updateWithRetrier(
+	retrier retry.Retrier,
+	op func(p placement.Placement) (placement.Placement, error),
+	applied func(initial, current placement.Placement) bool,
+) (placement.Placement, error) {
+	var initial, result placement.Placement
+	updateFn := func() error {
+		p, v, err := ps.Placement()
+		if err != nil {
+			return err
+		}
+
+		if initial == nil {
+			initial = p
+		} else if applied != nil && applied(initial, p) {
+			ps.logger.Infof("placement operation already applied in version %d", v)
+			result = p
+			return nil
+		}
+
+		newPlacement, err := op(p)
+		if err != nil {
+			return err
+		}
+		if newPlacement == nil {
+			result = p
+			return nil
+		}
+
+		if err := placement.Validate(newPlacement); err != nil {
+			return err
+		}
+
+		if err := ps.CheckAndSet(newPlacement, v); err != nil {
+			return err
+		}
+		result = newPlacement
+		return nil
 	}
 
-	if p, err = ps.algo.MarkShardAvailable(p, instanceID, shardID); err != nil {
-		return err
+	if retrier == nil {
+		err := updateFn()
+		return result, err
 	}
 
-	if err := placement.Validate(p); err != nil {
+	err := retrier.Attempt(func() error {
+		err := updateFn()
+		if err != nil && err != kv.ErrVersionMismatch {
+			return retry.NonRetryableError(err)
+		}
+		if err == kv.ErrVersionMismatch {
+			ps.logger.Infof("version conflict updating placement, retrying")
+		}
 		return err
+	})
+	if inner := xerrors.GetInnerNonRetryableError(err); inner != nil {
+		return nil, inner
 	}
-
-	return ps.CheckAndSet(p, v)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
-func (ps *placementService) MarkInstanceAvailable(instanceID string) error {
-	p, v, err := ps.Placement()
-	if err != nil {
-		return err
+// newInstances returns the candidates that are in the current placement but
+// not in the initial placement.
+func newInstances(initial, current placement.Placement, candidates []placement.Instance) []placement.Instance {
+	var res []placement.Instance
+	for _, candidate := range candidates {
+		if _, ok := initial.Instance(candidate.ID()); ok {
+			continue
+		}
+		if _, ok := current.Instance(candidate.ID()); ok {
+			res = append(res, candidate)
+		}
 	}
+	return res
+}
 
-	instance, exist := p.Instance(instanceID)
-	if !exist {
-		return fmt.Errorf("could not find instance %s in placement", instanceID)
+// instancesInPlacement returns the instances as they are in the placement.
+func instancesInPlacement(p placement.Placement, instances []placement.Instance) ([]placement.Instance, error) {
+	res := make([]placement.Instance, 0, len(instances))
+	for _, instance := range instances {
+		instanceInPlacement, ok := p.Instance(instance.ID())
+		if !ok {
+			return nil, fmt.Errorf("unable to find added instance [%s] in new placement", instance.ID())
+		}
+		res = append(res, instanceInPlacement)
 	}
+	return res, nil
+}
 
-	for _, s := range instance.Shards().All() {
-		if s.State() != shard.Initializing {
+// allLeaving returns true if none of the instances owns any shard other than
+// Leaving shards in the placement.
+func allLeaving(p placement.Placement, instanceIDs []string) bool {
+	for _, id := range instanceIDs {
+		instance, ok := p.Instance(id)
+		if !ok {
 			continue
 		}
-		p, err = ps.algo.MarkShardAvailable(p, instanceID, s.ID())
-		if err != nil {
-			return err
+		shards := instance.Shards()
+		if shards.NumShards() != shards.NumShardsForState(shard.Leaving) {
+			return false
 		}
 	}
+	return true
+}
 
-	if err := placement.Validate(p); err != nil {
-		return err
+func isShardAvailable(p placement.Placement, instanceID string, shardID uint32) bool {
+	instance, ok := p.Instance(instanceID)
+	if !ok {
+		return false
 	}
-
-	return ps.CheckAndSet(p, v)
+	s, ok := instance.Shards().Shard(shardID)
+	return ok && s.State() == shard.Available
 }
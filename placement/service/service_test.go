@@ -22,12 +22,15 @@ package service
 
 import (
 	"errors"
+	"fmt"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/m3db/m3cluster/kv"
 	"github.com/m3db/m3cluster/placement"
 	"github.com/m3db/m3cluster/shard"
+	"github.com/m3db/m3x/retry"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/stretchr/testify/assert"
@@ -416,6 +419,247 @@ func TestReplaceInstanceWithLooseRackCheck(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestRebalance(t *testing.T) {
+	ps := NewPlacementService(NewMockStorage(), placement.NewOptions().SetValidZone("z1"))
+	_, err := ps.Rebalance(1)
+	assert.Error(t, err)
+
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i2 := placement.NewEmptyInstance("i2", "r2", "z1", "endpoint", 1)
+	_, err = ps.BuildInitialPlacement([]placement.Instance{i1, i2}, 10, 1)
+	require.NoError(t, err)
+	markAllInstancesAvailable(t, ps)
+
+	p, v, err := ps.Placement()
+	require.NoError(t, err)
+	i1, _ = p.Instance("i1")
+	i1.SetWeight(4)
+	require.NoError(t, ps.CheckAndSet(p, v))
+
+	p, err = ps.Rebalance(2)
+	require.NoError(t, err)
+	i1, _ = p.Instance("i1")
+	assert.Equal(t, 2, i1.Shards().NumShardsForState(shard.Initializing))
+	i2, _ = p.Instance("i2")
+	assert.Equal(t, 2, i2.Shards().NumShardsForState(shard.Leaving))
+
+	stored, _, err := ps.Placement()
+	require.NoError(t, err)
+	assert.Equal(t, p.String(), stored.String())
+}
+
+func TestUpdateInstances(t *testing.T) {
+	ps := NewPlacementService(NewMockStorage(), placement.NewOptions().SetValidZone("z1"))
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint1", 1)
+	i2 := placement.NewEmptyInstance("i2", "r2", "z1", "endpoint2", 1)
+	_, err := ps.BuildInitialPlacement([]placement.Instance{i1, i2}, 10, 1)
+	require.NoError(t, err)
+	markAllInstancesAvailable(t, ps)
+
+	p, err := ps.UpdateInstances([]placement.Instance{i1.Clone().SetEndpoint("endpoint11")})
+	require.NoError(t, err)
+	i1, _ = p.Instance("i1")
+	assert.Equal(t, "endpoint11", i1.Endpoint())
+	assert.Equal(t, 5, i1.Shards().NumShardsForState(shard.Available))
+
+	p, err = ps.UpdateInstances([]placement.Instance{i2.Clone().SetWeight(4)})
+	require.NoError(t, err)
+	i2, _ = p.Instance("i2")
+	assert.Equal(t, 3, i2.Shards().NumShardsForState(shard.Initializing))
+
+	stored, _, err := ps.Placement()
+	require.NoError(t, err)
+	assert.Equal(t, p.String(), stored.String())
+
+	_, err = ps.UpdateInstances([]placement.Instance{placement.NewEmptyInstance("i3", "r3", "z1", "endpoint3", 1)})
+	assert.Error(t, err)
+}
+
+func TestRemoveReplica(t *testing.T) {
+	ps := NewPlacementService(NewMockStorage(), placement.NewOptions().SetValidZone("z1"))
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i2 := placement.NewEmptyInstance("i2", "r2", "z1", "endpoint", 1)
+	_, err := ps.BuildInitialPlacement([]placement.Instance{i1, i2}, 10, 2)
+	require.NoError(t, err)
+	markAllInstancesAvailable(t, ps)
+
+	p, err := ps.RemoveReplica()
+	require.NoError(t, err)
+	assert.Equal(t, 1, p.ReplicaFactor())
+
+	_, err = ps.RemoveReplica()
+	assert.Error(t, err)
+
+	for _, id := range []string{"i1", "i2"} {
+		instance, _ := p.Instance(id)
+		assert.Equal(t, 5, instance.Shards().NumShardsForState(shard.Leaving))
+		require.NoError(t, ps.MarkInstanceAvailable(id))
+	}
+
+	p, _, err = ps.Placement()
+	require.NoError(t, err)
+	for _, instance := range p.Instances() {
+		assert.Equal(t, 5, instance.Shards().NumShards())
+		assert.Equal(t, 5, instance.Shards().NumShardsForState(shard.Available))
+	}
+}
+
+func TestRollback(t *testing.T) {
+	ps := NewPlacementService(NewMockStorage(), placement.NewOptions().SetValidZone("z1"))
+	_, err := ps.Rollback(1)
+	assert.Error(t, err)
+
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i2 := placement.NewEmptyInstance("i2", "r2", "z1", "endpoint", 1)
+	i3 := placement.NewEmptyInstance("i3", "r3", "z1", "endpoint", 1)
+	_, err = ps.BuildInitialPlacement([]placement.Instance{i1, i2}, 12, 1)
+	require.NoError(t, err)
+	markAllInstancesAvailable(t, ps)
+	before, v, err := ps.Placement()
+	require.NoError(t, err)
+
+	_, err = ps.Rollback(v)
+	assert.Error(t, err)
+
+	// Roll back while the shards are still moving to the new instance.
+	_, _, err = ps.AddInstances([]placement.Instance{i3})
+	require.NoError(t, err)
+	p, err := ps.Rollback(v)
+	require.NoError(t, err)
+	_, ok := p.Instance("i3")
+	assert.False(t, ok)
+	for _, id := range []string{"i1", "i2"} {
+		expected, _ := before.Instance(id)
+		instance, _ := p.Instance(id)
+		assert.Equal(t, expected.Shards().AllIDs(), instance.Shards().AllIDs())
+		assert.Equal(t, 6, instance.Shards().NumShardsForState(shard.Available))
+	}
+
+	// Roll back after the shards are moved to the new instance.
+	i3 = placement.NewEmptyInstance("i3", "r3", "z1", "endpoint", 1)
+	_, _, err = ps.AddInstances([]placement.Instance{i3})
+	require.NoError(t, err)
+	markAllInstancesAvailable(t, ps)
+	p, err = ps.Rollback(v)
+	require.NoError(t, err)
+	i3, _ = p.Instance("i3")
+	assert.Equal(t, 4, i3.Shards().NumShardsForState(shard.Leaving))
+	for _, s := range i3.Shards().All() {
+		owners := before.InstancesForShard(s.ID())
+		require.Equal(t, 1, len(owners))
+		instance, _ := p.Instance(owners[0].ID())
+		initShard, ok := instance.Shards().Shard(s.ID())
+		require.True(t, ok)
+		assert.Equal(t, shard.Initializing, initShard.State())
+		assert.Equal(t, "i3", initShard.SourceID())
+	}
+
+	markAllInstancesAvailable(t, ps)
+	p, _, err = ps.Placement()
+	require.NoError(t, err)
+	assert.Equal(t, 2, p.NumInstances())
+	for _, id := range []string{"i1", "i2"} {
+		expected, _ := before.Instance(id)
+		instance, _ := p.Instance(id)
+		assert.Equal(t, expected.Shards().AllIDs(), instance.Shards().AllIDs())
+		assert.Equal(t, 6, instance.Shards().NumShardsForState(shard.Available))
+	}
+}
+
+func TestRollbackMirrored(t *testing.T) {
+	newInstance := func(id string, shardSetID uint32, shards ...uint32) placement.Instance {
+		instance := placement.NewEmptyInstance(id, "r-"+id, "z1", "endpoint", 1).SetShardSetID(shardSetID)
+		for _, s := range shards {
+			instance.Shards().Add(shard.NewShard(s).SetState(shard.Available))
+		}
+		return instance
+	}
+	newPlacement := func(instances ...placement.Instance) placement.Placement {
+		return placement.NewPlacement().
+			SetInstances(instances).
+			SetShards([]uint32{0, 1, 2, 3}).
+			SetReplicaFactor(2).
+			SetIsSharded(true).
+			SetIsMirrored(true)
+	}
+
+	// The shard sets swapped instances since the target placement.
+	target := newPlacement(
+		newInstance("i1", 1, 0, 1), newInstance("i2", 1, 0, 1),
+		newInstance("i3", 2, 2, 3), newInstance("i4", 2, 2, 3),
+	)
+	current := newPlacement(
+		newInstance("i1", 2, 2, 3), newInstance("i2", 2, 2, 3),
+		newInstance("i3", 1, 0, 1), newInstance("i4", 1, 0, 1),
+	)
+
+	p, err := rollback(current, target, placement.NewOptions().SetIsMirrored(true))
+	require.NoError(t, err)
+	for _, expected := range target.Instances() {
+		instance, ok := p.Instance(expected.ID())
+		require.True(t, ok)
+		assert.Equal(t, expected.ShardSetID(), instance.ShardSetID())
+
+		initializing := instance.Shards().ShardsForState(shard.Initializing)
+		ids := make([]uint32, 0, len(initializing))
+		for _, s := range initializing {
+			ids = append(ids, s.ID())
+		}
+		assert.Equal(t, placement.SortedShardIDs(expected.Shards().AllIDs()), placement.SortedShardIDs(ids))
+		assert.Equal(t, 2, instance.Shards().NumShardsForState(shard.Leaving))
+	}
+
+	// The current placement is not changed.
+	i1, _ := current.Instance("i1")
+	assert.Equal(t, uint32(2), i1.ShardSetID())
+}
+
+func TestSplitShards(t *testing.T) {
+	ps := NewPlacementService(NewMockStorage(), placement.NewOptions().SetValidZone("z1"))
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i2 := placement.NewEmptyInstance("i2", "r2", "z1", "endpoint", 1)
+	_, err := ps.BuildInitialPlacement([]placement.Instance{i1, i2}, 4, 1)
+	require.NoError(t, err)
+
+	p, err := ps.SplitShards(2)
+	require.NoError(t, err)
+	assert.Equal(t, 8, p.NumShards())
+
+	markAllInstancesAvailable(t, ps)
+	p, _, err = ps.Placement()
+	require.NoError(t, err)
+	for _, instance := range p.Instances() {
+		assert.Equal(t, 4, instance.Shards().NumShardsForState(shard.Available))
+	}
+}
+
+func TestThrottledShardMoves(t *testing.T) {
+	ps := NewPlacementService(NewMockStorage(), placement.NewOptions().
+		SetValidZone("z1").
+		SetMaxInitializingShardsPerInstance(1))
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i2 := placement.NewEmptyInstance("i2", "r2", "z1", "endpoint", 1)
+	_, err := ps.BuildInitialPlacement([]placement.Instance{i1}, 4, 1)
+	require.NoError(t, err)
+
+	p, _, err := ps.AddInstances([]placement.Instance{i2})
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(p.PendingShardMoves()))
+
+	require.NoError(t, ps.MarkInstanceAvailable("i2"))
+	p, _, err = ps.Placement()
+	require.NoError(t, err)
+	assert.Empty(t, p.PendingShardMoves())
+	i2, _ = p.Instance("i2")
+	assert.Equal(t, 1, i2.Shards().NumShardsForState(shard.Initializing))
+
+	require.NoError(t, ps.MarkInstanceAvailable("i2"))
+	p, _, err = ps.Placement()
+	require.NoError(t, err)
+	i2, _ = p.Instance("i2")
+	assert.Equal(t, 2, i2.Shards().NumShardsForState(shard.Available))
+}
+
 func TestMarkShard(t *testing.T) {
 	ms := NewMockStorage()
 
@@ -523,6 +767,232 @@ func TestMarkInstance(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestMarkShardsAvailable(t *testing.T) {
+	var rejected uint32
+	ps := NewPlacementService(NewMockStorage(), placement.NewOptions().
+		SetValidZone("z1").
+		SetIsShardCutoverFn(func(s shard.Shard) error {
+			if s.ID() == rejected {
+				return fmt.Errorf("shard %d is not cut over", s.ID())
+			}
+			return nil
+		}))
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i2 := placement.NewEmptyInstance("i2", "r2", "z1", "endpoint", 1)
+	_, err := ps.BuildInitialPlacement([]placement.Instance{i1}, 8, 1)
+	require.NoError(t, err)
+	p, _, err := ps.AddInstances([]placement.Instance{i2})
+	require.NoError(t, err)
+	_, v, err := ps.Placement()
+	require.NoError(t, err)
+
+	i2, _ = p.Instance("i2")
+	initializing := i2.Shards().AllIDs()
+	require.Equal(t, 4, len(initializing))
+	rejected = initializing[0]
+
+	var shards []placement.InstanceShard
+	for _, id := range initializing {
+		shards = append(shards, placement.InstanceShard{InstanceID: "i2", ShardID: id})
+	}
+	i1, _ = p.Instance("i1")
+	available := i1.Shards().ShardsForState(shard.Available)[0].ID()
+	shards = append(shards,
+		placement.InstanceShard{InstanceID: "i1", ShardID: available},
+		placement.InstanceShard{InstanceID: "i3", ShardID: 0},
+	)
+
+	results, err := ps.MarkShardsAvailable(shards)
+	require.NoError(t, err)
+	require.Equal(t, len(shards), len(results))
+	for i, result := range results {
+		assert.Equal(t, shards[i], result.InstanceShard)
+		if result.InstanceID == "i2" && result.ShardID != rejected {
+			assert.NoError(t, result.Err)
+			continue
+		}
+		assert.Error(t, result.Err)
+	}
+
+	p, newVersion, err := ps.Placement()
+	require.NoError(t, err)
+	assert.Equal(t, v+1, newVersion)
+	i1, _ = p.Instance("i1")
+	assert.Equal(t, 4, i1.Shards().NumShardsForState(shard.Available))
+	assert.Equal(t, 1, i1.Shards().NumShardsForState(shard.Leaving))
+	i2, _ = p.Instance("i2")
+	assert.Equal(t, 3, i2.Shards().NumShardsForState(shard.Available))
+	assert.Equal(t, 1, i2.Shards().NumShardsForState(shard.Initializing))
+	s, _ := i2.Shards().Shard(rejected)
+	assert.Equal(t, shard.Initializing, s.State())
+
+	// Nothing is written when no shard could be marked available.
+	results, err = ps.MarkShardsAvailable(shards[len(shards)-1:])
+	require.NoError(t, err)
+	require.Equal(t, 1, len(results))
+	assert.Error(t, results[0].Err)
+	_, v, err = ps.Placement()
+	require.NoError(t, err)
+	assert.Equal(t, newVersion, v)
+}
+
+func TestMarkShardsAvailableVersionConflict(t *testing.T) {
+	ms := &conflictStorage{Storage: NewMockStorage()}
+	ps := NewPlacementService(ms, placement.NewOptions().SetValidZone("z1"))
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i2 := placement.NewEmptyInstance("i2", "r2", "z1", "endpoint", 1)
+	_, err := ps.BuildInitialPlacement([]placement.Instance{i1}, 2, 1)
+	require.NoError(t, err)
+	p, _, err := ps.AddInstances([]placement.Instance{i2})
+	require.NoError(t, err)
+	i2, _ = p.Instance("i2")
+	shards := []placement.InstanceShard{{InstanceID: "i2", ShardID: i2.Shards().AllIDs()[0]}}
+
+	ms.conflicts = defaultMarkShardsRetryOptions.MaxRetries() + 1
+	_, err = ps.MarkShardsAvailable(shards)
+	assert.Equal(t, kv.ErrVersionMismatch, err)
+
+	ms.conflicts = 2
+	results, err := ps.MarkShardsAvailable(shards)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(results))
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, 0, ms.conflicts)
+
+	p, _, err = ps.Placement()
+	require.NoError(t, err)
+	i2, _ = p.Instance("i2")
+	assert.Equal(t, 1, i2.Shards().NumShardsForState(shard.Available))
+}
+
+func TestConflictRetry(t *testing.T) {
+	ms := &conflictStorage{Storage: NewMockStorage()}
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i2 := placement.NewEmptyInstance("i2", "r2", "z1", "endpoint", 1)
+	ps := NewPlacementService(ms, placement.NewOptions().SetValidZone("z1"))
+	_, err := ps.BuildInitialPlacement([]placement.Instance{i1, i2}, 4, 1)
+	require.NoError(t, err)
+
+	// Not retried without the conflict retry options.
+	ms.conflicts = 1
+	_, err = ps.AddReplica()
+	assert.Equal(t, kv.ErrVersionMismatch, err)
+
+	ps = NewPlacementService(ms, placement.NewOptions().
+		SetValidZone("z1").
+		SetConflictRetryOptions(retry.NewOptions().SetMaxRetries(2).SetInitialBackoff(time.Millisecond)))
+	ms.conflicts = 2
+	p, err := ps.AddReplica()
+	require.NoError(t, err)
+	assert.Equal(t, 2, p.ReplicaFactor())
+	assert.Equal(t, 0, ms.conflicts)
+
+	ms.conflicts = 3
+	_, err = ps.RemoveReplica()
+	assert.Equal(t, kv.ErrVersionMismatch, err)
+
+	_, err = ps.RemoveInstances([]string{"i3"})
+	assert.Error(t, err)
+}
+
+func TestConflictRetryAlreadyApplied(t *testing.T) {
+	ms := &conflictStorage{Storage: NewMockStorage(), writeOnConflict: true}
+	ps := NewPlacementService(ms, placement.NewOptions().
+		SetValidZone("z1").
+		SetConflictRetryOptions(retry.NewOptions().SetMaxRetries(2).SetInitialBackoff(time.Millisecond)))
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i2 := placement.NewEmptyInstance("i2", "r2", "z1", "endpoint", 1)
+	_, err := ps.BuildInitialPlacement([]placement.Instance{i1, i2}, 4, 1)
+	require.NoError(t, err)
+	markAllInstancesAvailable(t, ps)
+	_, v, err := ps.Placement()
+	require.NoError(t, err)
+
+	ms.conflicts = 1
+	p, err := ps.AddReplica()
+	require.NoError(t, err)
+	assert.Equal(t, 2, p.ReplicaFactor())
+	_, newVersion, err := ps.Placement()
+	require.NoError(t, err)
+	assert.Equal(t, v+1, newVersion)
+
+	ms.conflicts = 1
+	p, added, err := ps.AddInstances([]placement.Instance{
+		placement.NewEmptyInstance("i3", "r3", "z1", "endpoint", 1),
+		placement.NewEmptyInstance("i4", "r4", "z1", "endpoint", 1),
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(added))
+	assert.Equal(t, 3, p.NumInstances())
+	_, ok := p.Instance(added[0].ID())
+	assert.True(t, ok)
+
+	_, v, err = ps.Placement()
+	require.NoError(t, err)
+	assert.Equal(t, newVersion+1, v)
+
+	markAllInstancesAvailable(t, ps)
+	_, v, err = ps.Placement()
+	require.NoError(t, err)
+	ms.conflicts = 1
+	p, err = ps.SplitShards(2)
+	require.NoError(t, err)
+	assert.Equal(t, 8, p.NumShards())
+	_, newVersion, err = ps.Placement()
+	require.NoError(t, err)
+	assert.Equal(t, v+1, newVersion)
+}
+
+func TestConflictRetryConcurrentWrite(t *testing.T) {
+	ms := &conflictStorage{Storage: NewMockStorage()}
+	ps := NewPlacementService(ms, placement.NewOptions().
+		SetValidZone("z1").
+		SetConflictRetryOptions(retry.NewOptions().SetMaxRetries(2).SetInitialBackoff(time.Millisecond)))
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 1)
+	i2 := placement.NewEmptyInstance("i2", "r2", "z1", "endpoint", 1)
+	_, err := ps.BuildInitialPlacement([]placement.Instance{i1, i2}, 4, 1)
+	require.NoError(t, err)
+	markAllInstancesAvailable(t, ps)
+
+	// Another writer updates the placement before the first attempt is written.
+	ms.onConflict = func() {
+		p, v, err := ms.Storage.Placement()
+		require.NoError(t, err)
+		require.NoError(t, ms.Storage.CheckAndSet(p, v))
+	}
+
+	_, v, err := ps.Placement()
+	require.NoError(t, err)
+	i3 := placement.NewEmptyInstance("i3", "r3", "z1", "endpoint", 1)
+	ms.conflicts = 1
+	p, added, err := ps.AddInstances([]placement.Instance{i3})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(added))
+	assert.NoError(t, placement.Validate(p))
+	assert.Equal(t, 0, i3.Shards().NumShards())
+	i3, ok := p.Instance("i3")
+	require.True(t, ok)
+	assert.Equal(t, i3.Shards().NumShards(), i3.Shards().NumShardsForState(shard.Initializing))
+	_, newVersion, err := ps.Placement()
+	require.NoError(t, err)
+	assert.Equal(t, v+2, newVersion)
+
+	markAllInstancesAvailable(t, ps)
+	i4 := placement.NewEmptyInstance("i4", "r4", "z1", "endpoint", 1)
+	ms.conflicts = 1
+	p, added, err = ps.ReplaceInstances([]string{"i1"}, []placement.Instance{i4})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(added))
+	assert.NoError(t, placement.Validate(p))
+	assert.Equal(t, 0, i4.Shards().NumShards())
+	i1, ok = p.Instance("i1")
+	require.True(t, ok)
+	assert.True(t, i1.IsLeaving())
+	i4, ok = p.Instance("i4")
+	require.True(t, ok)
+	assert.Equal(t, i1.Shards().NumShards(), i4.Shards().NumShardsForState(shard.Initializing))
+}
+
 func TestFindReplaceInstance(t *testing.T) {
 	i1 := placement.NewEmptyInstance("i1", "r11", "z1", "endpoint", 1)
 	i1.Shards().Add(shard.NewShard(1).SetState(shard.Available))
@@ -771,6 +1241,75 @@ func TestMirrorWorkflow(t *testing.T) {
 	assert.Equal(t, h4p3.Shards().AllIDs(), addedInstances[2].Shards().AllIDs())
 }
 
+func TestMultiZoneWorkflow(t *testing.T) {
+	storage := NewMockStorage()
+	opts := placement.NewOptions().SetZoneReplicas(map[string]int{"z1": 2, "z2": 1})
+	ps := NewPlacementService(storage, opts)
+
+	a1 := placement.NewEmptyInstance("a1", "r1", "z1", "endpoint", 1)
+	a2 := placement.NewEmptyInstance("a2", "r2", "z1", "endpoint", 1)
+	a3 := placement.NewEmptyInstance("a3", "r3", "z1", "endpoint", 1)
+	b1 := placement.NewEmptyInstance("b1", "r4", "z2", "endpoint", 1)
+	b2 := placement.NewEmptyInstance("b2", "r5", "z2", "endpoint", 1)
+	c1 := placement.NewEmptyInstance("c1", "r6", "z3", "endpoint", 1)
+
+	_, err := ps.BuildInitialPlacement([]placement.Instance{a1, a2, a3, b1, b2, c1}, 12, 2)
+	require.Error(t, err)
+
+	p, err := ps.BuildInitialPlacement([]placement.Instance{a1, a2, a3, b1, b2, c1}, 12, 3)
+	require.NoError(t, err)
+	require.Equal(t, 3, p.ReplicaFactor())
+	require.Equal(t, map[string]int{"z1": 2, "z2": 1}, p.ZoneReplicas())
+	_, ok := p.Instance(c1.ID())
+	require.False(t, ok)
+	assertZoneReplicas(t, p)
+
+	a4 := placement.NewEmptyInstance("a4", "r7", "z1", "endpoint", 1)
+	p, added, err := ps.AddInstances([]placement.Instance{a4, c1})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(added))
+	require.Equal(t, a4.ID(), added[0].ID())
+	assertZoneReplicas(t, p)
+	markAllInstancesAvailable(t, ps)
+
+	b3 := placement.NewEmptyInstance("b3", "r8", "z2", "endpoint", 1)
+	a5 := placement.NewEmptyInstance("a5", "r9", "z1", "endpoint", 1)
+	p, used, err := ps.ReplaceInstances([]string{b1.ID()}, []placement.Instance{c1, a5, b3})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(used))
+	require.Equal(t, b3.ID(), used[0].ID())
+	assertZoneReplicas(t, p)
+	markAllInstancesAvailable(t, ps)
+
+	// The existing service could not add a replica without changing the zone replicas.
+	_, err = ps.AddReplica()
+	require.Error(t, err)
+
+	ps = NewPlacementService(storage, opts.SetZoneReplicas(map[string]int{"z1": 2, "z2": 2}))
+	p, err = ps.AddReplica()
+	require.NoError(t, err)
+	require.Equal(t, 4, p.ReplicaFactor())
+	require.Equal(t, map[string]int{"z1": 2, "z2": 2}, p.ZoneReplicas())
+	assertZoneReplicas(t, p)
+	markAllInstancesAvailable(t, ps)
+
+	p, _, err = ps.Placement()
+	require.NoError(t, err)
+	require.NoError(t, placement.Validate(p))
+}
+
+func assertZoneReplicas(t *testing.T, p placement.Placement) {
+	for _, id := range p.Shards() {
+		counts := make(map[string]int)
+		for _, instance := range p.InstancesForShard(id) {
+			if s, ok := instance.Shards().Shard(id); ok && s.State() != shard.Leaving {
+				counts[instance.Zone()]++
+			}
+		}
+		require.Equal(t, p.ZoneReplicas(), counts, "zone replicas for shard %d", id)
+	}
+}
+
 func TestManyShards(t *testing.T) {
 	p := NewPlacementService(NewMockStorage(), placement.NewOptions().SetValidZone("z1"))
 	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint", 2)
@@ -789,6 +1328,7 @@ type mockStorage struct {
 
 	p       placement.Placement
 	version int
+	history []placement.Placement
 }
 
 func NewMockStorage() placement.Storage {
@@ -801,6 +1341,7 @@ func (ms *mockStorage) Set(p placement.Placement) error {
 
 	ms.p = p
 	ms.version++
+	ms.history = append(ms.history, p.Clone().SetVersion(ms.version))
 
 	return nil
 }
@@ -812,6 +1353,7 @@ func (ms *mockStorage) CheckAndSet(p placement.Placement, v int) error {
 	if ms.version == v {
 		ms.p = p
 		ms.version++
+		ms.history = append(ms.history, p.Clone().SetVersion(ms.version))
 	} else {
 		return errors.New("wrong version")
 	}
@@ -829,6 +1371,7 @@ func (ms *mockStorage) SetIfNotExist(p placement.Placement) error {
 
 	ms.p = p
 	ms.version = 1
+	ms.history = []placement.Placement{p.Clone().SetVersion(ms.version)}
 	return nil
 }
 
@@ -842,6 +1385,7 @@ func (ms *mockStorage) Delete() error {
 
 	ms.p = nil
 	ms.version = 0
+	ms.history = nil
 	return nil
 }
 
@@ -856,6 +1400,19 @@ func (ms *mockStorage) Placement() (placement.Placement, int, error) {
 	return nil, 0, kv.ErrNotFound
 }
 
+func (ms *mockStorage) History(from, to int) ([]placement.Placement, error) {
+	ms.Lock()
+	defer ms.Unlock()
+
+	var res []placement.Placement
+	for i := from; i < to; i++ {
+		if i > 0 && i <= len(ms.history) {
+			res = append(res, ms.history[i-1])
+		}
+	}
+	return res, nil
+}
+
 func (ms *mockStorage) CheckAndSetProto(p proto.Message, v int) error {
 	return errors.New("not implemented")
 }
@@ -868,6 +1425,33 @@ func (ms *mockStorage) Proto() (proto.Message, int, error) {
 	return nil, 0, errors.New("not implemented")
 }
 
+// conflictStorage fails the first conflicts CheckAndSet calls with a version mismatch,
+// writing the placement anyway if writeOnConflict is set to mimic an operator
+// running the same operation concurrently.
+type conflictStorage struct {
+	placement.Storage
+
+	conflicts       int
+	writeOnConflict bool
+	onConflict      func()
+}
+
+func (s *conflictStorage) CheckAndSet(p placement.Placement, v int) error {
+	if s.conflicts > 0 {
+		s.conflicts--
+		if s.onConflict != nil {
+			s.onConflict()
+		}
+		if s.writeOnConflict {
+			if err := s.Storage.CheckAndSet(p, v); err != nil {
+				return err
+			}
+		}
+		return kv.ErrVersionMismatch
+	}
+	return s.Storage.CheckAndSet(p, v)
+}
+
 func markAllInstancesAvailable(
 	t *testing.T,
 	ps placement.Service,
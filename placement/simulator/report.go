@@ -0,0 +1,169 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package simulator
+
+import (
+	"math"
+
+	"github.com/m3db/m3cluster/placement"
+	"github.com/m3db/m3cluster/placement/diff"
+	"github.com/m3db/m3cluster/shard"
+)
+
+// Report is the result of a sequence of simulated operations.
+type Report struct {
+	// Steps are the reports of the operations, in order.
+	Steps []StepReport
+
+	// Placement is the simulated placement after all the operations.
+	Placement placement.Placement
+
+	// ShardMoves are the shard moves from the initial to the simulated placement.
+	ShardMoves []diff.ShardMove
+}
+
+// StepReport is the result of a simulated operation.
+type StepReport struct {
+	// Operation describes the operation.
+	Operation string
+
+	// Placement is the placement resulting from the operation.
+	Placement placement.Placement
+
+	// ShardMoves are the shard moves made by the operation.
+	ShardMoves []diff.ShardMove
+
+	// LoadBalance is the load balance of the resulting placement.
+	LoadBalance LoadBalance
+
+	// RackSpread is the rack spread of the resulting placement.
+	RackSpread RackSpread
+}
+
+// InstanceLoad is the load of an instance.
+type InstanceLoad struct {
+	ID        string
+	Zone      string
+	Weight    uint32
+	NumShards int
+
+	// TargetShards is the number of shards the instance would own if the shards
+	// in its zone were spread in proportion to the instance weights, it is 0 for
+	// instances only owning Leaving shards.
+	TargetShards float64
+}
+
+// LoadBalance describes how evenly the shards are spread across the instances.
+// Only Initializing and Available shards count towards the load of an instance.
+type LoadBalance struct {
+	// Instances are the loads of the instances, sorted by instance id.
+	Instances []InstanceLoad
+
+	// MaxDeviation is the largest difference between the load and the target
+	// load of an instance, relative to the target load.
+	MaxDeviation float64
+}
+
+// RackSpread describes how the replicas of the shards are spread across racks.
+// Leaving shards are not counted as replicas.
+type RackSpread struct {
+	// MinRacks is the smallest number of racks the replicas of a shard are on.
+	MinRacks int
+
+	// SharedRackShards are the shards with more than one replica on a rack, sorted.
+	SharedRackShards []uint32
+}
+
+func newLoadBalance(p placement.Placement) LoadBalance {
+	var (
+		instances  = p.Instances()
+		zoneLoad   = make(map[string]int)
+		zoneWeight = make(map[string]uint32)
+		lb         = LoadBalance{Instances: make([]InstanceLoad, 0, len(instances))}
+	)
+	for _, instance := range instances {
+		load := InstanceLoad{
+			ID:        instance.ID(),
+			Zone:      instance.Zone(),
+			Weight:    instance.Weight(),
+			NumShards: numOwnedShards(instance),
+		}
+		lb.Instances = append(lb.Instances, load)
+		if isLeaving(instance) {
+			continue
+		}
+		zoneLoad[load.Zone] += load.NumShards
+		zoneWeight[load.Zone] += load.Weight
+	}
+
+	for i, load := range lb.Instances {
+		if w := zoneWeight[load.Zone]; w > 0 && !isLeaving(instances[i]) {
+			load.TargetShards = float64(zoneLoad[load.Zone]) * float64(load.Weight) / float64(w)
+		}
+		lb.Instances[i] = load
+
+		if load.TargetShards > 0 {
+			deviation := math.Abs(float64(load.NumShards)-load.TargetShards) / load.TargetShards
+			lb.MaxDeviation = math.Max(lb.MaxDeviation, deviation)
+		}
+	}
+	return lb
+}
+
+func newRackSpread(p placement.Placement) RackSpread {
+	var (
+		rs       RackSpread
+		replicas = make(map[uint32][]string)
+	)
+	for _, instance := range p.Instances() {
+		for _, s := range instance.Shards().All() {
+			if s.State() == shard.Leaving {
+				continue
+			}
+			replicas[s.ID()] = append(replicas[s.ID()], instance.Rack())
+		}
+	}
+
+	for i, shardID := range placement.SortedShardIDs(p.Shards()) {
+		racks := make(map[string]struct{})
+		for _, rack := range replicas[shardID] {
+			racks[rack] = struct{}{}
+		}
+
+		if i == 0 || len(racks) < rs.MinRacks {
+			rs.MinRacks = len(racks)
+		}
+		if len(racks) < len(replicas[shardID]) {
+			rs.SharedRackShards = append(rs.SharedRackShards, shardID)
+		}
+	}
+	return rs
+}
+
+func numOwnedShards(instance placement.Instance) int {
+	shards := instance.Shards()
+	return shards.NumShards() - shards.NumShardsForState(shard.Leaving)
+}
+
+func isLeaving(instance placement.Instance) bool {
+	shards := instance.Shards()
+	return shards.NumShards() > 0 && shards.NumShards() == shards.NumShardsForState(shard.Leaving)
+}
@@ -0,0 +1,302 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package simulator applies placement operations to a placement in memory, so
+// that an operation such as ReplaceInstances can be tried out before it is run
+// against the placement in production. Like the Dryrun option, nothing is
+// written to the placement's storage: the operations are run by a placement
+// service over an in-memory copy of the placement, so they behave exactly as
+// they would in production and can be chained. The load balance, shard moves
+// and rack spread are reported after each operation.
+package simulator
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/m3db/m3cluster/generated/proto/placementpb"
+	"github.com/m3db/m3cluster/kv/mem"
+	"github.com/m3db/m3cluster/placement"
+	"github.com/m3db/m3cluster/placement/diff"
+	"github.com/m3db/m3cluster/placement/service"
+	"github.com/m3db/m3cluster/placement/storage"
+	"github.com/m3db/m3cluster/shard"
+
+	"github.com/golang/protobuf/proto"
+)
+
+const (
+	placementKey = "placement"
+)
+
+// Simulator applies placement operations to a placement in memory.
+type Simulator interface {
+	// Placement returns the simulated placement.
+	Placement() placement.Placement
+
+	// Apply applies the operation to the simulated placement and reports the result.
+	Apply(op Operation) (StepReport, error)
+
+	// Run applies the operations in order and reports the result of each of them.
+	Run(ops []Operation) (Report, error)
+}
+
+// Operation is an operation on a placement.
+type Operation interface {
+	fmt.Stringer
+
+	apply(ps placement.Service) error
+}
+
+type simulator struct {
+	ps placement.Service
+	p  placement.Placement
+}
+
+// NewSimulator returns a simulator starting from the given placement.
+func NewSimulator(p placement.Placement, opts placement.Options) (Simulator, error) {
+	// NB: the in-memory storage must be written to for the operations to build
+	// on each other, whether or not the options are for a dry run.
+	opts = opts.SetDryrun(false)
+	ps := service.NewPlacementService(storage.NewPlacementStorage(mem.NewStore(), placementKey, opts), opts)
+	if err := ps.SetIfNotExist(p); err != nil {
+		return nil, err
+	}
+
+	current, _, err := ps.Placement()
+	if err != nil {
+		return nil, err
+	}
+	return &simulator{ps: ps, p: current}, nil
+}
+
+// NewSimulatorFromStorage returns a simulator starting from the placement in
+// the storage. The storage is only read from.
+func NewSimulatorFromStorage(s placement.Storage, opts placement.Options) (Simulator, error) {
+	p, _, err := s.Placement()
+	if err != nil {
+		return nil, err
+	}
+	return NewSimulator(p, opts)
+}
+
+// NewSimulatorFromFile returns a simulator starting from the placement in the
+// file, which holds a serialized placement proto as stored in kv.
+func NewSimulatorFromFile(path string, opts placement.Options) (Simulator, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var pb placementpb.Placement
+	if err := proto.Unmarshal(b, &pb); err != nil {
+		return nil, err
+	}
+
+	p, err := placement.NewPlacementFromProto(&pb)
+	if err != nil {
+		return nil, err
+	}
+	return NewSimulator(p, opts)
+}
+
+func (s *simulator) Placement() placement.Placement {
+	return s.p.Clone()
+}
+
+// Apply leaves the simulated placement unchanged if the operation fails,
+// including when the resulting placement is invalid, as the placement service
+// does not persist it either.
+func (s *simulator) Apply(op Operation) (StepReport, error) {
+	if err := op.apply(s.ps); err != nil {
+		return StepReport{}, fmt.Errorf("could not apply %s: %v", op.String(), err)
+	}
+
+	p, _, err := s.ps.Placement()
+	if err != nil {
+		return StepReport{}, err
+	}
+
+	step := StepReport{
+		Operation:   op.String(),
+		Placement:   p,
+		ShardMoves:  diff.Placements(s.p, p).ShardMoves,
+		LoadBalance: newLoadBalance(p),
+		RackSpread:  newRackSpread(p),
+	}
+	s.p = p
+	return step, nil
+}
+
+func (s *simulator) Run(ops []Operation) (Report, error) {
+	var (
+		initial = s.p
+		report  Report
+	)
+	for _, op := range ops {
+		step, err := s.Apply(op)
+		if err != nil {
+			return report, err
+		}
+		report.Steps = append(report.Steps, step)
+	}
+
+	report.Placement = s.Placement()
+	report.ShardMoves = diff.Placements(initial, s.p).ShardMoves
+	return report, nil
+}
+
+type addInstancesOp struct {
+	candidates []placement.Instance
+}
+
+// NewAddInstancesOp returns an operation adding instances selected from the candidates.
+func NewAddInstancesOp(candidates []placement.Instance) Operation {
+	return addInstancesOp{candidates: candidates}
+}
+
+func (op addInstancesOp) apply(ps placement.Service) error {
+	_, _, err := ps.AddInstances(op.candidates)
+	return err
+}
+
+func (op addInstancesOp) String() string {
+	return fmt.Sprintf("AddInstances(%v)", instanceIDs(op.candidates))
+}
+
+type removeInstancesOp struct {
+	instanceIDs []string
+}
+
+// NewRemoveInstancesOp returns an operation removing the instances.
+func NewRemoveInstancesOp(instanceIDs []string) Operation {
+	return removeInstancesOp{instanceIDs: instanceIDs}
+}
+
+func (op removeInstancesOp) apply(ps placement.Service) error {
+	_, err := ps.RemoveInstances(op.instanceIDs)
+	return err
+}
+
+func (op removeInstancesOp) String() string {
+	return fmt.Sprintf("RemoveInstances(%v)", op.instanceIDs)
+}
+
+type replaceInstancesOp struct {
+	leavingInstanceIDs []string
+	candidates         []placement.Instance
+}
+
+// NewReplaceInstancesOp returns an operation replacing the leaving instances with
+// instances selected from the candidates.
+func NewReplaceInstancesOp(leavingInstanceIDs []string, candidates []placement.Instance) Operation {
+	return replaceInstancesOp{leavingInstanceIDs: leavingInstanceIDs, candidates: candidates}
+}
+
+func (op replaceInstancesOp) apply(ps placement.Service) error {
+	_, _, err := ps.ReplaceInstances(op.leavingInstanceIDs, op.candidates)
+	return err
+}
+
+func (op replaceInstancesOp) String() string {
+	return fmt.Sprintf("ReplaceInstances(%v, %v)", op.leavingInstanceIDs, instanceIDs(op.candidates))
+}
+
+type addReplicaOp struct{}
+
+// NewAddReplicaOp returns an operation adding a replica.
+func NewAddReplicaOp() Operation {
+	return addReplicaOp{}
+}
+
+func (op addReplicaOp) apply(ps placement.Service) error {
+	_, err := ps.AddReplica()
+	return err
+}
+
+func (op addReplicaOp) String() string {
+	return "AddReplica()"
+}
+
+type rebalanceOp struct {
+	maxShardMoves int
+}
+
+// NewRebalanceOp returns an operation rebalancing the placement with at most
+// maxShardMoves shard moves, 0 means no limit.
+func NewRebalanceOp(maxShardMoves int) Operation {
+	return rebalanceOp{maxShardMoves: maxShardMoves}
+}
+
+func (op rebalanceOp) apply(ps placement.Service) error {
+	_, err := ps.Rebalance(op.maxShardMoves)
+	return err
+}
+
+func (op rebalanceOp) String() string {
+	return fmt.Sprintf("Rebalance(%d)", op.maxShardMoves)
+}
+
+type markAllShardsAvailableOp struct{}
+
+// NewMarkAllShardsAvailableOp returns an operation marking all the Initializing
+// shards and dropped replicas in the placement as available, as if all the
+// pending shard handoffs completed.
+func NewMarkAllShardsAvailableOp() Operation {
+	return markAllShardsAvailableOp{}
+}
+
+func (op markAllShardsAvailableOp) apply(ps placement.Service) error {
+	p, _, err := ps.Placement()
+	if err != nil {
+		return err
+	}
+
+	for _, instance := range p.Instances() {
+		if !hasShardsToMark(instance) {
+			continue
+		}
+		if err := ps.MarkInstanceAvailable(instance.ID()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (op markAllShardsAvailableOp) String() string {
+	return "MarkAllShardsAvailable()"
+}
+
+func hasShardsToMark(instance placement.Instance) bool {
+	for _, s := range instance.Shards().All() {
+		if s.State() == shard.Initializing || placement.IsDroppedReplica(instance, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func instanceIDs(instances []placement.Instance) []string {
+	ids := make([]string, len(instances))
+	for i, instance := range instances {
+		ids[i] = instance.ID()
+	}
+	return ids
+}
@@ -0,0 +1,236 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package simulator
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/m3db/m3cluster/kv/mem"
+	"github.com/m3db/m3cluster/placement"
+	"github.com/m3db/m3cluster/placement/algo"
+	"github.com/m3db/m3cluster/placement/service"
+	"github.com/m3db/m3cluster/placement/storage"
+	"github.com/m3db/m3cluster/shard"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimulatorReplaceInstances(t *testing.T) {
+	opts := placement.NewOptions().SetValidZone("z1")
+	p := testInitialPlacement(t, opts)
+
+	s, err := NewSimulator(p, opts)
+	require.NoError(t, err)
+	i1, _ := p.Instance("i1")
+	report, err := s.Run([]Operation{
+		NewReplaceInstancesOp([]string{"i1"}, []placement.Instance{
+			placement.NewEmptyInstance("i5", "r1", "z1", "endpoint5", 1),
+		}),
+		NewMarkAllShardsAvailableOp(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(report.Steps))
+
+	replace := report.Steps[0]
+	assert.Equal(t, "ReplaceInstances([i1], [i5])", replace.Operation)
+	assert.Equal(t, i1.Shards().NumShards(), len(replace.ShardMoves))
+	for _, move := range replace.ShardMoves {
+		assert.Equal(t, "i1", move.From)
+		assert.Equal(t, "i5", move.To)
+	}
+	assert.Equal(t, 0.0, replace.LoadBalance.MaxDeviation)
+	assert.Equal(t, 2, replace.RackSpread.MinRacks)
+	assert.Empty(t, replace.RackSpread.SharedRackShards)
+
+	markAvailable := report.Steps[1]
+	assert.Equal(t, "MarkAllShardsAvailable()", markAvailable.Operation)
+	_, ok := markAvailable.Placement.Instance("i1")
+	assert.False(t, ok)
+
+	assert.Equal(t, i1.Shards().NumShards(), len(report.ShardMoves))
+	for _, move := range report.ShardMoves {
+		assert.Equal(t, "i1", move.From)
+		assert.Equal(t, "i5", move.To)
+	}
+	i5, ok := report.Placement.Instance("i5")
+	require.True(t, ok)
+	assert.Equal(t, i1.Shards().AllIDs(), i5.Shards().AllIDs())
+	assert.Equal(t, i1.Shards().NumShards(), i5.Shards().NumShardsForState(shard.Available))
+
+	// The initial placement is untouched.
+	_, ok = p.Instance("i5")
+	assert.False(t, ok)
+}
+
+func TestSimulatorMatchesPlacementService(t *testing.T) {
+	opts := placement.NewOptions().
+		SetValidZone("z1").
+		SetPlacementCutoverNanosFn(func() int64 { return 1000 }).
+		SetShardCutoverNanosFn(func() int64 { return 2000 }).
+		SetShardCutoffNanosFn(func() int64 { return 3000 })
+	p := testInitialPlacement(t, opts)
+	candidates := func() []placement.Instance {
+		return []placement.Instance{placement.NewEmptyInstance("i5", "r1", "z1", "endpoint5", 1)}
+	}
+
+	ps := service.NewPlacementService(storage.NewPlacementStorage(mem.NewStore(), "placement", opts), opts)
+	require.NoError(t, ps.SetIfNotExist(p))
+	_, _, err := ps.ReplaceInstances([]string{"i1"}, candidates())
+	require.NoError(t, err)
+	expected, _, err := ps.Placement()
+	require.NoError(t, err)
+
+	s, err := NewSimulator(p, opts.SetDryrun(true))
+	require.NoError(t, err)
+	step, err := s.Apply(NewReplaceInstancesOp([]string{"i1"}, candidates()))
+	require.NoError(t, err)
+	assert.Equal(t, expected.String(), step.Placement.String())
+	assert.Equal(t, int64(1000), step.Placement.CutoverNanos())
+
+	i5, ok := step.Placement.Instance("i5")
+	require.True(t, ok)
+	for _, s := range i5.Shards().All() {
+		assert.Equal(t, int64(2000), s.CutoverNanos())
+	}
+}
+
+func TestSimulatorOperations(t *testing.T) {
+	opts := placement.NewOptions().SetValidZone("z1")
+	s, err := NewSimulator(testInitialPlacement(t, opts), opts)
+	require.NoError(t, err)
+
+	step, err := s.Apply(NewAddInstancesOp([]placement.Instance{
+		placement.NewEmptyInstance("i5", "r5", "z1", "endpoint5", 1),
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "AddInstances([i5])", step.Operation)
+	assert.NotEmpty(t, step.ShardMoves)
+	_, ok := s.Placement().Instance("i5")
+	assert.True(t, ok)
+
+	_, err = s.Apply(NewMarkAllShardsAvailableOp())
+	require.NoError(t, err)
+
+	step, err = s.Apply(NewRemoveInstancesOp([]string{"i5"}))
+	require.NoError(t, err)
+	assert.Equal(t, "RemoveInstances([i5])", step.Operation)
+	assert.NotEmpty(t, step.ShardMoves)
+
+	step, err = s.Apply(NewRebalanceOp(1))
+	require.NoError(t, err)
+	assert.Equal(t, "Rebalance(1)", step.Operation)
+
+	step, err = s.Apply(NewAddReplicaOp())
+	require.NoError(t, err)
+	assert.Equal(t, "AddReplica()", step.Operation)
+	assert.Equal(t, 3, s.Placement().ReplicaFactor())
+
+	_, err = s.Apply(NewRemoveInstancesOp([]string{"i6"}))
+	assert.Error(t, err)
+}
+
+func TestNewSimulatorFromStorage(t *testing.T) {
+	opts := placement.NewOptions().SetValidZone("z1")
+	ps := storage.NewPlacementStorage(mem.NewStore(), "key", opts)
+	_, err := NewSimulatorFromStorage(ps, opts)
+	assert.Error(t, err)
+
+	p := testInitialPlacement(t, opts)
+	require.NoError(t, ps.SetIfNotExist(p))
+
+	s, err := NewSimulatorFromStorage(ps, opts)
+	require.NoError(t, err)
+	_, err = s.Apply(NewRemoveInstancesOp([]string{"i1"}))
+	require.NoError(t, err)
+
+	stored, _, err := ps.Placement()
+	require.NoError(t, err)
+	assert.Equal(t, p.SetVersion(1), stored)
+}
+
+func TestNewSimulatorFromFile(t *testing.T) {
+	opts := placement.NewOptions().SetValidZone("z1")
+	p := testInitialPlacement(t, opts)
+	pb, err := p.Proto()
+	require.NoError(t, err)
+	b, err := proto.Marshal(pb)
+	require.NoError(t, err)
+
+	f, err := ioutil.TempFile("", "placement")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.Write(b)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	s, err := NewSimulatorFromFile(f.Name(), opts)
+	require.NoError(t, err)
+	assert.Equal(t, p.String(), s.Placement().String())
+
+	_, err = NewSimulatorFromFile(f.Name()+"-non-existent", opts)
+	assert.Error(t, err)
+}
+
+func TestLoadBalanceAndRackSpread(t *testing.T) {
+	i1 := placement.NewEmptyInstance("i1", "r1", "z1", "endpoint1", 1)
+	i1.Shards().Add(shard.NewShard(0).SetState(shard.Available))
+	i1.Shards().Add(shard.NewShard(1).SetState(shard.Available))
+	i1.Shards().Add(shard.NewShard(2).SetState(shard.Leaving))
+	i2 := placement.NewEmptyInstance("i2", "r1", "z1", "endpoint2", 1)
+	i2.Shards().Add(shard.NewShard(0).SetState(shard.Available))
+	i2.Shards().Add(shard.NewShard(2).SetState(shard.Initializing).SetSourceID("i1"))
+	i3 := placement.NewEmptyInstance("i3", "r2", "z1", "endpoint3", 2)
+	i3.Shards().Add(shard.NewShard(1).SetState(shard.Available))
+	i3.Shards().Add(shard.NewShard(2).SetState(shard.Available))
+	p := placement.NewPlacement().
+		SetInstances([]placement.Instance{i1, i2, i3}).
+		SetShards([]uint32{0, 1, 2}).
+		SetReplicaFactor(2).
+		SetIsSharded(true)
+
+	lb := newLoadBalance(p)
+	assert.Equal(t, []InstanceLoad{
+		{ID: "i1", Zone: "z1", Weight: 1, NumShards: 2, TargetShards: 1.5},
+		{ID: "i2", Zone: "z1", Weight: 1, NumShards: 2, TargetShards: 1.5},
+		{ID: "i3", Zone: "z1", Weight: 2, NumShards: 2, TargetShards: 3},
+	}, lb.Instances)
+	assert.InDelta(t, 1.0/3, lb.MaxDeviation, 1e-9)
+
+	rs := newRackSpread(p)
+	assert.Equal(t, 1, rs.MinRacks)
+	assert.Equal(t, []uint32{0}, rs.SharedRackShards)
+}
+
+func testInitialPlacement(t *testing.T, opts placement.Options) placement.Placement {
+	instances := []placement.Instance{
+		placement.NewEmptyInstance("i1", "r1", "z1", "endpoint1", 1),
+		placement.NewEmptyInstance("i2", "r2", "z1", "endpoint2", 1),
+		placement.NewEmptyInstance("i3", "r3", "z1", "endpoint3", 1),
+		placement.NewEmptyInstance("i4", "r4", "z1", "endpoint4", 1),
+	}
+	p, err := algo.NewAlgorithm(opts).InitialPlacement(instances, []uint32{0, 1, 2, 3, 4, 5, 6, 7}, 2)
+	require.NoError(t, err)
+	return p
+}
@@ -45,6 +45,9 @@ type Helper interface {
 	// PlacementProto retrieves the proto stored on kv.Store.
 	PlacementProto() (proto.Message, int, error)
 
+	// History retrieves the placements of the versions in range [from, to) stored on kv.Store.
+	History(from, to int) ([]placement.Placement, error)
+
 	// GenerateProto generates the proto message for the new placement, it may read the kv.Store
 	// if existing placement data is needed.
 	GenerateProto(p placement.Placement) (proto.Message, error)
@@ -94,6 +97,23 @@ func (h *placementHelper) PlacementProto() (proto.Message, int, error) {
 	return p, v.Version(), err
 }
 
+func (h *placementHelper) History(from, to int) ([]placement.Placement, error) {
+	values, err := h.store.History(h.key, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	ps := make([]placement.Placement, 0, len(values))
+	for _, v := range values {
+		p, err := placementFromValue(v)
+		if err != nil {
+			return nil, err
+		}
+		ps = append(ps, p)
+	}
+	return ps, nil
+}
+
 func (h *placementHelper) GenerateProto(p placement.Placement) (proto.Message, error) {
 	return p.Proto()
 }
@@ -149,6 +169,29 @@ func (h *stagedPlacementHelper) PlacementProto() (proto.Message, int, error) {
 	return ps, value.Version(), err
 }
 
+// History returns the last placement in the snapshots of each version.
+func (h *stagedPlacementHelper) History(from, to int) ([]placement.Placement, error) {
+	values, err := h.store.History(h.key, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	ps := make([]placement.Placement, 0, len(values))
+	for _, v := range values {
+		snapshots, err := placementsFromValue(v)
+		if err != nil {
+			return nil, err
+		}
+
+		l := len(snapshots)
+		if l == 0 {
+			return nil, errNoPlacementInTheSnapshots
+		}
+		ps = append(ps, snapshots[l-1])
+	}
+	return ps, nil
+}
+
 // GenerateProto generates a proto message with the placement appended to the snapshots.
 func (h *stagedPlacementHelper) GenerateProto(p placement.Placement) (proto.Message, error) {
 	ps, _, err := h.placements()
@@ -179,6 +179,50 @@ func TestCheckAndSetProto(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestHistory(t *testing.T) {
+	ps := newTestPlacementStorage(mem.NewStore(), placement.NewOptions())
+
+	p := placement.NewPlacement().
+		SetInstances([]placement.Instance{}).
+		SetShards([]uint32{}).
+		SetReplicaFactor(1)
+	require.NoError(t, ps.SetIfNotExist(p))
+	require.NoError(t, ps.CheckAndSet(p.Clone().SetReplicaFactor(2), 1))
+	require.NoError(t, ps.CheckAndSet(p.Clone().SetReplicaFactor(3), 2))
+
+	history, err := ps.History(1, 3)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(history))
+	require.Equal(t, p.Clone().SetVersion(1), history[0])
+	require.Equal(t, p.Clone().SetReplicaFactor(2).SetVersion(2), history[1])
+
+	history, err = ps.History(3, 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(history))
+	require.Equal(t, 3, history[0].ReplicaFactor())
+
+	_, err = ps.History(0, 1)
+	require.Error(t, err)
+}
+
+func TestHistoryWithPlacementSnapshots(t *testing.T) {
+	ps := newTestPlacementStorage(mem.NewStore(), placement.NewOptions().SetIsStaged(true))
+
+	p := placement.NewPlacement().
+		SetInstances([]placement.Instance{}).
+		SetShards([]uint32{}).
+		SetReplicaFactor(0).
+		SetCutoverNanos(100)
+	require.NoError(t, ps.SetIfNotExist(p))
+	require.NoError(t, ps.CheckAndSet(p.Clone().SetCutoverNanos(101), 1))
+
+	history, err := ps.History(1, 3)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(history))
+	require.Equal(t, p.Clone().SetVersion(1), history[0])
+	require.Equal(t, p.Clone().SetCutoverNanos(101).SetVersion(2), history[1])
+}
+
 func TestDryrun(t *testing.T) {
 	m := mem.NewStore()
 	dryrunPS := newTestPlacementStorage(m, placement.NewOptions().SetDryrun(true))
@@ -28,6 +28,7 @@ import (
 	"github.com/m3db/m3cluster/shard"
 	"github.com/m3db/m3x/clock"
 	"github.com/m3db/m3x/instrument"
+	"github.com/m3db/m3x/retry"
 
 	"github.com/golang/protobuf/proto"
 )
@@ -55,6 +56,12 @@ type Instance interface {
 	// SetZone sets the zone of the instance.
 	SetZone(z string) Instance
 
+	// Region is the region of the instance.
+	Region() string
+
+	// SetRegion sets the region of the instance.
+	SetRegion(r string) Instance
+
 	// Weight is the weight of the instance.
 	Weight() uint32
 
@@ -104,6 +111,28 @@ type Instance interface {
 	Clone() Instance
 }
 
+// PendingShardMove is a move of a shard from the source instance to the target
+// instance, staged until the number of Initializing shards allows it to start.
+type PendingShardMove struct {
+	ShardID  uint32
+	SourceID string
+	TargetID string
+}
+
+// InstanceShard identifies a shard on an instance.
+type InstanceShard struct {
+	InstanceID string
+	ShardID    uint32
+}
+
+// MarkShardResult is the result of marking a shard on an instance as available,
+// Err is set if the shard could not be marked, e.g. when it was rejected by the
+// IsShardCutoverFn or IsShardCutoffFn.
+type MarkShardResult struct {
+	InstanceShard
+	Err error
+}
+
 // Placement describes how instances are placed.
 type Placement interface {
 	// InstancesForShard returns the instances for a given shard id.
@@ -154,6 +183,20 @@ type Placement interface {
 	// SetIsMirrored() sets IsMirrored.
 	SetIsMirrored(v bool) Placement
 
+	// ZoneReplicas returns the number of replicas of each shard placed in
+	// each zone, it is empty unless the placement spans multiple zones.
+	ZoneReplicas() map[string]int
+
+	// SetZoneReplicas sets ZoneReplicas.
+	SetZoneReplicas(zoneReplicas map[string]int) Placement
+
+	// PendingShardMoves returns the shard moves staged until the number of
+	// Initializing shards is under the configured limits.
+	PendingShardMoves() []PendingShardMove
+
+	// SetPendingShardMoves sets PendingShardMoves.
+	SetPendingShardMoves(moves []PendingShardMove) Placement
+
 	// String returns a description of the placement
 	String() string
 
@@ -309,6 +352,20 @@ type Options interface {
 	// SetLooseRackCheck sets LooseRackCheck.
 	SetLooseRackCheck(looseRackCheck bool) Options
 
+	// IsolationLevel returns the failure domain level that replicas of
+	// a shard are spread across, defaults to rack.
+	IsolationLevel() IsolationLevel
+
+	// SetIsolationLevel sets IsolationLevel.
+	SetIsolationLevel(level IsolationLevel) Options
+
+	// IsolationMode returns how strictly the isolation level is enforced,
+	// defaults to strict.
+	IsolationMode() IsolationMode
+
+	// SetIsolationMode sets IsolationMode.
+	SetIsolationMode(mode IsolationMode) Options
+
 	// AllowPartialReplace allows shards from the leaving instance to be
 	// placed on instances other than the new instances in a replace operation
 	AllowPartialReplace() bool
@@ -359,6 +416,39 @@ type Options interface {
 	// instance.
 	SetValidZone(z string) Options
 
+	// ZoneReplicas returns the number of replicas of each shard to place in
+	// each zone. When set, a sharded placement that is not mirrored spans all
+	// the zones in the map rather than the valid zone, and its replica factor
+	// is the sum of the replicas.
+	ZoneReplicas() map[string]int
+
+	// SetZoneReplicas sets ZoneReplicas.
+	SetZoneReplicas(zoneReplicas map[string]int) Options
+
+	// MaxInitializingShardsPerInstance returns the maximum number of shards
+	// moving onto an instance at the same time, 0 means no limit. The shard
+	// moves over the limits are staged in the placement until Initializing
+	// shards are marked as available. The limits do not apply to mirrored
+	// placements.
+	MaxInitializingShardsPerInstance() int
+
+	// SetMaxInitializingShardsPerInstance sets MaxInitializingShardsPerInstance.
+	SetMaxInitializingShardsPerInstance(value int) Options
+
+	// MaxInitializingShardsPerPlacement returns the maximum number of shards
+	// moving in the placement at the same time, 0 means no limit.
+	MaxInitializingShardsPerPlacement() int
+
+	// SetMaxInitializingShardsPerPlacement sets MaxInitializingShardsPerPlacement.
+	SetMaxInitializingShardsPerPlacement(value int) Options
+
+	// ConflictRetryOptions returns the options for retrying placement updates
+	// that fail on version conflicts, nil means the updates are not retried.
+	ConflictRetryOptions() retry.Options
+
+	// SetConflictRetryOptions sets ConflictRetryOptions.
+	SetConflictRetryOptions(value retry.Options) Options
+
 	// PlacementCutoverNanosFn returns the TimeNanosFn for placement cutover time.
 	PlacementCutoverNanosFn() TimeNanosFn
 
@@ -411,6 +501,9 @@ type Storage interface {
 	// Placement reads placement and version.
 	Placement() (Placement, int, error)
 
+	// History returns the placements of the versions in range [from, to).
+	History(from, to int) ([]Placement, error)
+
 	// Delete deletes the placement.
 	Delete() error
 
@@ -436,6 +529,23 @@ type Service interface {
 	// AddReplica up the replica factor by 1 in the placement.
 	AddReplica() (Placement, error)
 
+	// Rollback changes the placement back to the placement of the given version,
+	// moving the shards back to their owners in that version with the usual
+	// Initializing and Leaving handoff.
+	Rollback(version int) (Placement, error)
+
+	// RemoveReplica reduces the replica factor by 1 in the placement.
+	RemoveReplica() (Placement, error)
+
+	// SplitShards increases the number of shards in the placement by splitting
+	// each shard into the given number of child shards.
+	SplitShards(factor int) (Placement, error)
+
+	// Rebalance moves shards from the most loaded instances to the least loaded
+	// instances, moving at most maxShardMoves shards. There is no limit on the
+	// number of moves when maxShardMoves is not positive.
+	Rebalance(maxShardMoves int) (Placement, error)
+
 	// AddInstances adds instances from the candidate list to the placement.
 	AddInstances(candidates []Instance) (newPlacement Placement, addedInstances []Instance, err error)
 
@@ -452,11 +562,20 @@ type Service interface {
 		err error,
 	)
 
+	// UpdateInstances updates the weight, endpoint, hostname and port of existing
+	// instances in the placement, shards are moved only when the weight changes.
+	UpdateInstances(instances []Instance) (Placement, error)
+
 	// MarkShardAvailable marks the state of a shard as available.
 	MarkShardAvailable(instanceID string, shardID uint32) error
 
 	// MarkInstanceAvailable marks all the shards on a given instance as available.
 	MarkInstanceAvailable(instanceID string) error
+
+	// MarkShardsAvailable marks the shards on the instances as available in a
+	// single placement update, retrying on version conflicts. The result of each
+	// shard is reported, and the shards that could not be marked are skipped.
+	MarkShardsAvailable(shards []InstanceShard) ([]MarkShardResult, error)
 }
 
 // Algorithm places shards on instances.
@@ -467,6 +586,19 @@ type Algorithm interface {
 	// AddReplica up the replica factor by 1 in the placement.
 	AddReplica(p Placement) (Placement, error)
 
+	// RemoveReplica reduces the replica factor by 1 in the placement, the dropped
+	// replicas are marked as Leaving until they are marked as available.
+	RemoveReplica(p Placement) (Placement, error)
+
+	// SplitShards splits each shard into the given number of child shards, the
+	// child shards are initially placed on the instances owning the parent shard.
+	SplitShards(p Placement, factor int) (Placement, error)
+
+	// Rebalance moves shards from the most loaded instances to the least loaded
+	// instances, moving at most maxShardMoves shards. There is no limit on the
+	// number of moves when maxShardMoves is not positive.
+	Rebalance(p Placement, maxShardMoves int) (Placement, error)
+
 	// AddInstances adds a list of instance to the placement.
 	AddInstances(p Placement, instances []Instance) (Placement, error)
 
@@ -480,6 +612,10 @@ type Algorithm interface {
 		addingInstances []Instance,
 	) (Placement, error)
 
+	// UpdateInstances updates the weight, endpoint, hostname and port of existing
+	// instances in the placement, shards are moved only when the weight changes.
+	UpdateInstances(p Placement, instances []Instance) (Placement, error)
+
 	// IsCompatibleWith checks whether the algorithm could be applied to given placement.
 	IsCompatibleWith(p Placement) error
 
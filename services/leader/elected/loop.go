@@ -0,0 +1,248 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package elected runs a function periodically on the leader of an election.
+// Any number of processes may run a Loop in the same election, only the one
+// elected leader runs the function.
+package elected
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/m3db/m3cluster/services/leader/campaign"
+
+	"github.com/uber-go/tally"
+)
+
+var (
+	// ErrAlreadyStarted is returned when starting a Loop that has already been started
+	ErrAlreadyStarted = errors.New("loop already started")
+
+	// ErrNotStarted is returned when stopping a Loop that is not running
+	ErrNotStarted = errors.New("loop not started")
+)
+
+// A Loop campaigns in an election and runs a function on an interval while
+// it is the leader. A lost campaign is followed by a new one until the Loop
+// is stopped.
+type Loop interface {
+	// Start campaigns for leadership and begins running the leader function
+	Start() error
+
+	// Stop resigns leadership and stops running the leader function. The loop
+	// stops even if resigning fails, in which case the error is returned
+	Stop() error
+}
+
+type loopState int
+
+const (
+	loopNotStarted loopState = iota
+	loopStarted
+	loopStopped
+)
+
+type loopMetrics struct {
+	leader       tally.Gauge
+	campaignErrs tally.Counter
+}
+
+func newLoopMetrics(scope tally.Scope) loopMetrics {
+	return loopMetrics{
+		leader:       scope.Gauge("leader"),
+		campaignErrs: scope.Counter("campaign-errors"),
+	}
+}
+
+type loop struct {
+	sync.RWMutex
+
+	opts     Options
+	metrics  loopMetrics
+	state    loopState
+	isLeader bool
+	doneCh   chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewLoop creates a new Loop. The metrics are reported in the scope of the
+// instrument options
+func NewLoop(opts Options) (Loop, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &loop{
+		opts:    opts,
+		metrics: newLoopMetrics(opts.InstrumentOptions().MetricsScope()),
+		doneCh:  make(chan struct{}),
+	}, nil
+}
+
+func (l *loop) Start() error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.state != loopNotStarted {
+		return ErrAlreadyStarted
+	}
+
+	statusCh, err := l.campaign()
+	if err != nil {
+		return err
+	}
+
+	l.state = loopStarted
+	l.wg.Add(2)
+	go l.watchCampaign(statusCh)
+	go l.run()
+	return nil
+}
+
+func (l *loop) Stop() error {
+	l.Lock()
+	if l.state != loopStarted {
+		l.Unlock()
+		return ErrNotStarted
+	}
+
+	l.state = loopStopped
+	close(l.doneCh)
+	l.Unlock()
+
+	// The campaign watcher exits once the loop is stopped, whether or not
+	// resigning closes the campaign status channel
+	err := l.opts.LeaderService().Resign(l.opts.ElectionID())
+	l.wg.Wait()
+	return err
+}
+
+func (l *loop) campaign() (<-chan campaign.Status, error) {
+	return l.opts.LeaderService().Campaign(l.opts.ElectionID(), l.opts.CampaignOptions())
+}
+
+func (l *loop) watchCampaign(statusCh <-chan campaign.Status) {
+	defer l.wg.Done()
+
+	log := l.opts.InstrumentOptions().Logger()
+	for {
+		if !l.watchStatus(statusCh) {
+			return
+		}
+
+		// The campaign has ended, campaign again unless the loop is stopping
+		l.setLeader(false)
+		for {
+			select {
+			case <-l.doneCh:
+				return
+			default:
+			}
+
+			ch, err := l.campaign()
+			if err == nil {
+				statusCh = ch
+				break
+			}
+
+			l.metrics.campaignErrs.Inc(1)
+			log.Errorf("could not campaign for leadership in election %s: %v", l.opts.ElectionID(), err)
+
+			select {
+			case <-l.doneCh:
+				return
+			case <-time.After(l.opts.Interval()):
+			}
+		}
+	}
+}
+
+// watchStatus follows the campaign until the status channel is closed,
+// returning false if the loop is stopped first
+func (l *loop) watchStatus(statusCh <-chan campaign.Status) bool {
+	log := l.opts.InstrumentOptions().Logger()
+	for {
+		select {
+		case <-l.doneCh:
+			l.setLeader(false)
+
+			// The status channel must be consumed until it is closed, which it
+			// only is if resigning succeeds
+			go func() {
+				for range statusCh {
+				}
+			}()
+			return false
+		case status, ok := <-statusCh:
+			if !ok {
+				return true
+			}
+
+			switch status.State {
+			case campaign.Leader:
+				l.setLeader(true)
+			case campaign.Error:
+				l.setLeader(false)
+				l.metrics.campaignErrs.Inc(1)
+				log.Errorf("error campaigning for leadership in election %s: %v", l.opts.ElectionID(), status.Err)
+			default:
+				l.setLeader(false)
+			}
+		}
+	}
+}
+
+func (l *loop) run() {
+	defer l.wg.Done()
+
+	ticker := time.NewTicker(l.opts.Interval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.doneCh:
+			return
+		case <-ticker.C:
+			if l.leader() {
+				l.opts.LeaderFn()()
+			}
+		}
+	}
+}
+
+func (l *loop) setLeader(isLeader bool) {
+	l.Lock()
+	l.isLeader = isLeader
+	l.Unlock()
+
+	if isLeader {
+		l.metrics.leader.Update(1)
+	} else {
+		l.metrics.leader.Update(0)
+	}
+}
+
+func (l *loop) leader() bool {
+	l.RLock()
+	defer l.RUnlock()
+	return l.isLeader
+}
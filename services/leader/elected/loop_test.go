@@ -0,0 +1,181 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package elected
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/m3db/m3cluster/services"
+	"github.com/m3db/m3cluster/services/leader/campaign"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+var errTestResign = errors.New("test resign error")
+
+func TestOptionsValidate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	opts := NewOptions()
+	require.Equal(t, errLeaderServiceNotSet, opts.Validate())
+
+	opts = opts.SetLeaderService(services.NewMockLeaderService(ctrl))
+	require.Equal(t, errElectionIDNotSet, opts.Validate())
+
+	opts = opts.SetElectionID("e1")
+	require.Equal(t, errCampaignOptionsNotSet, opts.Validate())
+
+	opts = opts.SetCampaignOptions(testCampaignOptions(t))
+	require.Equal(t, errLeaderFnNotSet, opts.Validate())
+
+	opts = opts.SetLeaderFn(func() {})
+	require.NoError(t, opts.Validate())
+
+	require.Equal(t, errInvalidInterval, opts.SetInterval(0).Validate())
+}
+
+func TestLoopRunsWhenLeader(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	campaignOpts := testCampaignOptions(t)
+	statusCh := make(chan campaign.Status, 2)
+	leaderSvc := services.NewMockLeaderService(ctrl)
+	leaderSvc.EXPECT().Campaign("e1", campaignOpts).Return((<-chan campaign.Status)(statusCh), nil)
+	leaderSvc.EXPECT().Resign("e1").DoAndReturn(func(string) error {
+		close(statusCh)
+		return nil
+	})
+
+	var runs testCounter
+	l, err := NewLoop(NewOptions().
+		SetLeaderService(leaderSvc).
+		SetElectionID("e1").
+		SetCampaignOptions(campaignOpts).
+		SetInterval(time.Millisecond).
+		SetLeaderFn(runs.inc))
+	require.NoError(t, err)
+	require.NoError(t, l.Start())
+	require.Equal(t, ErrAlreadyStarted, l.Start())
+
+	// Followers never run the function
+	statusCh <- campaign.NewStatus(campaign.Follower)
+	time.Sleep(20 * time.Millisecond)
+	require.Equal(t, 0, runs.get())
+
+	statusCh <- campaign.NewStatus(campaign.Leader)
+	for runs.get() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	require.NoError(t, l.Stop())
+	require.Equal(t, ErrNotStarted, l.Stop())
+}
+
+func TestLoopRecampaigns(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	firstCh := make(chan campaign.Status)
+	secondCh := make(chan campaign.Status)
+	recampaigned := make(chan struct{})
+
+	leaderSvc := services.NewMockLeaderService(ctrl)
+	gomock.InOrder(
+		leaderSvc.EXPECT().Campaign("e1", gomock.Any()).Return((<-chan campaign.Status)(firstCh), nil),
+		leaderSvc.EXPECT().Campaign("e1", gomock.Any()).DoAndReturn(
+			func(string, services.CampaignOptions) (<-chan campaign.Status, error) {
+				close(recampaigned)
+				return secondCh, nil
+			}),
+	)
+	leaderSvc.EXPECT().Resign("e1").DoAndReturn(func(string) error {
+		close(secondCh)
+		return nil
+	})
+
+	l, err := NewLoop(testOptions(t, leaderSvc))
+	require.NoError(t, err)
+	require.NoError(t, l.Start())
+
+	// Losing the campaign results in a new one
+	close(firstCh)
+	<-recampaigned
+
+	require.NoError(t, l.Stop())
+}
+
+func TestLoopStopsWhenResignFails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	statusCh := make(chan campaign.Status)
+	leaderSvc := services.NewMockLeaderService(ctrl)
+	leaderSvc.EXPECT().Campaign("e1", gomock.Any()).Return((<-chan campaign.Status)(statusCh), nil)
+	leaderSvc.EXPECT().Resign("e1").Return(errTestResign)
+
+	l, err := NewLoop(testOptions(t, leaderSvc))
+	require.NoError(t, err)
+	require.NoError(t, l.Start())
+
+	// The status channel is not closed, but stopping still completes and the
+	// channel is still consumed
+	require.Equal(t, errTestResign, l.Stop())
+	statusCh <- campaign.NewStatus(campaign.Follower)
+}
+
+func testOptions(t *testing.T, leaderSvc services.LeaderService) Options {
+	return NewOptions().
+		SetLeaderService(leaderSvc).
+		SetElectionID("e1").
+		SetCampaignOptions(testCampaignOptions(t)).
+		SetInterval(time.Millisecond).
+		SetLeaderFn(func() {})
+}
+
+func testCampaignOptions(t *testing.T) services.CampaignOptions {
+	opts, err := services.NewCampaignOptions()
+	require.NoError(t, err)
+	return opts
+}
+
+type testCounter struct {
+	sync.Mutex
+
+	n int
+}
+
+func (c *testCounter) inc() {
+	c.Lock()
+	c.n++
+	c.Unlock()
+}
+
+func (c *testCounter) get() int {
+	c.Lock()
+	defer c.Unlock()
+	return c.n
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package elected
+
+import (
+	"errors"
+	"time"
+
+	"github.com/m3db/m3cluster/services"
+	"github.com/m3db/m3x/instrument"
+)
+
+const (
+	defaultInterval = 10 * time.Second
+)
+
+var (
+	errLeaderServiceNotSet   = errors.New("leader service must be specified")
+	errElectionIDNotSet      = errors.New("election id must be specified")
+	errCampaignOptionsNotSet = errors.New("campaign options must be specified")
+	errLeaderFnNotSet        = errors.New("leader function must be specified")
+	errInvalidInterval       = errors.New("interval must be positive")
+)
+
+// Options are options used in creating a new Loop
+type Options interface {
+	// LeaderService is the service used to elect the leader
+	LeaderService() services.LeaderService
+	SetLeaderService(value services.LeaderService) Options
+
+	// ElectionID is the election to campaign in
+	ElectionID() string
+	SetElectionID(value string) Options
+
+	// CampaignOptions are the options used when campaigning for leadership
+	CampaignOptions() services.CampaignOptions
+	SetCampaignOptions(value services.CampaignOptions) Options
+
+	// Interval is how often the leader runs the LeaderFn, and how long to wait
+	// before campaigning again after a failed campaign
+	Interval() time.Duration
+	SetInterval(value time.Duration) Options
+
+	// LeaderFn is run on every interval while the loop is the leader
+	LeaderFn() func()
+	SetLeaderFn(value func()) Options
+
+	// InstrumentOptions are the instrument options
+	InstrumentOptions() instrument.Options
+	SetInstrumentOptions(value instrument.Options) Options
+
+	// Validate validates the options
+	Validate() error
+}
+
+type options struct {
+	leaderService  services.LeaderService
+	electionID     string
+	campaignOpts   services.CampaignOptions
+	interval       time.Duration
+	leaderFn       func()
+	instrumentOpts instrument.Options
+}
+
+// NewOptions creates a new set of Options
+func NewOptions() Options {
+	return &options{
+		interval:       defaultInterval,
+		instrumentOpts: instrument.NewOptions(),
+	}
+}
+
+func (o *options) LeaderService() services.LeaderService {
+	return o.leaderService
+}
+
+func (o *options) SetLeaderService(value services.LeaderService) Options {
+	opts := *o
+	opts.leaderService = value
+	return &opts
+}
+
+func (o *options) ElectionID() string {
+	return o.electionID
+}
+
+func (o *options) SetElectionID(value string) Options {
+	opts := *o
+	opts.electionID = value
+	return &opts
+}
+
+func (o *options) CampaignOptions() services.CampaignOptions {
+	return o.campaignOpts
+}
+
+func (o *options) SetCampaignOptions(value services.CampaignOptions) Options {
+	opts := *o
+	opts.campaignOpts = value
+	return &opts
+}
+
+func (o *options) Interval() time.Duration {
+	return o.interval
+}
+
+func (o *options) SetInterval(value time.Duration) Options {
+	opts := *o
+	opts.interval = value
+	return &opts
+}
+
+func (o *options) LeaderFn() func() {
+	return o.leaderFn
+}
+
+func (o *options) SetLeaderFn(value func()) Options {
+	opts := *o
+	opts.leaderFn = value
+	return &opts
+}
+
+func (o *options) InstrumentOptions() instrument.Options {
+	return o.instrumentOpts
+}
+
+func (o *options) SetInstrumentOptions(value instrument.Options) Options {
+	opts := *o
+	opts.instrumentOpts = value
+	return &opts
+}
+
+func (o *options) Validate() error {
+	if o.leaderService == nil {
+		return errLeaderServiceNotSet
+	}
+
+	if o.electionID == "" {
+		return errElectionIDNotSet
+	}
+
+	if o.campaignOpts == nil {
+		return errCampaignOptionsNotSet
+	}
+
+	if o.leaderFn == nil {
+		return errLeaderFnNotSet
+	}
+
+	if o.interval <= 0 {
+		return errInvalidInterval
+	}
+
+	return nil
+}